@@ -1,40 +1,395 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	"gopkg.in/yaml.v3"
 
 	"tripwire/pkg/client"
+	"tripwire/pkg/events"
+	"tripwire/pkg/metrics"
+	"tripwire/pkg/notify"
 	"tripwire/pkg/policy"
+	"tripwire/pkg/reaction"
+	"tripwire/pkg/results"
 	"tripwire/pkg/server"
+	"tripwire/pkg/tracing"
 	"tripwire/pkg/util"
 )
 
 type Config struct {
+	// Description and Tags describe the scenario in its results, so that stored runs can be filtered and organized
+	Description string   `yaml:"description"`
+	Tags        []string `yaml:"tags"`
+
 	Client     *client.Config `yaml:"client"`
 	Server     *server.Config `yaml:"server"`
 	Strategies []*Strategy    `yaml:"strategies"`
+
+	// Baseline runs an implicit strategy without any policies, named baseline, as the control that the results of the
+	// other strategies are compared to
+	Baseline bool `yaml:"baseline"`
+
+	// Reaction optionally applies a step change to each strategy and measures how it reacts
+	Reaction *reaction.Config `yaml:"reaction"`
+
+	// SharedRateLimiters declares named rate limiters that the policies of multiple strategies can share, such as to model
+	// a quota that's enforced by a common upstream
+	SharedRateLimiters map[string]*policy.RateLimiterConfig `yaml:"shared_ratelimiters"`
+
+	// Bursts inject sudden spikes of load into each strategy's run, separately from any stage transitions
+	Bursts []*client.BurstConfig `yaml:"bursts"`
+
+	// Output configures the directory that run results are written to
+	Output *results.Config `yaml:"output"`
+
+	// Sequential configures how staged strategies are run one after another
+	Sequential *SequentialConfig `yaml:"sequential"`
+
+	// Listeners configures the addresses that tripwire listens on
+	Listeners *ListenersConfig `yaml:"listeners"`
+
+	// Metrics configures how metrics are exposed to scrapers
+	Metrics *metrics.Config `yaml:"metrics"`
+
+	// Notifications configures where the run's summary is sent when the run ends
+	Notifications *notify.Config `yaml:"notifications"`
+
+	// Tracing exports spans for each request to an OpenTelemetry collector
+	Tracing *tracing.Config `yaml:"tracing"`
+
+	// Seed seeds random service times, arrivals, and faults, but not policies. A seed is generated if none is configured.
+	Seed          int64 `yaml:"seed"`
+	seedGenerated bool
+
+	// Seeds overrides the seeds of individual sources of randomness
+	Seeds *SeedsConfig `yaml:"seeds"`
+
+	// Shard is the share of the scenario's load that this process runs, if any, which is set by the --shard flag
+	Shard *Shard `yaml:"-"`
+}
+
+type SequentialConfig struct {
+	// ReuseServer keeps the same server running across strategies, rather than starting a new one for each, so that
+	// comparisons exclude cold-start differences
+	ReuseServer bool `yaml:"reuse_server"`
+
+	// Cooldown is how long to wait between strategies. When CooldownUntil is set, it's the max time to wait.
+	Cooldown time.Duration `yaml:"cooldown"`
+
+	// CooldownUntil ends the cooldown early once a condition is met. The only supported condition is "idle", which is
+	// met once the previous strategy's client and server have no inflight requests.
+	CooldownUntil string `yaml:"cooldown_until"`
+
+	// WatchdogMargin is how much longer than its expected duration a strategy can run, such as when its shutdown or
+	// drain hangs, before it's aborted and the next strategy is run. 0 disables the watchdog.
+	WatchdogMargin time.Duration `yaml:"watchdog_margin"`
+}
+
+// ListenersConfig configures the addresses that tripwire's listeners bind to. An address with a port of 0, such as
+// "127.0.0.1:0", listens on a free port, which is reported by the /status API and in the run's results, so that runs
+// don't collide on fixed ports in containers and on CI runners.
+type ListenersConfig struct {
+	Metrics string `yaml:"metrics"` // the Prometheus metrics endpoint
+	Config  string `yaml:"config"`  // the REST API for updating workloads and servers
+	Server  string `yaml:"server"`  // the simulated servers, which always listen on a free port by default
+}
+
+func (c *ListenersConfig) UnmarshalYAML(value *yaml.Node) error {
+	*c = ListenersConfig{
+		Metrics: ":8080",
+		Config:  ":9095",
+		Server:  ":0",
+	}
+	type Alias ListenersConfig
+	var alias = Alias(*c)
+	if err := value.Decode(&alias); err != nil {
+		return err
+	}
+	*c = ListenersConfig(alias)
+	return nil
+}
+
+// Validate checks that the addresses are valid. Parallel strategies each run their own server, so their servers can't
+// share a fixed port.
+func (c *ListenersConfig) Validate(parallel bool) error {
+	for name, addr := range map[string]string{"metrics": c.Metrics, "config": c.Config, "server": c.Server} {
+		if _, port, err := net.SplitHostPort(addr); err != nil {
+			return fmt.Errorf("invalid %s listener address %q: %w", name, addr, err)
+		} else if name == "server" && parallel && port != "0" {
+			return fmt.Errorf("server listener port must be 0 when strategies run in parallel")
+		}
+	}
+	return nil
+}
+
+// overridePorts replaces the ports of the metrics and config listeners, keeping their hosts. Negative ports aren't
+// overridden.
+func (c *ListenersConfig) overridePorts(metricsPort int, configPort int) error {
+	for _, override := range []struct {
+		addr *string
+		port int
+	}{{&c.Metrics, metricsPort}, {&c.Config, configPort}} {
+		if override.port < 0 {
+			continue
+		}
+		if override.port > 65535 {
+			return fmt.Errorf("invalid port: %d", override.port)
+		}
+		host, _, err := net.SplitHostPort(*override.addr)
+		if err != nil {
+			return err
+		}
+		*override.addr = net.JoinHostPort(host, strconv.Itoa(override.port))
+	}
+	return nil
+}
+
+const CooldownUntilIdle = "idle"
+
+func (c *SequentialConfig) UnmarshalYAML(value *yaml.Node) error {
+	*c = SequentialConfig{
+		Cooldown: 5 * time.Second,
+	}
+	type Alias SequentialConfig
+	var alias = Alias(*c)
+	if err := value.Decode(&alias); err != nil {
+		return err
+	}
+	*c = SequentialConfig(alias)
+	return nil
 }
 
 type Strategy struct {
-	Name           string         `yaml:"name"`
-	ClientPolicies policy.Configs `yaml:"client_policies"`
-	ServerPolicies policy.Configs `yaml:"server_policies"`
+	Name               string         `yaml:"name"`
+	Description        string         `yaml:"description"`
+	Tags               []string       `yaml:"tags"`
+	ClientPolicies     policy.Configs `yaml:"client_policies"`
+	ServerPolicies     policy.Configs `yaml:"server_policies"`
+	DownstreamPolicies policy.Configs `yaml:"downstream_policies"` // guard the server's calls to its downstream, if any
+
+	// Prioritizers declares named prioritizers, which prioritized policies can be bound to for independent priority domains
+	Prioritizers map[string]*policy.PrioritizerConfig `yaml:"prioritizers"`
+
+	// LogLevel overrides the log level for the strategy, such as debug to log its policies' decisions
+	LogLevel *zapcore.Level `yaml:"log_level"`
+
+	// Parameters are the swept parameter values that the strategy was generated with, by path, if any
+	Parameters map[string]string `yaml:"-"`
+
+	// Control is whether the strategy is the control that other strategies are compared to, such as the implicit
+	// baseline. A control is pinned to the configured workloads or stages, without bursts, reaction steps, or workload
+	// and server updates, so that it's an untouched reference.
+	Control bool `yaml:"control"`
 }
 
+// BaselineStrategy is the name of the implicit strategy without any policies that's run when the baseline is enabled.
+const BaselineStrategy = "baseline"
+
 func parseConfig(configData []byte) (*Config, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(configData, &doc); err != nil {
+		return &Config{}, err
+	}
+	sweepParameters, err := expandSweep(&doc)
+	if err != nil {
+		return &Config{}, err
+	}
 	var result Config
-	err := yaml.Unmarshal(configData, &result)
+	if sweepParameters == nil {
+		err = yaml.Unmarshal(configData, &result)
+	} else {
+		err = doc.Decode(&result)
+	}
 	if err != nil {
 		return &Config{}, err
 	}
+	for _, strategy := range result.Strategies {
+		strategy.Parameters = sweepParameters[strategy.Name]
+	}
+	if result.Baseline {
+		for _, strategy := range result.Strategies {
+			if strategy.Name == BaselineStrategy {
+				return &Config{}, fmt.Errorf("strategy name %s is reserved when the baseline is enabled", BaselineStrategy)
+			}
+		}
+		baseline := &Strategy{Name: BaselineStrategy, Description: "no policies", Control: true}
+		result.Strategies = append([]*Strategy{baseline}, result.Strategies...)
+	}
+	var control string
+	for _, strategy := range result.Strategies {
+		if strategy.Control {
+			if control != "" {
+				return &Config{}, fmt.Errorf("strategies %s and %s cannot both be the control", control, strategy.Name)
+			}
+			control = strategy.Name
+		}
+	}
+
+	if result.Sequential == nil {
+		result.Sequential = &SequentialConfig{Cooldown: 5 * time.Second}
+	}
+	if until := result.Sequential.CooldownUntil; until != "" && until != CooldownUntilIdle {
+		return &Config{}, fmt.Errorf("invalid cooldown_until condition: %s", until)
+	}
+	if result.Sequential.WatchdogMargin < 0 {
+		return &Config{}, fmt.Errorf("watchdog_margin cannot be negative")
+	}
+	if result.Output == nil {
+		result.Output = &results.Config{Dir: "results"}
+	}
+	if err = result.Output.Validate(); err != nil {
+		return &Config{}, err
+	}
+	if result.Listeners == nil {
+		result.Listeners = &ListenersConfig{Metrics: ":8080", Config: ":9095", Server: ":0"}
+	}
+	if err = result.Listeners.Validate(len(result.Client.Workloads) > 0 && len(result.Strategies) > 1); err != nil {
+		return &Config{}, err
+	}
+	result.Server.Addr = result.Listeners.Server
+	if result.Seed == 0 {
+		result.Seed = time.Now().UnixNano()
+		result.seedGenerated = true
+	}
+	if result.Seeds == nil {
+		result.Seeds = &SeedsConfig{}
+	}
+	if err = result.Seeds.Validate(); err != nil {
+		return &Config{}, err
+	}
+	result.Seeds.resolve(result.Seed)
+	result.Seeds.apply(&result)
 
-	configureWorkloads(result.Client.Workloads)
+	if err = validateWorkloads(result.Client); err != nil {
+		return &Config{}, err
+	}
+	if err = client.ValidateBursts(result.Bursts, workloadNames(&result)); err != nil {
+		return &Config{}, err
+	}
+	result.Client.Bursts = result.Bursts
+	if result.Client.Transport != nil {
+		if err = result.Client.Transport.Validate(); err != nil {
+			return &Config{}, err
+		}
+	}
+	if result.Server.HTTP2 == nil && result.Server.ExternalURL == "" {
+		if result.Client.Transport.H2C() {
+			return &Config{}, fmt.Errorf("h2c transport requires server http2")
+		}
+		for _, w := range result.Client.Workloads {
+			if w.Transport.H2C() {
+				return &Config{}, fmt.Errorf("workload %s: h2c transport requires server http2", w.Name)
+			}
+		}
+	}
+	if result.Client.Hooks != nil {
+		if err = result.Client.Hooks.Validate(); err != nil {
+			return &Config{}, err
+		}
+	}
+	if result.Client.HostBulkhead != nil {
+		if err = result.Client.HostBulkhead.Validate(); err != nil {
+			return &Config{}, err
+		}
+	}
+	if result.Client.SelfProtection != nil {
+		if err = result.Client.SelfProtection.Validate(); err != nil {
+			return &Config{}, err
+		}
+	}
+	if result.Client.Regions != nil {
+		if err = result.Client.Regions.Validate(result.Client.Workloads); err != nil {
+			return &Config{}, err
+		}
+		for _, region := range result.Client.Regions.Regions {
+			if region.Threads > server.MaxThreads {
+				return &Config{}, fmt.Errorf("region %s threads cannot exceed %d", region.Name, server.MaxThreads)
+			}
+		}
+	}
+	if result.Server.ExternalURL != "" {
+		if err = validateExternalURL(&result); err != nil {
+			return &Config{}, err
+		}
+	}
+	if result.Server.Threads > server.MaxThreads {
+		return &Config{}, fmt.Errorf("server threads cannot exceed %d", server.MaxThreads)
+	}
+	if _, err = server.NewWorkModel(result.Server.WorkModel); err != nil {
+		return &Config{}, err
+	}
+	if result.Notifications != nil {
+		if err = result.Notifications.Validate(); err != nil {
+			return &Config{}, err
+		}
+	}
+	if result.Metrics != nil {
+		if err = result.Metrics.Validate(); err != nil {
+			return &Config{}, err
+		}
+	}
+	if result.Tracing != nil {
+		if err = result.Tracing.Validate(); err != nil {
+			return &Config{}, err
+		}
+	}
+	if result.Server.Autoscaler != nil {
+		if err = result.Server.Autoscaler.Validate(); err != nil {
+			return &Config{}, err
+		}
+	}
+	if result.Server.Faults != nil {
+		if err = result.Server.Faults.Validate(); err != nil {
+			return &Config{}, err
+		}
+	}
+	if err = server.ValidateEndpoints(result.Server.Endpoints); err != nil {
+		return &Config{}, err
+	}
+	if len(result.Server.Endpoints) > 0 {
+		for _, workload := range result.Client.Workloads {
+			if method, path := workload.Endpoint(); server.Route(result.Server.Endpoints, method, path) == nil {
+				return &Config{}, fmt.Errorf("workload %s: no server endpoint for %s %s", workload.Name, method, path)
+			}
+		}
+		if len(result.Client.Stages) > 0 && server.Route(result.Server.Endpoints, http.MethodPost, "/") == nil {
+			return &Config{}, fmt.Errorf("stages require a server endpoint for POST /")
+		}
+	}
+	if result.Server.Async != nil && result.Server.Async.JobTTL <= 0 {
+		return &Config{}, fmt.Errorf("server async job_ttl must be positive")
+	}
+	if result.Server.Delivery != nil {
+		if err = result.Server.Delivery.Validate(result.Server.Async != nil || result.Server.Streaming != nil); err != nil {
+			return &Config{}, err
+		}
+	}
+	if err = policy.ValidateSharedRateLimiters(result.SharedRateLimiters); err != nil {
+		return &Config{}, err
+	}
+	for _, strategy := range result.Strategies {
+		if err = policy.ValidatePrioritizers(strategy.Prioritizers); err != nil {
+			return &Config{}, fmt.Errorf("strategy %s: %w", strategy.Name, err)
+		}
+		for _, policies := range []policy.Configs{strategy.ClientPolicies, strategy.ServerPolicies, strategy.DownstreamPolicies} {
+			if err = policies.Validate(strategy.Prioritizers, result.SharedRateLimiters); err != nil {
+				return &Config{}, fmt.Errorf("strategy %s: %w", strategy.Name, err)
+			}
+		}
+	}
 	var previousStage *client.Stage
 	for _, stage := range result.Client.Stages {
 		// Carry over RPS and service times from one stage to another if needed
@@ -42,16 +397,57 @@ func parseConfig(configData []byte) (*Config, error) {
 			if stage.RPS == 0 {
 				stage.RPS = previousStage.RPS
 			}
+			if stage.Ramping() && stage.RPSStart == 0 {
+				stage.RPSStart = previousStage.RPS
+			}
 			if stage.ServiceTimes == nil {
 				stage.ServiceTimes = previousStage.ServiceTimes
 			}
 		}
-		result.Client.MaxDuration += stage.Duration
+		if err = stage.Validate(); err != nil {
+			return &Config{}, err
+		}
+		if stage.Concurrency != 0 || stage.RampingUsers() {
+			return &Config{}, fmt.Errorf("client stages don't support concurrency, which requires a closed-loop workload")
+		}
+		if stage.RPS == 0 && !stage.Ramping() && (result.Client.Generator == nil || result.Client.Generator.Type != "trace") {
+			return &Config{}, fmt.Errorf("stage rps must be positive unless a trace generator is used")
+		}
+		// Ramped stages carry over the RPS that they ramp to
+		if stage.Ramping() {
+			stage.RPS = stage.RPSEnd
+		}
+		result.Client.MaxDuration += stage.Duration + stage.Drain
 		stage.WeightSum = int(stage.ServiceTimes.Sum())
 		previousStage = stage
 	}
-	if result.Client.MaxDuration != 0 {
-		result.Server.Duration = result.Client.MaxDuration
+	if err = client.NormalizeStageSLOs(result.Client.StageSLOs); err != nil {
+		return &Config{}, err
+	}
+	for _, slo := range result.Client.StageSLOs {
+		// Client stages are sent as the staged workload, and other workloads can have their own stages
+		stages := len(result.Client.Stages)
+		if slo.Workload != "" && slo.Workload != "staged" {
+			stages = 0
+		}
+		for _, workload := range result.Client.Workloads {
+			if slo.Workload == "" || slo.Workload == workload.Name {
+				stages = max(stages, len(workload.Stages))
+			}
+		}
+		if slo.Workload != "" && stages == 0 {
+			return &Config{}, fmt.Errorf("stage slo %s: workload %s doesn't have stages", slo.Name, slo.Workload)
+		}
+		if slo.Stage >= stages {
+			return &Config{}, fmt.Errorf("stage slo %s: there is no stage %d", slo.Name, slo.Stage)
+		}
+	}
+	if result.Sequential.ReuseServer && result.Client.MaxDuration != 0 {
+		// Keep the server up until every strategy has run
+		result.Server.Duration = 0
+	} else if result.Client.MaxDuration != 0 {
+		// Keep the server up while the client drains
+		result.Server.Duration = result.Client.MaxDuration + result.Client.Drain
 	} else {
 		result.Server.Duration = 24 * time.Hour
 	}
@@ -59,47 +455,350 @@ func parseConfig(configData []byte) (*Config, error) {
 	return &result, nil
 }
 
-func configureWorkloads(workloads []*client.Workload) {
-	for _, workload := range workloads {
-		workload.WeightSum = int(workload.ServiceTimes.Sum())
+// filterStrategies retains only the config's strategies with the names, in their configured order, along with the
+// baseline, if it's enabled.
+func filterStrategies(config *Config, names []string) error {
+	selected := make(map[string]bool)
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			selected[name] = true
+		}
+	}
+	var strategies []*Strategy
+	for _, strategy := range config.Strategies {
+		if selected[strategy.Name] || strategy.Control {
+			strategies = append(strategies, strategy)
+			delete(selected, strategy.Name)
+		}
+	}
+	for name := range selected {
+		return fmt.Errorf("unknown strategy: %s", name)
 	}
+	config.Strategies = strategies
+	return nil
 }
 
-func NewConfigServer(clients []*client.Client, servers []*server.Server, logger *zap.SugaredLogger) *util.Server {
+// validateWorkloads normalizes the client's workloads and checks that their generators can be created.
+func validateWorkloads(config *client.Config) error {
+	if err := client.NormalizeWorkloads(config.Workloads, config.Generator); err != nil {
+		return err
+	}
+	configs := []*client.GeneratorConfig{config.Generator}
+	for _, workload := range config.Workloads {
+		configs = append(configs, workload.Generator)
+	}
+	for _, generatorConfig := range configs {
+		if _, err := client.NewGenerator(generatorConfig); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateExternalURL validates a server's external URL, and that the config doesn't require or configure an embedded
+// server.
+func validateExternalURL(config *Config) error {
+	u, err := url.Parse(config.Server.ExternalURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid server external_url: %s", config.Server.ExternalURL)
+	}
+	if config.Client.Regions != nil {
+		return fmt.Errorf("server external_url cannot be used with regions")
+	}
+	if config.Reaction != nil {
+		return fmt.Errorf("server external_url cannot be used with reaction")
+	}
+	// Server settings configure the embedded server, so they'd be silently ignored
+	srv := config.Server
+	for _, setting := range []struct {
+		name string
+		set  bool
+	}{
+		{"prioritize", srv.Prioritize},
+		{"threads", srv.Threads != 0},
+		{"enforce_deadline", srv.EnforceDeadline},
+		{"clock_skew", srv.ClockSkew != 0},
+		{"work_model", srv.WorkModel != nil},
+		{"downstream", srv.Downstream != nil},
+		{"async", srv.Async != nil},
+		{"streaming", srv.Streaming != nil},
+		{"delivery", srv.Delivery != nil},
+		{"faults", srv.Faults != nil},
+		{"client_limits", srv.ClientLimits != nil},
+		{"endpoints", len(srv.Endpoints) > 0},
+		{"autoscaler", srv.Autoscaler != nil},
+		{"trace_decisions", srv.TraceDecisions},
+		{"http2", srv.HTTP2 != nil},
+		{"deduplication", srv.Deduplication != nil},
+	} {
+		if setting.set {
+			return fmt.Errorf("server external_url cannot be used with server %s", setting.name)
+		}
+	}
+	for _, strategy := range config.Strategies {
+		if len(strategy.ServerPolicies) > 0 {
+			return fmt.Errorf("strategy %s: server external_url cannot be used with server_policies", strategy.Name)
+		}
+		if len(strategy.DownstreamPolicies) > 0 {
+			return fmt.Errorf("strategy %s: server external_url cannot be used with downstream_policies", strategy.Name)
+		}
+	}
+	return nil
+}
+
+// Status reports the addresses that tripwire is listening on, which may have been chosen at runtime, and a snapshot of
+// each strategy's current config, which reflects any runtime updates.
+type Status struct {
+	Listeners map[string]string          `json:"listeners"`
+	Servers   map[string]string          `json:"servers"` // by strategy
+	Configs   map[string]*ConfigSnapshot `json:"configs"` // by strategy
+}
+
+// ConfigSnapshot is the config that a strategy's client and server are currently running with.
+type ConfigSnapshot struct {
+	Workloads []*client.Workload `json:"workloads,omitempty"`
+	Server    *server.Config     `json:"server,omitempty"` // omitted for an external server
+}
+
+func NewConfigServer(addr string, metricsAddr string, clients []*client.Client, servers []*server.Server, strategyChains map[string]map[string]policy.Chain, shard *Shard, eventLog *events.Log,
+	logger *zap.SugaredLogger) *util.Server {
 	mux := http.NewServeMux()
+	configServer := util.NewServer(mux, addr, logger)
+	var chainsMtx sync.Mutex // Guards strategyChains, which are replaced when policies are enabled or disabled
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			status := &Status{
+				Listeners: map[string]string{"metrics": metricsAddr, "config": configServer.Addr()},
+				Servers:   make(map[string]string),
+				Configs:   make(map[string]*ConfigSnapshot),
+			}
+			for i, srv := range servers {
+				snapshot := &ConfigSnapshot{Workloads: clients[i].Workloads()}
+				if srv != nil {
+					status.Servers[clients[i].Strategy()] = srv.Addr().String()
+					snapshot.Server = srv.Config()
+				} else {
+					status.Servers[clients[i].Strategy()] = clients[i].ServerURL()
+				}
+				status.Configs[clients[i].Strategy()] = snapshot
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(status)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/policies", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			chainsMtx.Lock()
+			defer chainsMtx.Unlock()
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(strategyChains)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/policies/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			chainsMtx.Lock()
+			defer chainsMtx.Unlock()
+			updatePolicyEnabled(clients, strategyChains, eventLog, w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
 	mux.HandleFunc("/client/workloads", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost {
-			updateClients(clients, w, r)
+			updateClients(clients, shard, eventLog, w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	})
+	mux.HandleFunc("/client/workloads/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			updateWorkloadRPS(clients, shard, eventLog, w, r)
+		case http.MethodPost:
+			updateWorkloadPaused(clients, eventLog, w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
 	mux.HandleFunc("/server", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost {
-			updateServers(servers, w, r)
+			updateServers(clients, servers, eventLog, w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	})
-	return util.NewServer(mux, 9095, logger)
+	return configServer
 }
 
-func updateClients(clients []*client.Client, w http.ResponseWriter, r *http.Request) {
+// updateClients updates the workloads of the clients that aren't pinned or stopped. When sharded, updated workloads
+// describe the scenario's total load, which is split the same way as the configured workloads.
+func updateClients(clients []*client.Client, shard *Shard, eventLog *events.Log, w http.ResponseWriter, r *http.Request) {
 	var workloads []*client.Workload
 	if parseConfigUpdate(w, r, &workloads) {
-		configureWorkloads(workloads)
+		if err := validateWorkloads(&client.Config{Workloads: workloads}); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if shard != nil {
+			if err := shard.splitWorkloads(workloads, nil); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		eventLog.Record(events.ConfigUpdated, "", "", map[string]any{"target": "client", "workloads": workloads})
 		for _, cl := range clients {
-			cl.UpdateWorkloads(workloads)
+			if cl.Pinned() {
+				continue
+			}
+			if err := cl.UpdateWorkloads(workloads); err != nil && !errors.Is(err, client.ErrStopped) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
 		}
 		fmt.Fprintf(w, "Client config updated successfully\n")
 	}
 }
 
-func updateServers(servers []*server.Server, w http.ResponseWriter, r *http.Request) {
+// updateWorkloadRPS handles PUT /client/workloads/{name}/rps, where the body is the workload's new RPS as plain text. When
+// sharded, the RPS is the scenario's total, which is split the same way as the configured workloads.
+func updateWorkloadRPS(clients []*client.Client, shard *Shard, eventLog *events.Log, w http.ResponseWriter, r *http.Request) {
+	name, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/client/workloads/"), "/rps")
+	if !ok || name == "" || strings.Contains(name, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+	rps, err := strconv.ParseUint(strings.TrimSpace(string(body)), 10, 32)
+	if err != nil || rps == 0 {
+		http.Error(w, "RPS must be a positive integer", http.StatusBadRequest)
+		return
+	}
+	if shard != nil {
+		if uint(rps) < shard.Count {
+			http.Error(w, fmt.Sprintf("RPS %d cannot be split across %d shards", rps, shard.Count), http.StatusBadRequest)
+			return
+		}
+		rps = uint64(shard.rps(uint(rps)))
+	}
+
+	var found bool
+	for _, cl := range clients {
+		if !cl.Pinned() {
+			found = cl.SetWorkloadRPS(name, uint(rps)) || found
+		}
+	}
+	if !found {
+		http.Error(w, fmt.Sprintf("Unknown workload: %s", name), http.StatusNotFound)
+		return
+	}
+	eventLog.Record(events.ConfigUpdated, "", "", map[string]any{"target": "client", "workload": name, "rps": rps})
+	fmt.Fprintf(w, "Workload %s RPS updated to %d\n", name, rps)
+}
+
+// updateWorkloadPaused handles POST /client/workloads/{name}/pause and /client/workloads/{name}/resume, which stop and
+// resume a single workload's requests without changing its other parameters.
+func updateWorkloadPaused(clients []*client.Client, eventLog *events.Log, w http.ResponseWriter, r *http.Request) {
+	name, action, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/client/workloads/"), "/")
+	if !ok || name == "" || (action != "pause" && action != "resume") {
+		http.NotFound(w, r)
+		return
+	}
+
+	var found bool
+	for _, cl := range clients {
+		if cl.Pinned() {
+			continue
+		}
+		if action == "pause" {
+			found = cl.PauseWorkload(name) || found
+		} else {
+			found = cl.ResumeWorkload(name) || found
+		}
+	}
+	if !found {
+		http.Error(w, fmt.Sprintf("Unknown workload: %s", name), http.StatusNotFound)
+		return
+	}
+	paused := action == "pause"
+	eventLog.Record(events.ConfigUpdated, "", "", map[string]any{"target": "client", "workload": name, "paused": paused})
+	if paused {
+		fmt.Fprintf(w, "Workload %s paused\n", name)
+	} else {
+		fmt.Fprintf(w, "Workload %s resumed\n", name)
+	}
+}
+
+// updatePolicyEnabled handles PUT /policies/{strategy}/{policy}/enabled, where the body is true or false as plain text,
+// and the policy is identified by its position in the strategy's chains, starting at 1, or by its type. The strategy's
+// executors are rebuilt from its enabled policies, which requests that are already in progress aren't affected by.
+func updatePolicyEnabled(clients []*client.Client, strategyChains map[string]map[string]policy.Chain, eventLog *events.Log, w http.ResponseWriter, r *http.Request) {
+	path, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/policies/"), "/enabled")
+	i := strings.LastIndex(path, "/")
+	if !ok || i <= 0 || i == len(path)-1 {
+		http.NotFound(w, r)
+		return
+	}
+	strategy, policyName := path[:i], path[i+1:]
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+	enabled, err := strconv.ParseBool(strings.TrimSpace(string(body)))
+	if err != nil {
+		http.Error(w, "Enabled must be true or false", http.StatusBadRequest)
+		return
+	}
+
+	chains, ok := strategyChains[strategy]
+	if !ok {
+		http.Error(w, fmt.Sprintf("Unknown strategy: %s", strategy), http.StatusNotFound)
+		return
+	}
+	updatedChains, executors, err := policy.SetEnabled(chains, policyName, enabled)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	for _, cl := range clients {
+		if cl.Strategy() == strategy {
+			cl.SetExecutors(executors)
+		}
+	}
+	strategyChains[strategy] = updatedChains
+	eventLog.Record(events.ConfigUpdated, "", strategy, map[string]any{"target": "policy", "policy": policyName, "enabled": enabled})
+	fmt.Fprintf(w, "Policy %s of strategy %s enabled: %t\n", policyName, strategy, enabled)
+}
+
+// updateServers updates the servers' threads, other than the servers of pinned clients, which are a control, and external
+// servers.
+func updateServers(clients []*client.Client, servers []*server.Server, eventLog *events.Log, w http.ResponseWriter, r *http.Request) {
 	var config *server.Config
 	if parseConfigUpdate(w, r, &config) {
-		for _, srv := range servers {
-			srv.UpdateConfig(config)
+		// An empty or null body parses without error, but doesn't describe a config
+		if config == nil {
+			http.Error(w, "Server config is required", http.StatusBadRequest)
+			return
+		}
+		if config.Threads > server.MaxThreads {
+			http.Error(w, fmt.Sprintf("Server threads cannot exceed %d", server.MaxThreads), http.StatusBadRequest)
+			return
+		}
+		eventLog.Record(events.ConfigUpdated, "", "", map[string]any{"target": "server", "threads": config.Threads})
+		for i, srv := range servers {
+			if srv != nil && !clients[i].Pinned() {
+				srv.UpdateConfig(config)
+			}
 		}
 		fmt.Fprintf(w, "Server config updated successfully\n")
 	}
@@ -1,11 +1,23 @@
 package main
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
 	"gopkg.in/yaml.v3"
+
+	"tripwire/pkg/client"
+	"tripwire/pkg/results"
 )
 
 var yamlData = `
@@ -86,6 +98,648 @@ func TestYAMLParsing(t *testing.T) {
 	assert.Equal(t, uint(8), config.Strategies[2].ClientPolicies[0].BulkheadConfig.MaxConcurrency)
 
 	assert.Equal(t, "client circuitbreaker and timeout", config.Strategies[3].Name)
-	assert.Equal(t, uint(10), config.Strategies[3].ClientPolicies[0].CircuitBreakerConfig.FailureRateThreshold)
+	assert.Equal(t, float64(10), config.Strategies[3].ClientPolicies[0].CircuitBreakerConfig.FailureRateThreshold)
 	assert.Equal(t, 300*time.Millisecond, config.Strategies[3].ClientPolicies[1].Timeout)
 }
+
+func TestPrioritizerValidation(t *testing.T) {
+	parse := func(prioritizerType string, prioritizer string) error {
+		_, err := parseConfig([]byte(`
+client:
+  stages:
+    - duration: 1s
+      rps: 10
+      service_times:
+        - service_time: 10ms
+server:
+  threads: 4
+strategies:
+  - name: prioritized
+    prioritizers:
+      client:
+        type: ` + prioritizerType + `
+    client_policies:
+      - adaptivelimiter:
+          prioritizer: ` + prioritizer + `
+`))
+		return err
+	}
+
+	assert.NoError(t, parse("adaptivelimiter", "client"))
+	assert.ErrorContains(t, parse("adaptivelimiter", "server"), "unknown prioritizer")
+	assert.ErrorContains(t, parse("adaptivethrottler", "client"), "has type adaptivethrottler")
+	assert.ErrorContains(t, parse("unknown", "client"), "must have a type")
+}
+
+func TestH2CValidation(t *testing.T) {
+	parse := func(server string) error {
+		_, err := parseConfig([]byte(`
+client:
+  transport:
+    type: h2c
+  workloads:
+    - name: orders
+      rps: 10
+      service_times:
+        - service_time: 10ms
+server:
+  threads: 4
+` + server + `
+strategies:
+  - name: multiplexed
+`))
+		return err
+	}
+
+	assert.NoError(t, parse("  http2:\n    max_concurrent_streams: 100"))
+	assert.ErrorContains(t, parse(""), "h2c transport requires server http2")
+}
+
+func TestExternalURLValidation(t *testing.T) {
+	parse := func(server string, strategy ...string) error {
+		_, err := parseConfig([]byte(`
+client:
+  workloads:
+    - name: orders
+      rps: 10
+      service_times:
+        - service_time: 10ms
+server:
+` + server + `
+strategies:
+  - name: external
+` + strings.Join(strategy, "\n")))
+		return err
+	}
+
+	assert.NoError(t, parse("  external_url: http://localhost:8080/"))
+	assert.ErrorContains(t, parse("  external_url: localhost:8080"), "invalid server external_url")
+	assert.ErrorContains(t, parse("  external_url: ftp://localhost"), "invalid server external_url")
+
+	// Settings for the embedded server are rejected rather than ignored
+	assert.ErrorContains(t, parse("  external_url: http://localhost:8080/\n  threads: 4"), "cannot be used with server threads")
+	assert.ErrorContains(t, parse("  external_url: http://localhost:8080/", "    server_policies:", "      - bulkhead:",
+		"          max_concurrency: 10"), "strategy external: server external_url cannot be used with server_policies")
+}
+
+func TestEndpointValidation(t *testing.T) {
+	parse := func(path string) error {
+		_, err := parseConfig([]byte(`
+client:
+  workloads:
+    - name: orders
+      rps: 10
+      method: GET
+      path: ` + path + `
+      service_times:
+        - service_time: 10ms
+server:
+  threads: 4
+  endpoints:
+    - method: GET
+      path: /orders
+      max_concurrency: 2
+strategies:
+  - name: routed
+`))
+		return err
+	}
+
+	assert.NoError(t, parse("/orders"))
+	assert.ErrorContains(t, parse("/search"), "no server endpoint for GET /search")
+}
+
+func TestApplyShard(t *testing.T) {
+	parse := func(shardValue string) (*Config, error) {
+		config, err := parseConfig([]byte(`
+seed: 42
+client:
+  workloads:
+    - name: writes
+      rps: 10
+      service_times:
+        - service_time: 10ms
+server:
+  threads: 4
+`))
+		assert.NoError(t, err)
+		shard, err := parseShard(shardValue)
+		if err != nil {
+			return nil, err
+		}
+		return config, applyShard(config, shard)
+	}
+
+	var total uint
+	seeds := make(map[int64]bool)
+	for i := 0; i < 3; i++ {
+		config, err := parse(fmt.Sprintf("%d/3", i))
+		assert.NoError(t, err)
+		assert.Equal(t, config.Shard.seed(42), config.Client.Seed)
+		assert.Equal(t, config.Shard.seed(deriveSeed(42, "arrivals")), config.Client.ArrivalSeed)
+		seeds[config.Client.Seed] = true
+		seeds[config.Client.ArrivalSeed] = true
+		total += config.Client.Workloads[0].RPS
+	}
+	assert.Equal(t, uint(10), total)
+	// Seeds are distinct across shards and sources, unlike offset seeds, where shard 1 of seed 42 would share shard 0 of 43
+	assert.Len(t, seeds, 6)
+	assert.NotEqual(t, (&Shard{Index: 1}).seed(42), (&Shard{Index: 0}).seed(43))
+
+	_, err := parse("3/3")
+	assert.ErrorContains(t, err, "must be less than")
+	_, err = parse("0/11")
+	assert.ErrorContains(t, err, "cannot be split")
+}
+
+func TestApplyShardUserStages(t *testing.T) {
+	config, err := parseConfig([]byte(`
+seed: 42
+client:
+  workloads:
+    - name: users
+      concurrency: 10
+      stages:
+        - duration: 2m
+          concurrency_end: 500
+server:
+  threads: 4
+`))
+	require.NoError(t, err)
+	require.NoError(t, applyShard(config, &Shard{Index: 1, Count: 4}))
+	stage := config.Client.Workloads[0].Stages[0]
+	assert.Equal(t, uint(3), stage.ConcurrencyStart)
+	assert.Equal(t, uint(125), stage.ConcurrencyEnd)
+	assert.Equal(t, uint(125), stage.Concurrency)
+
+	_, err = parseConfig([]byte(`
+client:
+  stages:
+    - duration: 1m
+      rps: 10
+      concurrency_end: 50
+server:
+  threads: 4
+`))
+	assert.ErrorContains(t, err, "client stages don't support concurrency")
+}
+
+func TestShardSplitTraceStages(t *testing.T) {
+	shard := &Shard{Index: 1, Count: 4}
+	trace := &client.GeneratorConfig{Type: "trace"}
+
+	// Trace stages don't use their RPS, and stages without an RPS aren't changed by splitting
+	assert.NoError(t, shard.splitStages("", []*client.Stage{{RPS: 2}, {RPS: 0}}, true))
+	assert.NoError(t, shard.splitStages("", []*client.Stage{{RPS: 0}}, false))
+	assert.ErrorContains(t, shard.splitStages("", []*client.Stage{{RPS: 2}}, false), "stage 0 rps 2 cannot be split")
+
+	// Workloads replay a trace if they or the client use a trace generator
+	assert.NoError(t, shard.splitWorkloads([]*client.Workload{{Name: "replay", Stages: []*client.Stage{{RPS: 2}}}}, trace))
+	assert.NoError(t, shard.splitWorkloads([]*client.Workload{{Name: "replay", Generator: trace, RPS: 2}}, nil))
+	err := shard.splitWorkloads([]*client.Workload{{Name: "writes", RPS: 2}}, nil)
+	assert.ErrorContains(t, err, "workload writes rps 2 cannot be split")
+}
+
+func TestSeeds(t *testing.T) {
+	parse := func(seeds string) (*Config, error) {
+		return parseConfig([]byte(`
+seed: 42
+` + seeds + `
+client:
+  workloads:
+    - name: writes
+      rps: 10
+      service_times:
+        - service_time: 10ms
+server:
+  threads: 4
+`))
+	}
+
+	// Service times and faults default to the seed, while other sources derive their own
+	config, err := parse("")
+	require.NoError(t, err)
+	assert.Equal(t, &SeedsConfig{Arrivals: deriveSeed(42, "arrivals"), ServiceTimes: 42, Faults: 42}, config.Seeds)
+	assert.NotEqual(t, config.Seeds.Arrivals, config.Seeds.ServiceTimes)
+	assert.Equal(t, int64(42), config.Client.Seed)
+	assert.Equal(t, config.Seeds.Arrivals, config.Client.ArrivalSeed)
+	assert.Equal(t, int64(42), config.Server.Seed)
+
+	// A configured seed only changes its own source
+	config, err = parse("seeds:\n  arrivals: 7\n  faults: 9")
+	require.NoError(t, err)
+	assert.Equal(t, &SeedsConfig{Arrivals: 7, ServiceTimes: 42, Faults: 9}, config.Seeds)
+	assert.Equal(t, int64(7), config.Client.ArrivalSeed)
+	assert.Equal(t, int64(9), config.Server.Seed)
+	assert.Empty(t, unseededSources(config))
+
+	// Policies can't be seeded, so their randomness is noted as not reproducible
+	_, err = parse("seeds:\n  policies: 11")
+	assert.ErrorContains(t, err, "randomness within policies")
+	config, err = parse("strategies:\n  - name: retries\n    client_policies:\n      - retry:\n          max_retries: 2")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"policies"}, unseededSources(config))
+}
+
+func TestStageRampCarryOver(t *testing.T) {
+	config, err := parseConfig([]byte(`
+client:
+  stages:
+    - duration: 10s
+      rps: 50
+    - duration: 30s
+      rps_end: 200
+      ramp: exponential
+    - duration: 10s
+server:
+  threads: 4
+`))
+	require.NoError(t, err)
+	assert.Equal(t, uint(50), config.Client.Stages[1].RPSStart)
+	assert.Equal(t, uint(200), config.Client.Stages[1].RPS)
+	assert.Equal(t, uint(200), config.Client.Stages[2].RPS)
+}
+
+func TestFilterStrategies(t *testing.T) {
+	var config Config
+	assert.NoError(t, yaml.Unmarshal([]byte(yamlData), &config))
+
+	assert.NoError(t, filterStrategies(&config, []string{"client bulkhead", " client timeout"}))
+	assert.Len(t, config.Strategies, 2)
+	assert.Equal(t, "client timeout", config.Strategies[0].Name)
+	assert.Equal(t, "client bulkhead", config.Strategies[1].Name)
+
+	assert.ErrorContains(t, filterStrategies(&config, []string{"client rate limiter"}), "unknown strategy")
+}
+
+func TestBaseline(t *testing.T) {
+	parse := func(strategy string) (*Config, error) {
+		return parseConfig([]byte(`
+baseline: true
+client:
+  stages:
+    - duration: 10s
+      rps: 50
+server:
+  threads: 4
+strategies:
+  - name: ` + strategy + `
+    client_policies:
+      - timeout: 100ms
+`))
+	}
+	config, err := parse("timeout")
+	require.NoError(t, err)
+	require.Len(t, config.Strategies, 2)
+	assert.Equal(t, BaselineStrategy, config.Strategies[0].Name)
+	assert.True(t, config.Strategies[0].Control)
+	assert.Empty(t, config.Strategies[0].ClientPolicies)
+
+	// The baseline is retained when strategies are filtered
+	require.NoError(t, filterStrategies(config, []string{"timeout"}))
+	assert.Len(t, config.Strategies, 2)
+
+	_, err = parse(BaselineStrategy)
+	assert.ErrorContains(t, err, "reserved")
+}
+
+func TestControlStrategy(t *testing.T) {
+	parse := func(baseline bool) (*Config, error) {
+		return parseConfig([]byte(`
+baseline: ` + strconv.FormatBool(baseline) + `
+client:
+  stages:
+    - duration: 10s
+      rps: 50
+server:
+  threads: 4
+strategies:
+  - name: reference
+    control: true
+  - name: timeout
+    client_policies:
+      - timeout: 100ms
+`))
+	}
+	config, err := parse(false)
+	require.NoError(t, err)
+	assert.True(t, config.Strategies[0].Control)
+	assert.False(t, config.Strategies[1].Control)
+
+	// The control is retained when strategies are filtered
+	require.NoError(t, filterStrategies(config, []string{"timeout"}))
+	assert.Len(t, config.Strategies, 2)
+
+	_, err = parse(true)
+	assert.ErrorContains(t, err, "cannot both be the control")
+}
+
+func TestReadConfigFileIncludes(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+		return path
+	}
+	write("policies/timeout.yaml", `
+- timeout: 300ms
+`)
+	write("defaults.yaml", `
+server:
+  threads: 8
+client:
+  workloads:
+    - name: reads
+      rps: 10
+`)
+	path := write("config.yaml", `
+include: defaults.yaml
+server:
+  threads: 16
+client:
+  workloads:
+    - name: writes
+      rps: 20
+strategies:
+  - name: timeout
+    client_policies:
+      - include: policies/timeout.yaml
+      - bulkhead:
+          max_concurrency: 8
+`)
+
+	configData, err := readConfigFile(path)
+	require.NoError(t, err)
+	var config Config
+	require.NoError(t, yaml.Unmarshal(configData, &config))
+	assert.Equal(t, uint(16), config.Server.Threads)
+	assert.Len(t, config.Client.Workloads, 2)
+	assert.Equal(t, "reads", config.Client.Workloads[0].Name)
+	assert.Equal(t, "writes", config.Client.Workloads[1].Name)
+	assert.Len(t, config.Strategies[0].ClientPolicies, 2)
+	assert.Equal(t, 300*time.Millisecond, config.Strategies[0].ClientPolicies[0].Timeout)
+
+	write("cycle.yaml", `include: cycle.yaml`)
+	_, err = readConfigFile(filepath.Join(dir, "cycle.yaml"))
+	assert.ErrorContains(t, err, "include cycle")
+}
+
+func TestReadConfigFromStdin(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "defaults.yaml"), []byte("server:\n  threads: 8\n"), 0o644))
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	defer func() { _ = os.Chdir(wd) }()
+	stdin := os.Stdin
+	defer func() { os.Stdin = stdin }()
+
+	// Includes are relative to the working directory
+	read := func(data string) []byte {
+		r, w, err := os.Pipe()
+		require.NoError(t, err)
+		_, err = w.WriteString(data)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		os.Stdin = r
+		configData, err := readConfigFile(stdinPath)
+		require.NoError(t, err)
+		return configData
+	}
+	var config Config
+	require.NoError(t, yaml.Unmarshal(read("include: defaults.yaml\nclient:\n  rps: 10\n"), &config))
+	assert.Equal(t, uint(8), config.Server.Threads)
+	assert.Equal(t, "client:\n  rps: 10\n", string(read("client:\n  rps: 10\n")))
+}
+
+func TestExpandSweep(t *testing.T) {
+	parse := func(parameters string) (*Config, error) {
+		return parseConfig([]byte(`
+client:
+  stages:
+    - duration: 1s
+      rps: 10
+      service_times:
+        - service_time: 10ms
+server:
+  threads: 4
+strategies:
+  - name: timeout
+    client_policies:
+      - timeout: 1s
+  - name: adaptivelimiter
+    client_policies:
+      - adaptivelimiter:
+          min_limit: 1
+      - timeout: 1s
+sweep:
+  strategy: adaptivelimiter
+  parameters:
+` + parameters))
+	}
+
+	config, err := parse(`
+    client_policies.adaptivelimiter.max_limit: [50, 100, 200]
+    client_policies.adaptivelimiter.recent_quantile: [0.5, 0.9]
+`)
+	require.NoError(t, err)
+	require.Len(t, config.Strategies, 7)
+	assert.Equal(t, "timeout", config.Strategies[0].Name)
+	assert.Equal(t, "adaptivelimiter max_limit=50 recent_quantile=0.5", config.Strategies[1].Name)
+	assert.Equal(t, "adaptivelimiter max_limit=200 recent_quantile=0.9", config.Strategies[6].Name)
+	limiter := config.Strategies[6].ClientPolicies[0].AdaptiveLimiterConfig
+	assert.Equal(t, uint(1), limiter.MinLimit)
+	assert.Equal(t, uint(200), limiter.MaxLimit)
+	assert.Equal(t, .9, limiter.RecentQuantile)
+	assert.Equal(t, map[string]string{
+		"client_policies.adaptivelimiter.max_limit":       "200",
+		"client_policies.adaptivelimiter.recent_quantile": "0.9",
+	}, config.Strategies[6].Parameters)
+	assert.Nil(t, config.Strategies[0].Parameters)
+
+	config, err = parse(`
+    client_policies.1.timeout: [500ms, 2s]
+`)
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Second, config.Strategies[2].ClientPolicies[1].Timeout)
+
+	_, err = parse(`
+    client_policies.bulkhead.max_concurrency: [10]
+`)
+	assert.ErrorContains(t, err, "not found")
+}
+
+func TestListenersOverridePorts(t *testing.T) {
+	listeners := &ListenersConfig{Metrics: "127.0.0.1:8080", Config: ":9095"}
+	assert.NoError(t, listeners.overridePorts(9100, -1))
+	assert.Equal(t, "127.0.0.1:9100", listeners.Metrics)
+	assert.Equal(t, ":9095", listeners.Config)
+
+	assert.NoError(t, listeners.overridePorts(-1, 0))
+	assert.Equal(t, ":0", listeners.Config)
+	assert.Error(t, listeners.overridePorts(70000, -1))
+}
+
+func TestBusinessWeights(t *testing.T) {
+	parse := func(weights string) *Config {
+		config, err := parseConfig([]byte(`
+client:
+` + weights + `
+  workloads:
+    - name: checkout
+      rps: 10
+      priority: 4
+      service_times:
+        - service_time: 10ms
+    - name: search
+      rps: 10
+      service_times:
+        - service_time: 10ms
+    - name: reports
+      rps: 10
+      business_weight: 0.5
+      service_times:
+        - service_time: 10ms
+server:
+  threads: 4
+`))
+		require.NoError(t, err)
+		return config
+	}
+
+	config := parse(`
+  business_weights:
+    4: 10`)
+	assert.Equal(t, map[string]float64{"checkout": 10, "search": 1, "reports": .5}, businessWeights(config.Client))
+
+	config.Client.Workloads[1].Priorities = client.WeightedPriorities{{Priority: 4, Weight: 1}, {Priority: 0, Weight: 3}}
+	assert.Equal(t, 3.25, config.Client.BusinessWeight(config.Client.Workloads[1]))
+
+	config = parse("")
+	config.Client.Workloads[2].BusinessWeight = nil
+	assert.Nil(t, businessWeights(config.Client))
+}
+
+func TestStrategyLogLevel(t *testing.T) {
+	var strategies []*Strategy
+	require.NoError(t, yaml.Unmarshal([]byte(`
+- name: debugged
+  log_level: debug
+- name: default
+`), &strategies))
+	logger, err := newLogger(jsonLogFormat, zapcore.InfoLevel)
+	require.NoError(t, err)
+
+	debugged := newStrategyLogger(logger, strategies[0])
+	assert.True(t, debugged.Desugar().Core().Enabled(zapcore.DebugLevel))
+	assert.True(t, debugged.With("workload", "reads").Desugar().Core().Enabled(zapcore.DebugLevel))
+	assert.False(t, newStrategyLogger(logger, strategies[1]).Desugar().Core().Enabled(zapcore.DebugLevel))
+	assert.False(t, logger.Desugar().Core().Enabled(zapcore.DebugLevel))
+
+	_, err = newLogger("xml", zapcore.InfoLevel)
+	assert.Error(t, err)
+}
+
+func TestResultVars(t *testing.T) {
+	dir := t.TempDir()
+	runDir, err := results.Create(&results.Config{Dir: dir}, "capacity.yaml", "", nil, 1)
+	require.NoError(t, err)
+	require.NoError(t, runDir.WriteResults(&results.Results{Runs: []*results.Run{{
+		Strategy:  "adaptivelimiter",
+		Workloads: []*results.WorkloadResult{{Workload: "writes", Goodput: 412.5}},
+	}}}))
+
+	resolved, err := resolveResultVars([]byte(`
+output:
+  dir: ` + dir + `
+client:
+  workloads:
+    - name: writes
+      rps: ${result:runs.adaptivelimiter.workloads.writes.goodput * 0.8}
+      description: ${result:runs.0.workloads.0.goodput}
+`))
+	require.NoError(t, err)
+	assert.Contains(t, string(resolved), "rps: 330\n")
+	assert.Contains(t, string(resolved), "description: 412.5\n")
+
+	_, err = resolveResultVars([]byte(`
+output:
+  dir: ` + dir + `
+client:
+  workloads:
+    - rps: ${result:runs.timeout.workloads.writes.goodput}
+`))
+	assert.ErrorContains(t, err, "no timeout in results")
+}
+
+func TestStageSLOValidation(t *testing.T) {
+	parse := func(slo string) error {
+		_, err := parseConfig([]byte(`
+client:
+  workloads:
+    - name: tenant-a
+      rps: 10
+      service_times:
+        - service_time: 10ms
+      stages:
+        - duration: 1s
+        - duration: 1s
+          rps: 100
+    - name: tenant-b
+      rps: 10
+  stage_slos:
+    - ` + slo + `
+server:
+  threads: 4
+`))
+		return err
+	}
+
+	assert.NoError(t, parse("{stage: 1, target: 0.99}"))
+	assert.NoError(t, parse("{stage: 1, workload: tenant-a, target: 0.99}"))
+	assert.ErrorContains(t, parse("{stage: 2, workload: tenant-a, target: 0.99}"), "there is no stage 2")
+	assert.ErrorContains(t, parse("{stage: 0, workload: tenant-b, target: 0.99}"), "workload tenant-b doesn't have stages")
+	assert.ErrorContains(t, parse("{stage: 0, target: 1.5}"), "target must be greater than 0")
+}
+
+func TestAnalyticModel(t *testing.T) {
+	parse := func(workload string) *Config {
+		config, err := parseConfig([]byte(`
+client:
+  workloads:
+    - name: orders
+      rps: 20
+      service_times:
+        - service_time: 100ms
+          weight: 3
+        - distribution: exponential
+          mean: 500ms
+` + workload + `
+server:
+  threads: 8
+strategies:
+  - name: bulkhead
+`))
+		require.NoError(t, err)
+		return config
+	}
+
+	// The mean service time is 200ms, so the workload keeps 4 of the 8 threads busy
+	analytic := analyticModel(parse(""))
+	require.NotNil(t, analytic)
+	assert.InDelta(t, .5, analytic.Utilization, 1e-9)
+
+	// Closed-loop workloads and load that changes over time aren't modeled
+	assert.Nil(t, analyticModel(parse("      concurrency: 10")))
+	assert.Nil(t, analyticModel(parse("      stages:\n        - duration: 10s\n          rps: 40")))
+}
+
+func TestUpdateServersWithoutConfig(t *testing.T) {
+	for _, body := range []string{"", "null"} {
+		w := httptest.NewRecorder()
+		updateServers(nil, nil, nil, w, httptest.NewRequest(http.MethodPut, "/server", strings.NewReader(body)))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "Server config is required")
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"tripwire/pkg/results"
+)
+
+// gc prunes run directories according to a retention policy, which defaults to a config's output settings, if a config
+// file is provided.
+func gc(args []string) {
+	gcFlags := flag.NewFlagSet("gc", flag.ExitOnError)
+	dir := gcFlags.String("dir", "", "the directory that run directories are created in (default from config, or results)")
+	keep := gcFlags.Int("keep", -1, "the number of run directories to retain (default from config)")
+	maxAge := gcFlags.Duration("max-age", -1, "the age after which run directories are removed (default from config)")
+	maxSize := gcFlags.Int64("max-size", -1, "the total size, in bytes, that run directories are pruned to (default from config)")
+	dryRun := gcFlags.Bool("dry-run", false, "print the run directories that would be removed without removing them")
+	args = parseArgs(gcFlags, args)
+	if len(args) > 1 {
+		fmt.Println("Usage: ./tripwire gc [flags] [configFile]")
+		gcFlags.PrintDefaults()
+		os.Exit(1)
+	}
+
+	config := &results.Config{Dir: "results"}
+	if len(args) == 1 {
+		configData, err := readConfigFile(args[0])
+		if err != nil {
+			fmt.Println("failed to read config file:", err)
+			os.Exit(1)
+		}
+		parsed, err := parseConfig(configData)
+		if err != nil {
+			fmt.Println("failed to parse config file:", err)
+			os.Exit(1)
+		}
+		config = parsed.Output
+	}
+	if *dir != "" {
+		config.Dir = *dir
+	}
+	if *keep >= 0 {
+		config.Keep = *keep
+	}
+	if *maxAge >= 0 {
+		config.MaxAge = *maxAge
+	}
+	if *maxSize >= 0 {
+		config.MaxSize = *maxSize
+	}
+
+	removed, err := results.Prune(config, time.Now(), *dryRun)
+	for _, path := range removed {
+		if *dryRun {
+			fmt.Println("would remove", path)
+		} else {
+			fmt.Println("removed", path)
+		}
+	}
+	if err != nil {
+		fmt.Println("failed to prune run directories:", err)
+		os.Exit(1)
+	}
+}
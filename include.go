@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	includeKey = "include"
+	stdinPath  = "-" // the path that reads a config from stdin
+)
+
+// readConfigFile reads a config file and resolves any includes, returning the resolved config data, which doesn't
+// depend on the included files. A path of - reads the config from stdin, with includes relative to the working
+// directory.
+//
+// A mapping with an include key, which names a file or a list of files relative to the including file, is merged on top
+// of the included files' contents: nested mappings are merged, sequences are appended to, and other values replace the
+// included values. A sequence item that only has an include key is replaced with the included files' items, so that
+// shared policies or workloads can be spliced into lists:
+//
+//	include: defaults.yaml
+//	strategies:
+//	  - name: adaptivelimiter
+//	    client_policies:
+//	      - include: policies/adaptivelimiter.yaml
+//	      - timeout: 1s
+func readConfigFile(path string) ([]byte, error) {
+	var data []byte
+	var err error
+	if path == stdinPath {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	resolver := &includeResolver{}
+	if path != stdinPath {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		resolver.files = append(resolver.files, absPath)
+	}
+	node, err := resolver.parse(data, path)
+	if err != nil {
+		return nil, err
+	}
+	if node == nil || resolver.includes == 0 {
+		// Configs without includes are used as is, preserving their formatting
+		return data, nil
+	}
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err = encoder.Encode(node); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), encoder.Close()
+}
+
+// includeResolver resolves the includes of a config file.
+type includeResolver struct {
+	files    []string // the files that are being read, which are used to detect cycles
+	includes int      // the number of includes that were resolved
+}
+
+// readFile reads and resolves the includes of a YAML file, returning nil if the file is empty.
+func (r *includeResolver) readFile(path string) (*yaml.Node, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if slices.Contains(r.files, absPath) {
+		return nil, fmt.Errorf("include cycle: %s", path)
+	}
+	r.files = append(r.files, absPath)
+	defer func() { r.files = r.files[:len(r.files)-1] }()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return r.parse(data, path)
+}
+
+// parse parses and resolves the includes of a YAML file's data, relative to the file's directory, returning nil if the
+// file is empty.
+func (r *includeResolver) parse(data []byte, path string) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	node := doc.Content[0]
+	expandAliases(node)
+	if err := r.resolve(node, filepath.Dir(path)); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return node, nil
+}
+
+// resolve resolves the include directives within node in place, relative to dir.
+func (r *includeResolver) resolve(node *yaml.Node, dir string) error {
+	switch node.Kind {
+	case yaml.SequenceNode:
+		var items []*yaml.Node
+		for _, item := range node.Content {
+			if paths, ok := includeOnly(item); ok {
+				for _, path := range paths {
+					includedNode, err := r.include(filepath.Join(dir, path))
+					if err != nil {
+						return err
+					}
+					if includedNode == nil {
+						continue
+					}
+					if includedNode.Kind == yaml.SequenceNode {
+						items = append(items, includedNode.Content...)
+					} else {
+						items = append(items, includedNode)
+					}
+				}
+				continue
+			}
+			if err := r.resolve(item, dir); err != nil {
+				return err
+			}
+			items = append(items, item)
+		}
+		node.Content = items
+	case yaml.MappingNode:
+		var paths []string
+		var content []*yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Value == includeKey {
+				var err error
+				if paths, err = includePaths(value); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := r.resolve(value, dir); err != nil {
+				return err
+			}
+			content = append(content, key, value)
+		}
+		node.Content = content
+		// Merge the mapping on top of the included files, in order
+		var base *yaml.Node
+		for _, path := range paths {
+			includedNode, err := r.include(filepath.Join(dir, path))
+			if err != nil {
+				return err
+			}
+			if includedNode == nil {
+				continue
+			}
+			if includedNode.Kind != yaml.MappingNode {
+				return fmt.Errorf("included file %s must contain a mapping", path)
+			}
+			base = mergeNodes(base, includedNode)
+		}
+		if base != nil {
+			*node = *mergeNodes(base, node)
+		}
+	}
+	return nil
+}
+
+func (r *includeResolver) include(path string) (*yaml.Node, error) {
+	r.includes++
+	return r.readFile(path)
+}
+
+// includeOnly returns the included paths if node is a mapping with only an include key.
+func includeOnly(node *yaml.Node) ([]string, bool) {
+	if node.Kind != yaml.MappingNode || len(node.Content) != 2 || node.Content[0].Value != includeKey {
+		return nil, false
+	}
+	paths, err := includePaths(node.Content[1])
+	return paths, err == nil
+}
+
+// includePaths returns the paths of an include directive, which is either a single path or a list of paths.
+func includePaths(node *yaml.Node) ([]string, error) {
+	var paths []string
+	if node.Kind == yaml.ScalarNode {
+		paths = []string{node.Value}
+	} else if err := node.Decode(&paths); err != nil {
+		return nil, fmt.Errorf("include must be a path or a list of paths")
+	}
+	return paths, nil
+}
+
+// mergeNodes merges override on top of base. Mappings are merged by key, sequences are concatenated, and other nodes are
+// replaced by the override.
+func mergeNodes(base *yaml.Node, override *yaml.Node) *yaml.Node {
+	if base == nil || base.Kind != override.Kind {
+		return override
+	}
+	switch override.Kind {
+	case yaml.MappingNode:
+		merged := *base
+		merged.Content = slices.Clone(base.Content)
+		for i := 0; i+1 < len(override.Content); i += 2 {
+			key, value := override.Content[i], override.Content[i+1]
+			if j := mappingIndex(&merged, key.Value); j >= 0 {
+				merged.Content[j+1] = mergeNodes(merged.Content[j+1], value)
+			} else {
+				merged.Content = append(merged.Content, key, value)
+			}
+		}
+		return &merged
+	case yaml.SequenceNode:
+		merged := *override
+		merged.Content = append(slices.Clone(base.Content), override.Content...)
+		return &merged
+	default:
+		return override
+	}
+}
+
+// mappingIndex returns the index of a key within a mapping node's content, or -1.
+func mappingIndex(node *yaml.Node, key string) int {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// expandAliases replaces aliases with copies of the nodes they refer to, so that anchors, which are local to a file, are
+// preserved when files are merged.
+func expandAliases(node *yaml.Node) {
+	if node.Kind == yaml.AliasNode {
+		*node = *deepCopy(node.Alias)
+	}
+	node.Anchor = ""
+	for _, child := range node.Content {
+		expandAliases(child)
+	}
+}
+
+func deepCopy(node *yaml.Node) *yaml.Node {
+	copied := *node
+	copied.Content = make([]*yaml.Node, len(node.Content))
+	for i, child := range node.Content {
+		copied.Content[i] = deepCopy(child)
+	}
+	return &copied
+}
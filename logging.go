@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	consoleLogFormat = "console"
+	jsonLogFormat    = "json"
+)
+
+// newLogger returns a logger that logs in the format, which is console or json, at the level. The logger's underlying
+// core logs every level, so that loggers derived from it via withLogLevel can log at a lower level.
+func newLogger(format string, level zapcore.Level) (*zap.SugaredLogger, error) {
+	var zapConf zap.Config
+	switch format {
+	case consoleLogFormat:
+		zapConf = zap.NewDevelopmentConfig()
+		zapConf.EncoderConfig.EncodeTime = zapcore.TimeEncoderOfLayout("2006-01-02 15:04:05")
+	case jsonLogFormat:
+		zapConf = zap.NewProductionConfig()
+		zapConf.Sampling = nil
+		zapConf.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	default:
+		return nil, fmt.Errorf("invalid log format: %s", format)
+	}
+	zapConf.Level = zap.NewAtomicLevelAt(zapcore.DebugLevel)
+	log, err := zapConf.Build(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return &leveledCore{Core: core, level: level}
+	}))
+	if err != nil {
+		return nil, err
+	}
+	return log.Sugar(), nil
+}
+
+// withLogLevel returns a logger that logs at the level, which may be lower than the level of the logger it's derived
+// from.
+func withLogLevel(logger *zap.SugaredLogger, level zapcore.Level) *zap.SugaredLogger {
+	return logger.Desugar().WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		if lc, ok := core.(*leveledCore); ok {
+			return &leveledCore{Core: lc.Core, level: level}
+		}
+		return core
+	})).Sugar()
+}
+
+// leveledCore filters the entries that are logged to a core by level.
+type leveledCore struct {
+	zapcore.Core
+	level zapcore.Level
+}
+
+func (c *leveledCore) Enabled(level zapcore.Level) bool {
+	return c.level.Enabled(level)
+}
+
+func (c *leveledCore) Level() zapcore.Level {
+	return c.level
+}
+
+func (c *leveledCore) With(fields []zapcore.Field) zapcore.Core {
+	return &leveledCore{Core: c.Core.With(fields), level: c.level}
+}
+
+func (c *leveledCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(entry.Level) {
+		return checked
+	}
+	return c.Core.Check(entry, checked)
+}
+
+// newStrategyLogger returns a logger for the strategy, which logs at the strategy's log level, if any.
+func newStrategyLogger(logger *zap.SugaredLogger, strategy *Strategy) *zap.SugaredLogger {
+	if strategy.LogLevel != nil {
+		logger = withLogLevel(logger, *strategy.LogLevel)
+	}
+	return logger.With("strategy", strategy.Name)
+}
@@ -2,12 +2,21 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log/slog"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 
+	"github.com/failsafe-go/failsafe-go"
 	"github.com/failsafe-go/failsafe-go/adaptivelimiter"
 	"github.com/failsafe-go/failsafe-go/adaptivethrottler"
 	"github.com/failsafe-go/failsafe-go/priority"
@@ -16,116 +25,713 @@ import (
 	"go.uber.org/zap/zapcore"
 
 	"tripwire/pkg/client"
+	"tripwire/pkg/events"
 	"tripwire/pkg/metrics"
+	"tripwire/pkg/notify"
+	"tripwire/pkg/policy"
+	"tripwire/pkg/reaction"
+	"tripwire/pkg/results"
 	"tripwire/pkg/server"
+	"tripwire/pkg/tracing"
 )
 
+// stdoutOutput is the output that writes results to stdout.
+const stdoutOutput = "-"
+
 func main() {
-	if len(os.Args) < 3 {
-		fmt.Println("Usage: ./tripwire run <configFile>")
+	if len(os.Args) < 2 {
+		fmt.Println("Usage: ./tripwire run [flags] <configFile | ->")
+		fmt.Println("       ./tripwire selftest [flags]")
+		fmt.Println("       ./tripwire rps [flags] <workload> <rps>")
+		fmt.Println("       ./tripwire gc [flags] [configFile]")
+		fmt.Println("       ./tripwire report [flags] [runDir]")
+		fmt.Println("       ./tripwire record [flags] --forward <url>")
 		os.Exit(1)
 	}
 
-	command := os.Args[1]
-	if command != "run" {
+	switch command := os.Args[1]; command {
+	case "run":
+		run(os.Args[2:])
+	case "selftest":
+		selftest(os.Args[2:])
+	case "rps":
+		setRPS(os.Args[2:])
+	case "gc":
+		gc(os.Args[2:])
+	case "report":
+		report(os.Args[2:])
+	case "record":
+		record(os.Args[2:])
+	default:
 		fmt.Printf("Unknown command: %s\n", command)
 		os.Exit(1)
 	}
+}
 
-	zapConf := zap.NewDevelopmentConfig()
-	zapConf.EncoderConfig.EncodeTime = zapcore.TimeEncoderOfLayout("2006-01-02 15:04:05")
-	log, _ := zapConf.Build()
-	logger := log.Sugar()
+func run(args []string) {
+	runFlags := flag.NewFlagSet("run", flag.ExitOnError)
+	dumpPolicies := runFlags.Bool("dump-policies", false, "print the policy chain that is built for each workload")
+	shardFlag := runFlags.String("shard", "", "run shard i of n, in the form i/n, with 1/n of the configured load")
+	shardRunFlag := runFlags.String("shard-run", "", "an ID that every shard of a run shares, which distinguishes its run IDs from repeated runs (default the start time to the minute)")
+	strategyFlag := runFlags.String("strategy", "", "a comma separated list of the strategies to run, rather than all of them")
+	metricsPort := runFlags.Int("metrics-port", -1, "the port to serve metrics on, where 0 chooses a free port (default from config, or 8080)")
+	controlPort := runFlags.Int("control-port", -1, "the port to serve the config REST API on, where 0 chooses a free port (default from config, or 9095)")
+	logFormat := runFlags.String("log-format", consoleLogFormat, "the log format, either console or json")
+	output := runFlags.String("output", "", "a file to write the JSON results to once the run completes, where - is stdout")
+	var logLevel zapcore.Level
+	runFlags.TextVar(&logLevel, "log-level", zapcore.InfoLevel, "the log level, such as debug, info, warn, or error")
+	args = parseArgs(runFlags, args)
+	if len(args) != 1 {
+		fmt.Println("Usage: ./tripwire run [flags] <configFile | ->")
+		runFlags.PrintDefaults()
+		os.Exit(1)
+	}
+	configName := args[0]
+	if configName == stdinPath {
+		configName = "stdin"
+	}
 
-	configData, err := os.ReadFile(os.Args[2])
+	logger, err := newLogger(*logFormat, logLevel)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	configData, err := readConfigFile(args[0])
 	if err != nil {
 		logger.Fatalw("failed to read config file", "error", err)
 	}
+	if configData, err = resolveResultVars(configData); err != nil {
+		logger.Fatalw("failed to resolve result variables", "error", err)
+	}
 	config, err := parseConfig(configData)
 	if err != nil {
 		logger.Fatalw("failed to parse config file", "error", err)
 	}
-	metrics := metrics.New(logger)
+	if *output == stdoutOutput && (config.Output.HasStdoutSink() || *dumpPolicies) {
+		logger.Fatalw("cannot write results to stdout along with a stdout_json sink or dumped policies")
+	}
+	if err = config.Listeners.overridePorts(*metricsPort, *controlPort); err != nil {
+		logger.Fatalw("failed to override listener ports", "error", err)
+	}
+	if *strategyFlag != "" {
+		if err = filterStrategies(config, strings.Split(*strategyFlag, ",")); err != nil {
+			logger.Fatalw("failed to filter strategies", "error", err)
+		}
+	}
+	if *shardFlag != "" {
+		shard, err := parseShard(*shardFlag)
+		if err == nil {
+			err = applyShard(config, shard)
+		}
+		if err != nil {
+			logger.Fatalw("failed to shard config", "error", err)
+		}
+		shard.Run = *shardRunFlag
+		if shard.Run == "" {
+			shard.Run = time.Now().Format("15:04")
+		}
+		logger.Infow("running shard", "shard", shard, "run", shard.Run, "seed", config.Client.Seed)
+	}
+	metrics := metrics.New(config.Metrics, logger)
+	stopTracing := func(ctx context.Context) error { return nil }
+	if config.Tracing != nil {
+		if stopTracing, err = tracing.Start(config.Tracing); err != nil {
+			logger.Fatalw("failed to start tracing", "error", err)
+		}
+	}
+
+	resultsDir, err := results.Create(config.Output, configName, shardName(config.Shard), configData, config.Seed)
+	if err != nil {
+		logger.Fatalw("failed to create results directory", "error", err)
+	}
+	sinks, err := results.OpenSinks(resultsDir, config.Output.Sinks)
+	if err != nil {
+		logger.Fatalw("failed to open output sinks", "error", err)
+	}
+	recorder := results.NewRecorder(sinks, metrics, config.Seed, shardName(config.Shard),
+		results.Metadata{Description: config.Description, Tags: config.Tags}, config.Output.RelativeTime)
+	recorder.SetLatencyPeriod(config.Output.LatencyPeriod)
+	recorder.SetSeeds(config.Seeds.byComponent(), unseededSources(config))
+	recorder.SetScenario(results.ScenarioName(configName))
+	requestLog, err := results.OpenRequestLog(resultsDir, config.Output.RequestLog)
+	if err != nil {
+		logger.Fatalw("failed to open request log", "error", err)
+	}
+	recorder.SetRequestLog(requestLog)
+	recorder.Start(time.Second)
+	eventLog := events.New(sinks)
+	var finishOnce sync.Once
+	finish := func(reason string) {
+		finishOnce.Do(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := stopTracing(ctx); err != nil {
+				logger.Errorw("failed to export spans", "error", err)
+			}
+			cancel()
+			err := recorder.Stop()
+			if closeErr := sinks.Close(); closeErr != nil {
+				logger.Errorw("failed to close output sinks", "error", closeErr)
+			}
+			if closeErr := requestLog.Close(); closeErr != nil {
+				logger.Errorw("failed to write request log", "error", closeErr)
+			}
+			if err != nil {
+				logger.Errorw("failed to write results", "error", err)
+				return
+			}
+			logger.Infow("wrote results", "dir", resultsDir.Path)
+			if *output != "" {
+				if err = writeOutput(*output, resultsDir); err != nil {
+					logger.Errorw("failed to write results output", "error", err)
+				}
+			}
+			sendSummary(logger, config, configName, resultsDir, reason)
+		})
+	}
+
+	// Write results if the run is interrupted
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		eventLog.Record(events.StopCondition, "", "", map[string]any{"reason": "signal", "signal": sig.String()})
+		finish("interrupted")
+		os.Exit(1)
+	}()
 
-	var wg sync.WaitGroup
 	if len(config.Client.Workloads) == 0 {
-		// Run staged strategies sequentially
-		for i, strategy := range config.Strategies {
-			if i > 0 {
-				time.Sleep(5 * time.Second)
+		runSequential(logger, config, metrics, recorder, eventLog, *dumpPolicies)
+	} else {
+		runParallel(logger, config, metrics, recorder, eventLog, *dumpPolicies, nil)
+	}
+	eventLog.Record(events.StopCondition, "", "", map[string]any{"reason": "completed"})
+	finish("completed")
+}
+
+// writeOutput writes a run's JSON results to the output, which is a file, or stdout if it's -, so that runs can be
+// composed in pipelines without reading the run directory.
+func writeOutput(output string, resultsDir *results.Dir) error {
+	data, err := os.ReadFile(resultsDir.File(results.ResultsFile))
+	if err != nil {
+		return err
+	}
+	if output == stdoutOutput {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(output, data, 0o644)
+}
+
+// sendSummary sends the run's summary to any configured notification sinks, led by any objectives that the run failed.
+func sendSummary(logger *zap.SugaredLogger, config *Config, configName string, resultsDir *results.Dir, reason string) {
+	if config.Notifications == nil {
+		return
+	}
+	summary, err := os.ReadFile(resultsDir.File(results.SummaryFile))
+	if err != nil {
+		logger.Errorw("failed to read summary for notifications", "error", err)
+		return
+	}
+	subject := fmt.Sprintf("tripwire run %s %s", filepath.Base(configName), reason)
+	// Failed objectives lead the summary, since they're what a notification's reader needs to act on
+	runResults, err := resultsDir.ReadResults()
+	if err != nil {
+		logger.Errorw("failed to read results for notifications", "error", err)
+	} else if failures := runResults.Failures(); len(failures) > 0 {
+		subject += fmt.Sprintf(" with %d failures", len(failures))
+		summary = append([]byte("failures:\n  "+strings.Join(failures, "\n  ")+"\n\n"), summary...)
+	}
+	if config.Description != "" {
+		subject += ": " + config.Description
+	}
+	if err = notify.Send(config.Notifications, subject, string(summary)); err != nil {
+		logger.Errorw("failed to send notifications", "error", err)
+		return
+	}
+	logger.Infow("sent notifications")
+}
+
+// runSequential runs staged strategies one after another. Strategies that exceed their expected duration by the
+// watchdog margin, if any, are aborted so that the next strategy can run.
+func runSequential(logger *zap.SugaredLogger, config *Config, metrics *metrics.Metrics, recorder *results.Recorder, eventLog *events.Log, dumpPolicies bool) {
+	var reusedServer *server.Server
+	var reusedServerWg sync.WaitGroup
+	var previousClient *client.Client
+	var previousServer *server.Server
+	sharedRateLimiters := policy.NewSharedRateLimiters(config.SharedRateLimiters, metrics)
+	for i, strategy := range config.Strategies {
+		if i > 0 {
+			coolDown(logger, config.Sequential, metrics, previousClient, previousServer)
+		}
+		startMetrics(logger, config, metrics, recorder)
+		strategyLogger := newStrategyLogger(logger, strategy)
+		var wg sync.WaitGroup // Per strategy, so that an aborted strategy isn't waited on by the next
+		serverWg := &wg
+		if config.Sequential.ReuseServer {
+			serverWg = &reusedServerWg
+		}
+		aClient, aServer, chains := startClientAndServer(strategyLogger, config, strategy, metrics, recorder, eventLog, sharedRateLimiters, reusedServer, serverWg, &wg)
+		if config.Sequential.ReuseServer {
+			reusedServer = aServer
+		}
+		previousClient, previousServer = aClient, aServer
+		if dumpPolicies {
+			policy.PrintChains(os.Stdout, strategy.Name, chains)
+		}
+		if awaitStrategy(&wg, watchdogTimeout(config)) {
+			eventLog.Record(events.StrategyStopped, aClient.RunID(), strategy.Name, nil)
+		} else {
+			reason := abortReason(config)
+			strategyLogger.Errorw("aborting strategy", "reason", reason)
+			aClient.Stop()
+			if !config.Sequential.ReuseServer && aServer != nil {
+				aServer.Stop()
 			}
-			metrics.Start()
-			logger = logger.With("strategy", strategy.Name)
-			startClientAndServer(logger, config, strategy, metrics, &wg)
-			wg.Wait()
-			metrics.Shutdown()
+			eventLog.Record(events.StrategyAborted, aClient.RunID(), strategy.Name, map[string]any{"reason": reason})
+			recorder.SetAborted(aClient.RunID(), reason)
 		}
-	} else {
-		metrics.Start()
-		// Run workloads with strategies in parallel
-		var clients []*client.Client
-		var servers []*server.Server
-		for _, strategy := range config.Strategies {
-			strategyLogger := logger.With("strategy", strategy.Name)
-			aClient, aServer := startClientAndServer(strategyLogger, config, strategy, metrics, &wg)
-			clients = append(clients, aClient)
-			servers = append(servers, aServer)
-		}
-
-		configServer := NewConfigServer(clients, servers, logger)
-		configServer.Start()
-		wg.Wait()
-		configServer.Shutdown()
+		responsiveness := aClient.Responsiveness()
+		recorder.SetResponsiveness(aClient.RunID(), responsiveness.TimeToFirstRejection, responsiveness.RecoveryTime)
+		recorder.EndRuns()
 		metrics.Shutdown()
 	}
+	if reusedServer != nil {
+		reusedServer.Stop()
+		reusedServerWg.Wait()
+	}
+}
+
+// startMetrics starts serving metrics on the configured address, and records the address that's listened on.
+func startMetrics(logger *zap.SugaredLogger, config *Config, metrics *metrics.Metrics, recorder *results.Recorder) {
+	metrics.SetAddr(config.Listeners.Metrics)
+	metrics.Start()
+	recorder.SetListener("metrics", metrics.Addr())
+	logger.Infow("serving metrics", "addr", metrics.Addr())
+}
+
+// runParallel runs strategies with workloads in parallel, along with the config server. The strategies run until their
+// servers' duration elapses or until stop is closed, if provided.
+func runParallel(logger *zap.SugaredLogger, config *Config, metrics *metrics.Metrics, recorder *results.Recorder, eventLog *events.Log, dumpPolicies bool, stop <-chan struct{}) {
+	var wg sync.WaitGroup
+	startMetrics(logger, config, metrics, recorder)
+	var clients []*client.Client
+	var servers []*server.Server
+	strategyChains := make(map[string]map[string]policy.Chain)
+	sharedRateLimiters := policy.NewSharedRateLimiters(config.SharedRateLimiters, metrics)
+	for _, strategy := range config.Strategies {
+		strategyLogger := newStrategyLogger(logger, strategy)
+		aClient, aServer, chains := startClientAndServer(strategyLogger, config, strategy, metrics, recorder, eventLog, sharedRateLimiters, nil, &wg, &wg)
+		clients = append(clients, aClient)
+		servers = append(servers, aServer)
+		strategyChains[strategy.Name] = chains
+		if dumpPolicies {
+			policy.PrintChains(os.Stdout, strategy.Name, chains)
+		}
+	}
+
+	configServer := NewConfigServer(config.Listeners.Config, metrics.Addr(), clients, servers, strategyChains, config.Shard, eventLog, logger)
+	configServer.Start()
+	recorder.SetListener("config", configServer.Addr())
+	logger.Infow("listening for config updates", "addr", configServer.Addr())
+	if stop != nil {
+		go func() {
+			<-stop
+			for i := range clients {
+				clients[i].Stop()
+				if servers[i] != nil {
+					servers[i].Stop()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	for i, strategy := range config.Strategies {
+		eventLog.Record(events.StrategyStopped, clients[i].RunID(), strategy.Name, nil)
+	}
+	configServer.Shutdown()
+	metrics.Shutdown()
+}
+
+// parseArgs parses flags that appear before or after positional args, returning the positional args.
+func parseArgs(flags *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		_ = flags.Parse(args)
+		if flags.NArg() == 0 {
+			return positional
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
 }
 
-func startClientAndServer(logger *zap.SugaredLogger, config *Config, strategy *Strategy, metrics *metrics.Metrics, wg *sync.WaitGroup) (*client.Client, *server.Server) {
-	logger.Info("running strategy ", strategy.Name)
-	runID := fmt.Sprintf("%s %s", time.Now().Format("15:04:05"), strategy.Name)
+// startClientAndServer starts a client and server for the strategy. If a reusedServer is provided, it's handed off to the
+// strategy rather than starting a new server. The strategy's policies share the sharedRateLimiters with other strategies.
+func startClientAndServer(logger *zap.SugaredLogger, config *Config, strategy *Strategy, metrics *metrics.Metrics, recorder *results.Recorder, eventLog *events.Log,
+	sharedRateLimiters *policy.SharedRateLimiters, reusedServer *server.Server, serverWg *sync.WaitGroup, clientWg *sync.WaitGroup) (*client.Client, *server.Server, map[string]policy.Chain) {
+	logger.Infow("running strategy")
+	runID := newRunID(config, strategy)
 	strategyMetrics := metrics.WithStrategy(runID, strategy.Name)
 	strategyMetrics.RunDuration.Set(config.Client.MaxDuration.Seconds())
+	eventLog.Record(events.StrategyStarted, runID, strategy.Name, nil)
+
+	// Prioritizers calibrate and record their signals until the strategy's client is done
+	prioritizersCtx, stopPrioritizers := context.WithCancel(context.Background())
+
+	// Named prioritizers are shared by the client and downstream policies that are bound to them
+	namedPrioritizers := newNamedPrioritizers(prioritizersCtx, logger, config, metrics, strategy)
 
 	// serverExecutors, _ := strategy.ServerPolicies.ToExecutors(strategy.Name, config.Client.Workloads, metrics, strategyMetrics, nil, logger.Desugar())
-	aServer, addr := server.NewServer(config.Server, strategy.Name, metrics, strategyMetrics, nil, logger)
-	wg.Add(1)
-	go aServer.Start(wg)
+	var downstreamExecutors map[string]failsafe.Executor[*http.Response]
+	if config.Server.Downstream != nil {
+		// Downstream policies are recorded as a separate strategy, with their own priority domain
+		downstreamStrategy := strategy.Name + "/downstream"
+		var downstreamPrioritizers *policy.Prioritizers
+		if config.Server.Downstream.PropagatePriority {
+			downstreamPrioritizers = newPrioritizers(prioritizersCtx, logger, config, metrics, downstreamStrategy, strategy.DownstreamPolicies, namedPrioritizers)
+		}
+		downstreamExecutors, _, _ = strategy.DownstreamPolicies.ToExecutors(downstreamStrategy, config.Client.ShareStrategies, config.Client.Stages, config.Client.Workloads, metrics,
+			metrics.WithStrategy(runID, downstreamStrategy), downstreamPrioritizers, sharedRateLimiters, logger.Desugar())
+		strategy.DownstreamPolicies.RecordParameters(metrics, downstreamStrategy)
+	}
+	aServer := reusedServer
+	if config.Server.ExternalURL != "" {
+		// Requests are sent to the external server, so no server is started
+	} else if aServer != nil {
+		aServer.Handoff(strategy.Name, strategyMetrics, downstreamExecutors, logger)
+	} else {
+		var homeRegion *client.Region
+		if config.Client.Regions != nil {
+			homeRegion = config.Client.Regions.Home()
+		}
+		aServer, _ = server.NewServer(regionServerConfig(config.Server, homeRegion), strategy.Name, metrics, strategyMetrics, nil, downstreamExecutors, logger)
+		serverWg.Add(1)
+		go aServer.Start(serverWg)
+	}
 
+	prioritizers := newPrioritizers(prioritizersCtx, logger, config, metrics, strategy.Name, strategy.ClientPolicies, namedPrioritizers)
+	clientExecutors, minClientTimeout, chains := strategy.ClientPolicies.ToExecutors(strategy.Name, config.Client.ShareStrategies, config.Client.Stages, config.Client.Workloads, metrics, strategyMetrics, prioritizers, sharedRateLimiters, logger.Desugar())
+	var serverAddr net.Addr
+	if aServer != nil {
+		serverAddr = aServer.Addr()
+	}
+	aClient := client.NewClient(serverAddr, config.Client, runID, strategy.Name, metrics, eventLog, clientExecutors, minClientTimeout, logger)
+	go func() {
+		<-aClient.Done()
+		stopPrioritizers()
+	}()
+	if config.Server.ExternalURL != "" {
+		aClient.SetServerURL(config.Server.ExternalURL)
+	}
+	aClient.SetRequestLog(recorder.RequestLog())
+	if strategy.Control {
+		aClient.Pin()
+	}
+	strategyMetrics.MinTimeout.Set(minClientTimeout.Seconds())
+	if config.Client.Regions != nil {
+		startRegions(logger, config, runID, strategy, metrics, eventLog, aClient, aServer, downstreamExecutors, clientWg)
+	}
+	strategy.ClientPolicies.RecordParameters(metrics, strategy.Name)
+	recordRunInfo(config, runID, strategy, aClient.ServerURL(), metrics, recorder)
+	clientWg.Add(1)
+	go aClient.Start(clientWg)
+
+	if config.Reaction != nil && !strategy.Control {
+		go measureReaction(logger, config, runID, strategy, metrics, strategyMetrics, eventLog, aClient, aServer)
+	}
+
+	return aClient, aServer, chains
+}
+
+// newRunID returns an ID for a strategy run. Sharded runs are identified by their shared run ID and the scenario's seed
+// rather than their start time, so that every shard's metrics share the same run IDs.
+func newRunID(config *Config, strategy *Strategy) string {
+	if config.Shard != nil {
+		return fmt.Sprintf("%s %d %s", config.Shard.Run, config.Seed, strategy.Name)
+	}
+	return fmt.Sprintf("%s %s", time.Now().Format("15:04:05"), strategy.Name)
+}
+
+func shardName(shard *Shard) string {
+	if shard == nil {
+		return ""
+	}
+	return shard.String()
+}
+
+// coolDown waits between sequential strategies, for the configured cooldown or until the cooldown condition is met.
+func coolDown(logger *zap.SugaredLogger, config *SequentialConfig, metrics *metrics.Metrics, previousClient *client.Client, previousServer *server.Server) {
+	if config.CooldownUntil == "" {
+		time.Sleep(config.Cooldown)
+		return
+	}
+
+	idle := func() bool {
+		clientInflight := metrics.Value(metrics.WithWorkload(previousClient.RunID(), "staged", previousClient.Strategy()).ClientInflightRequests)
+		return clientInflight == 0 && (previousServer == nil || previousServer.Inflight() == 0)
+	}
+	start := time.Now()
+	timeout := time.After(config.Cooldown)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for !idle() {
+		select {
+		case <-timeout:
+			logger.Warnw("cooldown condition was not met before the cooldown elapsed", "condition", config.CooldownUntil, "cooldown", config.Cooldown)
+			return
+		case <-ticker.C:
+		}
+	}
+	logger.Infow("cooldown condition met", "condition", config.CooldownUntil, "elapsed", time.Since(start))
+}
+
+// newPrioritizers creates default limiter and throttler prioritizers for any of the policies that aren't bound to a named
+// prioritizer, if prioritization is configured.
+func newPrioritizers(ctx context.Context, logger *zap.SugaredLogger, config *Config, metrics *metrics.Metrics, strategy string, policies policy.Configs, named map[string]priority.Prioritizer) *policy.Prioritizers {
 	hasLimiter := false
 	hasThrottler := false
-	for _, pConfig := range strategy.ClientPolicies {
-		if pConfig.AdaptiveLimiterConfig != nil {
+	for _, pConfig := range policies {
+		if pConfig.AdaptiveLimiterConfig != nil && pConfig.AdaptiveLimiterConfig.Prioritizer == "" {
 			hasLimiter = true
-		} else if pConfig.AdaptiveThrottlerConfig != nil {
+		} else if pConfig.AdaptiveThrottlerConfig != nil && pConfig.AdaptiveThrottlerConfig.Prioritizer == "" {
 			hasThrottler = true
 		}
 	}
 
-	// Create prioritizers if configuration is provided
-	var limiterPrioritizer, throttlerPrioritizer priority.Prioritizer
-	if config.Client.Prioritize && len(config.Client.Workloads) > 1 {
+	prioritizers := &policy.Prioritizers{Named: named}
+	if prioritize(config) {
 		if hasLimiter {
-			lpBuilder := adaptivelimiter.NewPrioritizerBuilder()
-			if config.Client.TrackUsage {
-				lpBuilder = lpBuilder.WithUsageTracker(priority.NewUsageTracker(5*time.Second, 10))
-			}
-			limiterPrioritizer = lpBuilder.WithLogger(slog.New(zapslog.NewHandler(logger.Desugar().Core()))).Build()
-			limiterPrioritizer.ScheduleCalibrations(context.Background(), 500*time.Millisecond)
+			prioritizers.Limiter = newPrioritizer(ctx, logger, config, metrics.WithPrioritizer(strategy, policy.LimiterPrioritizer), policy.LimiterPrioritizer)
 		}
-
 		if hasThrottler {
-			throttlerPrioritizer = adaptivethrottler.NewPrioritizerBuilder().
-				WithLogger(slog.New(zapslog.NewHandler(logger.Desugar().Core()))).
-				Build()
-			throttlerPrioritizer.ScheduleCalibrations(context.Background(), 500*time.Millisecond)
+			prioritizers.Throttler = newPrioritizer(ctx, logger, config, metrics.WithPrioritizer(strategy, policy.ThrottlerPrioritizer), policy.ThrottlerPrioritizer)
 		}
 	}
+	return prioritizers
+}
 
-	clientExecutors, minClientTimeout := strategy.ClientPolicies.ToExecutors(strategy.Name, config.Client.ShareStrategies, config.Client.Stages, config.Client.Workloads, metrics, strategyMetrics, limiterPrioritizer, throttlerPrioritizer, logger.Desugar())
-	aClient := client.NewClient(addr, config.Client, runID, strategy.Name, metrics, clientExecutors, logger)
-	strategyMetrics.MinTimeout.Set(minClientTimeout.Seconds())
-	wg.Add(1)
-	go aClient.Start(wg)
+// newNamedPrioritizers creates the strategy's named prioritizers, if prioritization is configured.
+func newNamedPrioritizers(ctx context.Context, logger *zap.SugaredLogger, config *Config, metrics *metrics.Metrics, strategy *Strategy) map[string]priority.Prioritizer {
+	if !prioritize(config) || len(strategy.Prioritizers) == 0 {
+		return nil
+	}
+	named := make(map[string]priority.Prioritizer)
+	for name, pConfig := range strategy.Prioritizers {
+		named[name] = newPrioritizer(ctx, logger.With("prioritizer", name), config, metrics.WithPrioritizer(strategy.Name, name), pConfig.Type)
+	}
+	return named
+}
+
+// prioritize returns whether policies should be prioritized, which requires more than one workload.
+func prioritize(config *Config) bool {
+	return config.Client.Prioritize && len(config.Client.Workloads) > 1
+}
+
+// calibrationInterval is how often prioritizers calibrate their rejection threshold.
+const calibrationInterval = 500 * time.Millisecond
+
+// newPrioritizer creates and starts calibrating a limiter or throttler prioritizer, recording its signals after each
+// calibration until the ctx is done.
+func newPrioritizer(ctx context.Context, logger *zap.SugaredLogger, config *Config, prioritizerMetrics *metrics.PrioritizerMetrics, prioritizerType string) priority.Prioritizer {
+	var prioritizer priority.Prioritizer
+	if prioritizerType == policy.LimiterPrioritizer {
+		lpBuilder := adaptivelimiter.NewPrioritizerBuilder()
+		if config.Client.TrackUsage {
+			lpBuilder = lpBuilder.WithUsageTracker(priority.NewUsageTracker(5*time.Second, 10))
+		}
+		prioritizer = lpBuilder.WithLogger(slog.New(zapslog.NewHandler(logger.Desugar().Core()))).Build()
+	} else {
+		prioritizer = adaptivethrottler.NewPrioritizerBuilder().
+			WithLogger(slog.New(zapslog.NewHandler(logger.Desugar().Core()))).
+			Build()
+	}
+	prioritizer.ScheduleCalibrations(ctx, calibrationInterval)
+	go recordPrioritizer(ctx, prioritizer, prioritizerMetrics)
+	return prioritizer
+}
+
+// recordPrioritizer periodically records a prioritizer's rejection rate, threshold, and registered policies, along with
+// the fraction of each priority's levels that the threshold admits, until the ctx is done.
+func recordPrioritizer(ctx context.Context, prioritizer priority.Prioritizer, prioritizerMetrics *metrics.PrioritizerMetrics) {
+	ticker := time.NewTicker(calibrationInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		threshold := prioritizer.RejectionThreshold()
+		prioritizerMetrics.RejectionRate.Set(prioritizer.RejectionRate())
+		prioritizerMetrics.RejectionThreshold.Set(float64(threshold))
+		prioritizerMetrics.RegisteredPolicies.Set(float64(prioritizer.RegisteredPolicies()))
+		for p := priority.VeryLow; p <= priority.VeryHigh; p++ {
+			prioritizerMetrics.AdmissionRate(strconv.Itoa(int(p))).Set(admissionRate(p, threshold))
+		}
+	}
+}
+
+// admissionRate returns the fraction of a priority's levels that are at or above the rejection threshold.
+func admissionRate(p priority.Priority, threshold int) float64 {
+	levels := p.MaxLevel() - p.MinLevel() + 1
+	admitted := min(max(p.MaxLevel()-threshold+1, 0), levels)
+	return float64(admitted) / float64(levels)
+}
+
+// recordRunInfo begins recording a strategy run, along with its server's address and the metadata of the scenario, strategy, and workloads.
+func recordRunInfo(config *Config, runID string, strategy *Strategy, serverAddr string, metrics *metrics.Metrics, recorder *results.Recorder) {
+	metrics.RecordRunInfo(runID, strategy.Name, append(append([]string{}, config.Tags...), strategy.Tags...))
+	workloadMetadata := make(map[string]results.Metadata)
+	for _, workload := range config.Client.Workloads {
+		workloadMetadata[workload.Name] = results.Metadata{Description: workload.Description, Tags: workload.Tags}
+		metrics.RecordWorkloadInfo(runID, workload.Name, strategy.Name, workload.Tags)
+	}
+	recorder.AddRun(runID, strategy.Name, serverAddr, results.Metadata{Description: strategy.Description, Tags: strategy.Tags}, workloadNames(config),
+		workloadMetadata)
+	if weights := businessWeights(config.Client); weights != nil {
+		recorder.SetBusinessWeights(runID, weights)
+	}
+	if strategy.Parameters != nil {
+		metrics.RecordRunParameters(runID, strategy.Name, strategy.Parameters)
+		recorder.SetParameters(runID, strategy.Parameters)
+	}
+	if strategy.Control {
+		recorder.SetControl(runID)
+	}
+	if slos := workloadSLOs(config.Client); slos != nil {
+		recorder.SetSLOs(runID, slos)
+	}
+	if analytic := analyticModel(config); analytic != nil {
+		recorder.SetAnalytic(runID, analytic)
+	}
+	if slos := stageSLOs(config.Client); slos != nil {
+		recorder.SetStageSLOs(runID, slos)
+	}
+}
+
+// analyticModel returns an M/M/c model of the server for the configured workloads, or nil if the config isn't simple
+// enough to model, such as when the load changes over time, requests are served by something besides the embedded
+// server's threads, or the server calls a downstream.
+func analyticModel(config *Config) *results.Analytic {
+	serverConfig := config.Server
+	if len(config.Client.Workloads) == 0 || config.Client.Regions != nil || len(config.Bursts) > 0 || config.Reaction != nil ||
+		serverConfig.ExternalURL != "" || (serverConfig.WorkModel != nil && serverConfig.WorkModel.Type != "threads") ||
+		serverConfig.Downstream != nil || serverConfig.Async != nil || serverConfig.Streaming != nil || serverConfig.Autoscaler != nil {
+		return nil
+	}
+	workloads := make(map[string]results.AnalyticWorkload)
+	for _, workload := range config.Client.Workloads {
+		if workload.Paused {
+			// Paused workloads don't offer any load
+			continue
+		}
+		if workload.Concurrency > 0 || len(workload.Stages) > 0 || workload.Pattern != nil ||
+			(workload.Generator != nil && workload.Generator.Type == "trace") {
+			return nil
+		}
+		workloads[workload.Name] = results.AnalyticWorkload{RPS: float64(workload.RPS), ServiceTime: workload.ServiceTimes.Mean()}
+	}
+	return results.NewAnalytic(serverConfig.Threads, workloads)
+}
+
+// workloadSLOs returns the SLOs of the workloads that have them, by name, or nil if none do.
+func workloadSLOs(config *client.Config) map[string]results.SLO {
+	var slos map[string]results.SLO
+	for _, workload := range config.Workloads {
+		if workload.SLO == nil {
+			continue
+		}
+		if slos == nil {
+			slos = make(map[string]results.SLO)
+		}
+		slos[workload.Name] = results.SLO{Target: workload.SLO.Target, Window: workload.SLO.Window}
+	}
+	return slos
+}
+
+// businessWeights returns the business weights of the workloads that metrics are recorded under, or nil if none are
+// configured. Stages don't have priorities, so they have a weight of 1.
+func businessWeights(config *client.Config) map[string]float64 {
+	configured := config.BusinessWeights != nil
+	weights := make(map[string]float64)
+	if len(config.Stages) > 0 {
+		weights["staged"] = 1
+	}
+	for _, workload := range config.Workloads {
+		weights[workload.Name] = config.BusinessWeight(workload)
+		configured = configured || workload.BusinessWeight != nil
+	}
+	if !configured {
+		return nil
+	}
+	return weights
+}
+
+// workloadNames returns the names that workload metrics are recorded under.
+func workloadNames(config *Config) []string {
+	if len(config.Client.Stages) > 0 {
+		return []string{"staged"}
+	}
+	var names []string
+	for _, workload := range config.Client.Workloads {
+		names = append(names, workload.Name)
+	}
+	return names
+}
+
+// stageSLOs returns the client's stage SLOs in the form that results are recorded with.
+func stageSLOs(config *client.Config) []results.StageSLO {
+	var slos []results.StageSLO
+	for _, slo := range config.StageSLOs {
+		var p *int
+		if slo.Priority != nil {
+			value := int(*slo.Priority)
+			p = &value
+		}
+		slos = append(slos, results.StageSLO{Name: slo.Name, Stage: slo.Stage, Workload: slo.Workload, Priority: p,
+			Target: slo.Target, Latency: slo.Latency})
+	}
+	return slos
+}
+
+// measureReaction applies the configured reaction step to a strategy's client or server and records how the strategy reacts.
+func measureReaction(logger *zap.SugaredLogger, config *Config, runID string, strategy *Strategy, metrics *metrics.Metrics, strategyMetrics *metrics.StrategyMetrics, eventLog *events.Log, aClient *client.Client, aServer *server.Server) {
+	// Determine the names that workload and policy metrics are recorded under
+	workloads := workloadNames(config)
+	policyScopes := workloads
+	if len(config.Client.Stages) == 0 && config.Client.ShareStrategies {
+		policyScopes = []string{"shared"}
+	}
+
+	step := func() {
+		logger.Infow("applying reaction step", "rps", config.Reaction.RPS, "threads", config.Reaction.Threads)
+		eventLog.Record(events.ConfigUpdated, runID, strategy.Name, map[string]any{"source": "reaction", "rps": config.Reaction.RPS,
+			"threads": config.Reaction.Threads})
+		if config.Reaction.RPS != 0 {
+			aClient.SetRPS(config.Reaction.RPS)
+		}
+		if config.Reaction.Threads != 0 {
+			aServer.UpdateConfig(&server.Config{Threads: config.Reaction.Threads})
+		}
+	}
+	signals := func() (limit float64, rejected float64, total float64, queued float64) {
+		for _, scope := range policyScopes {
+			limit += metrics.Value(metrics.WithConcurrencyLimit(scope, strategy.Name))
+		}
+		for _, workload := range workloads {
+			workloadMetrics := metrics.WithWorkload(runID, workload, strategy.Name)
+			rejected += metrics.Value(workloadMetrics.ClientReqRejected)
+			total += metrics.Value(workloadMetrics.ClientReqTotal)
+			queued += metrics.Value(workloadMetrics.ClientInflightRequests)
+		}
+		return
+	}
 
-	return aClient, aServer
+	result := reaction.Run(config.Reaction, step, signals)
+	strategyMetrics.ReactionLimitSettleTime.Set(result.LimitSettleTime.Seconds())
+	strategyMetrics.ReactionRejectionSettleTime.Set(result.RejectionSettleTime.Seconds())
+	strategyMetrics.ReactionExcessQueueing.Set(result.ExcessQueueing)
+	logger.Infow("reaction measured", "limitSettleTime", result.LimitSettleTime, "rejectionSettleTime", result.RejectionSettleTime,
+		"excessQueueing", result.ExcessQueueing)
 }
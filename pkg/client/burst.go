@@ -0,0 +1,157 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"tripwire/pkg/events"
+)
+
+// BurstConfig injects a sudden spike of load partway through each strategy's run, separately from any stage transitions,
+// either by multiplying the RPS of open-loop workloads and stages for a Duration, or by firing a number of simultaneous
+// Requests, such as to model a thundering herd.
+type BurstConfig struct {
+	At         time.Duration `yaml:"at"`         // when the burst starts, relative to the start of the strategy
+	Multiplier float64       `yaml:"multiplier"` // multiplies the RPS for the duration
+	Duration   time.Duration `yaml:"duration"`   // how long the RPS is multiplied for
+	Requests   uint          `yaml:"requests"`   // the number of simultaneous requests to fire for each workload
+	Workloads  []string      `yaml:"workloads"`  // the workloads that the burst applies to. Defaults to all, or to stages.
+}
+
+func (c *BurstConfig) Validate() error {
+	if c.At < 0 {
+		return fmt.Errorf("burst at cannot be negative")
+	}
+	if (c.Multiplier != 0) == (c.Requests != 0) {
+		return fmt.Errorf("burst requires either a multiplier or requests")
+	}
+	if c.Multiplier < 0 {
+		return fmt.Errorf("burst multiplier cannot be negative")
+	}
+	if c.Multiplier != 0 && c.Duration <= 0 {
+		return fmt.Errorf("burst multiplier requires a positive duration")
+	}
+	return nil
+}
+
+// appliesTo returns whether the burst applies to a workload.
+func (c *BurstConfig) appliesTo(workload string) bool {
+	return len(c.Workloads) == 0 || slices.Contains(c.Workloads, workload)
+}
+
+// ValidateBursts validates the bursts and that their workloads exist, where workloads are the names that the client's
+// requests are recorded under.
+func ValidateBursts(bursts []*BurstConfig, workloads []string) error {
+	for i, burst := range bursts {
+		if err := burst.Validate(); err != nil {
+			return fmt.Errorf("burst %d: %w", i, err)
+		}
+		for _, workload := range burst.Workloads {
+			if !slices.Contains(workloads, workload) {
+				return fmt.Errorf("burst %d: unknown workload: %s", i, workload)
+			}
+		}
+	}
+	return nil
+}
+
+// runBursts applies the client's bursts at their times, relative to when it's called, until ctx is done.
+func (c *Client) runBursts(ctx context.Context) {
+	start := time.Now()
+	var wg sync.WaitGroup
+	for _, burst := range c.config.Bursts {
+		wg.Add(1)
+		go func(burst *BurstConfig) {
+			defer wg.Done()
+			sleep(ctx, time.Until(start.Add(burst.At)))
+			if ctx.Err() != nil {
+				return
+			}
+			c.logger.Infow("starting burst", "multiplier", burst.Multiplier, "duration", burst.Duration, "requests", burst.Requests,
+				"workloads", burst.Workloads)
+			c.events.Record(events.Burst, c.runID, c.strategy, map[string]any{
+				"multiplier": burst.Multiplier,
+				"duration":   burst.Duration.Seconds(),
+				"requests":   burst.Requests,
+				"workloads":  burst.Workloads,
+			})
+			if burst.Requests > 0 {
+				c.fireBurst(burst)
+				return
+			}
+			c.setBurstActive(burst, true)
+			sleep(ctx, burst.Duration)
+			c.setBurstActive(burst, false)
+		}(burst)
+	}
+	wg.Wait()
+}
+
+// setBurstActive adds or removes a burst from the active bursts whose multipliers are applied.
+func (c *Client) setBurstActive(burst *BurstConfig, active bool) {
+	c.burstMtx.Lock()
+	defer c.burstMtx.Unlock()
+	var bursts []*BurstConfig
+	if current := c.activeBursts.Load(); current != nil {
+		bursts = slices.Clone(*current)
+	}
+	if active {
+		bursts = append(bursts, burst)
+	} else if i := slices.Index(bursts, burst); i != -1 {
+		bursts = slices.Delete(bursts, i, i+1)
+	}
+	c.activeBursts.Store(&bursts)
+}
+
+// burstRPS returns an RPS for a workload multiplied by any active bursts that apply to it.
+func (c *Client) burstRPS(workload string, rps uint) uint {
+	bursts := c.activeBursts.Load()
+	if bursts == nil {
+		return rps
+	}
+	multiplied := float64(rps)
+	for _, burst := range *bursts {
+		if burst.appliesTo(workload) {
+			multiplied *= burst.Multiplier
+		}
+	}
+	return max(1, uint(multiplied))
+}
+
+// fireBurst sends a burst's requests for each of the unpaused workloads it applies to, or for the current stage, at once.
+// Requests that are fired during a client stage count toward its SLOs.
+func (c *Client) fireBurst(burst *BurstConfig) {
+	if workloads := c.Workloads(); workloads != nil {
+		for _, w := range workloads {
+			if w.Paused || !burst.appliesTo(w.Name) {
+				continue
+			}
+			workloadMetrics := c.metrics.WithWorkload(c.runID, w.Name, c.strategy)
+			logger := c.logger.With("workload", w.Name)
+			for i := uint(0); i < burst.Requests; i++ {
+				c.inflight.Add(1)
+				go c.sendRequest(&request{workload: w.Name, user: w.User, region: w.Region, method: w.Method, path: w.Path,
+					clientID: c.clientID(w.Name, c.workloadClients(w)), metrics: workloadMetrics,
+					serviceTime: w.ServiceTimes.Random(c.rng, w.WeightSum), priority: w.Priorities.Random(c.rng, w.Priority),
+					sloLatency: w.SLO.latency(), logger: c.sampledLogger(logger, c.workloadLogSample(w))})
+			}
+		}
+		return
+	}
+	stage := c.currentStage.Load()
+	if stage == nil {
+		return
+	}
+	stageSLOs := c.stageSLOsAt(slices.Index(c.config.Stages, stage), "staged")
+	workloadMetrics := c.metrics.WithWorkload(c.runID, "staged", c.strategy)
+	logger := c.logger.With("workload", "staged")
+	for i := uint(0); i < burst.Requests; i++ {
+		c.inflight.Add(1)
+		go c.sendRequest(&request{workload: "staged", clientID: c.clientID("staged", c.config.Clients), metrics: workloadMetrics,
+			serviceTime: stage.ServiceTimes.Random(c.rng, stage.WeightSum), stageSLOs: stageSLOs,
+			logger: c.sampledLogger(logger, c.config.LogSample)})
+	}
+}
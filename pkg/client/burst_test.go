@@ -0,0 +1,61 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateBursts(t *testing.T) {
+	workloads := []string{"reads", "writes"}
+	assert.NoError(t, ValidateBursts([]*BurstConfig{{At: time.Second, Multiplier: 3, Duration: time.Second}, {Requests: 100, Workloads: []string{"reads"}}}, workloads))
+	assert.Error(t, ValidateBursts([]*BurstConfig{{Multiplier: 3}}, workloads))
+	assert.Error(t, ValidateBursts([]*BurstConfig{{Multiplier: 3, Duration: time.Second, Requests: 100}}, workloads))
+	assert.Error(t, ValidateBursts([]*BurstConfig{{}}, workloads))
+	assert.Error(t, ValidateBursts([]*BurstConfig{{Requests: 100, Workloads: []string{"deletes"}}}, workloads))
+}
+
+func TestBurstRPS(t *testing.T) {
+	c := &Client{}
+	assert.Equal(t, uint(100), c.burstRPS("reads", 100))
+
+	// Overlapping bursts compound, and only apply to their workloads
+	all := &BurstConfig{Multiplier: 3, Duration: time.Second}
+	reads := &BurstConfig{Multiplier: 2, Duration: time.Second, Workloads: []string{"reads"}}
+	c.setBurstActive(all, true)
+	c.setBurstActive(reads, true)
+	assert.Equal(t, uint(600), c.burstRPS("reads", 100))
+	assert.Equal(t, uint(300), c.burstRPS("writes", 100))
+
+	c.setBurstActive(all, false)
+	assert.Equal(t, uint(200), c.burstRPS("reads", 100))
+	assert.Equal(t, uint(100), c.burstRPS("writes", 100))
+}
+
+func TestFireBurst(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer server.Close()
+	config := &Config{Workloads: []*Workload{
+		{Name: "reads", ServiceTimes: WeightedServiceTimes{{Weight: 1}}, WeightSum: 1},
+		{Name: "writes", ServiceTimes: WeightedServiceTimes{{Weight: 1}}, WeightSum: 1},
+		{Name: "paused", ServiceTimes: WeightedServiceTimes{{Weight: 1}}, WeightSum: 1, Paused: true},
+	}}
+	c := newTestClient(t, server.Listener.Addr(), config, "burst", withoutPolicies("reads"))
+
+	c.fireBurst(&BurstConfig{Requests: 20, Workloads: []string{"reads"}})
+	c.inflight.Wait()
+	assert.Equal(t, int32(20), requests.Load())
+	assert.Equal(t, 20.0, testMetrics.Value(testMetrics.WithWorkload("burst", "reads", "burst").ClientReqTotal))
+
+	// Paused workloads don't receive bursts
+	c.fireBurst(&BurstConfig{Requests: 20, Workloads: []string{"paused"}})
+	c.inflight.Wait()
+	assert.Equal(t, int32(20), requests.Load())
+}
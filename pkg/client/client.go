@@ -5,10 +5,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"math/rand"
+	"math"
 	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/failsafe-go/failsafe-go"
@@ -22,11 +26,17 @@ import (
 
 	"github.com/failsafe-go/failsafe-go/ratelimiter"
 	"github.com/failsafe-go/failsafe-go/timeout"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
-	"gopkg.in/yaml.v3"
 
+	"tripwire/pkg/events"
 	"tripwire/pkg/metrics"
+	"tripwire/pkg/results"
 	"tripwire/pkg/server"
+	"tripwire/pkg/tracing"
 	"tripwire/pkg/util"
 )
 
@@ -35,37 +45,352 @@ type Config struct {
 	TrackUsage      bool `yaml:"track_usage"`
 	ShareStrategies bool `yaml:"share_strategies"`
 
-	Workloads   []*Workload `yaml:"workloads"` // workloads run in parallel
-	Stages      []*Stage    `yaml:"stages"`    // stages run in sequence
+	PollInterval     time.Duration `yaml:"poll_interval"`     // how often to poll for the completion of async requests
+	UpdateTransition time.Duration `yaml:"update_transition"` // how long to ramp workloads from their old to new RPS when updated
+	Drain            time.Duration `yaml:"drain"`             // how long to wait for inflight requests after the last stage
+
+	// CorrectedLatency measures the response times that results and SLOs are based on from when requests were scheduled
+	// to be sent, rather than when they were sent, so that latency isn't under-reported when the client falls behind
+	CorrectedLatency bool `yaml:"corrected_latency"`
+
+	Generator *GeneratorConfig `yaml:"generator"`  // generates stage arrivals, and workload arrivals by default. Defaults to uniform.
+	LogSample float64          `yaml:"log_sample"` // the fraction of stage requests to log, and of workload requests by default
+	ReadRate  uint             `yaml:"read_rate"`  // bytes per second that response bodies are read at, to simulate slow consumers
+	Clients   uint             `yaml:"clients"`    // distinct client identities that stage requests, and workload requests by default, are spread across
+	Transport *TransportConfig `yaml:"transport"`  // defaults to a connection per request
+	Regions   *RegionsConfig   `yaml:"regions"`    // groups servers into regions that requests are routed between
+
+	// HostBulkhead limits the concurrent requests to each host, which is the server in each region
+	HostBulkhead *HostBulkheadConfig `yaml:"host_bulkhead"`
+
+	// Hooks are expressions that customize how requests are prioritized, shed, and classified
+	Hooks *HooksConfig `yaml:"hooks"`
+
+	// SelfProtection backs off the client's load when the host that tripwire runs on is saturated
+	SelfProtection *SelfProtectionConfig `yaml:"self_protection"`
+
+	// BusinessWeights are the business value of workloads' requests, by priority, which results are weighted by
+	BusinessWeights map[priority.Priority]float64 `yaml:"business_weights"`
+
+	Workloads   []*Workload    `yaml:"workloads"`  // workloads run in parallel
+	Stages      []*Stage       `yaml:"stages"`     // stages run in sequence
+	StageSLOs   []*StageSLO    `yaml:"stage_slos"` // objectives that are evaluated against the requests sent during a stage
+	Bursts      []*BurstConfig `yaml:"-"`          // spikes of load that are injected into each run, which are configured for the scenario
 	MaxDuration time.Duration
+	Seed        int64 // seeds service times, priorities, and client identities
+	ArrivalSeed int64 // seeds inter-arrival times and think times
 }
 
 type Workload struct {
-	Name         string               `yaml:"name"`
-	RPS          uint                 `yaml:"rps"`
-	User         string               `yaml:"user"`
-	Priority     priority.Priority    `yaml:"priority"`
-	ServiceTimes WeightedServiceTimes `yaml:"service_times"`
-	WeightSum    int
+	Name           string               `yaml:"name"`
+	Description    string               `yaml:"description"`
+	Tags           []string             `yaml:"tags"`
+	RPS            uint                 `yaml:"rps"`
+	User           string               `yaml:"user"`
+	Priority       priority.Priority    `yaml:"priority"`
+	Priorities     WeightedPriorities   `yaml:"priorities"` // a weighted mix of priorities that overrides the priority
+	ServiceTimes   WeightedServiceTimes `yaml:"service_times"`
+	Generator      *GeneratorConfig     `yaml:"generator"`       // overrides the client's generator
+	LogSample      *float64             `yaml:"log_sample"`      // overrides the client's log sample, for debugging specific workloads
+	Clients        *uint                `yaml:"clients"`         // overrides the client's client identities
+	BusinessWeight *float64             `yaml:"business_weight"` // overrides the business weight of the workload's priority
+	Region         string               `yaml:"region"`          // the region that the workload's requests are sent from, which defaults to the first
+	Method         string               `yaml:"method"`          // the method that the workload's requests are sent with. Defaults to POST.
+	Path           string               `yaml:"path"`            // the path that the workload's requests are sent to. Defaults to /.
+	Pattern        *PatternConfig       `yaml:"pattern"`         // modulates the RPS over time
+	SLO            *SLOConfig           `yaml:"slo"`             // the objective that the workload's error budget is measured against
+	Transport      *TransportConfig     `yaml:"transport"`       // overrides the client's transport with a separate connection pool
+	Paused         bool                 `yaml:"paused"`          // stops the workload from sending requests until it's resumed
+	WeightSum      int
+
+	// When set, the workload is closed-loop, where each of Concurrency virtual users sends a request, waits for it to
+	// complete, then waits for the think time, if any, before sending the next. The RPS and generator are not used.
+	Concurrency uint       `yaml:"concurrency"`
+	ThinkTime   *ThinkTime `yaml:"think_time"`
+
+	// Stages change a workload's RPS, or concurrency for a closed-loop workload, and service times over time, starting
+	// when the workload starts, such as to spike one tenant while others stay constant. Each stage's RPS or concurrency
+	// and service times are carried over from the previous stage, or the workload. Once the stages end, the workload
+	// returns to its own RPS or concurrency and service times.
+	Stages []*Stage `yaml:"stages"`
+}
+
+// Normalize validates the workload and computes its WeightSum, which must be done before the workload is run.
+func (w *Workload) Normalize() error {
+	if w.Name == "" {
+		return fmt.Errorf("workload name is required")
+	}
+	if err := w.Validate(); err != nil {
+		return err
+	}
+	w.WeightSum = int(w.ServiceTimes.Sum())
+	if len(w.ServiceTimes) > 0 && w.WeightSum == 0 {
+		return fmt.Errorf("workload %s: service times must have a positive weight", w.Name)
+	}
+	return w.normalizeStages()
+}
+
+// normalizeStages validates the workload's stages and carries over their RPS or concurrency and service times. The
+// stages are replaced with copies, since the originals may be in use by a running workload.
+func (w *Workload) normalizeStages() error {
+	stages := make([]*Stage, 0, len(w.Stages))
+	previousRPS, previousConcurrency, previousServiceTimes := w.RPS, w.Concurrency, w.ServiceTimes
+	for i, original := range w.Stages {
+		stage := *original
+		if w.Concurrency > 0 {
+			if stage.RPS != 0 || stage.Ramping() {
+				return fmt.Errorf("workload %s stage %d: rps requires an open-loop workload", w.Name, i)
+			}
+			if stage.Concurrency == 0 {
+				stage.Concurrency = previousConcurrency
+			}
+			if stage.RampingUsers() && stage.ConcurrencyStart == 0 {
+				stage.ConcurrencyStart = previousConcurrency
+			}
+		} else {
+			if stage.Concurrency != 0 || stage.ConcurrencyStart != 0 || stage.RampingUsers() {
+				return fmt.Errorf("workload %s stage %d: concurrency requires a closed-loop workload", w.Name, i)
+			}
+			if stage.RPS == 0 {
+				stage.RPS = previousRPS
+			}
+			if stage.Ramping() && stage.RPSStart == 0 {
+				stage.RPSStart = previousRPS
+			}
+		}
+		if stage.ServiceTimes == nil {
+			stage.ServiceTimes = previousServiceTimes
+		}
+		if stage.Duration <= 0 {
+			return fmt.Errorf("workload %s stage %d: duration must be positive", w.Name, i)
+		}
+		if stage.Drain != 0 {
+			return fmt.Errorf("workload %s stage %d: drain is only supported for client stages", w.Name, i)
+		}
+		if err := stage.Validate(); err != nil {
+			return fmt.Errorf("workload %s stage %d: %w", w.Name, i, err)
+		}
+		if stage.Ramping() {
+			stage.RPS = stage.RPSEnd
+		}
+		if stage.RampingUsers() {
+			stage.Concurrency = stage.ConcurrencyEnd
+		}
+		stage.WeightSum = int(stage.ServiceTimes.Sum())
+		previousRPS, previousConcurrency, previousServiceTimes = stage.RPS, stage.Concurrency, stage.ServiceTimes
+		stages = append(stages, &stage)
+	}
+	if w.Stages != nil {
+		w.Stages = stages
+	}
+	return nil
+}
+
+// NormalizeWorkloads normalizes each of the workloads, which must have unique names and a rate to send requests at. The
+// generator is the client's, which workloads can override.
+func NormalizeWorkloads(workloads []*Workload, generator *GeneratorConfig) error {
+	names := make(map[string]bool)
+	for _, workload := range workloads {
+		if err := workload.Normalize(); err != nil {
+			return err
+		}
+		if err := workload.validateRate(generator); err != nil {
+			return err
+		}
+		if names[workload.Name] {
+			return fmt.Errorf("duplicate workload name: %s", workload.Name)
+		}
+		names[workload.Name] = true
+	}
+	return nil
+}
+
+func (w *Workload) Validate() error {
+	if w.Path != "" && !strings.HasPrefix(w.Path, "/") {
+		return fmt.Errorf("workload %s: path must start with /: %q", w.Name, w.Path)
+	}
+	if err := w.ServiceTimes.Validate(); err != nil {
+		return fmt.Errorf("workload %s: %w", w.Name, err)
+	}
+	if err := w.Priorities.Validate(); err != nil {
+		return fmt.Errorf("workload %s: %w", w.Name, err)
+	}
+	if w.Pattern != nil {
+		if err := w.Pattern.Validate(); err != nil {
+			return fmt.Errorf("workload %s: %w", w.Name, err)
+		}
+	}
+	if w.SLO != nil {
+		if err := w.SLO.Validate(); err != nil {
+			return fmt.Errorf("workload %s: %w", w.Name, err)
+		}
+	}
+	if w.ThinkTime != nil {
+		if err := w.ThinkTime.Validate(); err != nil {
+			return fmt.Errorf("workload %s: %w", w.Name, err)
+		}
+	}
+	if w.Transport != nil {
+		if err := w.Transport.Validate(); err != nil {
+			return fmt.Errorf("workload %s: %w", w.Name, err)
+		}
+	}
+	return nil
+}
+
+// validateRate checks that an open-loop workload has an RPS, since only a trace generator sends requests without one. Any
+// stages carry over the workload's RPS, and the workload returns to it once they end.
+func (w *Workload) validateRate(generator *GeneratorConfig) error {
+	if w.Generator != nil {
+		generator = w.Generator
+	}
+	if w.Concurrency > 0 || w.RPS > 0 || (generator != nil && generator.Type == "trace") {
+		return nil
+	}
+	return fmt.Errorf("workload %s: rps must be positive for an open-loop workload", w.Name)
+}
+
+// Endpoint returns the method and path that the workload's requests are sent to, including any defaults.
+func (w *Workload) Endpoint() (string, string) {
+	method, path := w.Method, w.Path
+	if method == "" {
+		method = http.MethodPost
+	}
+	if path == "" {
+		path = "/"
+	}
+	return method, path
+}
+
+// BusinessWeight returns the business value of a workload's requests, which defaults to the weight of its priority, or 1.
+// For a mix of priorities, the weights of the priorities are averaged by their share of requests.
+func (c *Config) BusinessWeight(workload *Workload) float64 {
+	if workload.BusinessWeight != nil {
+		return *workload.BusinessWeight
+	}
+	if sum := workload.Priorities.Sum(); sum > 0 {
+		var total float64
+		for _, p := range workload.Priorities {
+			total += c.priorityBusinessWeight(p.Priority) * float64(p.Weight)
+		}
+		return total / float64(sum)
+	}
+	return c.priorityBusinessWeight(workload.Priority)
+}
+
+func (c *Config) priorityBusinessWeight(p priority.Priority) float64 {
+	if weight, ok := c.BusinessWeights[p]; ok {
+		return weight
+	}
+	return 1
 }
 
 type Stage struct {
 	Duration     time.Duration        `yaml:"duration"`
+	Drain        time.Duration        `yaml:"drain"`         // how long to wait for inflight requests after the stage
 	RPS          uint                 `yaml:"rps"`           // can be carried over from the previous stage
 	ServiceTimes WeightedServiceTimes `yaml:"service_times"` // can be carried over from the previous stage
 	WeightSum    int
+
+	// When RPSEnd is set, the stage's RPS ramps from RPSStart, which defaults to the previous stage's RPS, to RPSEnd over
+	// the stage's duration, in the shape of the Ramp, which defaults to linear.
+	RPSStart uint   `yaml:"rps_start"`
+	RPSEnd   uint   `yaml:"rps_end"`
+	Ramp     string `yaml:"ramp"`
+
+	// For a closed-loop workload's stages, the Concurrency is the number of virtual users, which can be carried over from
+	// the previous stage. When ConcurrencyEnd is set, the number of users ramps from ConcurrencyStart, which defaults to
+	// the previous stage's concurrency, to ConcurrencyEnd over the stage's duration, in the shape of the Ramp.
+	Concurrency      uint `yaml:"concurrency"`
+	ConcurrencyStart uint `yaml:"concurrency_start"`
+	ConcurrencyEnd   uint `yaml:"concurrency_end"`
+}
+
+const (
+	RampLinear      = "linear"
+	RampExponential = "exponential" // the RPS grows by the same factor in each period, for finding a tipping point quickly
+)
+
+// Ramping returns whether the stage's RPS ramps over its duration.
+func (s *Stage) Ramping() bool {
+	return s.RPSEnd != 0
+}
+
+// RampingUsers returns whether the stage's number of virtual users ramps over its duration.
+func (s *Stage) RampingUsers() bool {
+	return s.ConcurrencyEnd != 0
+}
+
+func (s *Stage) Validate() error {
+	if s.Ramp != "" && s.Ramp != RampLinear && s.Ramp != RampExponential {
+		return fmt.Errorf("unknown stage ramp: %s", s.Ramp)
+	}
+	if s.RPSStart != 0 && s.RPSEnd == 0 {
+		return fmt.Errorf("stage rps_start requires an rps_end")
+	}
+	if s.Ramping() && s.RPSStart == 0 {
+		return fmt.Errorf("stage rps_start is required when there's no previous stage to ramp from")
+	}
+	if s.ConcurrencyStart != 0 && s.ConcurrencyEnd == 0 {
+		return fmt.Errorf("stage concurrency_start requires a concurrency_end")
+	}
+	return s.ServiceTimes.Validate()
+}
+
+// rpsAt returns the stage's RPS at some elapsed time into the stage.
+func (s *Stage) rpsAt(elapsed time.Duration) uint {
+	if !s.Ramping() {
+		return s.RPS
+	}
+	return s.rampAt(s.RPSStart, s.RPSEnd, elapsed)
+}
+
+// concurrencyAt returns the stage's number of virtual users at some elapsed time into the stage.
+func (s *Stage) concurrencyAt(elapsed time.Duration) uint {
+	if !s.RampingUsers() {
+		return s.Concurrency
+	}
+	return s.rampAt(s.ConcurrencyStart, s.ConcurrencyEnd, elapsed)
+}
+
+// rampAt returns a value at some elapsed time into the stage as it ramps from start to end in the shape of the Ramp.
+func (s *Stage) rampAt(start uint, end uint, elapsed time.Duration) uint {
+	if s.Ramp == RampExponential {
+		progress := min(float64(elapsed)/float64(s.Duration), 1)
+		return max(1, uint(float64(start)*math.Pow(float64(end)/float64(start), progress)))
+	}
+	return rampedRPS(start, end, elapsed, s.Duration)
 }
 
 func (s *Stage) String() string {
-	return fmt.Sprintf("RPS: %d, Duration: %ds, ServiceTimes: %s", s.RPS, int(s.Duration.Seconds()), s.ServiceTimes.String())
+	rps := strconv.Itoa(int(s.RPS))
+	if s.Ramping() {
+		ramp := s.Ramp
+		if ramp == "" {
+			ramp = RampLinear
+		}
+		rps = fmt.Sprintf("%d->%d (%s)", s.RPSStart, s.RPSEnd, ramp)
+	}
+	return fmt.Sprintf("RPS: %s, Duration: %ds, ServiceTimes: %s", rps, int(s.Duration.Seconds()), s.ServiceTimes.String())
 }
 
+// WeightedServiceTime is either a fixed service time or, when a Distribution is configured, a distribution that service
+// times are sampled from.
 type WeightedServiceTime struct {
 	ServiceTime time.Duration `yaml:"service_time"`
 	Weight      uint          `yaml:"weight"`
+
+	Distribution string        `yaml:"distribution"` // lognormal, exponential, or pareto
+	Mean         time.Duration `yaml:"mean"`         // the mean of the distribution
+	Sigma        float64       `yaml:"sigma"`        // the standard deviation of the log of lognormal service times
+	Alpha        float64       `yaml:"alpha"`        // the shape of pareto service times, where lower is heavier tailed
+	Max          time.Duration `yaml:"max"`          // caps sampled service times, if set
 }
 
 func (w *WeightedServiceTime) String() string {
+	if w.Distribution != "" {
+		return fmt.Sprintf(`{Distribution: %s, Mean: %dms, Weight: %d}`, w.Distribution, int(w.Mean.Milliseconds()), w.Weight)
+	}
 	return fmt.Sprintf(`{ServiceTime: %dms, Weight: %d}`, int(w.ServiceTime.Milliseconds()), w.Weight)
 }
 
@@ -91,19 +416,56 @@ func (w WeightedServiceTimes) Sum() uint {
 	return sum
 }
 
-// Random selects a random service time based on the weightSum.
-func (w WeightedServiceTimes) Random(weightSum int) time.Duration {
-	return w.Weighted(rand.Intn(weightSum))
+// Random selects a random service time based on the weightSum, sampling it from the selected entry's distribution, if any.
+// Returns 0 if the weightSum isn't positive.
+func (w WeightedServiceTimes) Random(rng *util.Rand, weightSum int) time.Duration {
+	if weightSum <= 0 {
+		return 0
+	}
+	if entry := w.weighted(rng.Intn(weightSum)); entry != nil {
+		return entry.sample(rng)
+	}
+	return 0
 }
 
+// Weighted returns the service time at a weight, which is the mean service time of distributions.
 func (w WeightedServiceTimes) Weighted(weight int) time.Duration {
+	if entry := w.weighted(weight); entry != nil {
+		return entry.mean()
+	}
+	return 0
+}
+
+// Mean returns the weighted mean of the service times, ignoring any max.
+func (w WeightedServiceTimes) Mean() time.Duration {
+	sum := w.Sum()
+	if sum == 0 {
+		return 0
+	}
+	var total float64
+	for _, st := range w {
+		total += float64(st.Weight) * float64(st.mean())
+	}
+	return time.Duration(total / float64(sum))
+}
+
+func (w WeightedServiceTimes) weighted(weight int) *WeightedServiceTime {
 	for _, wl := range w {
 		weight -= int(wl.Weight)
 		if weight < 0 {
-			return wl.ServiceTime
+			return wl
 		}
 	}
-	return 0
+	return nil
+}
+
+func (w WeightedServiceTimes) Validate() error {
+	for _, st := range w {
+		if err := st.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (w WeightedServiceTimes) String() string {
@@ -124,173 +486,746 @@ type Client struct {
 	runID      string
 	strategy   string
 	metrics    *metrics.Metrics
+	events     *events.Log
 	logger     *zap.SugaredLogger
 	httpClient *http.Client
+	transport  http.RoundTripper // The base transport, beneath any policies
 	adaptive   bool
+	timeout    time.Duration // The deadline that is propagated to the server, if any
+	rng        *util.Rand
+	arrivalRng *util.Rand          // Separate from rng, so that arrivals can vary independently of service times
+	regions    []*regionTarget     // The servers in each region, if any
+	protector  *selfProtector      // Backs off when the host is saturated, if configured
+	bulkheads  *hostBulkheads      // Limits the concurrent requests to each host, if configured
+	pinned     bool                // Whether bursts and workload updates are ignored, such as for a control strategy
+	external   bool                // Whether requests are sent to an external server rather than the embedded server
+	hooks      *hooks              // Evaluates expressions that customize how requests are handled, if configured
+	tracer     trace.Tracer        // Records a span for each request, which is a no-op unless tracing is configured
+	requestLog *results.RequestLog // Records the outcome of each request, if configured
+
+	// The base transports of workloads that override the client's transport, which are fixed when the client is created
+	workloadTransports map[string]*clientTransport
+	// Routes requests through their workload's executor, which is replaced when policies are enabled or disabled
+	roundTripper *util.WorkloadRoundTripper
 
-	mtx             sync.RWMutex
-	config          *Config // Workloads is guarded by mtx
-	cancelWorkloads func()  // Guarded by mtx
+	config    *Config
+	workloads atomic.Pointer[[]*Workload] // An immutable snapshot that's replaced when workloads are updated
+	mtx       sync.Mutex                  // Serializes workload updates
+	runners   map[string]*workloadRunner  // Guarded by mtx
+
+	stageRPS         atomic.Uint64 // Overrides the RPS of stages when non-zero
+	stageRPSChanged  chan struct{}
+	currentStage     atomic.Pointer[Stage]          // The stage that's running, if any
+	activeBursts     atomic.Pointer[[]*BurstConfig] // An immutable snapshot of the bursts whose multipliers are applied
+	burstMtx         sync.Mutex                     // Serializes changes to the active bursts
+	tracker          *responsivenessTracker         // Measures the responsiveness of stages, if any
+	nextRequestID    atomic.Uint64
+	requestsInflight atomic.Int64 // The requests that have been sent and haven't completed, across workloads
+	latestCompleted  sync.Map     // The latest request ID that completed for each workload, as an *atomic.Uint64
+	inflight         sync.WaitGroup
+	stop             chan struct{}
+	done             chan struct{}
 }
 
-func NewClient(serverAddr net.Addr, config *Config, runID string, strategy string, metrics *metrics.Metrics, workloadExecutors map[string]failsafe.Executor[*http.Response], logger *zap.SugaredLogger) *Client {
-	workloadRoundTrippers := make(map[string]http.RoundTripper)
-	for wl, exec := range workloadExecutors {
-		workloadRoundTrippers[wl] = failsafehttp.NewRoundTripperWithExecutor(http.DefaultTransport, exec)
+func NewClient(serverAddr net.Addr, config *Config, runID string, strategy string, metrics *metrics.Metrics, events *events.Log, workloadExecutors map[string]failsafe.Executor[*http.Response], timeout time.Duration, logger *zap.SugaredLogger) *Client {
+	baseTransport := newTransport(config.Transport)
+	workloadTransports := make(map[string]*clientTransport)
+	for _, w := range config.Workloads {
+		if w.Transport != nil {
+			workloadTransports[w.Name] = &clientTransport{config: w.Transport, transport: newTransport(w.Transport)}
+		}
+	}
+
+	var protector *selfProtector
+	if config.SelfProtection != nil {
+		protector = &selfProtector{config: config.SelfProtection}
 	}
 
-	return &Client{
+	var bulkheads *hostBulkheads
+	if config.HostBulkhead != nil {
+		maxConcurrency := config.HostBulkhead.MaxConcurrency
+		bulkheads = newHostBulkheads(config.HostBulkhead, config.hosts(), func(host string, inflight uint) {
+			metrics.WithClientHostSaturation(strategy, host).Set(float64(inflight) / float64(maxConcurrency))
+			metrics.WithClientHostBorrowed(strategy, host).Set(float64(inflight - min(inflight, maxConcurrency)))
+		}, func(host string) {
+			metrics.WithClientHostRejected(strategy, host).Inc()
+		})
+	}
+
+	c := &Client{
 		runID:      runID,
 		strategy:   strategy,
-		serverAddr: fmt.Sprintf("http://localhost:%d", serverAddr.(*net.TCPAddr).Port),
+		serverAddr: serverURL(serverAddr),
 		config:     config,
 		metrics:    metrics,
+		events:     events,
 		logger:     logger.With("runID", runID),
-		httpClient: &http.Client{Transport: util.NewWorkloadRoundTripper(workloadRoundTrippers)},
+		timeout:    timeout,
+		rng:        util.NewRand(config.Seed),
+		arrivalRng: util.NewRand(config.ArrivalSeed),
+		transport:  baseTransport,
+		protector:  protector,
+		bulkheads:  bulkheads,
+		tracer:     otel.Tracer("tripwire/pkg/client"),
+		hooks: newHooks(config.Hooks, func(name string, err error) {
+			logger.Warnw("failed to evaluate hook", "hook", name, "error", err)
+		}),
+
+		workloadTransports: workloadTransports,
+
+		runners:         make(map[string]*workloadRunner),
+		stageRPSChanged: make(chan struct{}, 1),
+		stop:            make(chan struct{}),
+		done:            make(chan struct{}),
+	}
+	c.roundTripper = util.NewWorkloadRoundTripper(c.roundTrippers(workloadExecutors))
+	c.httpClient = &http.Client{Transport: c.roundTripper}
+	if config.Workloads != nil {
+		workloads := config.Workloads
+		c.workloads.Store(&workloads)
 	}
+	return c
+}
+
+// Workloads returns a snapshot of the client's current workloads, which must not be modified, or nil if the client runs
+// stages instead.
+func (c *Client) Workloads() []*Workload {
+	if workloads := c.workloads.Load(); workloads != nil {
+		return *workloads
+	}
+	return nil
+}
+
+// serverURL returns the URL of a server that's listening on addr. Servers that listen on all interfaces are reached via
+// localhost.
+func serverURL(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+	tcpAddr := addr.(*net.TCPAddr)
+	if tcpAddr.IP == nil || tcpAddr.IP.IsUnspecified() {
+		return fmt.Sprintf("http://localhost:%d", tcpAddr.Port)
+	}
+	return "http://" + tcpAddr.String()
+}
+
+// roundTrippers returns a round tripper for each workload that executes requests with its executor, over the workload's
+// transport, and propagates priorities and deadlines to the server.
+func (c *Client) roundTrippers(workloadExecutors map[string]failsafe.Executor[*http.Response]) map[string]http.RoundTripper {
+	workloadRoundTrippers := make(map[string]http.RoundTripper)
+	for wl, exec := range workloadExecutors {
+		transport := c.transport
+		if t, ok := c.workloadTransports[wl]; ok {
+			transport = t.transport
+		}
+		transport = failsafehttp.NewRoundTripperWithLevel(util.NewDeadlineRoundTripper(newBodyReader(transport, c.config.ReadRate)))
+		workloadRoundTrippers[wl] = failsafehttp.NewRoundTripperWithExecutor(transport, exec)
+	}
+	return workloadRoundTrippers
+}
+
+// SetExecutors replaces the executors of workloads, such as when a policy is enabled or disabled at runtime. Requests
+// that are already in progress complete with the executor they started with.
+func (c *Client) SetExecutors(workloadExecutors map[string]failsafe.Executor[*http.Response]) {
+	c.roundTripper.Update(c.roundTrippers(workloadExecutors))
+}
+
+// SetServerURL sets the URL of an external server that requests are sent to instead of the embedded server, which must
+// be called before the client is started. Responses from an external server are classified by their status class.
+func (c *Client) SetServerURL(url string) {
+	c.serverAddr = strings.TrimSuffix(url, "/")
+	c.external = true
+}
+
+// SetRequestLog sets the log that the outcome of each request is written to, which must be called before the client is
+// started.
+func (c *Client) SetRequestLog(requestLog *results.RequestLog) {
+	c.requestLog = requestLog
+}
+
+// ServerURL returns the URL of the server that requests are sent to, when not routed to regions.
+func (c *Client) ServerURL() string {
+	return c.serverAddr
+}
+
+func (c *Client) RunID() string {
+	return c.runID
+}
+
+func (c *Client) Strategy() string {
+	return c.strategy
+}
+
+// Responsiveness returns the responsiveness of the client's stages, which should only be called after the client has
+// stopped.
+func (c *Client) Responsiveness() Responsiveness {
+	if c.tracker == nil {
+		return Responsiveness{}
+	}
+	return c.tracker.responsiveness()
+}
+
+// Done returns a channel that's closed once the client's workloads have stopped, or its stages have finished.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
 }
 
 func (c *Client) Start(wg *sync.WaitGroup) {
 	defer wg.Done()
+	defer close(c.done)
+	c.prewarm()
+	if c.protector != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go c.runSelfProtection(ctx, c.protector)
+	}
+	if len(c.config.Bursts) > 0 && !c.pinned {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go c.runBursts(ctx)
+	}
 
-	if c.config.Workloads != nil {
-		for {
-			ctx, cancelFn := context.WithCancel(context.Background())
-			c.mtx.Lock()
-			c.cancelWorkloads = cancelFn
-			c.mtx.Unlock()
-			c.mtx.RLock()
-			for _, workload := range c.config.Workloads {
-				go c.runWorkload(ctx, workload)
+	if workloads := c.Workloads(); workloads != nil {
+		c.mtx.Lock()
+		for _, workload := range workloads {
+			c.startWorkload(workload)
+		}
+		c.mtx.Unlock()
+		<-c.stop
+		c.mtx.Lock()
+		for name, runner := range c.runners {
+			runner.cancel()
+			delete(c.runners, name)
+		}
+		c.mtx.Unlock()
+		c.logger.Infow("client workloads stopped")
+		if c.config.Drain != 0 {
+			c.drain(c.config.Drain)
+		}
+	} else if c.config.Stages != nil {
+		generator := c.newGenerator(c.config.Generator)
+		workloadMetrics := c.metrics.WithWorkload(c.runID, "staged", c.strategy)
+		c.tracker = newResponsivenessTracker(c.config.Stages)
+		for i, stage := range c.config.Stages {
+			c.events.Record(events.StageStarted, c.runID, c.strategy, map[string]any{
+				"stage":    i,
+				"duration": stage.Duration.Seconds(),
+				"rps":      stage.RPS,
+			})
+			workloadMetrics.ResponseTimes.RotateNamed(fmt.Sprintf("stage %d", i), time.Now())
+			c.tracker.startStage(i, time.Now(), c.metrics.Value(workloadMetrics.ClientReqSuccesses))
+			if !c.runStage(i, stage, generator) {
+				break
 			}
-			c.mtx.RUnlock()
-			select {
-			case <-ctx.Done():
+			if stage.Drain != 0 {
+				c.drain(stage.Drain)
 			}
 		}
-	} else if c.config.Stages != nil {
-		for _, stage := range c.config.Stages {
-			c.runStage(stage)
+		c.currentStage.Store(nil)
+		if c.config.Drain != 0 {
+			c.drain(c.config.Drain)
 		}
+		c.tracker.responsiveness().record(c.metrics.WithStrategy(c.runID, c.strategy))
 
 		c.logger.Infow("client stages finished")
 	}
 }
 
-func (c *Client) runWorkload(ctx context.Context, workload *Workload) {
-	workloadMetrics := c.metrics.WithWorkload(c.runID, workload.Name, c.strategy)
-	workloadMetrics.ClientReqTimeouts.Add(0)
-
-	c.logger.Infow("starting client workload", "workload", workload)
-	ticker := time.NewTicker(time.Second / time.Duration(workload.RPS))
-	defer ticker.Stop()
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			workloadMetrics.ClientExpectedRps.Set(float64(workload.RPS))
-			go c.sendRequest(workload.Name, workload.User, workloadMetrics, workload.ServiceTimes.Random(workload.WeightSum), workload.Priority)
-		}
-	}
-
+// Stop stops the client's workloads, or its current stage. Either is still followed by the client's drain, if any.
+func (c *Client) Stop() {
+	close(c.stop)
 }
 
-func (c *Client) runStage(stage *Stage) {
+// runStage runs the stage at an index until it completes, returning true, or until the client is stopped, returning
+// false.
+func (c *Client) runStage(index int, stage *Stage, generator WorkloadGenerator) bool {
 	workloadMetrics := c.metrics.WithWorkload(c.runID, "staged", c.strategy)
 	workloadMetrics.ClientReqTimeouts.Add(0)
+	workloadMetrics.ClientDroppedArrivals.Add(0)
+	workloadMetrics.ClientBackedOffArrivals.Add(0)
 
 	c.logger.Infow("starting client stage", "stage", stage)
+	stageLogger := c.logger.With("workload", "staged")
+	start := time.Now()
+	c.currentStage.Store(stage)
+	baseRPS := stage.rpsAt(0)
+	if override := uint(c.stageRPS.Load()); override != 0 {
+		baseRPS = override
+	}
+	params := &GeneratorParams{
+		RPS:          c.burstRPS("staged", baseRPS),
+		ServiceTimes: stage.ServiceTimes,
+		WeightSum:    stage.WeightSum,
+		Rand:         c.rng,
+		ArrivalRand:  c.arrivalRng,
+	}
 	duration := time.After(stage.Duration)
-	ticker := time.NewTicker(time.Second / time.Duration(stage.RPS))
-	defer ticker.Stop()
+	arrivals := newArrivalTimer(generator, params)
+	defer arrivals.stop()
+	sampler := time.NewTicker(goodputSampleInterval)
+	defer sampler.Stop()
 	for {
 		select {
 		case <-duration:
-			return
-		case <-ticker.C:
-			workloadMetrics.ClientExpectedRps.Set(float64(stage.RPS))
-			go c.sendRequest("staged", "", workloadMetrics, stage.ServiceTimes.Random(stage.WeightSum), 0)
+			return true
+		case <-c.stop:
+			return false
+		case <-c.stageRPSChanged:
+			baseRPS = uint(c.stageRPS.Load())
+			params.RPS = c.burstRPS("staged", baseRPS)
+		case now := <-sampler.C:
+			c.tracker.sample(now, c.metrics.Value(workloadMetrics.ClientReqSuccesses))
+		case <-arrivals.timer.C:
+			// Ramp the stage's RPS unless it's been overridden
+			if stage.Ramping() && c.stageRPS.Load() == 0 {
+				baseRPS = stage.rpsAt(time.Since(start))
+			}
+			params.RPS = c.burstRPS("staged", baseRPS)
+			workloadMetrics.ClientExpectedRps.Set(float64(params.RPS))
+			workloadMetrics.ClientArrivalLateness.Observe(arrivals.lateness().Seconds())
+			arrival := arrivals.arrival
+			if c.protector.isSaturated() {
+				workloadMetrics.ClientBackedOffArrivals.Inc()
+			} else {
+				c.inflight.Add(1)
+				go c.sendRequest(&request{workload: "staged", clientID: c.clientID("staged", c.config.Clients), metrics: workloadMetrics,
+					serviceTime: arrival.ServiceTime, priority: arrival.Priority, stageSLOs: c.stageSLOsAt(index, "staged"),
+					scheduled: arrivals.scheduled, logger: c.sampledLogger(stageLogger, c.config.LogSample)})
+			}
+			workloadMetrics.ClientDroppedArrivals.Add(float64(arrivals.advance(params)))
 		}
 	}
 }
 
-func (c *Client) sendRequest(workloadName string, user string, workloadMetrics *metrics.WorkloadMetrics, serviceTime time.Duration, p priority.Priority) {
-	start := time.Now()
-	request := server.Request{ServiceTime: serviceTime}
-	reqBody, err := yaml.Marshal(&request)
+// newGenerator creates a generator for the config, which must have already been validated.
+func (c *Client) newGenerator(config *GeneratorConfig) WorkloadGenerator {
+	generator, err := NewGenerator(config)
 	if err != nil {
-		c.logger.Fatalw("error marshalling YAML", "error", err)
-		return
+		c.logger.Fatalw("failed to create workload generator", "error", err)
+	}
+	return generator
+}
+
+// drain waits up to timeout for inflight requests to complete, while no new requests are sent.
+func (c *Client) drain(timeout time.Duration) {
+	c.logger.Infow("draining client requests", "timeout", timeout)
+	drained := make(chan struct{})
+	go func() {
+		c.inflight.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(timeout):
+		c.logger.Warnw("client requests did not drain before timeout", "timeout", timeout)
 	}
+}
+
+// sampledLogger returns the logger if a request should be logged based on the sample fraction, else nil.
+func (c *Client) sampledLogger(logger *zap.SugaredLogger, sample float64) *zap.SugaredLogger {
+	if sample <= 0 || (sample < 1 && c.rng.Float64() >= sample) {
+		return nil
+	}
+	return logger
+}
+
+// request is a request to send, along with how its outcome is recorded.
+type request struct {
+	workload    string
+	user        string // the user that the request is sent on behalf of, if any
+	region      string // the region that the request is sent from, if any
+	method      string // defaults to POST
+	path        string // defaults to /
+	clientID    string // the client identity that the request is sent with, if any
+	metrics     *metrics.WorkloadMetrics
+	serviceTime time.Duration
+	priority    priority.Priority
+	sloLatency  time.Duration      // successful requests that are slower than this, if it's positive, are recorded as slow
+	stageSLOs   []*StageSLO        // the SLOs of the stage that the request was sent during, if any
+	scheduled   time.Time          // when the request was scheduled to be sent, if it was
+	logger      *zap.SugaredLogger // logs the request and its outcome, if provided
+}
+
+// sendRequest sends a request and records its outcome, including for any stage SLOs. Response times are also measured
+// from when the request was scheduled to be sent, if it was, which corrects for coordinated omission when the client falls
+// behind. Callers must add to c.inflight before calling.
+func (c *Client) sendRequest(r *request) {
+	defer c.inflight.Done()
+	workloadMetrics, p := r.metrics, r.priority
+	start := time.Now()
+	scheduled := r.scheduled
+	if scheduled.IsZero() || scheduled.After(start) {
+		scheduled = start
+	}
+	latencyStart := start // when the response times that results and SLOs are based on are measured from
+	if c.config.CorrectedLatency {
+		latencyStart = scheduled
+	}
+	requestID := strconv.FormatUint(c.nextRequestID.Add(1), 10)
+	var vars map[string]any // the variables that hooks are evaluated with, if any
+	if c.hooks != nil {
+		vars = hookVars(r.workload, r.user, r.region, r.method, r.path, r.clientID, p, r.serviceTime, c.requestsInflight.Load())
+		p = c.hooks.prioritize(vars, p)
+	}
+	outcome := "failure"
+	tier := util.TierServer // the tier that a failure originated from
+	var decisions string    // the server's decision trace, if any
+	var reason string       // why a rejected request was rejected
+	defer func() {
+		workloadMetrics.ClientReqPriorities.WithLabelValues(strconv.Itoa(int(p)), outcome).Inc()
+	}()
+	if len(r.stageSLOs) > 0 {
+		defer func() { c.recordStageSLOs(r.stageSLOs, p, outcome, time.Since(latencyStart)) }()
+	}
+	if c.requestLog != nil {
+		defer func() {
+			request := &results.Request{Time: start, Strategy: c.strategy, Workload: r.workload, Priority: int(p),
+				Latency: time.Since(latencyStart), Outcome: outcome, Reason: reason}
+			if outcome != "success" {
+				request.Tier = tier
+			}
+			c.requestLog.Record(request)
+		}()
+	}
+	if r.logger != nil {
+		defer func() {
+			r.logger.Infow("sampled request", "requestID", requestID, "serviceTime", r.serviceTime, "priority", p,
+				"outcome", outcome, "tier", tier, "responseTime", time.Since(start), "decisions", decisions)
+		}()
+	}
+	reqBody := server.Request{ServiceTime: r.serviceTime}.AppendYAML(make([]byte, 0, 32))
 
 	ctx := priority.ContextWithPriority(context.Background(), p)
-	ctx = priority.ContextWithUser(ctx, user)
-	req, err := http.NewRequestWithContext(ctx, "POST", c.serverAddr, bytes.NewBuffer(reqBody))
-	if err != nil {
-		c.logger.Errorw("error creating request", "error", err)
-		return
+	if r.user != "" {
+		ctx = priority.ContextWithUser(ctx, r.user)
 	}
-	req.Header.Set(util.WorkloadHeaderId, workloadName)
-	req.Close = true
+	if c.timeout != 0 {
+		ctx = util.ContextWithDeadline(ctx, start.Add(c.timeout))
+	}
+	ctx, span := c.tracer.Start(ctx, "request", trace.WithSpanKind(trace.SpanKindClient), trace.WithTimestamp(start),
+		trace.WithAttributes(attribute.String("run_id", c.runID), attribute.String("strategy", c.strategy), attribute.String("workload", r.workload),
+			attribute.String("request_id", requestID), attribute.Int("priority", int(p))))
+	defer func() {
+		span.SetAttributes(attribute.String("outcome", outcome), attribute.String("tier", tier))
+		if decisions != "" {
+			span.SetAttributes(attribute.String("decisions", decisions))
+		}
+		if outcome != "success" {
+			span.SetStatus(codes.Error, outcome)
+		}
+		span.End()
+	}()
 
 	workloadMetrics.ClientReqTotal.Inc()
+	if c.hooks.shouldShed(vars) {
+		// Shed requests are rejected by the client without being sent
+		span.AddEvent("shed")
+		workloadMetrics.ClientReqShed.Inc()
+		workloadMetrics.ClientReqRejected.Inc()
+		c.tracker.rejected(time.Now())
+		outcome = "rejected"
+		reason = "shed"
+		tier = util.TierClient
+		workloadMetrics.ClientReqErrors.WithLabelValues(tier, outcome).Inc()
+		workloadMetrics.ClientReqFailures.Inc()
+		return
+	}
 	workloadMetrics.ClientInflightRequests.Inc()
-	resp, err := c.httpClient.Do(req)
+	c.requestsInflight.Add(1)
+	resp, serverAddr, err := c.send(ctx, r, requestID, reqBody)
+	c.requestsInflight.Add(-1)
 	workloadMetrics.ClientInflightRequests.Dec()
 
 	// Handle errors
 	if err != nil {
 		// Handle rejections
-		if errors.Is(err, ratelimiter.ErrExceeded) ||
-			errors.Is(err, adaptivelimiter.ErrExceeded) ||
-			errors.Is(err, adaptivethrottler.ErrExceeded) ||
-			errors.Is(err, bulkhead.ErrFull) ||
-			errors.Is(err, circuitbreaker.ErrOpen) {
+		if reason = rejectionReason(err); reason != "" {
 			// Do not record response time for rejected requests
 			workloadMetrics.ClientReqRejected.Inc()
+			c.tracker.rejected(time.Now())
+			outcome = "rejected"
+			tier = util.TierClient
+			span.AddEvent("rejected", trace.WithAttributes(attribute.String("error", err.Error())))
 		}
 		// Handle timeouts
 		var netErr net.Error
 		if errors.Is(err, timeout.ErrExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
-			c.recordResponseTime(workloadMetrics, start)
+			c.recordResponseTime(workloadMetrics, start, scheduled)
 			workloadMetrics.ClientReqTimeouts.Inc()
+			outcome = "timeout"
+			tier = util.TierClient
+			span.AddEvent("timeout", trace.WithAttributes(attribute.String("error", err.Error())))
 		}
+		workloadMetrics.ClientReqErrors.WithLabelValues(tier, outcome).Inc()
 		workloadMetrics.ClientReqFailures.Inc()
 		return
 	}
 
 	if resp != nil {
 		_ = resp.Body.Close()
+		status, statusTier := resp.StatusCode, resp.Header.Get(util.TierHeaderId)
+		if c.external {
+			status = externalStatus(status, resp.Header.Get("Location") != "")
+		}
+		decisions = resp.Header.Get(util.DecisionTraceHeaderId)
+		if status == http.StatusAccepted {
+			status, statusTier = c.awaitCompletion(serverAddr, resp.Header.Get("Location"), r.workload, requestID, workloadMetrics, start)
+		} else if status == http.StatusOK && duplicated(resp) {
+			workloadMetrics.ClientReqDuplicates.Inc()
+		}
+		if statusTier != "" {
+			tier = statusTier
+		}
+		status = c.hooks.classify(vars, status, time.Since(latencyStart), tier)
 
 		// Handle responses
-		switch resp.StatusCode {
+		switch status {
 		case http.StatusOK:
-			c.recordResponseTime(workloadMetrics, start)
+			c.recordResponseTime(workloadMetrics, start, scheduled)
 			workloadMetrics.ClientReqSuccesses.Inc()
+			if c.completedOutOfOrder(r.workload, requestID) {
+				workloadMetrics.ClientReqOutOfOrder.Inc()
+			}
+			if r.sloLatency > 0 && time.Since(latencyStart) > r.sloLatency {
+				workloadMetrics.ClientReqSlow.Inc()
+			}
+			outcome = "success"
 			return
 		case http.StatusTooManyRequests:
 			// Do not record response time for rejected requests
 			workloadMetrics.ClientReqRejected.Inc()
-		case http.StatusInternalServerError:
-			// Do not record response time for internal server errors
+			c.tracker.rejected(time.Now())
+			outcome = "rejected"
+			reason = strconv.Itoa(status)
+		case http.StatusInternalServerError, http.StatusNotFound, http.StatusMethodNotAllowed:
+			// Do not record response time for internal server errors or requests that the server couldn't route
 		case http.StatusRequestTimeout, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
-			c.recordResponseTime(workloadMetrics, start)
+			c.recordResponseTime(workloadMetrics, start, scheduled)
 			workloadMetrics.ClientReqTimeouts.Inc()
+			outcome = "timeout"
 		default:
-			c.logger.Fatalw("unknown response code", "status", resp.StatusCode)
+			// Statuses that aren't otherwise classified, such as those of an external server or a hook, are failures
+			c.logger.Debugw("unknown response code", "status", status)
 		}
 	}
+	workloadMetrics.ClientReqErrors.WithLabelValues(tier, outcome).Inc()
 	workloadMetrics.ClientReqFailures.Inc()
 }
-func (c *Client) UpdateWorkloads(workloads []*Workload) {
-	c.mtx.Lock()
-	c.config.Workloads = workloads
-	c.cancelWorkloads()
-	c.mtx.Unlock()
+
+// rejectionReason returns the client policy that rejected a request with the err, or "" if the err isn't a rejection.
+func rejectionReason(err error) string {
+	switch {
+	case errors.Is(err, ratelimiter.ErrExceeded):
+		return "ratelimiter"
+	case errors.Is(err, adaptivelimiter.ErrExceeded):
+		return "adaptivelimiter"
+	case errors.Is(err, adaptivethrottler.ErrExceeded):
+		return "adaptivethrottler"
+	case errors.Is(err, bulkhead.ErrFull):
+		return "bulkhead"
+	case errors.Is(err, ErrHostFull):
+		return "host_bulkhead"
+	case errors.Is(err, circuitbreaker.ErrOpen):
+		return "circuitbreaker"
+	default:
+		return ""
+	}
+}
+
+// send sends a request to the regions that it's routed to from its region, failing over to the next region while a
+// region rejects the request, is unavailable, or its host bulkhead is full. Returns the response along with the address
+// of the server that sent it.
+func (c *Client) send(ctx context.Context, r *request, requestID string, body []byte) (*http.Response, string, error) {
+	method := r.method
+	if method == "" {
+		method = http.MethodPost
+	}
+	targets := c.route(r.region)
+	for i, target := range targets {
+		if i > 0 {
+			c.metrics.WithClientRegionFailovers(r.workload, c.strategy, target.name).Inc()
+			time.Sleep(c.config.Regions.Latency)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, target.url+r.path, bytes.NewReader(body))
+		if err != nil {
+			return nil, "", err
+		}
+		req.Header.Set(util.WorkloadHeaderId, r.workload)
+		req.Header.Set(util.RequestIdHeaderId, requestID)
+		tracing.Inject(ctx, req.Header)
+		if r.clientID != "" {
+			req.Header.Set(util.ClientIdHeaderId, r.clientID)
+		}
+		req.Close = !c.transportFor(r.workload).reusesConnections()
+
+		release, ok := c.bulkheads.acquire(target.host())
+		if !ok {
+			if i == len(targets)-1 {
+				return nil, "", ErrHostFull
+			}
+			continue
+		}
+		resp, err := c.httpClient.Do(req)
+		release()
+		if err != nil || i == len(targets)-1 ||
+			resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+			return resp, target.url, err
+		}
+		_ = resp.Body.Close()
+	}
+	return nil, "", nil
 }
 
-func (c *Client) recordResponseTime(workloadMetrics *metrics.WorkloadMetrics, start time.Time) {
-	responseTime := time.Since(start)
+// externalStatus maps the status of an external server's response to the status that the embedded server would respond
+// with, so that responses are classified by their status class. Accepted responses are only polled for completion when
+// they have a location.
+func externalStatus(status int, hasLocation bool) int {
+	switch {
+	case status == http.StatusAccepted && hasLocation, status == http.StatusTooManyRequests, status == http.StatusRequestTimeout,
+		status == http.StatusServiceUnavailable, status == http.StatusGatewayTimeout:
+		return status
+	case status >= 200 && status < 300:
+		return http.StatusOK
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// clientID returns a random one of a workload's client identities, if it has any, so that the server can limit clients
+// individually.
+func (c *Client) clientID(workloadName string, clients uint) string {
+	if clients == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s-%d", workloadName, c.rng.Intn(int(clients)))
+}
+
+// awaitCompletion polls an async request at its location, relative to the server's address, until it completes,
+// returning its final status and the tier that a failed status originated from, if known. Completions that are delivered
+// for a different request are recorded as duplicates and polling continues. Polls are sent with the workload's
+// transport, but are not subject to the client's policies.
+func (c *Client) awaitCompletion(serverAddr string, location string, workloadName string, requestID string, workloadMetrics *metrics.WorkloadMetrics, start time.Time) (int, string) {
+	pollURL, err := resolveLocation(serverAddr, location)
+	if err != nil {
+		c.logger.Errorw("invalid async request location", "location", location, "error", err)
+		return http.StatusInternalServerError, util.TierServer
+	}
+	interval := c.config.PollInterval
+	if interval == 0 {
+		interval = 50 * time.Millisecond
+	}
+	pollClient := &http.Client{Transport: c.baseTransportFor(workloadName)}
+	for {
+		if c.timeout != 0 && time.Since(start) > c.timeout {
+			return http.StatusGatewayTimeout, util.TierClient
+		}
+		time.Sleep(interval)
+		resp, err := pollClient.Get(pollURL)
+		if err != nil {
+			c.logger.Errorw("error polling request", "error", err)
+			return http.StatusInternalServerError, util.TierServer
+		}
+		_ = resp.Body.Close()
+		status := resp.StatusCode
+		if c.external {
+			// Polls are already at a location, so an accepted poll is still pending
+			status = externalStatus(status, true)
+		}
+		if status == http.StatusOK {
+			if delivered := resp.Header.Get(util.RequestIdHeaderId); delivered != "" && delivered != requestID {
+				workloadMetrics.ClientReqDuplicates.Inc()
+				continue
+			}
+		}
+		if status != http.StatusAccepted {
+			return status, resp.Header.Get(util.TierHeaderId)
+		}
+	}
+}
+
+// resolveLocation resolves an async request's location against the address of the server that accepted it, so that
+// both relative and absolute locations can be polled.
+func resolveLocation(serverAddr string, location string) (string, error) {
+	base, err := url.Parse(serverAddr)
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(location)
+	if err != nil {
+		return "", err
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+// duplicated returns whether a streaming response body was delivered more than once, based on the size the server
+// streamed versus the size that was received.
+func duplicated(resp *http.Response) bool {
+	size, err := strconv.ParseUint(resp.Header.Get(util.StreamSizeHeaderId), 10, 64)
+	if err != nil {
+		return false
+	}
+	received, err := strconv.ParseUint(resp.Header.Get(util.ReceivedSizeHeaderId), 10, 64)
+	return err == nil && received > size
+}
+
+// completedOutOfOrder records that a workload's request completed, returning whether a later request of the same
+// workload already completed before it.
+func (c *Client) completedOutOfOrder(workloadName string, requestID string) bool {
+	id, _ := strconv.ParseUint(requestID, 10, 64)
+	latest, _ := c.latestCompleted.LoadOrStore(workloadName, &atomic.Uint64{})
+	for {
+		current := latest.(*atomic.Uint64).Load()
+		if id < current {
+			return true
+		}
+		if latest.(*atomic.Uint64).CompareAndSwap(current, id) {
+			return false
+		}
+	}
+}
+
+// Pin pins the client to its configured workloads or stages, so that bursts don't apply to it, and so that callers that
+// update workloads can leave it untouched as a reference, such as for a control strategy. Must be called before Start.
+func (c *Client) Pin() {
+	c.pinned = true
+}
+
+// Pinned returns whether the client is pinned to its configured workloads or stages.
+func (c *Client) Pinned() bool {
+	return c.pinned
+}
+
+// SetRPS changes the rate of every workload, or of the current and any subsequent stages, to rps.
+func (c *Client) SetRPS(rps uint) {
+	current := c.Workloads()
+	if current == nil {
+		c.stageRPS.Store(uint64(rps))
+		select {
+		case c.stageRPSChanged <- struct{}{}:
+		default:
+		}
+		return
+	}
+
+	workloads := make([]*Workload, 0, len(current))
+	for _, workload := range current {
+		updated := *workload
+		updated.RPS = rps
+		workloads = append(workloads, &updated)
+	}
+	if err := c.UpdateWorkloads(workloads); err != nil {
+		c.logger.Errorw("failed to update workload rps", "error", err)
+	}
+}
+
+func (c *Client) recordResponseTime(workloadMetrics *metrics.WorkloadMetrics, start time.Time, scheduled time.Time) {
+	now := time.Now()
+	responseTime, correctedTime := now.Sub(start), now.Sub(scheduled)
 	workloadMetrics.ClientReqResponseTimes.Observe(responseTime.Seconds())
+	workloadMetrics.ClientReqCorrectedTimes.Observe(correctedTime.Seconds())
+	if c.config.CorrectedLatency {
+		workloadMetrics.ResponseTimes.Record(correctedTime)
+	} else {
+		workloadMetrics.ResponseTimes.Record(responseTime)
+	}
 }
@@ -0,0 +1,351 @@
+package client
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/failsafe-go/failsafe-go"
+	"github.com/failsafe-go/failsafe-go/circuitbreaker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	"tripwire/pkg/metrics"
+	"tripwire/pkg/results"
+	"tripwire/pkg/util"
+)
+
+// testMetrics are shared by the package's tests, since metrics can only be registered once.
+var testMetrics = metrics.New(nil, zap.NewNop().Sugar())
+
+// newTestClient returns a client of the server at addr, whose run ID and strategy are the runID, and whose workloads'
+// requests are executed with the executors. Any workloads that the client runs are stopped when the test ends.
+func newTestClient(t testing.TB, addr net.Addr, config *Config, runID string, executors map[string]failsafe.Executor[*http.Response]) *Client {
+	c := NewClient(addr, config, runID, runID, testMetrics, nil, executors, time.Second, zap.NewNop().Sugar())
+	t.Cleanup(func() { _ = c.UpdateWorkloads(nil) })
+	return c
+}
+
+// withoutPolicies returns executors for the workloads that don't have any policies.
+func withoutPolicies(workloads ...string) map[string]failsafe.Executor[*http.Response] {
+	executors := make(map[string]failsafe.Executor[*http.Response])
+	for _, workload := range workloads {
+		executors[workload] = failsafe.With[*http.Response]()
+	}
+	return executors
+}
+
+// BenchmarkSendRequest measures the client's overhead for sending a request to a server that responds immediately,
+// including the HTTP round trip.
+func BenchmarkSendRequest(b *testing.B) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	c := newTestClient(b, server.Listener.Addr(), &Config{}, "bench", withoutPolicies("bench"))
+	workloadMetrics := testMetrics.WithWorkload("bench", "bench", "bench")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.inflight.Add(1)
+		c.sendRequest(&request{workload: "bench", metrics: workloadMetrics})
+	}
+}
+
+func TestCorrectedLatency(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	scheduled := time.Now().Add(-time.Second)
+
+	// Results are measured from when requests were sent, unless corrected latency is enabled
+	for _, corrected := range []bool{false, true} {
+		runID := fmt.Sprintf("corrected-%t", corrected)
+		c := newTestClient(t, server.Listener.Addr(), &Config{CorrectedLatency: corrected}, runID, withoutPolicies("corrected"))
+		workloadMetrics := testMetrics.WithWorkload(runID, "corrected", runID)
+		c.inflight.Add(1)
+		c.sendRequest(&request{workload: "corrected", metrics: workloadMetrics, scheduled: scheduled})
+		assert.Equal(t, corrected, workloadMetrics.ResponseTimes.Merged().Max() >= time.Second)
+	}
+}
+
+func TestDuplicateDelivery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(util.StreamSizeHeaderId, "10")
+		_, _ = w.Write(make([]byte, 20))
+	}))
+	defer server.Close()
+	c := newTestClient(t, server.Listener.Addr(), &Config{}, "duplicated", withoutPolicies("duplicated"))
+	workloadMetrics := testMetrics.WithWorkload("duplicated", "duplicated", "duplicated")
+	c.inflight.Add(1)
+	c.sendRequest(&request{workload: "duplicated", metrics: workloadMetrics})
+	assert.Equal(t, 1.0, testMetrics.Value(workloadMetrics.ClientReqDuplicates))
+	assert.Equal(t, 1.0, testMetrics.Value(workloadMetrics.ClientReqSuccesses))
+}
+
+func TestWorkloadEndpoint(t *testing.T) {
+	var method, path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+	}))
+	defer server.Close()
+	c := newTestClient(t, server.Listener.Addr(), &Config{}, "endpoint", withoutPolicies("endpoint"))
+	workloadMetrics := testMetrics.WithWorkload("endpoint", "endpoint", "endpoint")
+	c.inflight.Add(1)
+	c.sendRequest(&request{workload: "endpoint", method: http.MethodPut, path: "/orders", metrics: workloadMetrics})
+	assert.Equal(t, http.MethodPut, method)
+	assert.Equal(t, "/orders", path)
+}
+
+func TestWorkloadTransport(t *testing.T) {
+	var mtx sync.Mutex
+	connections := make(map[string]map[string]bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		workload := r.Header.Get(util.WorkloadHeaderId)
+		if connections[workload] == nil {
+			connections[workload] = make(map[string]bool)
+		}
+		connections[workload][r.RemoteAddr] = true
+	}))
+	defer server.Close()
+	config := &Config{Workloads: []*Workload{
+		{Name: "per-request"},
+		{Name: "pooled", Transport: &TransportConfig{Type: TransportPooled, MaxIdleConns: 10}},
+	}}
+	c := newTestClient(t, server.Listener.Addr(), config, "transport", withoutPolicies("per-request", "pooled"))
+
+	// Only the workload that overrides the client's transport reuses its connections
+	for _, workload := range []string{"per-request", "pooled"} {
+		workloadMetrics := testMetrics.WithWorkload("transport", workload, "transport")
+		for i := 0; i < 3; i++ {
+			c.inflight.Add(1)
+			c.sendRequest(&request{workload: workload, metrics: workloadMetrics})
+		}
+	}
+	assert.Len(t, connections["per-request"], 3)
+	assert.Len(t, connections["pooled"], 1)
+}
+
+func TestH2CTransport(t *testing.T) {
+	var mtx sync.Mutex
+	connections := make(map[string]bool)
+	var protos []int
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		connections[r.RemoteAddr] = true
+		protos = append(protos, r.ProtoMajor)
+	})
+	server := httptest.NewServer(h2c.NewHandler(handler, &http2.Server{}))
+	defer server.Close()
+	config := &Config{Transport: &TransportConfig{Type: TransportH2C}}
+	c := newTestClient(t, server.Listener.Addr(), config, "h2c", withoutPolicies("h2c"))
+
+	// Requests are multiplexed over a single connection
+	workloadMetrics := testMetrics.WithWorkload("h2c", "h2c", "h2c")
+	for i := 0; i < 3; i++ {
+		c.inflight.Add(1)
+		c.sendRequest(&request{workload: "h2c", metrics: workloadMetrics})
+	}
+	assert.Equal(t, []int{2, 2, 2}, protos)
+	assert.Len(t, connections, 1)
+	assert.Equal(t, 3.0, testMetrics.Value(workloadMetrics.ClientReqSuccesses))
+}
+
+func TestExternalServer(t *testing.T) {
+	statuses := []int{http.StatusCreated, http.StatusBadRequest, http.StatusTooManyRequests, http.StatusAccepted}
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(statuses[requests.Add(1)-1])
+	}))
+	defer server.Close()
+	c := newTestClient(t, nil, &Config{}, "external", withoutPolicies("external"))
+	c.SetServerURL(server.URL + "/")
+	assert.Equal(t, server.URL, c.ServerURL())
+
+	// Responses are classified by their status class, and accepted responses without a location aren't polled
+	workloadMetrics := testMetrics.WithWorkload("external", "external", "external")
+	for range statuses {
+		c.inflight.Add(1)
+		c.sendRequest(&request{workload: "external", metrics: workloadMetrics})
+	}
+	assert.Equal(t, 2.0, testMetrics.Value(workloadMetrics.ClientReqSuccesses))
+	assert.Equal(t, 1.0, testMetrics.Value(workloadMetrics.ClientReqRejected))
+	assert.Equal(t, 2.0, testMetrics.Value(workloadMetrics.ClientReqFailures))
+}
+
+func TestExternalServerPolling(t *testing.T) {
+	var polls atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/orders", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "/api/jobs/1")
+		w.WriteHeader(http.StatusAccepted)
+	})
+	mux.HandleFunc("/api/jobs/1", func(w http.ResponseWriter, r *http.Request) {
+		// Polls are classified like other external responses, where a 204 completes the request
+		if polls.Add(1) < 2 {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	c := newTestClient(t, nil, &Config{PollInterval: time.Millisecond}, "polled", withoutPolicies("polled"))
+	c.SetServerURL(server.URL + "/api/")
+
+	// Locations are resolved against the external URL
+	workloadMetrics := testMetrics.WithWorkload("polled", "polled", "polled")
+	c.inflight.Add(1)
+	c.sendRequest(&request{workload: "polled", path: "/orders", metrics: workloadMetrics})
+	assert.Equal(t, int32(2), polls.Load())
+	assert.Equal(t, 1.0, testMetrics.Value(workloadMetrics.ClientReqSuccesses))
+}
+
+func TestResolveLocation(t *testing.T) {
+	for _, tc := range []struct{ serverAddr, location, expected string }{
+		{"http://localhost:8080", "/async/1", "http://localhost:8080/async/1"},
+		{"http://localhost:8080/api/", "jobs/1", "http://localhost:8080/api/jobs/1"},
+		{"http://localhost:8080/api", "http://status.local/jobs/1", "http://status.local/jobs/1"},
+	} {
+		resolved, err := resolveLocation(tc.serverAddr, tc.location)
+		require.NoError(t, err)
+		assert.Equal(t, tc.expected, resolved)
+	}
+	_, err := resolveLocation("http://localhost:8080", "http://%zz")
+	assert.Error(t, err)
+}
+
+func TestUnknownStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer server.Close()
+	c := newTestClient(t, server.Listener.Addr(), &Config{}, "teapot", withoutPolicies("teapot"))
+
+	// Statuses that aren't classified count as failures rather than stopping the client
+	workloadMetrics := testMetrics.WithWorkload("teapot", "teapot", "teapot")
+	c.inflight.Add(1)
+	c.sendRequest(&request{workload: "teapot", metrics: workloadMetrics})
+	assert.Equal(t, 1.0, testMetrics.Value(workloadMetrics.ClientReqFailures))
+}
+
+func TestSetExecutors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	cb := circuitbreaker.NewWithDefaults[*http.Response]()
+	cb.Open()
+	c := newTestClient(t, server.Listener.Addr(), &Config{}, "toggled", map[string]failsafe.Executor[*http.Response]{"toggled": failsafe.With[*http.Response](cb)})
+	workloadMetrics := testMetrics.WithWorkload("toggled", "toggled", "toggled")
+	send := func() {
+		c.inflight.Add(1)
+		c.sendRequest(&request{workload: "toggled", metrics: workloadMetrics})
+	}
+
+	send()
+	assert.Equal(t, 0.0, testMetrics.Value(workloadMetrics.ClientReqSuccesses))
+
+	// Requests are executed without the circuit breaker once it's removed
+	c.SetExecutors(withoutPolicies("toggled"))
+	send()
+	assert.Equal(t, 1.0, testMetrics.Value(workloadMetrics.ClientReqSuccesses))
+}
+
+func TestRequestLog(t *testing.T) {
+	statuses := []int{http.StatusTooManyRequests, http.StatusOK}
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(statuses[requests.Add(1)-1])
+	}))
+	defer server.Close()
+	cb := circuitbreaker.NewWithDefaults[*http.Response]()
+	cb.Open()
+	c := newTestClient(t, server.Listener.Addr(), &Config{}, "logged", map[string]failsafe.Executor[*http.Response]{"logged": failsafe.With[*http.Response](cb)})
+	dir := &results.Dir{Path: t.TempDir()}
+	requestLog, err := results.OpenRequestLog(dir, &results.RequestLogConfig{Format: results.RequestLogCSV})
+	require.NoError(t, err)
+	c.SetRequestLog(requestLog)
+	workloadMetrics := testMetrics.WithWorkload("logged", "logged", "logged")
+	send := func() {
+		c.inflight.Add(1)
+		c.sendRequest(&request{workload: "logged", metrics: workloadMetrics, priority: 3})
+	}
+
+	send()
+	c.SetExecutors(withoutPolicies("logged"))
+	send()
+	send()
+	require.NoError(t, requestLog.Close())
+
+	// Each request's outcome is logged along with why it was rejected
+	file, err := os.Open(dir.File(results.RequestLogFile))
+	require.NoError(t, err)
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	require.NoError(t, err)
+	records, err := csv.NewReader(reader).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+	var logged [][]string
+	for _, record := range records[1:] {
+		assert.Equal(t, []string{"logged", "logged", "3"}, record[1:4])
+		logged = append(logged, record[5:])
+	}
+	assert.Equal(t, [][]string{
+		{"rejected", util.TierClient, "circuitbreaker"},
+		{"rejected", util.TierServer, "429"},
+		{"success", "", ""},
+	}, logged)
+}
+
+func TestCompletedOutOfOrder(t *testing.T) {
+	c := &Client{}
+	assert.False(t, c.completedOutOfOrder("reads", "2"))
+	assert.True(t, c.completedOutOfOrder("reads", "1"))
+	assert.False(t, c.completedOutOfOrder("writes", "1"))
+	assert.False(t, c.completedOutOfOrder("reads", "3"))
+}
+
+func TestStageRamp(t *testing.T) {
+	linear := &Stage{Duration: 10 * time.Second, RPSStart: 10, RPSEnd: 110}
+	assert.Equal(t, uint(10), linear.rpsAt(0))
+	assert.Equal(t, uint(60), linear.rpsAt(5*time.Second))
+	assert.Equal(t, uint(110), linear.rpsAt(20*time.Second))
+
+	exponential := &Stage{Duration: 10 * time.Second, RPSStart: 10, RPSEnd: 1000, Ramp: RampExponential}
+	assert.Equal(t, uint(10), exponential.rpsAt(0))
+	assert.InDelta(t, 100, exponential.rpsAt(5*time.Second), 1)
+	assert.Equal(t, uint(1000), exponential.rpsAt(10*time.Second))
+
+	assert.Equal(t, uint(50), (&Stage{RPS: 50}).rpsAt(time.Second))
+	assert.Error(t, (&Stage{RPSEnd: 10}).Validate())
+	assert.Error(t, (&Stage{RPSStart: 10}).Validate())
+	assert.Error(t, (&Stage{RPSStart: 10, RPSEnd: 20, Ramp: "sine"}).Validate())
+}
+
+func TestNormalizeWorkloads(t *testing.T) {
+	workloads := []*Workload{{Name: "writes", RPS: 10, ServiceTimes: WeightedServiceTimes{{Weight: 2}, {Weight: 3}}}}
+	require.NoError(t, NormalizeWorkloads(workloads, nil))
+	assert.Equal(t, 5, workloads[0].WeightSum)
+
+	assert.ErrorContains(t, NormalizeWorkloads([]*Workload{{Name: "writes", ServiceTimes: WeightedServiceTimes{{Weight: 0}}}}, nil), "positive weight")
+	assert.ErrorContains(t, NormalizeWorkloads([]*Workload{{Name: "writes", RPS: 10}, {Name: "writes", RPS: 10}}, nil), "duplicate")
+	assert.ErrorContains(t, NormalizeWorkloads([]*Workload{{}}, nil), "name is required")
+
+	// Open-loop workloads need an rps unless a trace generator replays arrivals
+	assert.ErrorContains(t, NormalizeWorkloads([]*Workload{{Name: "writes"}}, nil), "rps must be positive")
+	assert.ErrorContains(t, NormalizeWorkloads([]*Workload{{Name: "writes", Stages: []*Stage{{Duration: time.Second, RPS: 10}}}}, nil), "rps must be positive")
+	require.NoError(t, NormalizeWorkloads([]*Workload{{Name: "writes"}}, &GeneratorConfig{Type: "trace"}))
+	require.NoError(t, NormalizeWorkloads([]*Workload{{Name: "writes", Generator: &GeneratorConfig{Type: "trace"}}}, nil))
+	require.NoError(t, NormalizeWorkloads([]*Workload{{Name: "reads", Concurrency: 2}}, nil))
+}
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"tripwire/pkg/util"
+)
+
+const (
+	DistributionLognormal   = "lognormal"
+	DistributionExponential = "exponential"
+	DistributionPareto      = "pareto"
+)
+
+func (w *WeightedServiceTime) Validate() error {
+	return validateDistribution("service time", w.Distribution, w.Mean, w.Sigma, w.Alpha)
+}
+
+// validateDistribution validates the parameters of a distribution, if any, where the kind describes what's sampled from
+// it in errors.
+func validateDistribution(kind string, distribution string, mean time.Duration, sigma float64, alpha float64) error {
+	switch distribution {
+	case "":
+		return nil
+	case DistributionLognormal:
+		if sigma <= 0 {
+			return fmt.Errorf("lognormal %ss require a positive sigma", kind)
+		}
+	case DistributionExponential:
+	case DistributionPareto:
+		if alpha <= 1 {
+			return fmt.Errorf("pareto %ss require an alpha greater than 1, for a finite mean", kind)
+		}
+	default:
+		return fmt.Errorf("unknown %s distribution: %s", kind, distribution)
+	}
+	if mean <= 0 {
+		return fmt.Errorf("%s %ss require a positive mean", distribution, kind)
+	}
+	return nil
+}
+
+// mean returns the mean service time, ignoring any max.
+func (w *WeightedServiceTime) mean() time.Duration {
+	if w.Distribution != "" {
+		return w.Mean
+	}
+	return w.ServiceTime
+}
+
+// sample returns a service time, which is sampled from the distribution, if any, and capped at the max.
+func (w *WeightedServiceTime) sample(rng *util.Rand) time.Duration {
+	if w.Distribution == "" {
+		return w.ServiceTime
+	}
+	return sampleDistribution(rng, w.Distribution, w.Mean, w.Sigma, w.Alpha, w.Max)
+}
+
+// sampleDistribution returns a duration that's sampled from a distribution and capped at the max, if it's positive.
+func sampleDistribution(rng *util.Rand, distribution string, meanDuration time.Duration, sigma float64, alpha float64, maxDuration time.Duration) time.Duration {
+	var sample float64
+	mean := float64(meanDuration)
+	switch distribution {
+	case DistributionLognormal:
+		// Choose mu so that the distribution's mean is the configured mean
+		mu := math.Log(mean) - sigma*sigma/2
+		sample = math.Exp(mu + sigma*rng.NormFloat64())
+	case DistributionExponential:
+		sample = -math.Log(1-rng.Float64()) * mean
+	case DistributionPareto:
+		// Choose the scale, which is the minimum duration, so that the distribution's mean is the configured mean
+		scale := mean * (alpha - 1) / alpha
+		sample = scale / math.Pow(1-rng.Float64(), 1/alpha)
+	}
+	duration := time.Duration(min(sample, math.MaxInt64))
+	if maxDuration > 0 {
+		duration = min(duration, maxDuration)
+	}
+	return duration
+}
+
+// ThinkTime is how long a closed-loop virtual user waits after each request before sending the next, which is either a
+// fixed duration, such as 1s, or a distribution that think times are sampled from.
+type ThinkTime struct {
+	Fixed        time.Duration `yaml:"-"`            // used when there's no distribution
+	Distribution string        `yaml:"distribution"` // lognormal, exponential, or pareto
+	Mean         time.Duration `yaml:"mean"`         // the mean of the distribution
+	Sigma        float64       `yaml:"sigma"`        // the standard deviation of the log of lognormal think times
+	Alpha        float64       `yaml:"alpha"`        // the shape of pareto think times, where lower is heavier tailed
+	Max          time.Duration `yaml:"max"`          // caps sampled think times, if set
+}
+
+func (t *ThinkTime) UnmarshalYAML(value *yaml.Node) error {
+	*t = ThinkTime{}
+	if value.Kind == yaml.ScalarNode {
+		return value.Decode(&t.Fixed)
+	}
+	type Alias ThinkTime
+	var alias = Alias(*t)
+	if err := value.Decode(&alias); err != nil {
+		return err
+	}
+	*t = ThinkTime(alias)
+	return nil
+}
+
+func (t *ThinkTime) Validate() error {
+	if t.Fixed < 0 {
+		return fmt.Errorf("think time cannot be negative")
+	}
+	return validateDistribution("think time", t.Distribution, t.Mean, t.Sigma, t.Alpha)
+}
+
+// sample returns a think time, which is sampled from the distribution, if any. A nil ThinkTime is 0.
+func (t *ThinkTime) sample(rng *util.Rand) time.Duration {
+	if t == nil {
+		return 0
+	} else if t.Distribution == "" {
+		return t.Fixed
+	}
+	return sampleDistribution(rng, t.Distribution, t.Mean, t.Sigma, t.Alpha, t.Max)
+}
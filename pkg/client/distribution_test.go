@@ -0,0 +1,59 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"tripwire/pkg/util"
+)
+
+func TestServiceTimeDistributions(t *testing.T) {
+	rng := util.NewRand(1)
+	for _, st := range []*WeightedServiceTime{
+		{Distribution: DistributionLognormal, Mean: 50 * time.Millisecond, Sigma: .5},
+		{Distribution: DistributionExponential, Mean: 50 * time.Millisecond},
+		{Distribution: DistributionPareto, Mean: 50 * time.Millisecond, Alpha: 3},
+	} {
+		t.Run(st.Distribution, func(t *testing.T) {
+			assert.NoError(t, st.Validate())
+			var total time.Duration
+			samples := 100000
+			for i := 0; i < samples; i++ {
+				total += st.sample(rng)
+			}
+			assert.InDelta(t, 50*time.Millisecond, total/time.Duration(samples), float64(2*time.Millisecond))
+		})
+	}
+
+	capped := &WeightedServiceTime{Distribution: DistributionExponential, Mean: 50 * time.Millisecond, Max: 60 * time.Millisecond}
+	for i := 0; i < 1000; i++ {
+		assert.LessOrEqual(t, capped.sample(rng), 60*time.Millisecond)
+	}
+
+	assert.Error(t, (&WeightedServiceTime{Distribution: "uniform", Mean: time.Millisecond}).Validate())
+	assert.Error(t, (&WeightedServiceTime{Distribution: DistributionLognormal, Mean: time.Millisecond}).Validate())
+	assert.Error(t, (&WeightedServiceTime{Distribution: DistributionPareto, Mean: time.Millisecond, Alpha: 1}).Validate())
+	assert.Error(t, (&WeightedServiceTime{Distribution: DistributionExponential}).Validate())
+}
+
+func TestThinkTime(t *testing.T) {
+	var fixed, sampled ThinkTime
+	require.NoError(t, yaml.Unmarshal([]byte("250ms"), &fixed))
+	require.NoError(t, fixed.Validate())
+	rng := util.NewRand(1)
+	assert.Equal(t, 250*time.Millisecond, fixed.sample(rng))
+
+	require.NoError(t, yaml.Unmarshal([]byte("{distribution: exponential, mean: 1s, max: 2s}"), &sampled))
+	require.NoError(t, sampled.Validate())
+	for i := 0; i < 1000; i++ {
+		assert.LessOrEqual(t, sampled.sample(rng), 2*time.Second)
+	}
+
+	assert.Zero(t, (*ThinkTime)(nil).sample(rng))
+	assert.Error(t, (&ThinkTime{Fixed: -time.Second}).Validate())
+	assert.ErrorContains(t, (&ThinkTime{Distribution: DistributionExponential}).Validate(), "think times require a positive mean")
+}
@@ -0,0 +1,244 @@
+package client
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/failsafe-go/failsafe-go/priority"
+
+	"tripwire/pkg/util"
+)
+
+// WorkloadGenerator generates the arrivals of requests for a workload or stages.
+type WorkloadGenerator interface {
+	// Next returns the next arrival given the current params, or false if the generator has no more arrivals.
+	Next(params *GeneratorParams) (*Arrival, bool)
+}
+
+// GeneratorParams are the current parameters of the workload or stage that a generator produces arrivals for. These can
+// change over the course of a run, as stages advance or workloads are updated.
+type GeneratorParams struct {
+	RPS          uint
+	ServiceTimes WeightedServiceTimes
+	WeightSum    int
+	Priority     priority.Priority
+	Priorities   WeightedPriorities // when set, arrivals are given a weighted mix of priorities rather than the Priority
+	Rand         *util.Rand         // samples service times and priorities
+	ArrivalRand  *util.Rand         // samples inter-arrival times, which defaults to the Rand
+}
+
+// NextPriority returns the priority for the next arrival, which is selected from the Priorities, if any.
+func (p *GeneratorParams) NextPriority() priority.Priority {
+	return p.Priorities.Random(p.Rand, p.Priority)
+}
+
+// arrivalRand returns the source of randomness for inter-arrival times.
+func (p *GeneratorParams) arrivalRand() *util.Rand {
+	if p.ArrivalRand != nil {
+		return p.ArrivalRand
+	}
+	return p.Rand
+}
+
+// Arrival describes a request to be sent.
+type Arrival struct {
+	Delay       time.Duration // the time from the previous arrival until this request is sent
+	ServiceTime time.Duration
+	Priority    priority.Priority
+}
+
+// GeneratorConfig selects a registered generator by Type. Path and Options are available for generators that need them.
+type GeneratorConfig struct {
+	Type      string         `yaml:"type"`
+	Path      string         `yaml:"path"`       // the file that a trace is read from
+	Format    TraceFormat    `yaml:"format"`     // the format of a trace. Defaults to one based on the path's extension.
+	TimeScale float64        `yaml:"time_scale"` // scales the delays between a trace's arrivals, where 0.5 replays twice as fast
+	Options   map[string]any `yaml:",inline"`
+}
+
+// GeneratorFactory creates a generator for a config.
+type GeneratorFactory func(config *GeneratorConfig) (WorkloadGenerator, error)
+
+var (
+	generatorsMtx sync.RWMutex
+	generators    = map[string]GeneratorFactory{
+		"uniform": func(*GeneratorConfig) (WorkloadGenerator, error) { return &uniformGenerator{}, nil },
+		"poisson": func(*GeneratorConfig) (WorkloadGenerator, error) { return &poissonGenerator{}, nil },
+		"trace":   newTraceGenerator,
+	}
+)
+
+// RegisterGenerator registers a generator factory under a name, which can then be used as a generator type in configs.
+func RegisterGenerator(name string, factory GeneratorFactory) {
+	generatorsMtx.Lock()
+	defer generatorsMtx.Unlock()
+	generators[name] = factory
+}
+
+// NewGenerator creates a generator for the config, defaulting to a uniform generator if config is nil.
+func NewGenerator(config *GeneratorConfig) (WorkloadGenerator, error) {
+	if config == nil {
+		return &uniformGenerator{}, nil
+	}
+	generatorsMtx.RLock()
+	factory, ok := generators[config.Type]
+	generatorsMtx.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown workload generator type: %s", config.Type)
+	}
+	return factory(config)
+}
+
+// uniformGenerator sends requests at evenly spaced intervals.
+type uniformGenerator struct{}
+
+func (g *uniformGenerator) Next(params *GeneratorParams) (*Arrival, bool) {
+	return &Arrival{
+		Delay:       time.Second / time.Duration(params.RPS),
+		ServiceTime: params.ServiceTimes.Random(params.Rand, params.WeightSum),
+		Priority:    params.NextPriority(),
+	}, true
+}
+
+// poissonGenerator sends requests with exponentially distributed inter-arrival times, which models independent clients.
+type poissonGenerator struct{}
+
+func (g *poissonGenerator) Next(params *GeneratorParams) (*Arrival, bool) {
+	delay := -math.Log(1-params.arrivalRand().Float64()) / float64(params.RPS)
+	return &Arrival{
+		Delay:       time.Duration(delay * float64(time.Second)),
+		ServiceTime: params.ServiceTimes.Random(params.Rand, params.WeightSum),
+		Priority:    params.NextPriority(),
+	}, true
+}
+
+// traceGenerator replays arrivals from a trace file, which is read according to its format. The RPS and service times
+// of the workload or stage are not used.
+type traceGenerator struct {
+	arrivals []*Arrival
+	next     int
+}
+
+func newTraceGenerator(config *GeneratorConfig) (WorkloadGenerator, error) {
+	if config.TimeScale < 0 {
+		return nil, fmt.Errorf("trace time_scale cannot be negative")
+	}
+	file, err := os.Open(config.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var arrivals []*Arrival
+	switch format := traceFormat(config); format {
+	case TextTrace:
+		arrivals, err = readTextTrace(file, config.Path)
+	case CSVTrace:
+		arrivals, err = readCSVTrace(file, config.Path)
+	case JSONTrace:
+		arrivals, err = readJSONTrace(file, config.Path)
+	default:
+		return nil, fmt.Errorf("unknown trace format: %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if config.TimeScale != 0 {
+		for _, arrival := range arrivals {
+			arrival.Delay = time.Duration(float64(arrival.Delay) * config.TimeScale)
+		}
+	}
+	return &traceGenerator{arrivals: arrivals}, nil
+}
+
+func (g *traceGenerator) Next(params *GeneratorParams) (*Arrival, bool) {
+	if g.next >= len(g.arrivals) {
+		return nil, false
+	}
+	arrival := *g.arrivals[g.next]
+	arrival.Priority = params.NextPriority()
+	g.next++
+	return &arrival, true
+}
+
+// arrivalTimer fires when the next arrival from a generator is due. Arrivals are scheduled relative to the previous
+// arrival rather than when it was sent, so that delays in sending don't accumulate, but arrivals that are already
+// overdue are not sent in a burst to catch up. Sending is paced separately from the schedule, so that when the client
+// falls behind, the backlog is still reflected in when later arrivals were scheduled.
+type arrivalTimer struct {
+	generator WorkloadGenerator
+	timer     *time.Timer
+	arrival   *Arrival
+	due       time.Time // when the arrival is sent, which is no earlier than when the previous arrival was sent
+	scheduled time.Time // when the arrival was scheduled to be sent, which includes any backlog
+	pausedAt  time.Time
+}
+
+func newArrivalTimer(generator WorkloadGenerator, params *GeneratorParams) *arrivalTimer {
+	now := time.Now()
+	a := &arrivalTimer{
+		generator: generator,
+		timer:     time.NewTimer(time.Duration(math.MaxInt64)),
+		due:       now,
+		scheduled: now,
+	}
+	a.timer.Stop()
+	a.advance(params)
+	return a
+}
+
+// advance schedules the next arrival, returning how many arrivals were dropped because the next arrival was already
+// overdue. Later arrivals remain scheduled after the overdue arrival rather than after when it was sent, so that their
+// corrected response times include the backlog. If the generator has no more arrivals, the timer won't fire again.
+func (a *arrivalTimer) advance(params *GeneratorParams) int {
+	arrival, ok := a.generator.Next(params)
+	if !ok {
+		a.arrival = nil
+		return 0
+	}
+	a.arrival = arrival
+	a.due = a.due.Add(arrival.Delay)
+	a.scheduled = a.scheduled.Add(arrival.Delay)
+	var dropped int
+	if now := time.Now(); a.due.Before(now) {
+		if arrival.Delay > 0 {
+			dropped = int(now.Sub(a.due) / arrival.Delay)
+		}
+		a.due = now
+	}
+	a.timer.Reset(time.Until(a.due))
+	return dropped
+}
+
+// lateness returns how late the current arrival is relative to when it was due.
+func (a *arrivalTimer) lateness() time.Duration {
+	return max(0, time.Since(a.due))
+}
+
+func (a *arrivalTimer) stop() {
+	a.timer.Stop()
+}
+
+// pause stops the timer until it's resumed, discarding any arrival that fired but wasn't received.
+func (a *arrivalTimer) pause() {
+	if !a.timer.Stop() {
+		select {
+		case <-a.timer.C:
+		default:
+		}
+	}
+	a.pausedAt = time.Now()
+}
+
+// resume restarts a paused timer, delaying the current arrival by the time the timer was paused.
+func (a *arrivalTimer) resume() {
+	paused := time.Since(a.pausedAt)
+	a.due = a.due.Add(paused)
+	a.scheduled = a.scheduled.Add(paused)
+	if a.arrival != nil {
+		a.timer.Reset(time.Until(a.due))
+	}
+}
@@ -0,0 +1,179 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/failsafe-go/failsafe-go/priority"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tripwire/pkg/util"
+)
+
+func TestPoissonGenerator(t *testing.T) {
+	generator, err := NewGenerator(&GeneratorConfig{Type: "poisson"})
+	require.NoError(t, err)
+	params := &GeneratorParams{
+		RPS:          100,
+		ServiceTimes: WeightedServiceTimes{{ServiceTime: time.Millisecond, Weight: 1}},
+		WeightSum:    1,
+		Rand:         util.NewRand(1),
+	}
+
+	var total time.Duration
+	for i := 0; i < 10000; i++ {
+		arrival, ok := generator.Next(params)
+		require.True(t, ok)
+		total += arrival.Delay
+	}
+	assert.InDelta(t, 10*time.Millisecond, total/10000, float64(time.Millisecond))
+}
+
+func TestPoissonGeneratorArrivalRand(t *testing.T) {
+	arrivals := func(arrivalSeed int64) []*Arrival {
+		params := &GeneratorParams{
+			RPS: 100,
+			ServiceTimes: WeightedServiceTimes{
+				{ServiceTime: time.Millisecond, Weight: 1},
+				{ServiceTime: 10 * time.Millisecond, Weight: 1},
+			},
+			WeightSum:   2,
+			Rand:        util.NewRand(1),
+			ArrivalRand: util.NewRand(arrivalSeed),
+		}
+		var result []*Arrival
+		for i := 0; i < 10; i++ {
+			arrival, _ := (&poissonGenerator{}).Next(params)
+			result = append(result, arrival)
+		}
+		return result
+	}
+
+	// Varying the arrival seed varies delays while holding service times constant
+	a, b := arrivals(1), arrivals(2)
+	for i := range a {
+		assert.Equal(t, a[i].ServiceTime, b[i].ServiceTime)
+		assert.NotEqual(t, a[i].Delay, b[i].Delay)
+	}
+}
+
+func TestTraceGenerator(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace")
+	require.NoError(t, os.WriteFile(path, []byte("# delay service_time\n10ms 50ms\n\n5ms 20ms\n"), 0o644))
+	generator, err := NewGenerator(&GeneratorConfig{Type: "trace", Path: path})
+	require.NoError(t, err)
+	params := &GeneratorParams{Priority: 2}
+
+	arrival, ok := generator.Next(params)
+	assert.True(t, ok)
+	assert.Equal(t, &Arrival{Delay: 10 * time.Millisecond, ServiceTime: 50 * time.Millisecond, Priority: 2}, arrival)
+	arrival, ok = generator.Next(params)
+	assert.True(t, ok)
+	assert.Equal(t, 5*time.Millisecond, arrival.Delay)
+	_, ok = generator.Next(params)
+	assert.False(t, ok)
+}
+
+func TestUnknownGenerator(t *testing.T) {
+	_, err := NewGenerator(&GeneratorConfig{Type: "unknown"})
+	assert.Error(t, err)
+}
+
+func TestArrivalTimerDropsOverdueArrivals(t *testing.T) {
+	params := &GeneratorParams{
+		RPS:          1000,
+		ServiceTimes: WeightedServiceTimes{{ServiceTime: time.Millisecond, Weight: 1}},
+		WeightSum:    1,
+		Rand:         util.NewRand(1),
+	}
+	arrivals := newArrivalTimer(&uniformGenerator{}, params)
+	defer arrivals.stop()
+
+	// Fall behind by roughly 50 arrivals
+	time.Sleep(50 * time.Millisecond)
+	assert.GreaterOrEqual(t, arrivals.lateness(), 49*time.Millisecond)
+	dropped := arrivals.advance(params)
+	assert.GreaterOrEqual(t, dropped, 48)
+	assert.Less(t, arrivals.lateness(), 5*time.Millisecond)
+
+	// The arrival that's sent is still scheduled for when it was originally due
+	assert.GreaterOrEqual(t, time.Since(arrivals.scheduled), 45*time.Millisecond)
+}
+
+func TestArrivalTimerStalledSender(t *testing.T) {
+	params := &GeneratorParams{
+		RPS:          100,
+		ServiceTimes: WeightedServiceTimes{{ServiceTime: time.Millisecond, Weight: 1}},
+		WeightSum:    1,
+		Rand:         util.NewRand(1),
+	}
+	arrivals := newArrivalTimer(&uniformGenerator{}, params)
+	defer arrivals.stop()
+	start := arrivals.scheduled.Add(-10 * time.Millisecond)
+
+	// Stall for several intervals before sending the first arrival, after which the next is overdue
+	time.Sleep(50 * time.Millisecond)
+	<-arrivals.timer.C
+	assert.GreaterOrEqual(t, arrivals.advance(params), 3)
+	<-arrivals.timer.C
+	assert.Equal(t, start.Add(20*time.Millisecond), arrivals.scheduled)
+	arrivals.advance(params)
+
+	// Later arrivals are sent an interval apart rather than in a burst, but remain on the original schedule
+	for i := 3; i <= 5; i++ {
+		sent := time.Now()
+		assert.Equal(t, start.Add(time.Duration(i)*10*time.Millisecond), arrivals.scheduled)
+		<-arrivals.timer.C
+		assert.GreaterOrEqual(t, time.Since(sent), 9*time.Millisecond)
+		assert.GreaterOrEqual(t, time.Since(arrivals.scheduled), 25*time.Millisecond)
+		arrivals.advance(params)
+	}
+}
+
+func TestArrivalTimerPause(t *testing.T) {
+	params := &GeneratorParams{
+		RPS:          10,
+		ServiceTimes: WeightedServiceTimes{{ServiceTime: time.Millisecond, Weight: 1}},
+		WeightSum:    1,
+		Rand:         util.NewRand(1),
+	}
+	arrivals := newArrivalTimer(&uniformGenerator{}, params)
+	defer arrivals.stop()
+	due := arrivals.due
+
+	// The current arrival is delayed by the time the timer was paused, rather than dropped
+	arrivals.pause()
+	time.Sleep(50 * time.Millisecond)
+	arrivals.resume()
+	assert.GreaterOrEqual(t, arrivals.due.Sub(due), 50*time.Millisecond)
+	assert.Equal(t, arrivals.due, arrivals.scheduled)
+	<-arrivals.timer.C
+	assert.Zero(t, arrivals.advance(params))
+}
+
+func TestPriorityMix(t *testing.T) {
+	params := &GeneratorParams{
+		RPS:          100,
+		ServiceTimes: WeightedServiceTimes{{ServiceTime: time.Millisecond, Weight: 1}},
+		WeightSum:    1,
+		Priority:     priority.Medium,
+		Rand:         util.NewRand(1),
+	}
+	arrival, _ := (&uniformGenerator{}).Next(params)
+	assert.Equal(t, priority.Medium, arrival.Priority)
+
+	params.Priorities = WeightedPriorities{{Priority: priority.High, Weight: 3}, {Priority: priority.Low, Weight: 1}}
+	counts := make(map[priority.Priority]int)
+	for i := 0; i < 10000; i++ {
+		arrival, _ = (&poissonGenerator{}).Next(params)
+		counts[arrival.Priority]++
+	}
+	assert.Len(t, counts, 2)
+	assert.InDelta(t, 7500, counts[priority.High], 250)
+
+	assert.Error(t, WeightedPriorities{{Priority: 5, Weight: 1}}.Validate())
+	assert.Error(t, WeightedPriorities{{Priority: priority.High}}.Validate())
+}
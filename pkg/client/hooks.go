@@ -0,0 +1,170 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/failsafe-go/failsafe-go/priority"
+	"gopkg.in/Knetic/govaluate.v3"
+)
+
+// HooksConfig configures expressions that customize how the client prioritizes, sheds, and classifies requests, as an
+// escape hatch for behavior that isn't otherwise configurable. Expressions are evaluated by a safe expression engine,
+// which only has access to the variables of each request.
+type HooksConfig struct {
+	Priority string `yaml:"priority"` // evaluates to a request's priority, from 0 to 4
+	Shed     string `yaml:"shed"`     // evaluates to whether a request is rejected by the client before it's sent
+	Success  string `yaml:"success"`  // evaluates to whether a response is a success, regardless of its status
+}
+
+var (
+	// requestVars are the variables that are available to each hook.
+	requestVars = []string{"workload", "user", "region", "method", "path", "client", "priority", "service_time", "inflight"}
+
+	// responseVars are the additional variables that are available to the success hook.
+	responseVars = []string{"status", "latency", "tier"}
+)
+
+func (c *HooksConfig) Validate() error {
+	for _, hook := range []struct {
+		name       string
+		expression string
+		vars       []string
+	}{
+		{"priority", c.Priority, requestVars},
+		{"shed", c.Shed, requestVars},
+		{"success", c.Success, append(slices.Clone(requestVars), responseVars...)},
+	} {
+		if hook.expression == "" {
+			continue
+		}
+		expression, err := govaluate.NewEvaluableExpression(hook.expression)
+		if err != nil {
+			return fmt.Errorf("invalid %s hook: %w", hook.name, err)
+		}
+		for _, v := range expression.Vars() {
+			if !slices.Contains(hook.vars, v) {
+				return fmt.Errorf("unknown variable in %s hook: %s", hook.name, v)
+			}
+		}
+	}
+	return nil
+}
+
+// hook is a compiled hook expression. A nil hook isn't evaluated.
+type hook struct {
+	name       string
+	expression *govaluate.EvaluableExpression
+	onError    func(name string, err error)
+	once       sync.Once // Reports the first evaluation error, since an expression usually fails for every request
+}
+
+func newHook(name string, expression string, onError func(name string, err error)) *hook {
+	if expression == "" {
+		return nil
+	}
+	compiled, err := govaluate.NewEvaluableExpression(expression)
+	if err != nil {
+		// The expression was already validated
+		panic(err)
+	}
+	return &hook{name: name, expression: compiled, onError: onError}
+}
+
+// evaluate evaluates the hook with the vars, returning false if the result isn't a T.
+func evaluate[T any](h *hook, vars map[string]any) (T, bool) {
+	var zero T
+	if h == nil {
+		return zero, false
+	}
+	result, err := h.expression.Evaluate(vars)
+	if err == nil {
+		if value, ok := result.(T); ok {
+			return value, true
+		}
+		err = fmt.Errorf("expected a %T result but got %v", zero, result)
+	}
+	h.once.Do(func() { h.onError(h.name, err) })
+	return zero, false
+}
+
+// hooks evaluates the client's hooks for requests. When a hook fails to evaluate, such as when its result has the wrong
+// type, the client's behavior is unchanged.
+type hooks struct {
+	priority *hook
+	shed     *hook
+	success  *hook
+}
+
+func newHooks(config *HooksConfig, onError func(name string, err error)) *hooks {
+	if config == nil {
+		return nil
+	}
+	return &hooks{
+		priority: newHook("priority", config.Priority, onError),
+		shed:     newHook("shed", config.Shed, onError),
+		success:  newHook("success", config.Success, onError),
+	}
+}
+
+// hookVars returns the variables of a request that hooks are evaluated with, where durations are in seconds.
+func hookVars(workload, user, region, method, path, clientID string, p priority.Priority, serviceTime time.Duration, inflight int64) map[string]any {
+	return map[string]any{
+		"workload":     workload,
+		"user":         user,
+		"region":       region,
+		"method":       method,
+		"path":         path,
+		"client":       clientID,
+		"priority":     float64(p),
+		"service_time": serviceTime.Seconds(),
+		"inflight":     float64(inflight),
+	}
+}
+
+// prioritize returns a request's priority from the priority hook, or p if there isn't one.
+func (h *hooks) prioritize(vars map[string]any, p priority.Priority) priority.Priority {
+	if h == nil {
+		return p
+	}
+	if value, ok := evaluate[float64](h.priority, vars); ok {
+		p = priority.Priority(min(max(value, float64(priority.VeryLow)), float64(priority.VeryHigh)))
+		vars["priority"] = float64(p)
+	}
+	return p
+}
+
+// shouldShed returns whether the shed hook rejects a request.
+func (h *hooks) shouldShed(vars map[string]any) bool {
+	if h == nil {
+		return false
+	}
+	shed, _ := evaluate[bool](h.shed, vars)
+	return shed
+}
+
+// classify returns the status that a response is classified by, based on the success hook, where latency is how long
+// the request took. A response that the hook considers successful is classified as a 200, and a 200 that it doesn't is
+// classified as a failure, while other statuses are classified as usual.
+func (h *hooks) classify(vars map[string]any, status int, latency time.Duration, tier string) int {
+	if h == nil || h.success == nil {
+		return status
+	}
+	vars["status"] = float64(status)
+	vars["latency"] = latency.Seconds()
+	vars["tier"] = tier
+	success, ok := evaluate[bool](h.success, vars)
+	switch {
+	case !ok:
+		return status
+	case success:
+		return http.StatusOK
+	case status == http.StatusOK:
+		return http.StatusInternalServerError
+	default:
+		return status
+	}
+}
@@ -0,0 +1,63 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/failsafe-go/failsafe-go/priority"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHooksConfigValidate(t *testing.T) {
+	assert.NoError(t, (&HooksConfig{Priority: "workload == 'checkout' ? 4 : priority", Shed: "inflight > 10 && priority < 2",
+		Success: "status < 500 || latency < 0.1"}).Validate())
+	assert.ErrorContains(t, (&HooksConfig{Priority: "priority +"}).Validate(), "invalid priority hook")
+	assert.ErrorContains(t, (&HooksConfig{Shed: "status == 503"}).Validate(), "unknown variable in shed hook: status")
+}
+
+func TestHooks(t *testing.T) {
+	var errors []string
+	h := newHooks(&HooksConfig{Priority: "workload == 'checkout' ? 9 : priority", Shed: "priority < 2 && inflight > 10",
+		Success: "status == 404 || (status == 200 && latency < 1)"}, func(name string, err error) {
+		errors = append(errors, name)
+	})
+
+	// Priorities are clamped to the valid range, and are available to later hooks
+	vars := hookVars("checkout", "", "", "", "", "", priority.Low, 0, 20)
+	assert.Equal(t, priority.VeryHigh, h.prioritize(vars, priority.Low))
+	assert.False(t, h.shouldShed(vars))
+	vars = hookVars("browse", "", "", "", "", "", priority.Low, 0, 20)
+	assert.Equal(t, priority.Low, h.prioritize(vars, priority.Low))
+	assert.True(t, h.shouldShed(vars))
+
+	assert.Equal(t, http.StatusOK, h.classify(vars, http.StatusNotFound, 0, ""))
+	assert.Equal(t, http.StatusInternalServerError, h.classify(vars, http.StatusOK, 2*time.Second, ""))
+	assert.Equal(t, http.StatusTooManyRequests, h.classify(vars, http.StatusTooManyRequests, 0, ""))
+
+	// A hook that fails to evaluate doesn't change behavior, and its first error is reported
+	h = newHooks(&HooksConfig{Priority: "workload"}, func(name string, err error) {
+		errors = append(errors, name)
+	})
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, priority.Low, h.prioritize(hookVars("browse", "", "", "", "", "", priority.Low, 0, 0), priority.Low))
+	}
+	assert.Equal(t, []string{"priority"}, errors)
+}
+
+func TestShedHook(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	config := &Config{Hooks: &HooksConfig{Shed: "priority < 2"}}
+	c := newTestClient(t, server.Listener.Addr(), config, "hooked", withoutPolicies("hooked"))
+
+	workloadMetrics := testMetrics.WithWorkload("hooked", "hooked", "hooked")
+	for _, p := range []priority.Priority{priority.VeryLow, priority.High} {
+		c.inflight.Add(1)
+		c.sendRequest(&request{workload: "hooked", metrics: workloadMetrics, priority: p})
+	}
+	assert.Equal(t, 1.0, testMetrics.Value(workloadMetrics.ClientReqShed))
+	assert.Equal(t, 1.0, testMetrics.Value(workloadMetrics.ClientReqRejected))
+	assert.Equal(t, 1.0, testMetrics.Value(workloadMetrics.ClientReqSuccesses))
+}
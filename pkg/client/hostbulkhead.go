@@ -0,0 +1,90 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrHostFull is returned when a request is rejected since the bulkhead of the host that it's sent to is full.
+var ErrHostFull = errors.New("host bulkhead full")
+
+// serverHost is the name of the host that requests are sent to when the client's servers aren't grouped into regions.
+const serverHost = "server"
+
+// HostBulkheadConfig limits the client's concurrent requests to each host that it sends requests to, which is the server
+// in each region, so that a saturated host can't consume the client's capacity for the others, as service meshes do with
+// a connection pool per backend. A host whose own permits are in use can borrow permits that other hosts aren't using.
+type HostBulkheadConfig struct {
+	MaxConcurrency uint `yaml:"max_concurrency"` // the permits for each host
+	MaxBorrow      uint `yaml:"max_borrow"`      // the most permits that a host can borrow from other hosts
+}
+
+func (c *HostBulkheadConfig) Validate() error {
+	if c.MaxConcurrency == 0 {
+		return fmt.Errorf("host_bulkhead max_concurrency must be positive")
+	}
+	return nil
+}
+
+// hostBulkheads limits the concurrent requests to each host. A nil hostBulkheads admits every request.
+type hostBulkheads struct {
+	config     *HostBulkheadConfig
+	capacity   uint                             // the permits of every host
+	onInflight func(host string, inflight uint) // called whenever a host's inflight requests change
+	onRejected func(host string)
+
+	mtx      sync.Mutex
+	inflight map[string]uint // Guarded by mtx
+	total    uint            // Guarded by mtx
+}
+
+func newHostBulkheads(config *HostBulkheadConfig, hosts []string, onInflight func(host string, inflight uint), onRejected func(host string)) *hostBulkheads {
+	return &hostBulkheads{
+		config:     config,
+		capacity:   config.MaxConcurrency * uint(len(hosts)),
+		onInflight: onInflight,
+		onRejected: onRejected,
+		inflight:   make(map[string]uint),
+	}
+}
+
+// acquire attempts to admit a request to a host, returning a func that must be called once the request is done, else
+// false if the host's permits are in use and it can't borrow any more. A host can borrow while any other host has
+// unused permits, and hosts can always use their own permits, even while they're lent.
+func (b *hostBulkheads) acquire(host string) (func(), bool) {
+	if b == nil {
+		return func() {}, true
+	}
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	inflight := b.inflight[host]
+	if inflight >= b.config.MaxConcurrency && (inflight-b.config.MaxConcurrency >= b.config.MaxBorrow || b.total >= b.capacity) {
+		b.onRejected(host)
+		return nil, false
+	}
+	b.inflight[host] = inflight + 1
+	b.total++
+	b.onInflight(host, inflight+1)
+	return func() { b.release(host) }, true
+}
+
+func (b *hostBulkheads) release(host string) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.inflight[host]--
+	b.total--
+	b.onInflight(host, b.inflight[host])
+}
+
+// hosts returns the names of the hosts that a client sends requests to.
+func (c *Config) hosts() []string {
+	if c.Regions == nil {
+		return []string{serverHost}
+	}
+	var hosts []string
+	for _, region := range c.Regions.Regions {
+		hosts = append(hosts, region.Name)
+	}
+	return hosts
+}
@@ -0,0 +1,56 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHostBulkheadConfigValidate(t *testing.T) {
+	assert.NoError(t, (&HostBulkheadConfig{MaxConcurrency: 10, MaxBorrow: 5}).Validate())
+	assert.Error(t, (&HostBulkheadConfig{MaxBorrow: 5}).Validate())
+}
+
+func TestHostBulkheadBorrowing(t *testing.T) {
+	var rejected []string
+	inflight := make(map[string]uint)
+	bulkheads := newHostBulkheads(&HostBulkheadConfig{MaxConcurrency: 2, MaxBorrow: 1}, []string{"us-east", "us-west"},
+		func(host string, n uint) { inflight[host] = n },
+		func(host string) { rejected = append(rejected, host) })
+	acquire := func(host string) func() {
+		release, ok := bulkheads.acquire(host)
+		if !ok {
+			return nil
+		}
+		return release
+	}
+
+	// A host borrows an unused permit from another host, up to its max
+	assert.NotNil(t, acquire("us-east"))
+	assert.NotNil(t, acquire("us-east"))
+	borrowed := acquire("us-east")
+	assert.NotNil(t, borrowed)
+	assert.Nil(t, acquire("us-east"))
+	assert.Equal(t, uint(3), inflight["us-east"])
+
+	// The lending host can still use its own permits, but can't borrow while none are unused
+	assert.NotNil(t, acquire("us-west"))
+	lent := acquire("us-west")
+	assert.NotNil(t, lent)
+	assert.Nil(t, acquire("us-west"))
+	borrowed()
+	assert.Equal(t, uint(2), inflight["us-east"])
+	assert.Nil(t, acquire("us-west"))
+	assert.Equal(t, []string{"us-east", "us-west", "us-west"}, rejected)
+
+	// Once the lending host has an unused permit again, it can be borrowed
+	lent()
+	assert.NotNil(t, acquire("us-east"))
+}
+
+func TestNilHostBulkheads(t *testing.T) {
+	var bulkheads *hostBulkheads
+	release, ok := bulkheads.acquire(serverHost)
+	assert.True(t, ok)
+	release()
+}
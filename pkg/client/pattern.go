@@ -0,0 +1,41 @@
+package client
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const PatternSine = "sine"
+
+// PatternConfig modulates a workload's RPS over time, such as to simulate daily traffic cycles. A sine pattern
+// oscillates around the workload's RPS by the Amplitude, a fraction of the RPS, once every Period.
+type PatternConfig struct {
+	Type      string        `yaml:"type"`
+	Period    time.Duration `yaml:"period"`
+	Amplitude float64       `yaml:"amplitude"`
+	Phase     time.Duration `yaml:"phase"` // how far into the period the pattern starts, where 0 starts at the RPS and rising
+}
+
+func (c *PatternConfig) Validate() error {
+	if c.Type != PatternSine {
+		return fmt.Errorf("unknown load pattern type: %s", c.Type)
+	}
+	if c.Period <= 0 {
+		return fmt.Errorf("load pattern period must be positive")
+	}
+	if c.Amplitude < 0 || c.Amplitude > 1 {
+		return fmt.Errorf("load pattern amplitude must be between 0 and 1")
+	}
+	return nil
+}
+
+// rps returns the modulated RPS at some elapsed time into the pattern, which is at least 1. A nil pattern returns the
+// rps unchanged.
+func (c *PatternConfig) rps(rps uint, elapsed time.Duration) uint {
+	if c == nil {
+		return rps
+	}
+	angle := 2 * math.Pi * float64(elapsed+c.Phase) / float64(c.Period)
+	return max(1, uint(math.Round(float64(rps)*(1+c.Amplitude*math.Sin(angle)))))
+}
@@ -0,0 +1,26 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSinePattern(t *testing.T) {
+	pattern := &PatternConfig{Type: PatternSine, Period: 4 * time.Minute, Amplitude: .5}
+	assert.NoError(t, pattern.Validate())
+	assert.Equal(t, uint(100), pattern.rps(100, 0))
+	assert.Equal(t, uint(150), pattern.rps(100, time.Minute), "peak")
+	assert.Equal(t, uint(50), pattern.rps(100, 3*time.Minute), "trough")
+	assert.Equal(t, uint(100), pattern.rps(100, 4*time.Minute))
+
+	pattern.Phase = time.Minute
+	assert.Equal(t, uint(150), pattern.rps(100, 0))
+
+	var none *PatternConfig
+	assert.Equal(t, uint(100), none.rps(100, time.Minute))
+	assert.Error(t, (&PatternConfig{Type: "square", Period: time.Minute}).Validate())
+	assert.Error(t, (&PatternConfig{Type: PatternSine}).Validate())
+	assert.Error(t, (&PatternConfig{Type: PatternSine, Period: time.Minute, Amplitude: 2}).Validate())
+}
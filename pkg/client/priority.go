@@ -0,0 +1,65 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/failsafe-go/failsafe-go/priority"
+
+	"tripwire/pkg/util"
+)
+
+// WeightedPriority is a priority that a workload sends a share of its requests with, in proportion to its weight.
+type WeightedPriority struct {
+	Priority priority.Priority `yaml:"priority"`
+	Weight   uint              `yaml:"weight"`
+}
+
+func (w *WeightedPriority) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type alias WeightedPriority
+	raw := alias{
+		Weight: 1,
+	}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+	*w = WeightedPriority(raw)
+	return nil
+}
+
+// WeightedPriorities is a weighted mix of priorities that a workload's requests are sent with.
+type WeightedPriorities []*WeightedPriority
+
+func (w WeightedPriorities) Sum() uint {
+	sum := uint(0)
+	for _, p := range w {
+		sum += p.Weight
+	}
+	return sum
+}
+
+func (w WeightedPriorities) Validate() error {
+	for _, p := range w {
+		if p.Priority < priority.VeryLow || p.Priority > priority.VeryHigh {
+			return fmt.Errorf("priority must be between %d and %d", priority.VeryLow, priority.VeryHigh)
+		}
+	}
+	if len(w) > 0 && w.Sum() == 0 {
+		return fmt.Errorf("priorities must have a positive weight")
+	}
+	return nil
+}
+
+// Random selects a random priority based on the weights, or returns the defaultPriority if there are no priorities.
+func (w WeightedPriorities) Random(rng *util.Rand, defaultPriority priority.Priority) priority.Priority {
+	if len(w) == 0 {
+		return defaultPriority
+	}
+	weight := rng.Intn(int(w.Sum()))
+	for _, p := range w {
+		weight -= int(p.Weight)
+		if weight < 0 {
+			return p.Priority
+		}
+	}
+	return defaultPriority
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"tripwire/pkg/util"
+)
+
+// bodyReader is a round tripper that reads response bodies before returning, at a limited rate if one is configured, so
+// that slow consumers apply backpressure to a streaming server. Since bodies are read within the round trip, reading
+// is subject to the client's policies, such as timeouts, and counts toward response times. When a streaming server
+// sends the expected size of a response, the number of bytes received is set on the response, so that duplicated
+// deliveries can be detected.
+type bodyReader struct {
+	next     http.RoundTripper
+	readRate uint // bytes per second, or 0 to read as fast as possible
+}
+
+func newBodyReader(next http.RoundTripper, readRate uint) http.RoundTripper {
+	return &bodyReader{next: next, readRate: readRate}
+}
+
+func (r *bodyReader) RoundTrip(request *http.Request) (*http.Response, error) {
+	resp, err := r.next.RoundTrip(request)
+	if err != nil {
+		return resp, err
+	}
+	read, err := r.read(request.Context(), resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if resp.Header.Get(util.StreamSizeHeaderId) != "" {
+		resp.Header.Set(util.ReceivedSizeHeaderId, strconv.FormatUint(uint64(read), 10))
+	}
+	resp.Body = http.NoBody
+	return resp, nil
+}
+
+// read reads a body, returning the number of bytes read.
+func (r *bodyReader) read(ctx context.Context, body io.Reader) (uint, error) {
+	if r.readRate == 0 {
+		n, err := io.Copy(io.Discard, body)
+		return uint(n), err
+	}
+
+	// Read in small enough chunks to approximate the rate
+	buf := make([]byte, max(1, r.readRate/20))
+	start := time.Now()
+	var read uint
+	for {
+		n, err := body.Read(buf)
+		read += uint(n)
+		if errors.Is(err, io.EOF) {
+			return read, nil
+		} else if err != nil {
+			return read, err
+		}
+		wait := time.Until(start.Add(time.Duration(float64(read) / float64(r.readRate) * float64(time.Second))))
+		select {
+		case <-ctx.Done():
+			return read, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"tripwire/pkg/server"
+)
+
+const (
+	RoutingLocalFirst = "local_first" // requests fail over to other regions when their local region is overloaded
+	RoutingLocalOnly  = "local_only"  // requests are only sent to their local region
+)
+
+// RegionsConfig groups a strategy's servers into named regions. Each workload's requests are sent to the server in its
+// local region, and depending on the routing, fail over to the servers in other regions, in order, when the local server
+// rejects them or is unavailable. Requests that are sent to another region incur the inter-region latency.
+type RegionsConfig struct {
+	Regions []*Region     `yaml:"regions"`
+	Latency time.Duration `yaml:"latency"` // added to each request that's sent to another region
+	Routing string        `yaml:"routing"` // local_first or local_only
+}
+
+type Region struct {
+	Name     string                 `yaml:"name"`
+	Threads  uint                   `yaml:"threads"`  // overrides the server's threads in the region
+	Degraded *server.DegradedConfig `yaml:"degraded"` // degrades the region's server partway through each strategy's run
+}
+
+func (c *RegionsConfig) UnmarshalYAML(value *yaml.Node) error {
+	*c = RegionsConfig{
+		Routing: RoutingLocalFirst,
+	}
+	type Alias RegionsConfig
+	var alias = Alias(*c)
+	if err := value.Decode(&alias); err != nil {
+		return err
+	}
+	*c = RegionsConfig(alias)
+	return nil
+}
+
+// Validate validates the regions, and that the workloads' regions exist.
+func (c *RegionsConfig) Validate(workloads []*Workload) error {
+	if len(c.Regions) == 0 {
+		return errors.New("regions requires at least one region")
+	}
+	if c.Routing != RoutingLocalFirst && c.Routing != RoutingLocalOnly {
+		return fmt.Errorf("invalid region routing: %s", c.Routing)
+	}
+	names := make(map[string]bool)
+	for _, region := range c.Regions {
+		if region.Name == "" {
+			return errors.New("regions must have a name")
+		}
+		if names[region.Name] {
+			return fmt.Errorf("duplicate region: %s", region.Name)
+		}
+		names[region.Name] = true
+		if region.Degraded != nil {
+			if err := region.Degraded.Validate(); err != nil {
+				return fmt.Errorf("region %s: %w", region.Name, err)
+			}
+		}
+	}
+	for _, workload := range workloads {
+		if workload.Region != "" && !names[workload.Region] {
+			return fmt.Errorf("workload %s has unknown region: %s", workload.Name, workload.Region)
+		}
+	}
+	return nil
+}
+
+// Home returns the first region, where workloads without a region and stages run.
+func (c *RegionsConfig) Home() *Region {
+	return c.Regions[0]
+}
+
+// regionTarget is the address of the server in a region.
+type regionTarget struct {
+	name string
+	url  string
+}
+
+// host returns the name of the host that the target's requests are sent to.
+func (t *regionTarget) host() string {
+	if t.name == "" {
+		return serverHost
+	}
+	return t.name
+}
+
+// SetRegionAddrs sets the addresses of the servers in each of the client's regions, which must be called before the
+// client is started.
+func (c *Client) SetRegionAddrs(addrs map[string]net.Addr) {
+	c.regions = nil
+	for _, region := range c.config.Regions.Regions {
+		c.regions = append(c.regions, &regionTarget{name: region.Name, url: serverURL(addrs[region.Name])})
+	}
+}
+
+// route returns the regions that a request from the local region is sent to, in order, where the local region is first.
+func (c *Client) route(local string) []*regionTarget {
+	if len(c.regions) == 0 {
+		return []*regionTarget{{url: c.serverAddr}}
+	}
+	if local == "" {
+		local = c.regions[0].name
+	}
+	var result []*regionTarget
+	for _, region := range c.regions {
+		if region.name == local {
+			result = append([]*regionTarget{region}, result...)
+		} else if c.config.Regions.Routing == RoutingLocalFirst {
+			result = append(result, region)
+		}
+	}
+	return result
+}
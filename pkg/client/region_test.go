@@ -0,0 +1,62 @@
+package client
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"tripwire/pkg/server"
+)
+
+func TestRegionsValidate(t *testing.T) {
+	regions := &RegionsConfig{Regions: []*Region{{Name: "east"}, {Name: "west"}}, Routing: RoutingLocalFirst}
+	assert.NoError(t, regions.Validate([]*Workload{{Name: "reads", Region: "west"}}))
+	assert.Error(t, regions.Validate([]*Workload{{Name: "reads", Region: "north"}}))
+	assert.Error(t, (&RegionsConfig{Regions: []*Region{{Name: "east"}, {Name: "east"}}, Routing: RoutingLocalFirst}).Validate(nil))
+	assert.Error(t, (&RegionsConfig{Regions: []*Region{{Name: "east"}}, Routing: "nearest"}).Validate(nil))
+	assert.Error(t, (&RegionsConfig{Routing: RoutingLocalFirst}).Validate(nil))
+	assert.Error(t, (&RegionsConfig{Regions: []*Region{{Name: "east", Degraded: &server.DegradedConfig{}}}, Routing: RoutingLocalFirst}).Validate(nil))
+}
+
+func TestRegionFailover(t *testing.T) {
+	overloaded := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer overloaded.Close()
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer healthy.Close()
+	addrs := map[string]net.Addr{"east": overloaded.Listener.Addr(), "west": healthy.Listener.Addr()}
+
+	tests := []struct {
+		routing   string
+		region    string
+		successes float64
+		failovers float64
+	}{
+		{RoutingLocalFirst, "east", 1, 1},
+		{RoutingLocalFirst, "west", 1, 0},
+		{RoutingLocalOnly, "east", 0, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.routing+"/"+tc.region, func(t *testing.T) {
+			runID := tc.routing + "/" + tc.region
+			config := &Config{Regions: &RegionsConfig{
+				Regions: []*Region{{Name: "east"}, {Name: "west"}},
+				Latency: time.Millisecond,
+				Routing: tc.routing,
+			}}
+			c := newTestClient(t, overloaded.Listener.Addr(), config, runID, withoutPolicies("failover"))
+			c.SetRegionAddrs(addrs)
+			workloadMetrics := testMetrics.WithWorkload(runID, "failover", runID)
+			c.inflight.Add(1)
+			c.sendRequest(&request{workload: "failover", region: tc.region, metrics: workloadMetrics})
+
+			assert.Equal(t, tc.successes, testMetrics.Value(workloadMetrics.ClientReqSuccesses))
+			assert.Equal(t, tc.failovers, testMetrics.Value(testMetrics.WithClientRegionFailovers("failover", runID, "west")))
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package client
+
+import (
+	"sync/atomic"
+	"time"
+
+	"tripwire/pkg/metrics"
+)
+
+const (
+	// recoveredGoodput is the fraction of baseline goodput that goodput must return to after overload to have recovered
+	recoveredGoodput = 0.95
+
+	goodputSampleInterval = 100 * time.Millisecond
+	goodputWindow         = time.Second
+)
+
+// Responsiveness summarizes how quickly a strategy responded to overload, and how quickly it recovered afterwards. Values
+// are nil if they weren't measured, such as when no requests were rejected.
+type Responsiveness struct {
+	TimeToFirstRejection *time.Duration // from overload onset until the first rejected request
+	RecoveryTime         *time.Duration // from overload end until goodput recovered to 95% of its baseline
+}
+
+// responsivenessTracker measures the Responsiveness of a staged client. Stages before the overload are the baseline,
+// whose goodput recovery is measured against. Except for rejections, it's only accessed by the goroutine that runs the
+// stages.
+type responsivenessTracker struct {
+	onsetStage int // the stage that overload begins at, or -1 if the stages never overload
+	endStage   int // the stage that overload ends at, or -1 if it doesn't end
+
+	baselineStart     time.Time
+	baselineSuccesses float64
+	baselineGoodput   float64
+	overloadStart     atomic.Int64 // unix nanos when overload began, or 0 if it hasn't
+	firstRejection    atomic.Int64 // unix nanos of the first rejection after overload began, or 0 if there hasn't been one
+	overloadEnd       time.Time
+	samples           []goodputSample // trailing successes, for the goodput over the last window
+	recoveryTime      *time.Duration
+}
+
+type goodputSample struct {
+	time      time.Time
+	successes float64
+}
+
+func newResponsivenessTracker(stages []*Stage) *responsivenessTracker {
+	onset, end := overloadStages(stages)
+	return &responsivenessTracker{onsetStage: onset, endStage: end}
+}
+
+// overloadStages returns the stage that overload begins at and the stage that it ends at, or -1 for either if there is
+// none. Stages overload when they offer more work, in terms of their RPS and mean service time, than the first stage,
+// and the overload ends at the next stage that offers no more work than the first stage.
+func overloadStages(stages []*Stage) (int, int) {
+	onset, end := -1, -1
+	if len(stages) == 0 {
+		return onset, end
+	}
+	baseline := offeredWork(stages[0])
+	for i, stage := range stages[1:] {
+		if onset == -1 && offeredWork(stage) > baseline {
+			onset = i + 1
+		} else if onset != -1 && offeredWork(stage) <= baseline {
+			end = i + 1
+			break
+		}
+	}
+	return onset, end
+}
+
+// offeredWork returns how much work a stage offers per second, in service time nanoseconds, where zero service times
+// count as 1ns so that stages without service times are compared by their RPS.
+func offeredWork(stage *Stage) float64 {
+	var total, weights float64
+	for _, st := range stage.ServiceTimes {
+		total += float64(max(st.mean(), 1)) * float64(st.Weight)
+		weights += float64(st.Weight)
+	}
+	if weights == 0 {
+		return float64(stage.RPS)
+	}
+	return float64(stage.RPS) * total / weights
+}
+
+// startStage records the start of a stage, given the client's successes so far.
+func (t *responsivenessTracker) startStage(stage int, now time.Time, successes float64) {
+	switch {
+	case stage == 0:
+		t.baselineStart, t.baselineSuccesses = now, successes
+	case stage == t.onsetStage:
+		if elapsed := now.Sub(t.baselineStart).Seconds(); elapsed > 0 {
+			t.baselineGoodput = (successes - t.baselineSuccesses) / elapsed
+		}
+		t.overloadStart.Store(now.UnixNano())
+	case stage == t.endStage:
+		t.overloadEnd = now
+	}
+}
+
+// rejected records a rejected request. Safe for concurrent use, and for a nil tracker, which ignores rejections.
+func (t *responsivenessTracker) rejected(now time.Time) {
+	if t != nil && t.overloadStart.Load() != 0 && t.firstRejection.Load() == 0 {
+		t.firstRejection.CompareAndSwap(0, now.UnixNano())
+	}
+}
+
+// sample records the client's successes so far, and records the recovery time once goodput over the trailing window
+// has recovered after overload.
+func (t *responsivenessTracker) sample(now time.Time, successes float64) {
+	t.samples = append(t.samples, goodputSample{time: now, successes: successes})
+	for len(t.samples) > 1 && now.Sub(t.samples[1].time) >= goodputWindow {
+		t.samples = t.samples[1:]
+	}
+	if t.overloadEnd.IsZero() || t.recoveryTime != nil || t.baselineGoodput == 0 {
+		return
+	}
+	oldest := t.samples[0]
+	if elapsed := now.Sub(oldest.time); elapsed >= goodputWindow && oldest.time.After(t.overloadEnd) {
+		goodput := (successes - oldest.successes) / elapsed.Seconds()
+		if goodput >= recoveredGoodput*t.baselineGoodput {
+			recoveryTime := now.Sub(t.overloadEnd)
+			t.recoveryTime = &recoveryTime
+		}
+	}
+}
+
+func (t *responsivenessTracker) responsiveness() Responsiveness {
+	var result Responsiveness
+	if start, rejection := t.overloadStart.Load(), t.firstRejection.Load(); start != 0 && rejection != 0 {
+		timeToFirstRejection := time.Duration(rejection - start)
+		result.TimeToFirstRejection = &timeToFirstRejection
+	}
+	result.RecoveryTime = t.recoveryTime
+	return result
+}
+
+// record sets the strategy's responsiveness metrics for any values that were measured.
+func (r Responsiveness) record(strategyMetrics *metrics.StrategyMetrics) {
+	if r.TimeToFirstRejection != nil {
+		strategyMetrics.TimeToFirstRejection.Set(r.TimeToFirstRejection.Seconds())
+	}
+	if r.RecoveryTime != nil {
+		strategyMetrics.RecoveryTime.Set(r.RecoveryTime.Seconds())
+	}
+}
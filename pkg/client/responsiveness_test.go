@@ -0,0 +1,67 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverloadStages(t *testing.T) {
+	serviceTimes := func(d time.Duration) WeightedServiceTimes {
+		return WeightedServiceTimes{{ServiceTime: d, Weight: 1}}
+	}
+	tests := []struct {
+		name   string
+		stages []*Stage
+		onset  int
+		end    int
+	}{
+		{"none", nil, -1, -1},
+		{"steady", []*Stage{{RPS: 100}, {RPS: 100}}, -1, -1},
+		{"spike", []*Stage{{RPS: 100}, {RPS: 300}, {RPS: 400}, {RPS: 100}}, 1, 3},
+		{"no end", []*Stage{{RPS: 100}, {RPS: 50}, {RPS: 300}}, 2, -1},
+		{"slower service times", []*Stage{
+			{RPS: 100, ServiceTimes: serviceTimes(10 * time.Millisecond)},
+			{RPS: 100, ServiceTimes: serviceTimes(50 * time.Millisecond)},
+			{RPS: 200, ServiceTimes: serviceTimes(5 * time.Millisecond)},
+		}, 1, 2},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			onset, end := overloadStages(tc.stages)
+			assert.Equal(t, tc.onset, onset)
+			assert.Equal(t, tc.end, end)
+		})
+	}
+}
+
+func TestResponsivenessTracker(t *testing.T) {
+	tracker := newResponsivenessTracker([]*Stage{{RPS: 100}, {RPS: 300}, {RPS: 100}})
+	start := time.Now()
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+
+	// Rejections before the overload are ignored
+	tracker.startStage(0, at(0), 0)
+	tracker.rejected(at(time.Second))
+	tracker.startStage(1, at(10*time.Second), 1000)
+	tracker.rejected(at(10*time.Second + 250*time.Millisecond))
+	tracker.rejected(at(11 * time.Second))
+	tracker.startStage(2, at(20*time.Second), 2000)
+
+	// Goodput recovers to the 100/s baseline once a full window after the overload is at 95/s or better
+	successes := 2000.0
+	for elapsed := 20 * time.Second; elapsed <= 25*time.Second; elapsed += goodputSampleInterval {
+		if elapsed >= 22*time.Second {
+			successes += 10
+		}
+		tracker.sample(at(elapsed), successes)
+	}
+
+	result := tracker.responsiveness()
+	require.NotNil(t, result.TimeToFirstRejection)
+	assert.Equal(t, 250*time.Millisecond, *result.TimeToFirstRejection)
+	require.NotNil(t, result.RecoveryTime)
+	assert.Equal(t, 2900*time.Millisecond, *result.RecoveryTime)
+}
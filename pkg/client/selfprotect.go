@@ -0,0 +1,115 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"tripwire/pkg/events"
+	"tripwire/pkg/metrics"
+)
+
+// SelfProtectionConfig configures the client to back off when the host that tripwire runs on is saturated, so that a
+// saturated load generator doesn't silently distort results. While saturated, open-loop arrivals are skipped rather than
+// sent, and closed-loop users wait before sending. Limits that are 0 aren't checked.
+type SelfProtectionConfig struct {
+	MaxCPU        float64       `yaml:"max_cpu"`        // the fraction of the host's CPUs that tripwire may use
+	MaxGoroutines int           `yaml:"max_goroutines"` // the most goroutines that tripwire may run
+	MaxOpenFiles  int           `yaml:"max_open_files"` // the most file descriptors, including connections. Only checked on Linux.
+	Interval      time.Duration `yaml:"interval"`       // how often usage is checked
+}
+
+func (c *SelfProtectionConfig) UnmarshalYAML(value *yaml.Node) error {
+	*c = SelfProtectionConfig{
+		Interval: time.Second,
+	}
+	type Alias SelfProtectionConfig
+	var alias = Alias(*c)
+	if err := value.Decode(&alias); err != nil {
+		return err
+	}
+	*c = SelfProtectionConfig(alias)
+	return nil
+}
+
+func (c *SelfProtectionConfig) Validate() error {
+	if c.MaxCPU < 0 || c.MaxCPU > 1 {
+		return fmt.Errorf("self_protection max_cpu must be between 0 and 1")
+	}
+	if c.MaxGoroutines < 0 || c.MaxOpenFiles < 0 {
+		return fmt.Errorf("self_protection limits cannot be negative")
+	}
+	if c.Interval <= 0 {
+		return fmt.Errorf("self_protection interval must be positive")
+	}
+	return nil
+}
+
+// selfProtectionBackoff is how long closed-loop users wait before checking again whether the host is still saturated.
+const selfProtectionBackoff = 100 * time.Millisecond
+
+// selfProtector periodically checks tripwire's own resource usage against the configured limits. A nil selfProtector is
+// never saturated.
+type selfProtector struct {
+	config    *SelfProtectionConfig
+	saturated atomic.Bool
+}
+
+// isSaturated returns whether the host is currently saturated, in which case the client should back off.
+func (p *selfProtector) isSaturated() bool {
+	return p != nil && p.saturated.Load()
+}
+
+// runSelfProtection checks usage every interval until ctx is done, recording an event whenever the host becomes saturated
+// or recovers.
+func (c *Client) runSelfProtection(ctx context.Context, p *selfProtector) {
+	ticker := time.NewTicker(p.config.Interval)
+	defer ticker.Stop()
+	lastCheck, lastCPU := time.Now(), metrics.ProcessCPU()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			cpu := metrics.ProcessCPU()
+			cpuUsage := float64(cpu-lastCPU) / float64(now.Sub(lastCheck)) / float64(runtime.NumCPU())
+			lastCheck, lastCPU = now, cpu
+			reason := p.config.saturation(cpuUsage, runtime.NumGoroutine(), openFiles())
+			if saturated := reason != ""; saturated != p.saturated.Load() {
+				p.saturated.Store(saturated)
+				if saturated {
+					c.logger.Warnw("host is saturated, backing off", "reason", reason)
+				} else {
+					c.logger.Infow("host is no longer saturated")
+				}
+				c.events.Record(events.SelfProtection, c.runID, c.strategy, map[string]any{"saturated": saturated, "reason": reason})
+			}
+		}
+	}
+}
+
+// saturation returns which resource exceeds its limit, if any, where openFiles is -1 if it's unknown.
+func (c *SelfProtectionConfig) saturation(cpuUsage float64, goroutines int, openFiles int) string {
+	if c.MaxCPU > 0 && cpuUsage > c.MaxCPU {
+		return "cpu"
+	} else if c.MaxGoroutines > 0 && goroutines > c.MaxGoroutines {
+		return "goroutines"
+	} else if c.MaxOpenFiles > 0 && openFiles > c.MaxOpenFiles {
+		return "open_files"
+	}
+	return ""
+}
+
+// openFiles returns the number of file descriptors that the process has open, or -1 if it can't be determined.
+func openFiles() int {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+	return len(entries)
+}
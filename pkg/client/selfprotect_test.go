@@ -0,0 +1,25 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestSelfProtectionConfig(t *testing.T) {
+	var config SelfProtectionConfig
+	assert.NoError(t, yaml.Unmarshal([]byte("max_cpu: 0.9"), &config))
+	assert.NoError(t, config.Validate())
+	assert.Error(t, (&SelfProtectionConfig{MaxCPU: 1.5, Interval: 1}).Validate())
+
+	config = SelfProtectionConfig{MaxCPU: .8, MaxGoroutines: 1000, MaxOpenFiles: 500}
+	assert.Equal(t, "", config.saturation(.5, 100, 100))
+	assert.Equal(t, "cpu", config.saturation(.9, 100, 100))
+	assert.Equal(t, "goroutines", config.saturation(.5, 2000, 100))
+	assert.Equal(t, "open_files", config.saturation(.5, 100, 1000))
+	assert.Equal(t, "", config.saturation(.5, 100, -1), "unknown open files")
+
+	var protector *selfProtector
+	assert.False(t, protector.isSaturated())
+}
@@ -0,0 +1,51 @@
+package client
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SLOConfig defines a workload's service level objective, whose error budget is the fraction of requests that may be bad.
+// Failed requests are bad, as are successful requests that are slower than the Latency, if it's set.
+type SLOConfig struct {
+	Target  float64       `yaml:"target"`  // the fraction of requests that must be good, such as 0.99
+	Latency time.Duration `yaml:"latency"` // successful requests slower than this are bad, if set
+	Window  time.Duration `yaml:"window"`  // the window that the burn rate is measured over. Defaults to 1m.
+}
+
+func (c *SLOConfig) UnmarshalYAML(value *yaml.Node) error {
+	*c = SLOConfig{
+		Window: time.Minute,
+	}
+	type Alias SLOConfig
+	var alias = Alias(*c)
+	if err := value.Decode(&alias); err != nil {
+		return err
+	}
+	*c = SLOConfig(alias)
+	return nil
+}
+
+func (c *SLOConfig) Validate() error {
+	if c.Target <= 0 || c.Target >= 1 {
+		return fmt.Errorf("slo target must be between 0 and 1")
+	}
+	if c.Latency < 0 {
+		return fmt.Errorf("slo latency cannot be negative")
+	}
+	if c.Window <= 0 {
+		return fmt.Errorf("slo window must be positive")
+	}
+	return nil
+}
+
+// latency returns the latency that successful requests must be within, or 0 if there isn't one. A nil SLOConfig has no
+// latency.
+func (c *SLOConfig) latency() time.Duration {
+	if c == nil {
+		return 0
+	}
+	return c.Latency
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/failsafe-go/failsafe-go/priority"
+)
+
+// StageSLO is an objective that the requests sent during a stage are evaluated against, rather than the requests of the
+// whole run, since whole-run results can hide the phase of a scenario that matters most. It can be scoped to a workload
+// and a priority, such as the success rate of high priority requests during an overload stage.
+type StageSLO struct {
+	Name     string             `yaml:"name"`     // identifies the SLO in results. Defaults to its index.
+	Stage    int                `yaml:"stage"`    // the index of the client stage, or of a workload's own stage, that is evaluated
+	Workload string             `yaml:"workload"` // only evaluates the workload's requests, if set
+	Priority *priority.Priority `yaml:"priority"` // only evaluates requests with the priority, if set
+	Target   float64            `yaml:"target"`   // the fraction of requests that must be good, such as 0.99
+	Latency  time.Duration      `yaml:"latency"`  // successful requests slower than this are bad, if set
+}
+
+func (s *StageSLO) Validate() error {
+	if s.Stage < 0 {
+		return fmt.Errorf("stage slo %s: stage cannot be negative", s.Name)
+	}
+	if s.Target <= 0 || s.Target > 1 {
+		return fmt.Errorf("stage slo %s: target must be greater than 0 and at most 1", s.Name)
+	}
+	if s.Latency < 0 {
+		return fmt.Errorf("stage slo %s: latency cannot be negative", s.Name)
+	}
+	return nil
+}
+
+// NormalizeStageSLOs names any unnamed SLOs after their index, and validates them.
+func NormalizeStageSLOs(slos []*StageSLO) error {
+	names := make(map[string]bool)
+	for i, slo := range slos {
+		if slo.Name == "" {
+			slo.Name = strconv.Itoa(i)
+		}
+		if names[slo.Name] {
+			return fmt.Errorf("duplicate stage slo name: %s", slo.Name)
+		}
+		names[slo.Name] = true
+		if err := slo.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stageSLOsAt returns the SLOs of the stage that apply to the workload's requests, or nil when the stage is negative.
+func (c *Client) stageSLOsAt(stage int, workload string) []*StageSLO {
+	if stage < 0 {
+		return nil
+	}
+	var slos []*StageSLO
+	for _, slo := range c.config.StageSLOs {
+		if slo.Stage == stage && (slo.Workload == "" || slo.Workload == workload) {
+			slos = append(slos, slo)
+		}
+	}
+	return slos
+}
+
+// recordStageSLOs records whether a request that was sent during a stage was good or bad for each of the stage's SLOs
+// that apply to its priority. Requests are good when they succeed within the SLO's latency, if any.
+func (c *Client) recordStageSLOs(slos []*StageSLO, p priority.Priority, outcome string, responseTime time.Duration) {
+	for _, slo := range slos {
+		if slo.Priority != nil && *slo.Priority != p {
+			continue
+		}
+		result := "bad"
+		if outcome == "success" && (slo.Latency == 0 || responseTime <= slo.Latency) {
+			result = "good"
+		}
+		c.metrics.ClientStageSLOReqs.WithLabelValues(c.runID, c.strategy, strconv.Itoa(slo.Stage), slo.Name, result).Inc()
+	}
+}
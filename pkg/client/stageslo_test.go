@@ -0,0 +1,33 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeStageSLOs(t *testing.T) {
+	slos := []*StageSLO{{Stage: 1, Target: 0.9}, {Name: "high", Stage: 1, Target: 0.99}}
+	require.NoError(t, NormalizeStageSLOs(slos))
+	assert.Equal(t, "0", slos[0].Name)
+	assert.Equal(t, "high", slos[1].Name)
+
+	err := NormalizeStageSLOs([]*StageSLO{{Name: "a", Target: 0.9}, {Name: "a", Target: 0.9}})
+	assert.EqualError(t, err, "duplicate stage slo name: a")
+	err = NormalizeStageSLOs([]*StageSLO{{Target: 1.5}})
+	assert.EqualError(t, err, "stage slo 0: target must be greater than 0 and at most 1")
+}
+
+func TestStageSLOsAt(t *testing.T) {
+	all := &StageSLO{Name: "all", Stage: 1}
+	checkout := &StageSLO{Name: "checkout", Stage: 1, Workload: "checkout"}
+	other := &StageSLO{Name: "other", Stage: 0}
+	c := &Client{config: &Config{StageSLOs: []*StageSLO{all, checkout, other}}}
+
+	assert.Equal(t, []*StageSLO{all, checkout}, c.stageSLOsAt(1, "checkout"))
+	assert.Equal(t, []*StageSLO{all}, c.stageSLOsAt(1, "search"))
+	assert.Equal(t, []*StageSLO{other}, c.stageSLOsAt(0, "search"))
+	assert.Nil(t, c.stageSLOsAt(2, "checkout"))
+	assert.Nil(t, c.stageSLOsAt(-1, "checkout"))
+}
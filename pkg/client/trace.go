@@ -0,0 +1,222 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TraceFormat is the format of a trace file.
+type TraceFormat string
+
+const (
+	// TextTrace contains a line per request with the delay since the previous request and its service time, such as
+	// "10ms 50ms", optionally followed by the status code that the request was recorded with, which isn't replayed.
+	// Blank lines and lines starting with # are ignored.
+	TextTrace TraceFormat = "text"
+
+	// CSVTrace contains a header row followed by a row per request, with arrival and service_time columns. Other
+	// columns are ignored.
+	CSVTrace TraceFormat = "csv"
+
+	// JSONTrace contains an array of requests, or a request per line, each of which is an object with arrival and
+	// service_time fields. Other fields are ignored.
+	JSONTrace TraceFormat = "json"
+)
+
+// traceFormat returns the configured format of a trace, or one based on the extension of its path.
+func traceFormat(config *GeneratorConfig) TraceFormat {
+	if config.Format != "" {
+		return config.Format
+	}
+	switch strings.ToLower(filepath.Ext(config.Path)) {
+	case ".csv":
+		return CSVTrace
+	case ".json", ".jsonl":
+		return JSONTrace
+	default:
+		return TextTrace
+	}
+}
+
+func readTextTrace(r io.Reader, path string) ([]*Arrival, error) {
+	var arrivals []*Arrival
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 && len(fields) != 3 {
+			return nil, fmt.Errorf("%s:%d: expected a delay, service time, and optional status", path, line)
+		}
+		delay, err := time.ParseDuration(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		serviceTime, err := time.ParseDuration(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		arrivals = append(arrivals, &Arrival{Delay: delay, ServiceTime: serviceTime})
+	}
+	return arrivals, scanner.Err()
+}
+
+// traceRequest is a request in a CSV or JSON trace, which is timestamped with its arrival rather than a delay.
+type traceRequest struct {
+	arrival     time.Time
+	serviceTime time.Duration
+}
+
+func readCSVTrace(r io.Reader, path string) ([]*Arrival, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to read header: %w", path, err)
+	}
+	arrivalColumn, serviceTimeColumn := -1, -1
+	for i, name := range header {
+		switch strings.TrimSpace(name) {
+		case "arrival":
+			arrivalColumn = i
+		case "service_time":
+			serviceTimeColumn = i
+		}
+	}
+	if arrivalColumn == -1 || serviceTimeColumn == -1 {
+		return nil, fmt.Errorf("%s: header must contain arrival and service_time columns", path)
+	}
+
+	var requests []traceRequest
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		line, _ := reader.FieldPos(0)
+		if len(record) <= max(arrivalColumn, serviceTimeColumn) {
+			return nil, fmt.Errorf("%s:%d: expected arrival and service_time columns", path, line)
+		}
+		arrival, err := parseTraceArrival(record[arrivalColumn])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		serviceTime, err := parseTraceServiceTime(record[serviceTimeColumn])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		requests = append(requests, traceRequest{arrival: arrival, serviceTime: serviceTime})
+	}
+	return traceArrivals(requests), nil
+}
+
+func readJSONTrace(r io.Reader, path string) ([]*Arrival, error) {
+	type jsonRequest struct {
+		Arrival     json.RawMessage `json:"arrival"`
+		ServiceTime json.RawMessage `json:"service_time"`
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var jsonRequests []jsonRequest
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err = json.Unmarshal(trimmed, &jsonRequests); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	} else {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		for decoder.More() {
+			var request jsonRequest
+			if err = decoder.Decode(&request); err != nil {
+				return nil, fmt.Errorf("%s: request %d: %w", path, len(jsonRequests)+1, err)
+			}
+			jsonRequests = append(jsonRequests, request)
+		}
+	}
+
+	requests := make([]traceRequest, 0, len(jsonRequests))
+	for i, request := range jsonRequests {
+		if request.Arrival == nil || request.ServiceTime == nil {
+			return nil, fmt.Errorf("%s: request %d: expected arrival and service_time fields", path, i+1)
+		}
+		arrival, err := parseTraceArrival(traceValue(request.Arrival))
+		if err != nil {
+			return nil, fmt.Errorf("%s: request %d: %w", path, i+1, err)
+		}
+		serviceTime, err := parseTraceServiceTime(traceValue(request.ServiceTime))
+		if err != nil {
+			return nil, fmt.Errorf("%s: request %d: %w", path, i+1, err)
+		}
+		requests = append(requests, traceRequest{arrival: arrival, serviceTime: serviceTime})
+	}
+	return traceArrivals(requests), nil
+}
+
+// traceValue returns a JSON string's contents, or a JSON number as is.
+func traceValue(value json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		return s
+	}
+	return string(value)
+}
+
+// parseTraceArrival parses an arrival, which is either an RFC 3339 timestamp or a number of seconds, such as since the
+// Unix epoch or the start of the trace. Only the differences between arrivals are replayed.
+func parseTraceArrival(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if arrival, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return arrival, nil
+	}
+	seconds, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid arrival %q: expected an RFC 3339 timestamp or seconds", s)
+	}
+	return time.Unix(0, int64(seconds*float64(time.Second))), nil
+}
+
+// parseTraceServiceTime parses a service time, which is either a duration, such as 50ms, or a number of milliseconds.
+func parseTraceServiceTime(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if serviceTime, err := time.ParseDuration(s); err == nil {
+		return serviceTime, nil
+	}
+	millis, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid service_time %q: expected a duration or milliseconds", s)
+	}
+	return time.Duration(millis * float64(time.Millisecond)), nil
+}
+
+// traceArrivals returns arrivals for requests in the order they arrived, where the first is sent immediately and the
+// rest are delayed by the time since the previous request arrived.
+func traceArrivals(requests []traceRequest) []*Arrival {
+	sort.SliceStable(requests, func(i, j int) bool {
+		return requests[i].arrival.Before(requests[j].arrival)
+	})
+	arrivals := make([]*Arrival, len(requests))
+	for i, request := range requests {
+		arrivals[i] = &Arrival{ServiceTime: request.serviceTime}
+		if i > 0 {
+			arrivals[i].Delay = request.arrival.Sub(requests[i-1].arrival)
+		}
+	}
+	return arrivals
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraceFormats(t *testing.T) {
+	dir := t.TempDir()
+	traces := map[string]string{
+		"trace.csv":   "status,arrival,service_time\n200,2025-01-01T12:00:00Z,50ms\n200,2025-01-01T12:00:00.030Z,20\n503,2025-01-01T12:00:00.010Z,5ms\n",
+		"trace.json":  `[{"arrival": 100, "service_time": "50ms"}, {"arrival": 100.03, "service_time": 20}, {"arrival": "100.01", "service_time": "5ms"}]`,
+		"trace.jsonl": "{\"arrival\": 100, \"service_time\": \"50ms\"}\n{\"arrival\": 100.03, \"service_time\": 20}\n{\"arrival\": 100.01, \"service_time\": \"5ms\"}\n",
+	}
+	for name, trace := range traces {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			require.NoError(t, os.WriteFile(path, []byte(trace), 0o644))
+			generator, err := NewGenerator(&GeneratorConfig{Type: "trace", Path: path})
+			require.NoError(t, err)
+
+			// Requests are replayed in the order they arrived
+			var arrivals []Arrival
+			for arrival, ok := generator.Next(&GeneratorParams{}); ok; arrival, ok = generator.Next(&GeneratorParams{}) {
+				arrivals = append(arrivals, *arrival)
+			}
+			require.Len(t, arrivals, 3)
+			assert.Equal(t, Arrival{ServiceTime: 50 * time.Millisecond}, arrivals[0])
+			assert.InDelta(t, 10*time.Millisecond, arrivals[1].Delay, float64(time.Microsecond))
+			assert.Equal(t, 5*time.Millisecond, arrivals[1].ServiceTime)
+			assert.InDelta(t, 20*time.Millisecond, arrivals[2].Delay, float64(time.Microsecond))
+			assert.Equal(t, 20*time.Millisecond, arrivals[2].ServiceTime)
+		})
+	}
+}
+
+func TestTraceTimeScale(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace")
+	require.NoError(t, os.WriteFile(path, []byte("10ms 50ms\n"), 0o644))
+	generator, err := NewGenerator(&GeneratorConfig{Type: "trace", Path: path, TimeScale: 0.5})
+	require.NoError(t, err)
+	arrival, _ := generator.Next(&GeneratorParams{})
+	assert.Equal(t, &Arrival{Delay: 5 * time.Millisecond, ServiceTime: 50 * time.Millisecond}, arrival)
+
+	_, err = NewGenerator(&GeneratorConfig{Type: "trace", Path: path, TimeScale: -1})
+	assert.Error(t, err)
+}
+
+func TestInvalidTraces(t *testing.T) {
+	dir := t.TempDir()
+	for name, trace := range map[string]string{
+		"header.csv":  "time,latency\n1,50ms\n",
+		"arrival.csv": "arrival,service_time\nnoon,50ms\n",
+		"fields.json": `[{"arrival": 1}]`,
+	} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(trace), 0o644))
+		_, err := NewGenerator(&GeneratorConfig{Type: "trace", Path: path})
+		assert.Error(t, err, name)
+	}
+	_, err := NewGenerator(&GeneratorConfig{Type: "trace", Path: filepath.Join(dir, "header.csv"), Format: "xml"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,180 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	TransportPerRequest = "per_request" // a new connection is established for each request
+	TransportPooled     = "pooled"      // connections are kept alive and reused from a pool
+	TransportH2C        = "h2c"         // requests are multiplexed over a connection to each server with HTTP/2 without TLS
+)
+
+// TransportConfig configures the client's HTTP transport. By default, a connection is established for each request,
+// which limits achievable request rates. A pooled transport reuses connections, which can be established before the
+// run starts. Workloads can override the client's transport, in which case they have their own connection pool.
+type TransportConfig struct {
+	Type                string        `yaml:"type"`
+	MaxConnsPerHost     int           `yaml:"max_conns_per_host"`      // the most connections to each server, or 0 for no limit
+	MaxIdleConns        int           `yaml:"max_idle_conns"`          // the most connections that are kept alive while idle
+	MaxIdleConnsPerHost int           `yaml:"max_idle_conns_per_host"` // the most idle connections to each server. Defaults to max_idle_conns.
+	IdleConnTimeout     time.Duration `yaml:"idle_conn_timeout"`       // how long idle connections are kept alive. Defaults to 90s.
+	Prewarm             int           `yaml:"prewarm"`                 // connections to establish before sending requests
+}
+
+func (c *TransportConfig) UnmarshalYAML(value *yaml.Node) error {
+	*c = TransportConfig{
+		Type:            TransportPerRequest,
+		MaxIdleConns:    1000,
+		IdleConnTimeout: 90 * time.Second,
+	}
+	type Alias TransportConfig
+	var alias = Alias(*c)
+	if err := value.Decode(&alias); err != nil {
+		return err
+	}
+	*c = TransportConfig(alias)
+	return nil
+}
+
+func (c *TransportConfig) Validate() error {
+	if c.Type != TransportPerRequest && c.Type != TransportPooled && c.Type != TransportH2C {
+		return fmt.Errorf("unknown transport type: %s", c.Type)
+	}
+	if c.MaxConnsPerHost < 0 || c.MaxIdleConns < 0 || c.MaxIdleConnsPerHost < 0 || c.Prewarm < 0 {
+		return fmt.Errorf("transport connections cannot be negative")
+	}
+	if c.IdleConnTimeout < 0 {
+		return fmt.Errorf("transport idle_conn_timeout cannot be negative")
+	}
+	if c.MaxConnsPerHost > 0 && c.Prewarm > c.MaxConnsPerHost {
+		return fmt.Errorf("transport prewarm cannot exceed max_conns_per_host")
+	}
+	return nil
+}
+
+// pooled returns whether connections should be reused from a pool.
+func (c *TransportConfig) pooled() bool {
+	return c != nil && c.Type == TransportPooled
+}
+
+// H2C returns whether requests are sent with h2c, which requires the server to serve HTTP/2.
+func (c *TransportConfig) H2C() bool {
+	return c != nil && c.Type == TransportH2C
+}
+
+// reusesConnections returns whether connections should be kept alive after requests.
+func (c *TransportConfig) reusesConnections() bool {
+	return c.pooled() || c.H2C()
+}
+
+// newTransport returns the base transport for a config.
+func newTransport(config *TransportConfig) http.RoundTripper {
+	if config.H2C() {
+		// Requests beyond the server's max concurrent streams wait for a stream rather than opening another connection
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+		return &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network string, addr string, _ *tls.Config) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, addr)
+			},
+			StrictMaxConcurrentStreams: true,
+			IdleConnTimeout:            config.IdleConnTimeout,
+		}
+	}
+	if !config.pooled() {
+		return http.DefaultTransport
+	}
+	maxIdleConnsPerHost := config.MaxIdleConnsPerHost
+	if maxIdleConnsPerHost == 0 {
+		maxIdleConnsPerHost = config.MaxIdleConns
+	}
+	return &http.Transport{
+		DialContext:         (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext,
+		MaxConnsPerHost:     config.MaxConnsPerHost,
+		MaxIdleConns:        config.MaxIdleConns,
+		MaxIdleConnsPerHost: maxIdleConnsPerHost,
+		IdleConnTimeout:     config.IdleConnTimeout,
+	}
+}
+
+// clientTransport is a base transport and the config it was created from.
+type clientTransport struct {
+	config    *TransportConfig
+	transport http.RoundTripper
+}
+
+// transportFor returns the config of the transport that a workload's requests are sent with.
+func (c *Client) transportFor(workload string) *TransportConfig {
+	if t, ok := c.workloadTransports[workload]; ok {
+		return t.config
+	}
+	return c.config.Transport
+}
+
+// baseTransportFor returns the base transport that a workload's requests are sent with, beneath any policies.
+func (c *Client) baseTransportFor(workload string) http.RoundTripper {
+	if t, ok := c.workloadTransports[workload]; ok {
+		return t.transport
+	}
+	return c.transport
+}
+
+// prewarm establishes the connections of the client's transport, and of any workloads' transports.
+func (c *Client) prewarm() {
+	c.prewarmTransport(&clientTransport{config: c.config.Transport, transport: c.transport}, "")
+	for workload, t := range c.workloadTransports {
+		c.prewarmTransport(t, workload)
+	}
+}
+
+// prewarmTransport establishes connections to the server concurrently, so that they're pooled before requests are sent.
+// Prewarm requests have no body, so the server rejects them without doing any work.
+func (c *Client) prewarmTransport(t *clientTransport, workload string) {
+	if !t.config.pooled() || t.config.Prewarm == 0 {
+		return
+	}
+	connections := t.config.Prewarm
+	// Hold each response until every connection is established, so that connections aren't reused between prewarms
+	responses := make([]*http.Response, connections)
+	var wg sync.WaitGroup
+	for i := 0; i < connections; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, err := t.transport.RoundTrip(newPrewarmRequest(c.serverAddr))
+			if err != nil {
+				c.logger.Warnw("failed to prewarm connection", "error", err)
+				return
+			}
+			responses[i] = resp
+		}(i)
+	}
+	wg.Wait()
+	for _, resp := range responses {
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+	}
+	if workload != "" {
+		c.logger.Infow("prewarmed workload connections", "workload", workload, "connections", connections)
+		return
+	}
+	c.logger.Infow("prewarmed client connections", "connections", connections)
+}
+
+func newPrewarmRequest(serverAddr string) *http.Request {
+	req, _ := http.NewRequest(http.MethodGet, serverAddr, http.NoBody)
+	return req
+}
@@ -0,0 +1,448 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"tripwire/pkg/metrics"
+	"tripwire/pkg/util"
+)
+
+// ErrStopped is returned when workloads are updated after the client has stopped.
+var ErrStopped = errors.New("client is stopped")
+
+// workloadRunner runs a single workload in its own loop, whose parameters can be changed in place.
+type workloadRunner struct {
+	updates chan *Workload
+	cancel  context.CancelFunc
+}
+
+// startWorkload starts running a workload. Requires c.mtx to be held.
+func (c *Client) startWorkload(workload *Workload) {
+	ctx, cancel := context.WithCancel(context.Background())
+	runner := &workloadRunner{
+		updates: make(chan *Workload, 1),
+		cancel:  cancel,
+	}
+	c.runners[workload.Name] = runner
+	go c.runWorkload(ctx, runner, workload)
+}
+
+// workloadState is the state of a running workload that's kept as it's paused or switched between open and closed-loop,
+// so that its stages, pattern, and arrivals continue where they left off rather than starting over.
+type workloadState struct {
+	start     time.Time        // when the workload started, shifted forward by the time it's been paused
+	arrivals  *arrivalTimer    // the workload's open-loop arrivals, if it has run open-loop
+	generator *GeneratorConfig // the config that the arrivals' generator was created from
+}
+
+// elapsed returns the time since the workload started, excluding the time it's been paused.
+func (s *workloadState) elapsed() time.Duration {
+	return time.Since(s.start)
+}
+
+// runWorkload runs a workload until ctx is done, applying any updates in place. A workload with a concurrency runs
+// closed-loop, and otherwise runs open-loop, and can be switched between the two, or paused, by an update.
+func (c *Client) runWorkload(ctx context.Context, runner *workloadRunner, workload *Workload) {
+	workloadMetrics := c.metrics.WithWorkload(c.runID, workload.Name, c.strategy)
+	workloadMetrics.ClientReqTimeouts.Add(0)
+	workloadMetrics.ClientDroppedArrivals.Add(0)
+	workloadMetrics.ClientBackedOffArrivals.Add(0)
+
+	logger := c.logger.With("workload", workload.Name)
+	logger.Infow("starting client workload", "config", workload)
+	state := &workloadState{start: time.Now()}
+	for workload != nil {
+		if workload.Paused {
+			workload = c.runPaused(ctx, runner, workload, state, workloadMetrics, logger)
+		} else if workload.Concurrency > 0 {
+			workload = c.runClosedLoop(ctx, runner, workload, state, workloadMetrics, logger)
+		} else {
+			workload = c.runOpenLoop(ctx, runner, workload, state, workloadMetrics, logger)
+		}
+	}
+	logger.Infow("stopping client workload")
+}
+
+// runOpenLoop sends a workload's requests as they arrive, regardless of whether previous requests have completed, until
+// ctx is done or the workload is updated to be closed-loop or paused, and returns the updated workload, if any. When a
+// workload is updated and an update transition is configured, its rate ramps linearly from its current RPS to its new
+// RPS over the transition. Any stages and load pattern follow the time since the workload started, and the pattern
+// modulates the workload's RPS, including while it ramps. Arrivals resume from where they left off, unless the
+// workload's generator was changed.
+func (c *Client) runOpenLoop(ctx context.Context, runner *workloadRunner, workload *Workload, state *workloadState, workloadMetrics *metrics.WorkloadMetrics, logger *zap.SugaredLogger) *Workload {
+	var fromRPS uint
+	var transition time.Duration
+	start := time.Now()
+	elapsed := state.elapsed()
+	baseRPS := workload.rpsAt(elapsed)
+	params := workloadParams(workload, workload.Pattern.rps(baseRPS, elapsed), c.rng, c.arrivalRng)
+	params.ServiceTimes, params.WeightSum = workload.serviceTimesAt(elapsed)
+	if state.arrivals != nil && reflect.DeepEqual(state.generator, c.workloadGenerator(workload)) {
+		state.arrivals.resume()
+	} else {
+		state.generator = c.workloadGenerator(workload)
+		state.arrivals = newArrivalTimer(c.newGenerator(state.generator), params)
+	}
+	arrivals := state.arrivals
+	defer func() { arrivals.pause() }()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case updated := <-runner.updates:
+			logger.Infow("updating client workload", "config", updated)
+			if updated.Concurrency > 0 || updated.Paused {
+				return updated
+			}
+			generatorChanged := !reflect.DeepEqual(c.workloadGenerator(workload), c.workloadGenerator(updated))
+			fromRPS, workload = baseRPS, updated
+			transition = c.config.UpdateTransition
+			start = time.Now()
+			elapsed := state.elapsed()
+			baseRPS = rampedRPS(fromRPS, workload.rpsAt(elapsed), 0, transition)
+			params = workloadParams(workload, workload.Pattern.rps(baseRPS, elapsed), c.rng, c.arrivalRng)
+			params.ServiceTimes, params.WeightSum = workload.serviceTimesAt(elapsed)
+			if generatorChanged {
+				arrivals.stop()
+				state.generator = c.workloadGenerator(workload)
+				arrivals = newArrivalTimer(c.newGenerator(state.generator), params)
+				state.arrivals = arrivals
+			}
+		case <-arrivals.timer.C:
+			elapsed := state.elapsed()
+			baseRPS = rampedRPS(fromRPS, workload.rpsAt(elapsed), time.Since(start), transition)
+			params.RPS = c.burstRPS(workload.Name, workload.Pattern.rps(baseRPS, elapsed))
+			params.ServiceTimes, params.WeightSum = workload.serviceTimesAt(elapsed)
+			workloadMetrics.ClientExpectedRps.Set(float64(params.RPS))
+			workloadMetrics.ClientArrivalLateness.Observe(arrivals.lateness().Seconds())
+			arrival := arrivals.arrival
+			if c.protector.isSaturated() {
+				workloadMetrics.ClientBackedOffArrivals.Inc()
+			} else {
+				c.inflight.Add(1)
+				go c.sendRequest(&request{workload: workload.Name, user: workload.User, region: workload.Region,
+					method: workload.Method, path: workload.Path,
+					clientID: c.clientID(workload.Name, c.workloadClients(workload)), metrics: workloadMetrics,
+					serviceTime: arrival.ServiceTime, priority: arrival.Priority, sloLatency: workload.SLO.latency(),
+					stageSLOs: c.stageSLOsAt(workload.stageIndexAt(elapsed), workload.Name), scheduled: arrivals.scheduled,
+					logger: c.sampledLogger(logger, c.workloadLogSample(workload))})
+			}
+			workloadMetrics.ClientDroppedArrivals.Add(float64(arrivals.advance(params)))
+			if arrivals.arrival == nil {
+				logger.Infow("client workload has no more arrivals")
+			}
+		}
+	}
+}
+
+// runPaused waits without sending any requests until ctx is done or the workload is updated to be resumed, and returns
+// the updated workload, if any. The time spent paused doesn't count towards the workload's stages and pattern.
+func (c *Client) runPaused(ctx context.Context, runner *workloadRunner, workload *Workload, state *workloadState, workloadMetrics *metrics.WorkloadMetrics, logger *zap.SugaredLogger) *Workload {
+	logger.Infow("pausing client workload")
+	workloadMetrics.ClientExpectedRps.Set(0)
+	pausedAt := time.Now()
+	defer func() { state.start = state.start.Add(time.Since(pausedAt)) }()
+	for workload.Paused {
+		select {
+		case <-ctx.Done():
+			return nil
+		case workload = <-runner.updates:
+			logger.Infow("updating client workload", "config", workload)
+		}
+	}
+	logger.Infow("resuming client workload")
+	return workload
+}
+
+// runClosedLoop runs a workload's virtual users, which each send a request, wait for it to complete, then wait for the
+// think time before sending the next, until ctx is done or the workload is updated to be open-loop or paused, and returns
+// the updated workload, if any. Updates to the concurrency add or stop virtual users, and any stages add or stop users
+// as they ramp, following the time since the workload started. Returns once the virtual users have stopped.
+func (c *Client) runClosedLoop(ctx context.Context, runner *workloadRunner, workload *Workload, state *workloadState, workloadMetrics *metrics.WorkloadMetrics, logger *zap.SugaredLogger) *Workload {
+	var current atomic.Pointer[Workload]
+	current.Store(workload)
+	workloadStart := state.start
+	var users []context.CancelFunc
+	var wg sync.WaitGroup
+	resize := func(concurrency uint) {
+		for uint(len(users)) < concurrency {
+			userCtx, cancel := context.WithCancel(ctx)
+			users = append(users, cancel)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				c.runUser(userCtx, &current, workloadStart, workloadMetrics, logger)
+			}()
+		}
+		for uint(len(users)) > concurrency {
+			users[len(users)-1]()
+			users = users[:len(users)-1]
+		}
+		workloadMetrics.ClientUsers.Set(float64(concurrency))
+	}
+	defer func() {
+		for _, cancel := range users {
+			cancel()
+		}
+		wg.Wait()
+		workloadMetrics.ClientUsers.Set(0)
+	}()
+
+	ramp := time.NewTicker(userRampInterval)
+	defer ramp.Stop()
+	resize(workload.concurrencyAt(time.Since(workloadStart)))
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case updated := <-runner.updates:
+			logger.Infow("updating client workload", "config", updated)
+			if updated.Concurrency == 0 || updated.Paused {
+				return updated
+			}
+			workload = updated
+			current.Store(updated)
+			resize(workload.concurrencyAt(time.Since(workloadStart)))
+		case <-ramp.C:
+			if concurrency := workload.concurrencyAt(time.Since(workloadStart)); concurrency != uint(len(users)) {
+				resize(concurrency)
+			}
+		}
+	}
+}
+
+// userRampInterval is how often the virtual users of a closed-loop workload are resized to follow its stages.
+const userRampInterval = 100 * time.Millisecond
+
+// runUser runs a closed-loop virtual user until ctx is done, using the current parameters of its workload for each
+// request. While the host is saturated, the user waits rather than sending.
+func (c *Client) runUser(ctx context.Context, workload *atomic.Pointer[Workload], workloadStart time.Time, workloadMetrics *metrics.WorkloadMetrics, logger *zap.SugaredLogger) {
+	for ctx.Err() == nil {
+		w := workload.Load()
+		elapsed := time.Since(workloadStart)
+		serviceTimes, weightSum := w.serviceTimesAt(elapsed)
+		if c.protector.isSaturated() {
+			workloadMetrics.ClientBackedOffArrivals.Inc()
+			sleep(ctx, selfProtectionBackoff)
+			continue
+		}
+		c.inflight.Add(1)
+		c.sendRequest(&request{workload: w.Name, user: w.User, region: w.Region, method: w.Method, path: w.Path,
+			clientID: c.clientID(w.Name, c.workloadClients(w)), metrics: workloadMetrics,
+			serviceTime: serviceTimes.Random(c.rng, weightSum), priority: w.Priorities.Random(c.rng, w.Priority),
+			sloLatency: w.SLO.latency(), stageSLOs: c.stageSLOsAt(w.stageIndexAt(elapsed), w.Name),
+			logger: c.sampledLogger(logger, c.workloadLogSample(w))})
+		if thinkTime := w.ThinkTime.sample(c.arrivalRng); thinkTime > 0 {
+			sleep(ctx, thinkTime)
+		}
+	}
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+func workloadParams(workload *Workload, rps uint, rng *util.Rand, arrivalRng *util.Rand) *GeneratorParams {
+	serviceTimes, weightSum := workload.serviceTimesAt(0)
+	return &GeneratorParams{
+		RPS:          rps,
+		ServiceTimes: serviceTimes,
+		WeightSum:    weightSum,
+		Priority:     workload.Priority,
+		Priorities:   workload.Priorities,
+		Rand:         rng,
+		ArrivalRand:  arrivalRng,
+	}
+}
+
+// stageAt returns the workload's stage at some elapsed time since the workload started, along with the time elapsed within
+// the stage, or nil once its stages have ended.
+func (w *Workload) stageAt(elapsed time.Duration) (*Stage, time.Duration) {
+	for _, stage := range w.Stages {
+		if elapsed < stage.Duration {
+			return stage, elapsed
+		}
+		elapsed -= stage.Duration
+	}
+	return nil, 0
+}
+
+// stageIndexAt returns the index of the workload's stage at some elapsed time since the workload started, or -1 once its
+// stages have ended.
+func (w *Workload) stageIndexAt(elapsed time.Duration) int {
+	for i, stage := range w.Stages {
+		if elapsed < stage.Duration {
+			return i
+		}
+		elapsed -= stage.Duration
+	}
+	return -1
+}
+
+// rpsAt returns the workload's RPS at some elapsed time since it started, which follows its stages, if any.
+func (w *Workload) rpsAt(elapsed time.Duration) uint {
+	if stage, stageElapsed := w.stageAt(elapsed); stage != nil {
+		return stage.rpsAt(stageElapsed)
+	}
+	return w.RPS
+}
+
+// concurrencyAt returns a closed-loop workload's number of virtual users at some elapsed time since it started, which
+// follows its stages, if any.
+func (w *Workload) concurrencyAt(elapsed time.Duration) uint {
+	if stage, stageElapsed := w.stageAt(elapsed); stage != nil {
+		return stage.concurrencyAt(stageElapsed)
+	}
+	return w.Concurrency
+}
+
+// serviceTimesAt returns the workload's service times and their weight sum at some elapsed time since it started, which
+// follow its stages, if any.
+func (w *Workload) serviceTimesAt(elapsed time.Duration) (WeightedServiceTimes, int) {
+	if stage, _ := w.stageAt(elapsed); stage != nil {
+		return stage.ServiceTimes, stage.WeightSum
+	}
+	return w.ServiceTimes, w.WeightSum
+}
+
+// workloadGenerator returns the generator config for a workload, which defaults to the client's.
+func (c *Client) workloadGenerator(workload *Workload) *GeneratorConfig {
+	if workload.Generator != nil {
+		return workload.Generator
+	}
+	return c.config.Generator
+}
+
+// workloadLogSample returns the fraction of a workload's requests to log, which defaults to the client's.
+func (c *Client) workloadLogSample(workload *Workload) float64 {
+	if workload.LogSample != nil {
+		return *workload.LogSample
+	}
+	return c.config.LogSample
+}
+
+// workloadClients returns the number of client identities a workload's requests are spread across, which defaults to the
+// client's.
+func (c *Client) workloadClients(workload *Workload) uint {
+	if workload.Clients != nil {
+		return *workload.Clients
+	}
+	return c.config.Clients
+}
+
+// rampedRPS returns the RPS at some elapsed time in a linear transition from one RPS to another.
+func rampedRPS(from uint, to uint, elapsed time.Duration, transition time.Duration) uint {
+	if elapsed >= transition {
+		return to
+	}
+	progress := float64(elapsed) / float64(transition)
+	return max(1, uint(float64(from)+(float64(to)-float64(from))*progress))
+}
+
+// UpdateWorkloads normalizes and applies workloads to the client. Existing workloads are updated in place, new workloads
+// are started, and workloads that are no longer present are stopped. Returns an error, without applying any workloads, if
+// any are invalid, or ErrStopped if the client has stopped.
+func (c *Client) UpdateWorkloads(workloads []*Workload) error {
+	if err := NormalizeWorkloads(workloads, c.config.Generator); err != nil {
+		return err
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	// Start cancels the runners under the same lock once the client is stopped, so none can be started after that
+	select {
+	case <-c.stop:
+		return ErrStopped
+	default:
+	}
+
+	names := make(map[string]bool)
+	for _, workload := range workloads {
+		names[workload.Name] = true
+		if runner, ok := c.runners[workload.Name]; ok {
+			// Replace any update that hasn't been applied yet
+			select {
+			case <-runner.updates:
+			default:
+			}
+			runner.updates <- workload
+		} else {
+			c.startWorkload(workload)
+		}
+	}
+	for name, runner := range c.runners {
+		if !names[name] {
+			runner.cancel()
+			delete(c.runners, name)
+		}
+	}
+	c.workloads.Store(&workloads)
+	return nil
+}
+
+// SetWorkloadRPS changes the rate of a single workload to rps, leaving its other parameters unchanged, and returns whether
+// the workload exists.
+func (c *Client) SetWorkloadRPS(name string, rps uint) bool {
+	return c.updateWorkload(name, func(workload *Workload) {
+		workload.RPS = rps
+	})
+}
+
+// PauseWorkload stops a single workload from sending requests, leaving its other parameters unchanged, and returns whether
+// the workload exists. Requests that are already inflight are allowed to complete.
+func (c *Client) PauseWorkload(name string) bool {
+	return c.updateWorkload(name, func(workload *Workload) {
+		workload.Paused = true
+	})
+}
+
+// ResumeWorkload resumes sending a paused workload's requests, and returns whether the workload exists.
+func (c *Client) ResumeWorkload(name string) bool {
+	return c.updateWorkload(name, func(workload *Workload) {
+		workload.Paused = false
+	})
+}
+
+// updateWorkload applies an update to a copy of a single workload, leaving the other workloads unchanged, and returns
+// whether the workload exists.
+func (c *Client) updateWorkload(name string, update func(workload *Workload)) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	runner, ok := c.runners[name]
+	if !ok {
+		return false
+	}
+	// Copy the workloads rather than updating the current snapshot, which may be read concurrently
+	current := c.Workloads()
+	workloads := make([]*Workload, len(current))
+	copy(workloads, current)
+	for i, workload := range workloads {
+		if workload.Name == name {
+			updated := *workload
+			update(&updated)
+			workloads[i] = &updated
+			// Replace any update that hasn't been applied yet
+			select {
+			case <-runner.updates:
+			default:
+			}
+			runner.updates <- &updated
+		}
+	}
+	c.workloads.Store(&workloads)
+	return true
+}
@@ -0,0 +1,220 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestClosedLoop(t *testing.T) {
+	var inflight, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := inflight.Add(1)
+		for {
+			previous := peak.Load()
+			if current <= previous || peak.CompareAndSwap(previous, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		inflight.Add(-1)
+	}))
+	defer server.Close()
+	c := newTestClient(t, server.Listener.Addr(), &Config{}, "closed", withoutPolicies("closed"))
+
+	workload := &Workload{Name: "closed", Concurrency: 3, ServiceTimes: WeightedServiceTimes{{Weight: 1}}}
+	require.NoError(t, c.UpdateWorkloads([]*Workload{workload}))
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int32(3), peak.Load())
+	assert.Equal(t, 3.0, testMetrics.Value(testMetrics.WithWorkload("closed", "closed", "closed").ClientUsers))
+
+	// Reducing the concurrency stops virtual users
+	require.NoError(t, c.UpdateWorkloads([]*Workload{{Name: "closed", Concurrency: 1, ServiceTimes: workload.ServiceTimes}}))
+	time.Sleep(50 * time.Millisecond)
+	peak.Store(0)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(1), peak.Load())
+
+	require.NoError(t, c.UpdateWorkloads(nil))
+	assert.Eventually(t, func() bool {
+		return testMetrics.Value(testMetrics.WithWorkload("closed", "closed", "closed").ClientUsers) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestWorkloadsDrain(t *testing.T) {
+	var received atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer server.Close()
+	config := &Config{Drain: time.Second, Workloads: []*Workload{{Name: "drain", RPS: 100, ServiceTimes: WeightedServiceTimes{{Weight: 1}}, WeightSum: 1}}}
+	c := newTestClient(t, server.Listener.Addr(), config, "drain", withoutPolicies("drain"))
+	successes := testMetrics.WithWorkload("drain", "drain", "drain").ClientReqSuccesses
+	before := testMetrics.Value(successes)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go c.Start(&wg)
+	assert.Eventually(t, func() bool { return received.Load() > 5 }, time.Second, 10*time.Millisecond)
+
+	// Requests that are inflight when the workloads are stopped complete before the client is done
+	c.Stop()
+	<-c.Done()
+	assert.Equal(t, float64(received.Load()), testMetrics.Value(successes)-before)
+}
+
+func TestWorkloadSnapshots(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	config := &Config{Workloads: []*Workload{{Name: "snapshot", RPS: 1, ServiceTimes: WeightedServiceTimes{{Weight: 1}}}}}
+	c := newTestClient(t, server.Listener.Addr(), config, "snapshot", withoutPolicies("snapshot"))
+	require.NoError(t, c.UpdateWorkloads(config.Workloads))
+	before := c.Workloads()
+
+	// Updates replace the snapshot rather than modifying the workloads that were shared with it
+	assert.True(t, c.SetWorkloadRPS("snapshot", 5))
+	assert.Equal(t, uint(1), before[0].RPS)
+	assert.Equal(t, uint(1), config.Workloads[0].RPS)
+	assert.Equal(t, uint(5), c.Workloads()[0].RPS)
+	require.NoError(t, c.UpdateWorkloads(nil))
+}
+
+func TestUpdateWorkloadsRequiresRate(t *testing.T) {
+	c := newTestClient(t, nil, &Config{}, "rate", nil)
+
+	// An open-loop workload without an rps would have no interval between requests
+	err := c.UpdateWorkloads([]*Workload{{Name: "rate", ServiceTimes: WeightedServiceTimes{{Weight: 1}}}})
+	assert.ErrorContains(t, err, "rps must be positive")
+	assert.Empty(t, c.Workloads())
+}
+
+func TestUpdateWorkloadsAfterStop(t *testing.T) {
+	c := newTestClient(t, nil, &Config{}, "stopped", nil)
+	c.Stop()
+
+	// Workloads aren't started once the client has stopped
+	err := c.UpdateWorkloads([]*Workload{{Name: "stopped", RPS: 10, ServiceTimes: WeightedServiceTimes{{Weight: 1}}}})
+	assert.ErrorIs(t, err, ErrStopped)
+	assert.Empty(t, c.runners)
+}
+
+func TestPauseWorkload(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer server.Close()
+	c := newTestClient(t, server.Listener.Addr(), &Config{}, "pause", withoutPolicies("pause"))
+	require.NoError(t, c.UpdateWorkloads([]*Workload{{Name: "pause", RPS: 200, ServiceTimes: WeightedServiceTimes{{Weight: 1}}}}))
+	assert.Eventually(t, func() bool { return requests.Load() > 0 }, time.Second, 10*time.Millisecond)
+
+	// A paused workload stops sending requests until it's resumed
+	assert.True(t, c.PauseWorkload("pause"))
+	assert.True(t, c.Workloads()[0].Paused)
+	time.Sleep(50 * time.Millisecond)
+	paused := requests.Load()
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, paused, requests.Load())
+
+	assert.True(t, c.ResumeWorkload("pause"))
+	assert.False(t, c.Workloads()[0].Paused)
+	assert.Eventually(t, func() bool { return requests.Load() > paused }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, uint(200), c.Workloads()[0].RPS)
+
+	assert.False(t, c.PauseWorkload("unknown"))
+	require.NoError(t, c.UpdateWorkloads(nil))
+}
+
+func TestPausedTimeExcludedFromStages(t *testing.T) {
+	runner := &workloadRunner{updates: make(chan *Workload, 1)}
+	state := &workloadState{start: time.Now().Add(-time.Second)}
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		runner.updates <- &Workload{Name: "paused"}
+	}()
+
+	// Stages resume where they were paused rather than counting the time spent paused
+	resumed := (&Client{}).runPaused(context.Background(), runner, &Workload{Name: "paused", Paused: true}, state,
+		testMetrics.WithWorkload("paused", "paused", "paused"), zap.NewNop().Sugar())
+	assert.False(t, resumed.Paused)
+	assert.InDelta(t, time.Second, state.elapsed(), float64(50*time.Millisecond))
+}
+
+func TestWorkloadStages(t *testing.T) {
+	spike := WeightedServiceTimes{{ServiceTime: 100 * time.Millisecond, Weight: 1}}
+	workload := &Workload{Name: "tenant", RPS: 50, ServiceTimes: WeightedServiceTimes{{ServiceTime: 10 * time.Millisecond, Weight: 1}}, Stages: []*Stage{
+		{Duration: 10 * time.Second},
+		{Duration: 10 * time.Second, RPSEnd: 250, ServiceTimes: spike},
+		{Duration: 10 * time.Second},
+	}}
+	require.NoError(t, workload.Normalize())
+
+	// Stages carry over the RPS and service times of the previous stage, or the workload
+	assert.Equal(t, uint(50), workload.rpsAt(5*time.Second))
+	assert.Equal(t, uint(150), workload.rpsAt(15*time.Second))
+	assert.Equal(t, uint(250), workload.rpsAt(25*time.Second))
+	serviceTimes, weightSum := workload.serviceTimesAt(25 * time.Second)
+	assert.Equal(t, spike, serviceTimes)
+	assert.Equal(t, 1, weightSum)
+
+	// Once the stages end, the workload returns to its own RPS and service times
+	assert.Equal(t, uint(50), workload.rpsAt(30*time.Second))
+	serviceTimes, _ = workload.serviceTimesAt(30 * time.Second)
+	assert.Equal(t, workload.ServiceTimes, serviceTimes)
+	assert.Equal(t, 1, workload.stageIndexAt(15*time.Second))
+	assert.Equal(t, -1, workload.stageIndexAt(30*time.Second))
+
+	assert.Error(t, (&Workload{Name: "closed", Concurrency: 1, Stages: []*Stage{{Duration: time.Second, RPS: 10}}}).Normalize())
+	assert.Error(t, (&Workload{Name: "open", RPS: 1, Stages: []*Stage{{Duration: time.Second, ConcurrencyEnd: 10}}}).Normalize())
+	assert.Error(t, (&Workload{Name: "unbounded", RPS: 1, Stages: []*Stage{{RPS: 10}}}).Normalize())
+}
+
+func TestClosedLoopStages(t *testing.T) {
+	workload := &Workload{Name: "users", Concurrency: 10, ServiceTimes: WeightedServiceTimes{{Weight: 1}}, Stages: []*Stage{
+		{Duration: 10 * time.Second, ConcurrencyEnd: 500},
+		{Duration: 10 * time.Second},
+		{Duration: 10 * time.Second, ConcurrencyEnd: 1000, Ramp: RampExponential},
+	}}
+	require.NoError(t, workload.Normalize())
+
+	// Users ramp from the previous stage's concurrency, or the workload's, and are carried over
+	assert.Equal(t, uint(10), workload.concurrencyAt(0))
+	assert.Equal(t, uint(255), workload.concurrencyAt(5*time.Second))
+	assert.Equal(t, uint(500), workload.concurrencyAt(15*time.Second))
+	assert.Equal(t, uint(707), workload.concurrencyAt(25*time.Second))
+	assert.Equal(t, uint(10), workload.concurrencyAt(30*time.Second))
+}
+
+func TestClosedLoopRamp(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+	}))
+	defer server.Close()
+	c := newTestClient(t, server.Listener.Addr(), &Config{}, "ramp", withoutPolicies("ramp"))
+
+	workload := &Workload{Name: "ramp", Concurrency: 1, ServiceTimes: WeightedServiceTimes{{Weight: 1}}, Stages: []*Stage{
+		{Duration: 300 * time.Millisecond, ConcurrencyEnd: 20},
+		{Duration: time.Hour},
+	}}
+	require.NoError(t, workload.Normalize())
+	require.NoError(t, c.UpdateWorkloads([]*Workload{workload}))
+	users := func() float64 {
+		return testMetrics.Value(testMetrics.WithWorkload("ramp", "ramp", "ramp").ClientUsers)
+	}
+
+	// Users are added as the stage ramps, then held at the concurrency it ramped to
+	assert.Eventually(t, func() bool { return users() == 20 }, time.Second, 10*time.Millisecond)
+	time.Sleep(2 * userRampInterval)
+	assert.Equal(t, 20.0, users())
+
+	require.NoError(t, c.UpdateWorkloads(nil))
+	assert.Eventually(t, func() bool { return users() == 0 }, time.Second, 10*time.Millisecond)
+}
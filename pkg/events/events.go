@@ -0,0 +1,69 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Type is the type of an orchestration event.
+type Type string
+
+const (
+	StrategyStarted Type = "strategy_started"
+	StrategyStopped Type = "strategy_stopped"
+	StrategyAborted Type = "strategy_aborted" // a strategy exceeded its expected duration and was abandoned
+	StageStarted    Type = "stage_started"
+	ConfigUpdated   Type = "config_updated"
+	Fault           Type = "fault"
+	Burst           Type = "burst" // a spike of load was injected
+	StopCondition   Type = "stop_condition"
+	SelfProtection  Type = "self_protection" // the host that tripwire runs on became saturated, or recovered
+)
+
+// Event describes something that happened while orchestrating a run, which can be correlated with metrics afterwards.
+type Event struct {
+	Time       time.Time      `json:"time"`
+	Elapsed    float64        `json:"elapsed"` // seconds since the log was created, measured with a monotonic clock
+	Type       Type           `json:"type"`
+	RunID      string         `json:"run_id,omitempty"`
+	Strategy   string         `json:"strategy,omitempty"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+// Writer writes events to a destination, such as a run's output sinks.
+type Writer interface {
+	WriteEvent(event *Event) error
+}
+
+// Log records events to a Writer. A nil Log discards events.
+type Log struct {
+	start time.Time
+
+	mtx    sync.Mutex
+	writer Writer // Guarded by mtx
+}
+
+func New(writer Writer) *Log {
+	return &Log{
+		start:  time.Now(),
+		writer: writer,
+	}
+}
+
+// Record writes an event. The runID and strategy may be empty for events that apply to the whole run.
+func (l *Log) Record(eventType Type, runID string, strategy string, attributes map[string]any) {
+	if l == nil {
+		return
+	}
+	now := time.Now()
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	_ = l.writer.WriteEvent(&Event{
+		Time:       now,
+		Elapsed:    now.Sub(l.start).Seconds(),
+		Type:       eventType,
+		RunID:      runID,
+		Strategy:   strategy,
+		Attributes: attributes,
+	})
+}
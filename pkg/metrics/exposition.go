@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/expfmt"
+)
+
+// Config configures how metrics are exposed to scrapers.
+type Config struct {
+	// OpenMetrics serves the OpenMetrics format, with _created timestamps for counters and histograms, to scrapers that
+	// accept it.
+	OpenMetrics bool `yaml:"open_metrics"`
+
+	// ClassicBuckets are the upper bounds of classic buckets to expose alongside native histogram buckets, for scrapers
+	// that don't support native histograms. Native histograms only have a +Inf classic bucket by default.
+	ClassicBuckets []float64 `yaml:"classic_buckets"`
+}
+
+func (c *Config) Validate() error {
+	if !sort.Float64sAreSorted(c.ClassicBuckets) {
+		return fmt.Errorf("metrics classic_buckets must be in increasing order")
+	}
+	return nil
+}
+
+// nativeHistogramOpts returns the options for a native histogram, along with any configured classic buckets.
+func (c *Config) nativeHistogramOpts(name string) prometheus.HistogramOpts {
+	return prometheus.HistogramOpts{
+		Name:                            name,
+		Buckets:                         c.classicBuckets(),
+		NativeHistogramBucketFactor:     1.1,
+		NativeHistogramMaxBucketNumber:  100,
+		NativeHistogramMinResetDuration: 1 * time.Hour,
+	}
+}
+
+func (c *Config) classicBuckets() []float64 {
+	if c == nil {
+		return nil
+	}
+	return c.ClassicBuckets
+}
+
+// handler returns the handler that serves metrics from the gatherer, in the OpenMetrics format with _created timestamps
+// when the config enables it and the scraper accepts it, else in the format that promhttp negotiates.
+func (c *Config) handler(gatherer prometheus.Gatherer) http.Handler {
+	defaultHandler := promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{})
+	if c == nil || !c.OpenMetrics {
+		return defaultHandler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		format := expfmt.NegotiateIncludingOpenMetrics(r.Header)
+		if format.FormatType() != expfmt.TypeOpenMetrics {
+			defaultHandler.ServeHTTP(w, r)
+			return
+		}
+		families, err := gatherer.Gather()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", string(format))
+		encoder := expfmt.NewEncoder(w, format, expfmt.WithCreatedLines())
+		for _, family := range families {
+			if err = encoder.Encode(family); err != nil {
+				return
+			}
+		}
+		if closer, ok := encoder.(expfmt.Closer); ok {
+			_ = closer.Close()
+		}
+	})
+}
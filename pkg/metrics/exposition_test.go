@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenMetricsExposition(t *testing.T) {
+	config := &Config{OpenMetrics: true, ClassicBuckets: []float64{.1, 1}}
+	require.NoError(t, config.Validate())
+	registry := prometheus.NewRegistry()
+	histogram := prometheus.NewHistogram(config.nativeHistogramOpts("test_response_times"))
+	registry.MustRegister(histogram)
+	histogram.Observe(.05)
+
+	scrape := func(accept string) string {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		config.handler(registry).ServeHTTP(rec, req)
+		body, err := io.ReadAll(rec.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	openMetrics := scrape("application/openmetrics-text; version=1.0.0")
+	assert.Contains(t, openMetrics, "test_response_times_created")
+	assert.Contains(t, openMetrics, `test_response_times_bucket{le="0.1"} 1`)
+	assert.Contains(t, openMetrics, "# EOF")
+
+	text := scrape("text/plain")
+	assert.NotContains(t, text, "_created")
+	assert.Contains(t, text, `test_response_times_bucket{le="1"} 1`)
+
+	assert.Error(t, (&Config{ClassicBuckets: []float64{1, .1}}).Validate())
+}
@@ -0,0 +1,168 @@
+package metrics
+
+import (
+	"math"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	histogramMin    = 100 * time.Microsecond
+	histogramFactor = 1.05
+	histogramSize   = 300 // covers up to ~2.3 hours with the min and factor
+)
+
+// Histogram records durations in exponentially sized buckets, allowing quantiles to be estimated within 5% using fixed
+// memory. Unlike Prometheus histograms, it can be read back to produce run results.
+type Histogram struct {
+	mtx     sync.Mutex
+	buckets [histogramSize]uint64
+	count   uint64
+	sum     time.Duration
+	max     time.Duration
+}
+
+func NewHistogram() *Histogram {
+	return &Histogram{}
+}
+
+func (h *Histogram) Record(d time.Duration) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.buckets[bucketFor(d)]++
+	h.count++
+	h.sum += d
+	h.max = max(h.max, d)
+}
+
+// Merge adds the recordings of other to h.
+func (h *Histogram) Merge(other *Histogram) {
+	other.mtx.Lock()
+	buckets, count, sum, maxValue := other.buckets, other.count, other.sum, other.max
+	other.mtx.Unlock()
+
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	for i, c := range buckets {
+		h.buckets[i] += c
+	}
+	h.count += count
+	h.sum += sum
+	h.max = max(h.max, maxValue)
+}
+
+func (h *Histogram) Count() uint64 {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	return h.count
+}
+
+func (h *Histogram) Mean() time.Duration {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	if h.count == 0 {
+		return 0
+	}
+	return h.sum / time.Duration(h.count)
+}
+
+func (h *Histogram) Max() time.Duration {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	return h.max
+}
+
+// Quantile returns the estimated duration at the quantile q, which should be between 0 and 1.
+func (h *Histogram) Quantile(q float64) time.Duration {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	if h.count == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(h.count)))
+	var seen uint64
+	for i, c := range h.buckets {
+		seen += c
+		if seen >= max(rank, 1) {
+			return min(bucketUpperBound(i), h.max)
+		}
+	}
+	return h.max
+}
+
+func bucketFor(d time.Duration) int {
+	if d <= histogramMin {
+		return 0
+	}
+	bucket := int(math.Ceil(math.Log(float64(d)/float64(histogramMin)) / math.Log(histogramFactor)))
+	return min(bucket, histogramSize-1)
+}
+
+func bucketUpperBound(bucket int) time.Duration {
+	return time.Duration(float64(histogramMin) * math.Pow(histogramFactor, float64(bucket)))
+}
+
+// HistogramPeriod is the recordings of a RotatingHistogram during a period, such as a stage or an hour.
+type HistogramPeriod struct {
+	*Histogram
+	Name  string    // the stage that the period covers, if any
+	Start time.Time // when the period started
+	End   time.Time // when the period was rotated, which is zero for the current period
+}
+
+// RotatingHistogram records durations into the Histogram of its current period, which is retained when it's rotated into
+// a new period, so that each period's quantiles are available at full resolution, and merged with the others to produce
+// the quantiles of every period. Since histograms use fixed memory, memory is bounded by the number of periods rather
+// than the number of recordings, even for very long runs.
+type RotatingHistogram struct {
+	current atomic.Pointer[HistogramPeriod] // Replaced when the histogram is rotated
+	mtx     sync.Mutex                      // Serializes rotations
+	periods []*HistogramPeriod              // Past periods, guarded by mtx
+}
+
+func NewRotatingHistogram(start time.Time) *RotatingHistogram {
+	h := &RotatingHistogram{}
+	h.current.Store(&HistogramPeriod{Histogram: NewHistogram(), Start: start})
+	return h
+}
+
+func (h *RotatingHistogram) Record(d time.Duration) {
+	h.current.Load().Record(d)
+}
+
+// Rotate starts a new period at now, with the same name as the current period.
+func (h *RotatingHistogram) Rotate(now time.Time) {
+	h.RotateNamed(h.current.Load().Name, now)
+}
+
+// RotateNamed starts a new period with the name at now. The current period is retained if it has any recordings.
+func (h *RotatingHistogram) RotateNamed(name string, now time.Time) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	current := h.current.Swap(&HistogramPeriod{Histogram: NewHistogram(), Name: name, Start: now})
+	if current.Count() > 0 {
+		h.periods = append(h.periods, &HistogramPeriod{Histogram: current.Histogram, Name: current.Name, Start: current.Start, End: now})
+	}
+}
+
+// Periods returns the past periods, along with the current period, if it has any recordings, as if it ended at end.
+func (h *RotatingHistogram) Periods(end time.Time) []*HistogramPeriod {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	periods := slices.Clone(h.periods)
+	if current := h.current.Load(); current.Count() > 0 {
+		periods = append(periods, &HistogramPeriod{Histogram: current.Histogram, Name: current.Name, Start: current.Start, End: end})
+	}
+	return periods
+}
+
+// Merged returns a histogram of the recordings of every period.
+func (h *RotatingHistogram) Merged() *Histogram {
+	merged := NewHistogram()
+	for _, period := range h.Periods(time.Time{}) {
+		merged.Merge(period.Histogram)
+	}
+	return merged
+}
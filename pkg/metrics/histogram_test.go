@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistogram(t *testing.T) {
+	h := NewHistogram()
+	for i := 1; i <= 100; i++ {
+		h.Record(time.Duration(i) * time.Millisecond)
+	}
+
+	assert.Equal(t, uint64(100), h.Count())
+	assert.Equal(t, 50500*time.Microsecond, h.Mean())
+	assert.Equal(t, 100*time.Millisecond, h.Max())
+	assert.InEpsilon(t, float64(50*time.Millisecond), float64(h.Quantile(.5)), .05)
+	assert.InEpsilon(t, float64(99*time.Millisecond), float64(h.Quantile(.99)), .05)
+
+	other := NewHistogram()
+	other.Record(time.Second)
+	h.Merge(other)
+	assert.Equal(t, uint64(101), h.Count())
+	assert.Equal(t, time.Second, h.Max())
+}
+
+func TestRotatingHistogram(t *testing.T) {
+	start := time.Unix(0, 0)
+	h := NewRotatingHistogram(start)
+
+	// Empty periods aren't retained
+	h.RotateNamed("stage 0", start)
+	h.Record(10 * time.Millisecond)
+	h.Record(20 * time.Millisecond)
+	h.Rotate(start.Add(time.Hour))
+	h.Record(time.Second)
+	h.RotateNamed("stage 1", start.Add(2*time.Hour))
+	h.Record(time.Millisecond)
+
+	periods := h.Periods(start.Add(3 * time.Hour))
+	assert.Len(t, periods, 3)
+	assert.Equal(t, "stage 0", periods[0].Name)
+	assert.Equal(t, start.Add(time.Hour), periods[0].End)
+	assert.Equal(t, uint64(2), periods[0].Count())
+	assert.Equal(t, "stage 0", periods[1].Name)
+	assert.Equal(t, time.Second, periods[1].Max())
+	assert.Equal(t, "stage 1", periods[2].Name)
+	assert.Equal(t, start.Add(3*time.Hour), periods[2].End)
+
+	// Periods are merged to produce the quantiles of every period
+	merged := h.Merged()
+	assert.Equal(t, uint64(4), merged.Count())
+	assert.Equal(t, time.Second, merged.Max())
+}
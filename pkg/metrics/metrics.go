@@ -2,11 +2,13 @@ package metrics
 
 import (
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
 	"go.uber.org/zap"
 
 	"tripwire/pkg/util"
@@ -15,44 +17,171 @@ import (
 type Metrics struct {
 	*util.Server
 
+	histogramsMtx sync.Mutex
+	histograms    map[string]*RotatingHistogram // Response time histograms by run ID and workload, guarded by histogramsMtx
+
 	// Run metrics for things that must be distinguishable in the scenario result table
-	ClientReqTotal         *prometheus.CounterVec
-	ClientReqSuccesses     *prometheus.CounterVec
-	ClientReqRejected      *prometheus.CounterVec
-	ClientReqResponseTimes *prometheus.HistogramVec
-	RunDuration            *prometheus.GaugeVec
+	ClientReqTotal          *prometheus.CounterVec
+	ClientReqSuccesses      *prometheus.CounterVec
+	ClientReqRejected       *prometheus.CounterVec
+	ClientReqResponseTimes  *prometheus.HistogramVec
+	ClientReqCorrectedTimes *prometheus.HistogramVec
+	ClientDroppedArrivals   *prometheus.CounterVec
+	ClientBackedOffArrivals *prometheus.CounterVec
+	ClientReqSlow           *prometheus.CounterVec
+	ClientReqDuplicates     *prometheus.CounterVec
+	ClientReqOutOfOrder     *prometheus.CounterVec
+	ClientReqShed           *prometheus.CounterVec
+	SLOBurnRate             *prometheus.GaugeVec
+	ClientReqErrors         *prometheus.CounterVec
+	ClientReqPriorities     *prometheus.CounterVec
+	RunDuration             *prometheus.GaugeVec
+	RunTimeToFirstRejection *prometheus.GaugeVec
+	RunRecoveryTime         *prometheus.GaugeVec
+	RunInfo                 *prometheus.GaugeVec
+	RunParameter            *prometheus.GaugeVec
+	WorkloadInfo            *prometheus.GaugeVec
+
+	// Result metrics, which are each workload's final results, labeled by scenario rather than run_id so that they're
+	// stable across runs
+	ResultGoodput        *prometheus.GaugeVec
+	ResultLatencyP99     *prometheus.GaugeVec
+	ResultRejectionShare *prometheus.GaugeVec
+
+	// Reaction metrics
+	ReactionLimitSettleTime     *prometheus.GaugeVec
+	ReactionRejectionSettleTime *prometheus.GaugeVec
+	ReactionExcessQueueing      *prometheus.GaugeVec
 
 	// Client metrics
 	ClientReqFailures      *prometheus.CounterVec
 	ClientExpectedRps      *prometheus.GaugeVec
 	ClientReqTimeouts      *prometheus.CounterVec
 	ClientInflightRequests *prometheus.GaugeVec
+	ClientArrivalLateness  *prometheus.HistogramVec
+	ClientRegionFailovers  *prometheus.CounterVec
+	ClientHostSaturation   *prometheus.GaugeVec
+	ClientHostBorrowed     *prometheus.GaugeVec
+	ClientHostRejected     *prometheus.CounterVec
+	ClientUsers            *prometheus.GaugeVec
+	ClientStageSLOReqs     *prometheus.CounterVec
 
 	// Server metrics
 	ServerThreads          prometheus.Gauge
+	ServerDesiredThreads   prometheus.Gauge
 	ServerServiceTime      *prometheus.GaugeVec
 	ServerInflightRequests *prometheus.GaugeVec
+	ServerAsyncQueued      *prometheus.GaugeVec
+	ServerDeduplicated     *prometheus.CounterVec
+	ServerCancelled        *prometheus.CounterVec
+	ServerCancelledWork    *prometheus.HistogramVec
+	ServerWastedWork       *prometheus.CounterVec
+	ServerStreamFailures   *prometheus.CounterVec
+	ServerConnectionFaults *prometheus.CounterVec
+	ServerClientRejections *prometheus.CounterVec
+	ServerEndpointInflight *prometheus.GaugeVec
+	ServerEndpointRejected *prometheus.CounterVec
+	ServerAsyncShed        *prometheus.CounterVec
+	ServerDeliveryFaults   *prometheus.CounterVec
 
 	// Policy metrics
-	MinTimeout          *prometheus.GaugeVec
-	RateLimit           *prometheus.GaugeVec
-	ConcurrencyLimit    *prometheus.GaugeVec
-	CircuitbreakerOpen  *prometheus.GaugeVec
-	ThrottleProbability *prometheus.GaugeVec
-	QueuedRequests      *prometheus.GaugeVec
+	MinTimeout           *prometheus.GaugeVec
+	RateLimit            *prometheus.GaugeVec
+	ConcurrencyLimit     *prometheus.GaugeVec
+	CircuitbreakerOpen   *prometheus.GaugeVec
+	CircuitBreakerState  *prometheus.GaugeVec
+	CircuitBreakerProbes *prometheus.CounterVec
+	Retries              *prometheus.CounterVec
+	RetryBudgetUsed      *prometheus.GaugeVec
+	ThrottleProbability  *prometheus.GaugeVec
+	QueuedRequests       *prometheus.GaugeVec
+	BulkheadWaiters      *prometheus.GaugeVec
+	BulkheadWaitTimes    *prometheus.HistogramVec
+	ClientQueueSize      *prometheus.GaugeVec
+	ClientQueueWaitTimes *prometheus.HistogramVec
+	ClientQueueShed      *prometheus.CounterVec
+	ClientQueueBoosted   *prometheus.CounterVec
+	RateLimiterWaiters   *prometheus.GaugeVec
+	RateLimiterWaitTimes *prometheus.HistogramVec
+	SharedRateLimit      *prometheus.GaugeVec
+	SharedRatePermits    *prometheus.CounterVec
+	SharedRateRejected   *prometheus.CounterVec
+	PolicyConfig         *prometheus.GaugeVec
+
+	// Adaptive limiter metrics
+	AdaptiveLimiterRecentLatency         *prometheus.GaugeVec
+	AdaptiveLimiterBaselineLatency       *prometheus.GaugeVec
+	AdaptiveLimiterLatencyCorrelation    *prometheus.GaugeVec
+	AdaptiveLimiterThroughputCorrelation *prometheus.GaugeVec
+
+	// Prioritizer metrics
+	PrioritizerRejectionRate      *prometheus.GaugeVec
+	PrioritizerRejectionThreshold *prometheus.GaugeVec
+	PrioritizerRegisteredPolicies *prometheus.GaugeVec
+	PrioritizerAdmissionRate      *prometheus.GaugeVec
 }
 
-func New(logger *zap.SugaredLogger) *Metrics {
+// New creates the metrics, which are exposed according to the config, if any.
+func New(config *Config, logger *zap.SugaredLogger) *Metrics {
 	mux := http.NewServeMux()
-	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/metrics", config.handler(prometheus.DefaultGatherer))
 	return &Metrics{
-		Server: util.NewServer(mux, 8080, logger),
+		Server:     util.NewServer(mux, ":8080", logger),
+		histograms: make(map[string]*RotatingHistogram),
 
 		// Run metrics
 		RunDuration: promauto.NewGaugeVec(
 			prometheus.GaugeOpts{Name: "run_duration"},
 			[]string{"run_id", "strategy"},
 		),
+		RunTimeToFirstRejection: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "run_time_to_first_rejection"},
+			[]string{"run_id", "strategy"},
+		),
+		RunRecoveryTime: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "run_recovery_time"},
+			[]string{"run_id", "strategy"},
+		),
+		RunInfo: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "run_info"},
+			[]string{"run_id", "strategy", "tags"},
+		),
+		RunParameter: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "run_parameter"},
+			[]string{"run_id", "strategy", "parameter", "value"},
+		),
+		WorkloadInfo: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "workload_info"},
+			[]string{"run_id", "workload", "strategy", "tags"},
+		),
+
+		// Result metrics
+		ResultGoodput: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "result_goodput"},
+			[]string{"scenario", "strategy", "workload"},
+		),
+		ResultLatencyP99: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "result_latency_p99"},
+			[]string{"scenario", "strategy", "workload"},
+		),
+		ResultRejectionShare: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "result_rejection_share"},
+			[]string{"scenario", "strategy", "workload"},
+		),
+
+		// Reaction metrics
+		ReactionLimitSettleTime: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "reaction_limit_settle_time"},
+			[]string{"run_id", "strategy"},
+		),
+		ReactionRejectionSettleTime: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "reaction_rejection_settle_time"},
+			[]string{"run_id", "strategy"},
+		),
+		ReactionExcessQueueing: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "reaction_excess_queueing"},
+			[]string{"run_id", "strategy"},
+		),
 
 		// Client metrics
 		ClientReqTotal: promauto.NewCounterVec(
@@ -68,14 +197,49 @@ func New(logger *zap.SugaredLogger) *Metrics {
 			[]string{"run_id", "workload", "strategy"},
 		),
 		ClientReqResponseTimes: promauto.NewHistogramVec(
-			prometheus.HistogramOpts{
-				Name:                            "client_req_response_times",
-				NativeHistogramBucketFactor:     1.1,
-				NativeHistogramMaxBucketNumber:  100,
-				NativeHistogramMinResetDuration: 1 * time.Hour,
-			},
+			config.nativeHistogramOpts("client_req_response_times"),
+			[]string{"run_id", "workload", "strategy"},
+		),
+		ClientReqCorrectedTimes: promauto.NewHistogramVec(
+			config.nativeHistogramOpts("client_req_corrected_response_times"),
+			[]string{"run_id", "workload", "strategy"},
+		),
+		ClientDroppedArrivals: promauto.NewCounterVec(
+			prometheus.CounterOpts{Name: "client_dropped_arrivals"},
+			[]string{"run_id", "workload", "strategy"},
+		),
+		ClientBackedOffArrivals: promauto.NewCounterVec(
+			prometheus.CounterOpts{Name: "client_backed_off_arrivals"},
+			[]string{"run_id", "workload", "strategy"},
+		),
+		ClientReqSlow: promauto.NewCounterVec(
+			prometheus.CounterOpts{Name: "client_req_slow"},
+			[]string{"run_id", "workload", "strategy"},
+		),
+		ClientReqDuplicates: promauto.NewCounterVec(
+			prometheus.CounterOpts{Name: "client_req_duplicates"},
+			[]string{"run_id", "workload", "strategy"},
+		),
+		ClientReqShed: promauto.NewCounterVec(
+			prometheus.CounterOpts{Name: "client_req_shed"},
+			[]string{"run_id", "workload", "strategy"},
+		),
+		ClientReqOutOfOrder: promauto.NewCounterVec(
+			prometheus.CounterOpts{Name: "client_req_out_of_order"},
 			[]string{"run_id", "workload", "strategy"},
 		),
+		SLOBurnRate: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "slo_burn_rate"},
+			[]string{"run_id", "workload", "strategy"},
+		),
+		ClientReqErrors: promauto.NewCounterVec(
+			prometheus.CounterOpts{Name: "client_req_errors"},
+			[]string{"run_id", "workload", "strategy", "tier", "outcome"},
+		),
+		ClientReqPriorities: promauto.NewCounterVec(
+			prometheus.CounterOpts{Name: "client_req_priorities"},
+			[]string{"run_id", "workload", "strategy", "priority", "outcome"},
+		),
 		ClientReqFailures: promauto.NewCounterVec(
 			prometheus.CounterOpts{Name: "client_req_failures"},
 			[]string{"workload", "strategy"},
@@ -92,6 +256,37 @@ func New(logger *zap.SugaredLogger) *Metrics {
 			prometheus.GaugeOpts{Name: "client_inflight_requests"},
 			[]string{"workload", "strategy"},
 		),
+		ClientArrivalLateness: promauto.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "client_arrival_lateness",
+				Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+			},
+			[]string{"workload", "strategy"},
+		),
+		ClientRegionFailovers: promauto.NewCounterVec(
+			prometheus.CounterOpts{Name: "client_region_failovers"},
+			[]string{"workload", "strategy", "region"},
+		),
+		ClientHostSaturation: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "client_host_saturation"},
+			[]string{"strategy", "host"},
+		),
+		ClientHostBorrowed: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "client_host_borrowed"},
+			[]string{"strategy", "host"},
+		),
+		ClientHostRejected: promauto.NewCounterVec(
+			prometheus.CounterOpts{Name: "client_host_rejected"},
+			[]string{"strategy", "host"},
+		),
+		ClientUsers: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "client_users"},
+			[]string{"workload", "strategy"},
+		),
+		ClientStageSLOReqs: promauto.NewCounterVec(
+			prometheus.CounterOpts{Name: "client_stage_slo_requests"},
+			[]string{"run_id", "strategy", "stage", "slo", "outcome"},
+		),
 		QueuedRequests: promauto.NewGaugeVec(
 			prometheus.GaugeOpts{Name: "queued_requests"},
 			[]string{"workload", "strategy"},
@@ -104,11 +299,74 @@ func New(logger *zap.SugaredLogger) *Metrics {
 			prometheus.GaugeOpts{Name: "throttle_probability"},
 			[]string{"workload", "strategy"},
 		),
+		CircuitBreakerState: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "circuitbreaker_state"},
+			[]string{"workload", "strategy"},
+		),
+		CircuitBreakerProbes: promauto.NewCounterVec(
+			prometheus.CounterOpts{Name: "circuitbreaker_probes"},
+			[]string{"workload", "strategy", "outcome"},
+		),
+		Retries: promauto.NewCounterVec(
+			prometheus.CounterOpts{Name: "retries"},
+			[]string{"workload", "strategy", "event"},
+		),
+		RetryBudgetUsed: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "retry_budget_used"},
+			[]string{"workload", "strategy"},
+		),
+		BulkheadWaiters: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "bulkhead_waiters"},
+			[]string{"workload", "strategy"},
+		),
+		BulkheadWaitTimes: promauto.NewHistogramVec(
+			config.nativeHistogramOpts("bulkhead_wait_times"),
+			[]string{"workload", "strategy"},
+		),
+		ClientQueueSize: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "client_queue_size"},
+			[]string{"workload", "strategy"},
+		),
+		ClientQueueWaitTimes: promauto.NewHistogramVec(
+			config.nativeHistogramOpts("client_queue_wait_times"),
+			[]string{"workload", "strategy"},
+		),
+		ClientQueueShed: promauto.NewCounterVec(
+			prometheus.CounterOpts{Name: "client_queue_shed"},
+			[]string{"workload", "strategy", "reason"},
+		),
+		ClientQueueBoosted: promauto.NewCounterVec(
+			prometheus.CounterOpts{Name: "client_queue_boosted"},
+			[]string{"workload", "strategy"},
+		),
+		RateLimiterWaiters: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "ratelimiter_waiters"},
+			[]string{"workload", "strategy"},
+		),
+		RateLimiterWaitTimes: promauto.NewHistogramVec(
+			config.nativeHistogramOpts("ratelimiter_wait_times"),
+			[]string{"workload", "strategy"},
+		),
+		SharedRateLimit: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "shared_rate_limit"},
+			[]string{"ratelimiter"},
+		),
+		SharedRatePermits: promauto.NewCounterVec(
+			prometheus.CounterOpts{Name: "shared_ratelimiter_permits"},
+			[]string{"ratelimiter", "strategy"},
+		),
+		SharedRateRejected: promauto.NewCounterVec(
+			prometheus.CounterOpts{Name: "shared_ratelimiter_rejected"},
+			[]string{"ratelimiter", "strategy"},
+		),
 
 		// Server metrics
 		ServerThreads: promauto.NewGauge(
 			prometheus.GaugeOpts{Name: "server_threads"},
 		),
+		ServerDesiredThreads: promauto.NewGauge(
+			prometheus.GaugeOpts{Name: "server_desired_threads"},
+		),
 		ServerServiceTime: promauto.NewGaugeVec(
 			prometheus.GaugeOpts{Name: "server_service_time"},
 			[]string{"strategy"},
@@ -117,6 +375,57 @@ func New(logger *zap.SugaredLogger) *Metrics {
 			prometheus.GaugeOpts{Name: "server_inflight_requests"},
 			[]string{"workload", "strategy"},
 		),
+		ServerDeduplicated: promauto.NewCounterVec(
+			prometheus.CounterOpts{Name: "server_deduplicated_requests"},
+			[]string{"workload", "strategy"},
+		),
+		ServerCancelled: promauto.NewCounterVec(
+			prometheus.CounterOpts{Name: "server_cancelled_requests"},
+			[]string{"workload", "strategy", "cause"},
+		),
+		ServerCancelledWork: promauto.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "server_cancelled_work_fraction",
+				Buckets: []float64{.1, .2, .3, .4, .5, .6, .7, .8, .9, 1},
+			},
+			[]string{"workload", "strategy"},
+		),
+		ServerWastedWork: promauto.NewCounterVec(
+			prometheus.CounterOpts{Name: "server_wasted_work_seconds"},
+			[]string{"workload", "strategy", "tier"},
+		),
+		ServerStreamFailures: promauto.NewCounterVec(
+			prometheus.CounterOpts{Name: "server_stream_failures"},
+			[]string{"workload", "strategy", "cause"},
+		),
+		ServerConnectionFaults: promauto.NewCounterVec(
+			prometheus.CounterOpts{Name: "server_connection_faults"},
+			[]string{"strategy", "fault"},
+		),
+		ServerDeliveryFaults: promauto.NewCounterVec(
+			prometheus.CounterOpts{Name: "server_delivery_faults"},
+			[]string{"workload", "strategy", "fault"},
+		),
+		ServerClientRejections: promauto.NewCounterVec(
+			prometheus.CounterOpts{Name: "server_client_rejections"},
+			[]string{"workload", "strategy"},
+		),
+		ServerEndpointInflight: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "server_endpoint_inflight"},
+			[]string{"endpoint", "strategy"},
+		),
+		ServerEndpointRejected: promauto.NewCounterVec(
+			prometheus.CounterOpts{Name: "server_endpoint_rejected"},
+			[]string{"endpoint", "strategy"},
+		),
+		ServerAsyncQueued: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "server_async_queued"},
+			[]string{"strategy"},
+		),
+		ServerAsyncShed: promauto.NewCounterVec(
+			prometheus.CounterOpts{Name: "server_async_shed"},
+			[]string{"strategy"},
+		),
 
 		// Policy metrics
 		MinTimeout: promauto.NewGaugeVec(
@@ -127,6 +436,46 @@ func New(logger *zap.SugaredLogger) *Metrics {
 			prometheus.GaugeOpts{Name: "rate_limit"},
 			[]string{"strategy"},
 		),
+		PolicyConfig: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "policy_config"},
+			[]string{"strategy", "policy", "position", "parameter"},
+		),
+
+		// Adaptive limiter metrics
+		AdaptiveLimiterRecentLatency: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "adaptivelimiter_recent_latency"},
+			[]string{"workload", "strategy"},
+		),
+		AdaptiveLimiterBaselineLatency: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "adaptivelimiter_baseline_latency"},
+			[]string{"workload", "strategy"},
+		),
+		AdaptiveLimiterLatencyCorrelation: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "adaptivelimiter_latency_correlation"},
+			[]string{"workload", "strategy"},
+		),
+		AdaptiveLimiterThroughputCorrelation: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "adaptivelimiter_throughput_correlation"},
+			[]string{"workload", "strategy"},
+		),
+
+		// Prioritizer metrics
+		PrioritizerRejectionRate: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "prioritizer_rejection_rate"},
+			[]string{"strategy", "prioritizer"},
+		),
+		PrioritizerRejectionThreshold: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "prioritizer_rejection_threshold"},
+			[]string{"strategy", "prioritizer"},
+		),
+		PrioritizerRegisteredPolicies: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "prioritizer_registered_policies"},
+			[]string{"strategy", "prioritizer"},
+		),
+		PrioritizerAdmissionRate: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "prioritizer_admission_rate"},
+			[]string{"strategy", "prioritizer", "priority"},
+		),
 	}
 }
 
@@ -136,14 +485,31 @@ type WorkloadMetrics struct {
 	RunLabels prometheus.Labels
 
 	// Client metrics
-	ClientReqTotal         prometheus.Counter
-	ClientReqSuccesses     prometheus.Counter
-	ClientReqRejected      prometheus.Counter
-	ClientReqResponseTimes prometheus.Observer
-	ClientReqFailures      prometheus.Counter
-	ClientExpectedRps      prometheus.Gauge
-	ClientReqTimeouts      prometheus.Counter
-	ClientInflightRequests prometheus.Gauge
+	ClientReqTotal          prometheus.Counter
+	ClientReqSuccesses      prometheus.Counter
+	ClientReqRejected       prometheus.Counter
+	ClientReqResponseTimes  prometheus.Observer
+	ClientReqCorrectedTimes prometheus.Observer // Response times measured from when requests were scheduled to be sent
+	ResponseTimes           *RotatingHistogram  // Records the response times that run results are based on, by period
+	ClientReqFailures       prometheus.Counter
+	ClientExpectedRps       prometheus.Gauge
+	ClientReqTimeouts       prometheus.Counter
+	ClientInflightRequests  prometheus.Gauge
+	ClientDroppedArrivals   prometheus.Counter  // Arrivals that were skipped because the generator fell behind
+	ClientBackedOffArrivals prometheus.Counter  // Arrivals that were skipped because the host was saturated
+	ClientArrivalLateness   prometheus.Observer // How late requests were sent relative to their scheduled arrival
+	ClientUsers             prometheus.Gauge    // The virtual users of a closed-loop workload
+	ClientReqSlow           prometheus.Counter  // Successful requests that were slower than the workload's SLO latency
+	ClientReqDuplicates     prometheus.Counter  // Responses that were delivered more than once, which were discarded
+	ClientReqOutOfOrder     prometheus.Counter  // Successful responses that completed after a later request's response
+	ClientReqShed           prometheus.Counter  // Requests that a shed hook rejected before they were sent
+	SLOBurnRate             prometheus.Gauge    // How fast the workload's error budget is consumed, over its SLO window
+
+	// Failed requests by the tier that they originated from and their outcome
+	ClientReqErrors *prometheus.CounterVec
+
+	// Requests by their priority and outcome
+	ClientReqPriorities *prometheus.CounterVec
 }
 
 func (m *Metrics) WithWorkload(runID string, workload string, strategy string) *WorkloadMetrics {
@@ -156,15 +522,40 @@ func (m *Metrics) WithWorkload(runID string, workload string, strategy string) *
 		RunLabels: runLabels,
 
 		// Workload metrics
-		ClientReqTotal:         m.ClientReqTotal.With(runLabels),
-		ClientReqSuccesses:     m.ClientReqSuccesses.With(runLabels),
-		ClientReqRejected:      m.ClientReqRejected.With(runLabels),
-		ClientReqResponseTimes: m.ClientReqResponseTimes.With(runLabels),
-		ClientReqFailures:      m.ClientReqFailures.With(labels),
-		ClientExpectedRps:      m.ClientExpectedRps.With(labels),
-		ClientReqTimeouts:      m.ClientReqTimeouts.With(labels),
-		ClientInflightRequests: m.ClientInflightRequests.With(labels),
+		ClientReqTotal:          m.ClientReqTotal.With(runLabels),
+		ClientReqSuccesses:      m.ClientReqSuccesses.With(runLabels),
+		ClientReqRejected:       m.ClientReqRejected.With(runLabels),
+		ClientReqResponseTimes:  m.ClientReqResponseTimes.With(runLabels),
+		ClientReqCorrectedTimes: m.ClientReqCorrectedTimes.With(runLabels),
+		ResponseTimes:           m.histogram(runID, workload),
+		ClientReqFailures:       m.ClientReqFailures.With(labels),
+		ClientExpectedRps:       m.ClientExpectedRps.With(labels),
+		ClientReqTimeouts:       m.ClientReqTimeouts.With(labels),
+		ClientInflightRequests:  m.ClientInflightRequests.With(labels),
+		ClientDroppedArrivals:   m.ClientDroppedArrivals.With(runLabels),
+		ClientBackedOffArrivals: m.ClientBackedOffArrivals.With(runLabels),
+		ClientReqErrors:         m.ClientReqErrors.MustCurryWith(runLabels),
+		ClientReqPriorities:     m.ClientReqPriorities.MustCurryWith(runLabels),
+		ClientArrivalLateness:   m.ClientArrivalLateness.With(labels),
+		ClientUsers:             m.ClientUsers.With(labels),
+		ClientReqSlow:           m.ClientReqSlow.With(runLabels),
+		ClientReqDuplicates:     m.ClientReqDuplicates.With(runLabels),
+		ClientReqOutOfOrder:     m.ClientReqOutOfOrder.With(runLabels),
+		ClientReqShed:           m.ClientReqShed.With(runLabels),
+		SLOBurnRate:             m.SLOBurnRate.With(runLabels),
+	}
+}
+
+func (m *Metrics) histogram(runID string, workload string) *RotatingHistogram {
+	m.histogramsMtx.Lock()
+	defer m.histogramsMtx.Unlock()
+	key := runID + "/" + workload
+	h, ok := m.histograms[key]
+	if !ok {
+		h = NewRotatingHistogram(time.Now())
+		m.histograms[key] = h
 	}
+	return h
 }
 
 func (m *Metrics) WithQueueWorkload(workload string, strategy string) prometheus.Gauge {
@@ -179,8 +570,188 @@ func (m *Metrics) WithThrottleProbability(workload string, strategy string) prom
 	return m.ThrottleProbability.With(prometheus.Labels{"workload": workload, "strategy": strategy})
 }
 
+// WithCircuitBreakerState returns the state gauge for a circuit breaker instance, which is 0 when closed, 1 when
+// half-open, and 2 when open.
+func (m *Metrics) WithCircuitBreakerState(workload string, strategy string) prometheus.Gauge {
+	return m.CircuitBreakerState.With(prometheus.Labels{"workload": workload, "strategy": strategy})
+}
+
+// WithCircuitBreakerProbes returns the counter of executions that were attempted while a circuit breaker was half-open,
+// where the outcome is "success", "failure", or "limited" when a probe was rejected by the probe rate limit.
+func (m *Metrics) WithCircuitBreakerProbes(workload string, strategy string, outcome string) prometheus.Counter {
+	return m.CircuitBreakerProbes.With(prometheus.Labels{"workload": workload, "strategy": strategy, "outcome": outcome})
+}
+
+// WithRetries returns the counter of retry policy events, where the event is "retry" when an attempt is retried,
+// "exceeded" when an execution fails after its last attempt, "aborted" when a failure isn't retried due to an abort_on
+// condition, or "budget_exceeded" when a failure isn't retried because the retry budget is used up.
+func (m *Metrics) WithRetries(workload string, strategy string, event string) prometheus.Counter {
+	return m.Retries.With(prometheus.Labels{"workload": workload, "strategy": strategy, "event": event})
+}
+
+// WithRetryBudgetUsed returns the gauge of the fraction of a retry policy's budget that's used within its window.
+func (m *Metrics) WithRetryBudgetUsed(workload string, strategy string) prometheus.Gauge {
+	return m.RetryBudgetUsed.With(prometheus.Labels{"workload": workload, "strategy": strategy})
+}
+
+func (m *Metrics) WithBulkheadWaiters(workload string, strategy string) prometheus.Gauge {
+	return m.BulkheadWaiters.With(prometheus.Labels{"workload": workload, "strategy": strategy})
+}
+
+func (m *Metrics) WithBulkheadWaitTimes(workload string, strategy string) prometheus.Observer {
+	return m.BulkheadWaitTimes.With(prometheus.Labels{"workload": workload, "strategy": strategy})
+}
+
+func (m *Metrics) WithClientQueueSize(workload string, strategy string) prometheus.Gauge {
+	return m.ClientQueueSize.With(prometheus.Labels{"workload": workload, "strategy": strategy})
+}
+
+func (m *Metrics) WithClientQueueWaitTimes(workload string, strategy string) prometheus.Observer {
+	return m.ClientQueueWaitTimes.With(prometheus.Labels{"workload": workload, "strategy": strategy})
+}
+
+func (m *Metrics) WithClientQueueShed(workload string, strategy string, reason string) prometheus.Counter {
+	return m.ClientQueueShed.With(prometheus.Labels{"workload": workload, "strategy": strategy, "reason": reason})
+}
+
+// WithClientQueueBoosted returns a counter of queued executions that were admitted with a priority boosted by their age.
+func (m *Metrics) WithClientQueueBoosted(workload string, strategy string) prometheus.Counter {
+	return m.ClientQueueBoosted.With(prometheus.Labels{"workload": workload, "strategy": strategy})
+}
+
+// WithClientRegionFailovers returns a counter of a workload's requests that failed over to another region.
+func (m *Metrics) WithClientRegionFailovers(workload string, strategy string, region string) prometheus.Counter {
+	return m.ClientRegionFailovers.With(prometheus.Labels{"workload": workload, "strategy": strategy, "region": region})
+}
+
+// WithClientHostSaturation returns the gauge of the fraction of a host's bulkhead permits that are in use, which exceeds 1
+// while the host borrows permits.
+func (m *Metrics) WithClientHostSaturation(strategy string, host string) prometheus.Gauge {
+	return m.ClientHostSaturation.With(prometheus.Labels{"strategy": strategy, "host": host})
+}
+
+// WithClientHostBorrowed returns the gauge of the permits that a host has borrowed from other hosts' bulkheads.
+func (m *Metrics) WithClientHostBorrowed(strategy string, host string) prometheus.Gauge {
+	return m.ClientHostBorrowed.With(prometheus.Labels{"strategy": strategy, "host": host})
+}
+
+// WithClientHostRejected returns the counter of requests that were rejected since their host's bulkhead was full.
+func (m *Metrics) WithClientHostRejected(strategy string, host string) prometheus.Counter {
+	return m.ClientHostRejected.With(prometheus.Labels{"strategy": strategy, "host": host})
+}
+
+func (m *Metrics) WithRateLimiterWaiters(workload string, strategy string) prometheus.Gauge {
+	return m.RateLimiterWaiters.With(prometheus.Labels{"workload": workload, "strategy": strategy})
+}
+
+func (m *Metrics) WithRateLimiterWaitTimes(workload string, strategy string) prometheus.Observer {
+	return m.RateLimiterWaitTimes.With(prometheus.Labels{"workload": workload, "strategy": strategy})
+}
+
+// WithSharedRateLimit returns a gauge of a shared rate limiter's current rate.
+func (m *Metrics) WithSharedRateLimit(rateLimiter string) prometheus.Gauge {
+	return m.SharedRateLimit.With(prometheus.Labels{"ratelimiter": rateLimiter})
+}
+
+// WithSharedRatePermits returns a counter of the permits that a strategy acquired from a shared rate limiter, which is
+// the strategy's share of the limiter's quota.
+func (m *Metrics) WithSharedRatePermits(rateLimiter string, strategy string) prometheus.Counter {
+	return m.SharedRatePermits.With(prometheus.Labels{"ratelimiter": rateLimiter, "strategy": strategy})
+}
+
+// WithSharedRateRejected returns a counter of a strategy's executions that a shared rate limiter rejected.
+func (m *Metrics) WithSharedRateRejected(rateLimiter string, strategy string) prometheus.Counter {
+	return m.SharedRateRejected.With(prometheus.Labels{"ratelimiter": rateLimiter, "strategy": strategy})
+}
+
+func (m *Metrics) WithPolicyConfig(strategy string, policy string, position string, parameter string) prometheus.Gauge {
+	return m.PolicyConfig.With(prometheus.Labels{"strategy": strategy, "policy": policy, "position": position, "parameter": parameter})
+}
+
+// WithServerInflight returns the gauge of a workload's inflight server requests. Since this is called for every request,
+// labels are passed by value to avoid allocating a labels map.
 func (m *Metrics) WithServerInflight(workload string, strategy string) prometheus.Gauge {
-	return m.ServerInflightRequests.With(prometheus.Labels{"workload": workload, "strategy": strategy})
+	return m.ServerInflightRequests.WithLabelValues(workload, strategy)
+}
+
+func (m *Metrics) WithServerDeduplicated(workload string, strategy string) prometheus.Counter {
+	return m.ServerDeduplicated.With(prometheus.Labels{"workload": workload, "strategy": strategy})
+}
+
+// WithServerCancelled returns the counter of requests whose work the server stopped before completing, where the cause
+// is either "deadline", when a propagated deadline expired, or "client", when the client abandoned the request.
+func (m *Metrics) WithServerCancelled(workload string, strategy string, cause string) prometheus.Counter {
+	return m.ServerCancelled.With(prometheus.Labels{"workload": workload, "strategy": strategy, "cause": cause})
+}
+
+// WithServerCancelledWork returns the histogram of the fraction of work that was completed for cancelled requests.
+func (m *Metrics) WithServerCancelledWork(workload string, strategy string) prometheus.Observer {
+	return m.ServerCancelledWork.With(prometheus.Labels{"workload": workload, "strategy": strategy})
+}
+
+// WithServerWastedWork returns the counter of seconds of work that a tier completed after the client's deadline had
+// already passed, such as when the tier's timeouts are misaligned with the client's.
+func (m *Metrics) WithServerWastedWork(workload string, strategy string, tier string) prometheus.Counter {
+	return m.ServerWastedWork.With(prometheus.Labels{"workload": workload, "strategy": strategy, "tier": tier})
+}
+
+// WithServerClientRejections returns the counter of requests that were rejected for exceeding their client's limits.
+func (m *Metrics) WithServerClientRejections(workload string, strategy string) prometheus.Counter {
+	return m.ServerClientRejections.With(prometheus.Labels{"workload": workload, "strategy": strategy})
+}
+
+// WithServerEndpointInflight returns the gauge of requests that an endpoint is serving.
+func (m *Metrics) WithServerEndpointInflight(endpoint string, strategy string) prometheus.Gauge {
+	return m.ServerEndpointInflight.With(prometheus.Labels{"endpoint": endpoint, "strategy": strategy})
+}
+
+// WithServerEndpointRejected returns the counter of requests that were rejected since their endpoint was full.
+func (m *Metrics) WithServerEndpointRejected(endpoint string, strategy string) prometheus.Counter {
+	return m.ServerEndpointRejected.With(prometheus.Labels{"endpoint": endpoint, "strategy": strategy})
+}
+
+// WithServerConnectionFaults returns the counter of connections that a fault was injected into, by fault.
+func (m *Metrics) WithServerConnectionFaults(strategy string, fault string) prometheus.Counter {
+	return m.ServerConnectionFaults.With(prometheus.Labels{"strategy": strategy, "fault": fault})
+}
+
+// WithServerDeliveryFaults returns the counter of responses that a delivery fault was injected into, by fault, which is
+// either duplicate or reorder.
+func (m *Metrics) WithServerDeliveryFaults(workload string, strategy string, fault string) prometheus.Counter {
+	return m.ServerDeliveryFaults.With(prometheus.Labels{"workload": workload, "strategy": strategy, "fault": fault})
+}
+
+// WithServerStreamFailures returns the counter of streamed responses that failed to be written, by cause, which is
+// either write_timeout or client.
+func (m *Metrics) WithServerStreamFailures(workload string, strategy string, cause string) prometheus.Counter {
+	return m.ServerStreamFailures.With(prometheus.Labels{"workload": workload, "strategy": strategy, "cause": cause})
+}
+
+// RecordRunInfo records a strategy run's tags, which can be joined to other metrics by run_id.
+func (m *Metrics) RecordRunInfo(runID string, strategy string, tags []string) {
+	m.RunInfo.With(prometheus.Labels{"run_id": runID, "strategy": strategy, "tags": strings.Join(tags, ",")}).Set(1)
+}
+
+// RecordRunParameters records the swept parameter values that a strategy was generated with, which can be joined to other
+// metrics by run_id.
+func (m *Metrics) RecordRunParameters(runID string, strategy string, parameters map[string]string) {
+	for parameter, value := range parameters {
+		m.RunParameter.With(prometheus.Labels{"run_id": runID, "strategy": strategy, "parameter": parameter, "value": value}).Set(1)
+	}
+}
+
+// RecordWorkloadInfo records a workload's tags, which can be joined to other metrics by run_id and workload.
+func (m *Metrics) RecordWorkloadInfo(runID string, workload string, strategy string, tags []string) {
+	m.WorkloadInfo.With(prometheus.Labels{"run_id": runID, "workload": workload, "strategy": strategy, "tags": strings.Join(tags, ",")}).Set(1)
+}
+
+// RecordResult records a workload's final goodput, in requests per second, p99 latency, in seconds, and the share of its
+// requests that were rejected, when its run ends.
+func (m *Metrics) RecordResult(scenario string, strategy string, workload string, goodput float64, p99 float64, rejectionShare float64) {
+	labels := prometheus.Labels{"scenario": scenario, "strategy": strategy, "workload": workload}
+	m.ResultGoodput.With(labels).Set(goodput)
+	m.ResultLatencyP99.With(labels).Set(p99)
+	m.ResultRejectionShare.With(labels).Set(rejectionShare)
 }
 
 func (m *Metrics) WithStrategy(runID string, strategy string) *StrategyMetrics {
@@ -193,11 +764,21 @@ func (m *Metrics) WithStrategy(runID string, strategy string) *StrategyMetrics {
 		RunLabels: runLabels,
 
 		// Run metrics
-		RunDuration: m.RunDuration.With(runLabels),
+		RunDuration:          m.RunDuration.With(runLabels),
+		TimeToFirstRejection: m.RunTimeToFirstRejection.With(runLabels),
+		RecoveryTime:         m.RunRecoveryTime.With(runLabels),
+
+		// Reaction metrics
+		ReactionLimitSettleTime:     m.ReactionLimitSettleTime.With(runLabels),
+		ReactionRejectionSettleTime: m.ReactionRejectionSettleTime.With(runLabels),
+		ReactionExcessQueueing:      m.ReactionExcessQueueing.With(runLabels),
 
 		// Server metrics
-		ServerThreads:     m.ServerThreads,
-		ServerServiceTime: m.ServerServiceTime.With(labels),
+		ServerThreads:        m.ServerThreads,
+		ServerDesiredThreads: m.ServerDesiredThreads,
+		ServerServiceTime:    m.ServerServiceTime.With(labels),
+		ServerAsyncQueued:    m.ServerAsyncQueued.With(labels),
+		ServerAsyncShed:      m.ServerAsyncShed.With(labels),
 
 		// Policy metrics
 		MinTimeout: m.MinTimeout.With(labels),
@@ -211,14 +792,81 @@ type StrategyMetrics struct {
 	RunLabels prometheus.Labels
 
 	// Run metrics for things that must be distinguishable in the scenario result table
-	RunDuration prometheus.Gauge
+	RunDuration          prometheus.Gauge
+	TimeToFirstRejection prometheus.Gauge
+	RecoveryTime         prometheus.Gauge
+
+	// Reaction metrics
+	ReactionLimitSettleTime     prometheus.Gauge
+	ReactionRejectionSettleTime prometheus.Gauge
+	ReactionExcessQueueing      prometheus.Gauge
 
 	// Server metrics
-	ServerThreads     prometheus.Gauge
-	ServerServiceTime prometheus.Gauge
+	ServerThreads        prometheus.Gauge
+	ServerDesiredThreads prometheus.Gauge
+	ServerServiceTime    prometheus.Gauge
+	ServerAsyncQueued    prometheus.Gauge
+	ServerAsyncShed      prometheus.Counter
 
 	// Policy metrics
 	MinTimeout         prometheus.Gauge
 	RateLimit          prometheus.Gauge
 	CircuitbreakerOpen prometheus.Gauge
 }
+
+// AdaptiveLimiterMetrics records the signals that an adaptive limiter adjusts its limit from.
+type AdaptiveLimiterMetrics struct {
+	RecentLatency         prometheus.Gauge // The recent quantile latency, in seconds
+	BaselineLatency       prometheus.Gauge // The baseline latency that recent latencies are compared to, in seconds
+	LatencyCorrelation    prometheus.Gauge // The correlation between inflight executions and latency
+	ThroughputCorrelation prometheus.Gauge // The correlation between inflight executions and throughput
+}
+
+func (m *Metrics) WithAdaptiveLimiter(workload string, strategy string) *AdaptiveLimiterMetrics {
+	labels := prometheus.Labels{"workload": workload, "strategy": strategy}
+	return &AdaptiveLimiterMetrics{
+		RecentLatency:         m.AdaptiveLimiterRecentLatency.With(labels),
+		BaselineLatency:       m.AdaptiveLimiterBaselineLatency.With(labels),
+		LatencyCorrelation:    m.AdaptiveLimiterLatencyCorrelation.With(labels),
+		ThroughputCorrelation: m.AdaptiveLimiterThroughputCorrelation.With(labels),
+	}
+}
+
+// PrioritizerMetrics records the signals a prioritizer calibrates from and decides with.
+type PrioritizerMetrics struct {
+	RejectionRate      prometheus.Gauge // The rejection rate computed from the registered policies' stats
+	RejectionThreshold prometheus.Gauge // The level below which executions are rejected
+	RegisteredPolicies prometheus.Gauge // The number of policies whose stats are combined when calibrating
+	metrics            *Metrics
+	labels             prometheus.Labels
+}
+
+func (m *Metrics) WithPrioritizer(strategy string, prioritizer string) *PrioritizerMetrics {
+	labels := prometheus.Labels{"strategy": strategy, "prioritizer": prioritizer}
+	return &PrioritizerMetrics{
+		RejectionRate:      m.PrioritizerRejectionRate.With(labels),
+		RejectionThreshold: m.PrioritizerRejectionThreshold.With(labels),
+		RegisteredPolicies: m.PrioritizerRegisteredPolicies.With(labels),
+		metrics:            m,
+		labels:             labels,
+	}
+}
+
+// AdmissionRate returns the gauge for the fraction of a priority's levels that are admitted by the rejection threshold.
+func (m *PrioritizerMetrics) AdmissionRate(priority string) prometheus.Gauge {
+	return m.metrics.PrioritizerAdmissionRate.With(prometheus.Labels{"strategy": m.labels["strategy"], "prioritizer": m.labels["prioritizer"], "priority": priority})
+}
+
+// Value returns the current value of a gauge or counter.
+func (m *Metrics) Value(metric prometheus.Metric) float64 {
+	var pb dto.Metric
+	if err := metric.Write(&pb); err != nil {
+		return 0
+	}
+	if pb.Gauge != nil {
+		return pb.Gauge.GetValue()
+	} else if pb.Counter != nil {
+		return pb.Counter.GetValue()
+	}
+	return 0
+}
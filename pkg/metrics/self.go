@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// SelfStats is a snapshot of tripwire's own resource usage, which helps distinguish degradation of the tool from
+// degradation of the system under test. The same stats are exported by the default Go and process collectors, such as
+// go_goroutines, go_gc_duration_seconds, and process_cpu_seconds_total.
+type SelfStats struct {
+	CPU        time.Duration // CPU time used by the process, or 0 if it's not available on the platform
+	GCPause    time.Duration // cumulative stop-the-world GC pause time
+	GCs        uint32
+	Goroutines int
+}
+
+func ReadSelfStats() SelfStats {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	return SelfStats{
+		CPU:        ProcessCPU(),
+		GCPause:    time.Duration(memStats.PauseTotalNs),
+		GCs:        memStats.NumGC,
+		Goroutines: runtime.NumGoroutine(),
+	}
+}
+
+// ProcessCPU reads the process's CPU time from the process collector, which supports more platforms than the runtime.
+func ProcessCPU() time.Duration {
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		return 0
+	}
+	for _, family := range families {
+		if family.GetName() == "process_cpu_seconds_total" && len(family.GetMetric()) > 0 {
+			seconds := family.GetMetric()[0].GetCounter().GetValue()
+			return time.Duration(seconds * float64(time.Second))
+		}
+	}
+	return 0
+}
@@ -0,0 +1,112 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+// Config configures the sinks that a run's summary is sent to when the run ends, such as for scheduled suites that
+// nobody is watching.
+type Config struct {
+	Slack *SlackConfig `yaml:"slack"`
+	Email *EmailConfig `yaml:"email"`
+}
+
+// SlackConfig sends summaries to a Slack incoming webhook. Since the webhook's URL is a credential, it's read from an
+// environment variable so that it isn't stored in the config.
+type SlackConfig struct {
+	WebhookURLEnv string `yaml:"webhook_url_env"` // the environment variable that contains the webhook URL
+}
+
+// EmailConfig sends summaries by email via an SMTP server. The password is read from an environment variable so that it
+// isn't stored in the config.
+type EmailConfig struct {
+	SMTPAddr    string   `yaml:"smtp_addr"` // host:port
+	From        string   `yaml:"from"`
+	To          []string `yaml:"to"`
+	Username    string   `yaml:"username"`     // authenticates with PLAIN auth when set
+	PasswordEnv string   `yaml:"password_env"` // the environment variable that contains the password
+}
+
+func (c *Config) Validate() error {
+	if c.Slack != nil && c.Slack.WebhookURLEnv == "" {
+		return errors.New("slack notifications require a webhook_url_env")
+	}
+	if c.Email != nil && (c.Email.SMTPAddr == "" || c.Email.From == "" || len(c.Email.To) == 0) {
+		return errors.New("email notifications require an smtp_addr, from, and to")
+	}
+	return nil
+}
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// Send sends the subject and summary to each configured sink, returning any errors. A nil config sends nothing.
+func Send(config *Config, subject string, summary string) error {
+	if config == nil {
+		return nil
+	}
+	var errs []error
+	if config.Slack != nil {
+		if err := sendSlack(config.Slack, subject, summary); err != nil {
+			errs = append(errs, fmt.Errorf("slack: %w", err))
+		}
+	}
+	if config.Email != nil {
+		if err := sendEmail(config.Email, subject, summary); err != nil {
+			errs = append(errs, fmt.Errorf("email: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func sendSlack(config *SlackConfig, subject string, summary string) error {
+	// Summaries are tables, so they're sent as a code block to preserve their alignment
+	body, err := json.Marshal(map[string]string{"text": fmt.Sprintf("*%s*\n```\n%s```", subject, summary)})
+	if err != nil {
+		return err
+	}
+	webhookURL := os.Getenv(config.WebhookURLEnv)
+	if webhookURL == "" {
+		return fmt.Errorf("%s is not set", config.WebhookURLEnv)
+	}
+	resp, err := httpClient.Post(webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func sendEmail(config *EmailConfig, subject string, summary string) error {
+	var auth smtp.Auth
+	if config.Username != "" {
+		host := config.SMTPAddr
+		if i := strings.LastIndex(host, ":"); i >= 0 {
+			host = host[:i]
+		}
+		auth = smtp.PlainAuth("", config.Username, os.Getenv(config.PasswordEnv), host)
+	}
+	return smtp.SendMail(config.SMTPAddr, auth, config.From, config.To, emailMessage(config, subject, summary))
+}
+
+// emailMessage returns the email's headers and body.
+func emailMessage(config *EmailConfig, subject string, summary string) []byte {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", config.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(config.To, ", "))
+	// The subject includes the config's description, so line breaks are removed to keep it from injecting headers
+	fmt.Fprintf(&msg, "Subject: %s\r\n", strings.NewReplacer("\r", " ", "\n", " ").Replace(subject))
+	fmt.Fprintf(&msg, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(summary, "\n", "\r\n"))
+	return msg.Bytes()
+}
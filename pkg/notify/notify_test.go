@@ -0,0 +1,48 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendSlack(t *testing.T) {
+	var text string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		text = body["text"]
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	config := &Config{Slack: &SlackConfig{WebhookURLEnv: "TRIPWIRE_TEST_SLACK_WEBHOOK_URL"}}
+	assert.ErrorContains(t, Send(config, "run completed", ""), "TRIPWIRE_TEST_SLACK_WEBHOOK_URL is not set")
+	t.Setenv("TRIPWIRE_TEST_SLACK_WEBHOOK_URL", server.URL)
+
+	require.NoError(t, Send(config, "run completed", "STRATEGY  TOTAL\ntimeout   100\n"))
+	assert.Equal(t, "*run completed*\n```\nSTRATEGY  TOTAL\ntimeout   100\n```", text)
+
+	status = http.StatusForbidden
+	assert.ErrorContains(t, Send(config, "run completed", ""), "status 403")
+}
+
+func TestEmailMessage(t *testing.T) {
+	config := &EmailConfig{From: "tripwire@example.com", To: []string{"a@example.com", "b@example.com"}}
+	msg := emailMessage(config, "run completed: nightly\r\nBcc: c@example.com", "STRATEGY  TOTAL\n")
+	assert.Equal(t, "From: tripwire@example.com\r\n"+
+		"To: a@example.com, b@example.com\r\n"+
+		"Subject: run completed: nightly  Bcc: c@example.com\r\n"+
+		"Content-Type: text/plain; charset=utf-8\r\n\r\n"+
+		"STRATEGY  TOTAL\r\n", string(msg))
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, (&Config{}).Validate())
+	assert.Error(t, (&Config{Slack: &SlackConfig{}}).Validate())
+	assert.Error(t, (&Config{Email: &EmailConfig{SMTPAddr: "localhost:25", From: "tripwire@example.com"}}).Validate())
+}
@@ -0,0 +1,71 @@
+package policy
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"tripwire/pkg/metrics"
+)
+
+// limitUpdateHandler is a slog.Handler that records the signals an adaptive limiter logs with each limit update, since
+// failsafe-go doesn't otherwise expose them. Log records are then passed to the next handler, if it's enabled for them.
+type limitUpdateHandler struct {
+	metrics *metrics.AdaptiveLimiterMetrics
+	next    slog.Handler
+}
+
+func newLimitUpdateLogger(metrics *metrics.AdaptiveLimiterMetrics, next slog.Handler) *slog.Logger {
+	return slog.New(&limitUpdateHandler{metrics: metrics, next: next})
+}
+
+func (h *limitUpdateHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= slog.LevelDebug
+}
+
+func (h *limitUpdateHandler) Handle(ctx context.Context, record slog.Record) error {
+	if h.next.Enabled(ctx, record.Level) {
+		if err := h.next.Handle(ctx, record); err != nil {
+			return err
+		}
+	}
+	if record.Message != "limit update" {
+		return nil
+	}
+	record.Attrs(func(attr slog.Attr) bool {
+		switch attr.Key {
+		case "recentRTT":
+			h.metrics.RecentLatency.Set(attrDuration(attr).Seconds())
+		case "baselineRTT":
+			h.metrics.BaselineLatency.Set(attrDuration(attr).Seconds())
+		case "rttCorr":
+			h.metrics.LatencyCorrelation.Set(attrFloat(attr))
+		case "thrptCorr":
+			h.metrics.ThroughputCorrelation.Set(attrFloat(attr))
+		}
+		return true
+	})
+	return nil
+}
+
+func (h *limitUpdateHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &limitUpdateHandler{metrics: h.metrics, next: h.next.WithAttrs(attrs)}
+}
+
+func (h *limitUpdateHandler) WithGroup(name string) slog.Handler {
+	return &limitUpdateHandler{metrics: h.metrics, next: h.next.WithGroup(name)}
+}
+
+func attrDuration(attr slog.Attr) time.Duration {
+	if attr.Value.Kind() == slog.KindDuration {
+		return attr.Value.Duration()
+	}
+	return 0
+}
+
+// attrFloat parses a float attr, which the limiter logs as a formatted string.
+func attrFloat(attr slog.Attr) float64 {
+	f, _ := strconv.ParseFloat(attr.Value.String(), 64)
+	return f
+}
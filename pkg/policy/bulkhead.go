@@ -0,0 +1,73 @@
+package policy
+
+import (
+	"time"
+
+	"github.com/failsafe-go/failsafe-go"
+	"github.com/failsafe-go/failsafe-go/bulkhead"
+	"github.com/failsafe-go/failsafe-go/common"
+	"github.com/failsafe-go/failsafe-go/policy"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"tripwire/pkg/tracing"
+)
+
+// instrumentedBulkhead is a bulkhead that records how many executions are waiting for a permit and how long they wait.
+type instrumentedBulkhead[R any] struct {
+	bulkhead.Bulkhead[R]
+	maxWaitTime time.Duration
+	waiters     prometheus.Gauge
+	waitTimes   prometheus.Observer
+}
+
+func (b *instrumentedBulkhead[R]) ToExecutor(_ R) any {
+	e := &bulkheadExecutor[R]{
+		BaseExecutor:         &policy.BaseExecutor[R]{},
+		instrumentedBulkhead: b,
+	}
+	e.Executor = e
+	return e
+}
+
+type bulkheadExecutor[R any] struct {
+	*policy.BaseExecutor[R]
+	*instrumentedBulkhead[R]
+}
+
+var _ policy.Executor[any] = &bulkheadExecutor[any]{}
+
+func (e *bulkheadExecutor[R]) Apply(innerFn func(failsafe.Execution[R]) *common.PolicyResult[R]) func(failsafe.Execution[R]) *common.PolicyResult[R] {
+	return func(exec failsafe.Execution[R]) *common.PolicyResult[R] {
+		if err := e.acquirePermit(exec); err != nil {
+			return &common.PolicyResult[R]{
+				Error: err,
+				Done:  true,
+			}
+		}
+
+		execInternal := exec.(policy.ExecutionInternal[R])
+		result := innerFn(exec)
+		result = e.PostExecute(execInternal, result)
+		e.ReleasePermit()
+		return result
+	}
+}
+
+func (e *bulkheadExecutor[R]) acquirePermit(exec failsafe.Execution[R]) error {
+	if e.TryAcquirePermit() {
+		e.waitTimes.Observe(0)
+		return nil
+	}
+	if e.maxWaitTime == 0 {
+		return bulkhead.ErrFull
+	}
+
+	start := time.Now()
+	e.waiters.Inc()
+	err := e.AcquirePermitWithMaxWait(exec.Context(), e.maxWaitTime)
+	e.waiters.Dec()
+	waited := time.Since(start)
+	e.waitTimes.Observe(waited.Seconds())
+	tracing.RecordSpan(exec.Context(), "bulkhead wait", start, waited)
+	return err
+}
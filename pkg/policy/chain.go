@@ -0,0 +1,79 @@
+package policy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/failsafe-go/failsafe-go"
+)
+
+// Chain describes the policies composed into an executor, from outermost to innermost.
+type Chain []*ChainEntry
+
+// ChainEntry describes a policy within a chain.
+type ChainEntry struct {
+	Type        string             `json:"type"`
+	Instance    string             `json:"instance"` // the name that the policy instance is shared under
+	Prioritized bool               `json:"prioritized"`
+	Prioritizer string             `json:"prioritizer,omitempty"` // the named prioritizer, if the policy is bound to one
+	Parameters  map[string]float64 `json:"parameters"`
+	Disabled    bool               `json:"disabled,omitempty"` // whether the policy was removed from the executor at runtime
+
+	policy failsafe.Policy[*http.Response]
+	onDone func() // records the policy's metrics after each execution, if any
+}
+
+func (e *ChainEntry) String() string {
+	var params []string
+	for _, name := range sortedKeys(e.Parameters) {
+		if value := e.Parameters[name]; value != 0 {
+			params = append(params, fmt.Sprintf("%s=%v", name, value))
+		}
+	}
+	prioritized := ""
+	if e.Prioritizer != "" {
+		prioritized = fmt.Sprintf(" prioritized(%s)", e.Prioritizer)
+	} else if e.Prioritized {
+		prioritized = " prioritized"
+	}
+	return fmt.Sprintf("%s [%s]%s %s", e.Type, e.Instance, prioritized, strings.Join(params, " "))
+}
+
+// Executor builds an executor from the chain's enabled policies.
+func (c Chain) Executor() failsafe.Executor[*http.Response] {
+	var policies []failsafe.Policy[*http.Response]
+	for _, entry := range c {
+		if !entry.Disabled {
+			policies = append(policies, entry.policy)
+		}
+	}
+	return failsafe.With(policies...).OnDone(func(e failsafe.ExecutionDoneEvent[*http.Response]) {
+		for _, entry := range c {
+			if entry.onDone != nil {
+				entry.onDone()
+			}
+		}
+	})
+}
+
+// PrintChains prints the policy chain for each of a strategy's workloads.
+func PrintChains(w io.Writer, strategy string, chains map[string]Chain) {
+	for _, workload := range sortedKeys(chains) {
+		fmt.Fprintf(w, "strategy %q, workload %q:\n", strategy, workload)
+		for i, entry := range chains[workload] {
+			fmt.Fprintf(w, "  %d. %s\n", i+1, entry)
+		}
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
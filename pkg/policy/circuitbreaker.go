@@ -0,0 +1,56 @@
+package policy
+
+import (
+	"github.com/failsafe-go/failsafe-go"
+	"github.com/failsafe-go/failsafe-go/circuitbreaker"
+	"github.com/failsafe-go/failsafe-go/common"
+	"github.com/failsafe-go/failsafe-go/policy"
+	"github.com/failsafe-go/failsafe-go/ratelimiter"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// probedCircuitBreaker is a circuit breaker that records the outcomes of executions that probe it while it's half-open,
+// and optionally limits the rate of probes.
+type probedCircuitBreaker[R any] struct {
+	circuitbreaker.CircuitBreaker[R]
+	probeLimiter ratelimiter.RateLimiter[R] // may be nil
+	probes       func(outcome string) prometheus.Counter
+}
+
+func (cb *probedCircuitBreaker[R]) ToExecutor(_ R) any {
+	return &probedCircuitBreakerExecutor[R]{
+		inner:                cb.CircuitBreaker.ToExecutor(*new(R)).(policy.Executor[R]),
+		probedCircuitBreaker: cb,
+	}
+}
+
+type probedCircuitBreakerExecutor[R any] struct {
+	inner policy.Executor[R]
+	*probedCircuitBreaker[R]
+}
+
+func (e *probedCircuitBreakerExecutor[R]) Apply(innerFn func(failsafe.Execution[R]) *common.PolicyResult[R]) func(failsafe.Execution[R]) *common.PolicyResult[R] {
+	circuitBreakerFn := e.inner.Apply(innerFn)
+	return func(exec failsafe.Execution[R]) *common.PolicyResult[R] {
+		// An open circuit breaker whose delay has elapsed transitions to half-open when the next execution is attempted
+		halfOpen := e.IsHalfOpen() || (e.IsOpen() && e.RemainingDelay() == 0)
+		if !halfOpen {
+			return circuitBreakerFn(exec)
+		}
+
+		if e.probeLimiter != nil && !e.probeLimiter.TryAcquirePermit() {
+			e.probes("limited").Inc()
+			return &common.PolicyResult[R]{
+				Error: circuitbreaker.ErrOpen,
+				Done:  true,
+			}
+		}
+		result := circuitBreakerFn(exec)
+		if result.Error == nil {
+			e.probes("success").Inc()
+		} else {
+			e.probes("failure").Inc()
+		}
+		return result
+	}
+}
@@ -1,7 +1,10 @@
 package policy
 
 import (
+	"fmt"
 	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
@@ -14,6 +17,55 @@ type Config struct {
 	*VegasConfig             `yaml:"vegaslimiter"`
 	*GradientConfig          `yaml:"gradientlimiter"`
 	*Gradient2Config         `yaml:"gradient2limiter"`
+	*QueueConfig             `yaml:"queue"`
+	*RetryConfig             `yaml:"retry"`
+}
+
+const (
+	SharedScope   = "shared"
+	WorkloadScope = "workload"
+)
+
+// Validate returns an error if any of the policy configs are invalid, including if they're bound to a prioritizer that
+// isn't declared in prioritizers or that has a different type, or reference a rate limiter that isn't declared in
+// sharedRateLimiters.
+func (c Configs) Validate(prioritizers map[string]*PrioritizerConfig, sharedRateLimiters map[string]*RateLimiterConfig) error {
+	for _, config := range c {
+		if rl := config.RateLimiterConfig; rl != nil && rl.Shared != "" {
+			if _, ok := sharedRateLimiters[rl.Shared]; !ok {
+				return fmt.Errorf("unknown shared ratelimiter: %s", rl.Shared)
+			} else if rl.RPS != 0 {
+				return fmt.Errorf("ratelimiter rps cannot be configured for shared ratelimiter %s", rl.Shared)
+			}
+		}
+		if config.QueueConfig != nil {
+			if err := config.QueueConfig.Validate(); err != nil {
+				return err
+			}
+		}
+		if config.RetryConfig != nil {
+			if err := config.RetryConfig.Validate(); err != nil {
+				return err
+			}
+		}
+		if cb := config.CircuitBreakerConfig; cb != nil && cb.Scope != "" && cb.Scope != SharedScope && cb.Scope != WorkloadScope {
+			return fmt.Errorf("invalid circuitbreaker scope: %s", cb.Scope)
+		}
+		var prioritizer, prioritizerType string
+		if config.AdaptiveLimiterConfig != nil {
+			prioritizer, prioritizerType = config.AdaptiveLimiterConfig.Prioritizer, LimiterPrioritizer
+		} else if config.AdaptiveThrottlerConfig != nil {
+			prioritizer, prioritizerType = config.AdaptiveThrottlerConfig.Prioritizer, ThrottlerPrioritizer
+		}
+		if prioritizer != "" {
+			if pc, ok := prioritizers[prioritizer]; !ok {
+				return fmt.Errorf("unknown prioritizer: %s", prioritizer)
+			} else if pc.Type != prioritizerType {
+				return fmt.Errorf("prioritizer %s has type %s but is used by an %s", prioritizer, pc.Type, prioritizerType)
+			}
+		}
+	}
+	return nil
 }
 
 type RateLimiterType int
@@ -29,6 +81,13 @@ type RateLimiterConfig struct {
 	Type        RateLimiterType `yaml:"type"`
 	RPS         uint            `yaml:"rps"`
 	MaxWaitTime time.Duration   `yaml:"max_wait_time"`
+	MaxWaiters  uint            `yaml:"max_waiters"` // rejects executions when this many are already waiting. 0 is unbounded.
+	WarmUp      time.Duration   `yaml:"warm_up"`     // how long to ramp from the warm_up_rps to the rps
+	WarmUpRPS   uint            `yaml:"warm_up_rps"` // the rate to start warming up from. Defaults to a third of the rps.
+
+	// Shared names a shared rate limiter whose rate and waiters are shared with any other strategies that reference it,
+	// in which case the rate limiter's other settings come from the shared rate limiter
+	Shared string `yaml:"shared"`
 }
 
 // See https://failsafe-go.dev/bulkhead/ for details on how bulkheads work.
@@ -43,6 +102,10 @@ type BulkheadConfig struct {
 type CircuitBreakerConfig struct {
 	Delay time.Duration `yaml:"delay"`
 
+	// Scope is either "shared", for one circuit breaker per strategy, or "workload", for one per workload. Defaults to
+	// the client's share_strategies setting.
+	Scope string `yaml:"scope"`
+
 	FailureThreshold            uint          `yaml:"failure_threshold"`
 	FailureRateThreshold        float64       `yaml:"failure_rate_threshold"`
 	FailureThresholdingCapacity uint          `yaml:"failure_thresholding_capacity"`
@@ -51,6 +114,93 @@ type CircuitBreakerConfig struct {
 
 	SuccessThreshold            uint `yaml:"success_threshold"`
 	SuccessThresholdingCapacity uint `yaml:"success_thresholding_capacity"`
+
+	HalfOpenProbeRPS uint `yaml:"half_open_probe_rps"` // limits the rate of executions while half-open. 0 is unlimited.
+}
+
+// See https://failsafe-go.dev/retry/ for details on how retry policies work.
+// See https://pkg.go.dev/github.com/failsafe-go/failsafe-go/retrypolicy#Builder for details on how retry policies are configured.
+//
+// Errors and 429 and 5xx responses are retried, other than those whose outcome is in AbortOn.
+type RetryConfig struct {
+	MaxAttempts  int           `yaml:"max_attempts"`  // including the first attempt. Defaults to 3. -1 is unlimited.
+	MaxDuration  time.Duration `yaml:"max_duration"`  // the most time to spend on an execution, including retries. 0 is unlimited.
+	Delay        time.Duration `yaml:"delay"`         // the delay between attempts
+	MaxDelay     time.Duration `yaml:"max_delay"`     // when set, delays grow from the delay to the max delay by the delay_factor
+	DelayFactor  float64       `yaml:"delay_factor"`  // how much delays grow by after each attempt. Defaults to 2.
+	JitterFactor float64       `yaml:"jitter_factor"` // randomly varies each delay by up to this fraction of it
+
+	// AbortOn are the outcomes that aren't retried, which can include "rejected", "timeout", and "failure"
+	AbortOn []string `yaml:"abort_on"`
+
+	Budget *RetryBudgetConfig `yaml:"budget"` // limits retries to a ratio of requests. Unbounded when nil.
+}
+
+// RetryBudgetConfig limits retries to a ratio of the requests over a sliding window, so that retries can't multiply the
+// load on a server that's browning out. Failures that would exceed the budget aren't retried.
+type RetryBudgetConfig struct {
+	Ratio      float64       `yaml:"ratio"`       // the most retries as a fraction of requests, such as 0.1 for 10%
+	Window     time.Duration `yaml:"window"`      // how long requests and retries are counted for. Defaults to 10s.
+	MinRetries uint          `yaml:"min_retries"` // retries that are allowed per window regardless of the ratio. Defaults to 10.
+}
+
+func (c *RetryBudgetConfig) UnmarshalYAML(value *yaml.Node) error {
+	*c = RetryBudgetConfig{
+		Window:     10 * time.Second,
+		MinRetries: 10,
+	}
+	type Alias RetryBudgetConfig
+	var alias = Alias(*c)
+	if err := value.Decode(&alias); err != nil {
+		return err
+	}
+	*c = RetryBudgetConfig(alias)
+	return nil
+}
+
+func (c *RetryBudgetConfig) Validate() error {
+	if c.Ratio <= 0 {
+		return fmt.Errorf("retry budget ratio must be positive")
+	}
+	if c.Window <= 0 {
+		return fmt.Errorf("retry budget window must be positive")
+	}
+	return nil
+}
+
+func (c *RetryConfig) UnmarshalYAML(value *yaml.Node) error {
+	*c = RetryConfig{
+		MaxAttempts: 3,
+		DelayFactor: 2,
+	}
+	type Alias RetryConfig
+	var alias = Alias(*c)
+	if err := value.Decode(&alias); err != nil {
+		return err
+	}
+	*c = RetryConfig(alias)
+	return nil
+}
+
+func (c *RetryConfig) Validate() error {
+	if c.MaxAttempts == 0 || c.MaxAttempts < -1 {
+		return fmt.Errorf("retry max_attempts must be positive, or -1 for unlimited")
+	}
+	if c.MaxDelay != 0 && (c.MaxDelay <= c.Delay || c.DelayFactor <= 1) {
+		return fmt.Errorf("retry max_delay must be greater than the delay, with a delay_factor greater than 1")
+	}
+	if c.JitterFactor < 0 || c.JitterFactor > 1 {
+		return fmt.Errorf("retry jitter_factor must be between 0 and 1")
+	}
+	for _, outcome := range c.AbortOn {
+		if outcome != OutcomeRejected && outcome != OutcomeTimeout && outcome != OutcomeFailure {
+			return fmt.Errorf("unknown retry abort_on outcome: %s", outcome)
+		}
+	}
+	if c.Budget != nil {
+		return c.Budget.Validate()
+	}
+	return nil
 }
 
 // See https://failsafe-go.dev/adaotive-limiter/ for details on how adaptive limiters work.
@@ -71,6 +221,8 @@ type AdaptiveLimiterConfig struct {
 	StabilizationWindowSize uint    `yaml:"stabilization_window_size"`
 	InitialRejectionFactor  float64 `yaml:"initial_rejection_factor"`
 	MaxRejectionFactor      float64 `yaml:"max_rejection_factor"`
+
+	Prioritizer string `yaml:"prioritizer"` // the named prioritizer to use, if prioritized. Defaults to the strategy's limiter prioritizer.
 }
 
 type AdaptiveThrottlerConfig struct {
@@ -78,6 +230,8 @@ type AdaptiveThrottlerConfig struct {
 	ThresholdingPeriod   time.Duration `yaml:"thresholding_period"`
 	ExecutionThreshold   uint          `yaml:"execution_threshold"`
 	MaxRejectionRate     float64       `yaml:"max_rejection_rate"`
+
+	Prioritizer string `yaml:"prioritizer"` // the named prioritizer to use, if prioritized. Defaults to the strategy's throttler prioritizer.
 }
 
 // See https://pkg.go.dev/github.com/platinummonkey/go-concurrency-limits@v0.8.0/limit#VegasLimit for details on how the Vegas limit works.
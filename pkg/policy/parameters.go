@@ -0,0 +1,74 @@
+package policy
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"tripwire/pkg/metrics"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Parameters returns the type of the configured policy, as named in YAML, along with its numeric parameters keyed by
+// their YAML names. Durations are expressed in seconds and bools as 0 or 1.
+func (c *Config) Parameters() (string, map[string]float64) {
+	if c.Timeout != 0 {
+		return "timeout", map[string]float64{"timeout": c.Timeout.Seconds()}
+	}
+
+	value := reflect.ValueOf(c).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if !field.Anonymous || value.Field(i).IsNil() {
+			continue
+		}
+		return yamlName(field), structParameters(value.Field(i).Elem())
+	}
+	return "", nil
+}
+
+// RecordParameters exports the parameters of each policy in the chain as policy_config gauges.
+func (c Configs) RecordParameters(metrics *metrics.Metrics, strategy string) {
+	for i, config := range c {
+		policyType, params := config.Parameters()
+		for param, value := range params {
+			metrics.WithPolicyConfig(strategy, policyType, strconv.Itoa(i), param).Set(value)
+		}
+	}
+}
+
+func structParameters(value reflect.Value) map[string]float64 {
+	params := make(map[string]float64)
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		name := yamlName(field)
+		if name == "" || name == "-" {
+			continue
+		}
+		fieldValue := value.Field(i)
+		switch {
+		case field.Type == durationType:
+			params[name] = time.Duration(fieldValue.Int()).Seconds()
+		case fieldValue.CanInt():
+			params[name] = float64(fieldValue.Int())
+		case fieldValue.CanUint():
+			params[name] = float64(fieldValue.Uint())
+		case fieldValue.CanFloat():
+			params[name] = fieldValue.Float()
+		case fieldValue.Kind() == reflect.Bool:
+			if fieldValue.Bool() {
+				params[name] = 1
+			} else {
+				params[name] = 0
+			}
+		}
+	}
+	return params
+}
+
+func yamlName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	return name
+}
@@ -0,0 +1,23 @@
+package policy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParameters(t *testing.T) {
+	policyType, params := (&Config{Timeout: 300 * time.Millisecond}).Parameters()
+	assert.Equal(t, "timeout", policyType)
+	assert.Equal(t, map[string]float64{"timeout": 0.3}, params)
+
+	policyType, params = (&Config{BulkheadConfig: &BulkheadConfig{MaxConcurrency: 8, MaxWaitTime: time.Second}}).Parameters()
+	assert.Equal(t, "bulkhead", policyType)
+	assert.Equal(t, map[string]float64{"max_concurrency": 8, "max_wait_time": 1}, params)
+
+	policyType, params = (&Config{RateLimiterConfig: &RateLimiterConfig{Type: Bursty, RPS: 100}}).Parameters()
+	assert.Equal(t, "ratelimiter", policyType)
+	assert.Equal(t, float64(1), params["type"])
+	assert.Equal(t, float64(100), params["rps"])
+}
@@ -1,6 +1,7 @@
 package policy
 
 import (
+	"fmt"
 	"log/slog"
 	"net/http"
 	"time"
@@ -8,13 +9,13 @@ import (
 	"github.com/failsafe-go/failsafe-go"
 	"github.com/failsafe-go/failsafe-go/adaptivelimiter"
 	"github.com/failsafe-go/failsafe-go/adaptivethrottler"
-	"github.com/failsafe-go/failsafe-go/priority"
 
 	"github.com/failsafe-go/failsafe-go/bulkhead"
 	"github.com/failsafe-go/failsafe-go/circuitbreaker"
 
 	"github.com/failsafe-go/failsafe-go/ratelimiter"
 	"github.com/failsafe-go/failsafe-go/timeout"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 	"go.uber.org/zap/exp/zapslog"
 	"gopkg.in/yaml.v3"
@@ -31,8 +32,8 @@ func (c *Config) UnmarshalYAML(value *yaml.Node) error {
 	return value.Decode(tmp)
 }
 
-func (c *Config) ToPolicy(metrics *metrics.Metrics, strategyMetrics *metrics.StrategyMetrics, limiterPrioritizer priority.Prioritizer, throttlerPrioritizer priority.Prioritizer, workload, strategy string, logger *zap.Logger) failsafe.Policy[*http.Response] {
-	slogger := slog.New(zapslog.NewHandler(logger.Core()))
+func (c *Config) ToPolicy(metrics *metrics.Metrics, strategyMetrics *metrics.StrategyMetrics, prioritizers *Prioritizers, sharedRateLimiters *SharedRateLimiters, workload, strategy string, logger *zap.Logger) failsafe.Policy[*http.Response] {
+	slogger := slog.New(zapslog.NewHandler(logger.Core())).With("workload", workload)
 	limitChangedListener := func(e adaptivelimiter.LimitChangedEvent) {
 		metrics.WithConcurrencyLimit(workload, strategy).Set(float64(e.NewLimit))
 	}
@@ -41,25 +42,20 @@ func (c *Config) ToPolicy(metrics *metrics.Metrics, strategyMetrics *metrics.Str
 		return timeout.New[*http.Response](c.Timeout)
 	} else if c.RateLimiterConfig != nil {
 		pc := c.RateLimiterConfig
-		strategyMetrics.RateLimit.Set(float64(pc.RPS))
-		switch pc.Type {
-		case Bursty:
-			return ratelimiter.NewBurstyBuilder[*http.Response](pc.RPS, time.Second).
-				WithMaxWaitTime(pc.MaxWaitTime).
-				Build()
-		case Smooth:
-			fallthrough
-		default:
-			return ratelimiter.NewSmoothBuilder[*http.Response](pc.RPS, time.Second).
-				WithMaxWaitTime(pc.MaxWaitTime).
-				Build()
+		if pc.Shared != "" {
+			return sharedRateLimiters.rateLimiter(pc.Shared, workload, strategy)
 		}
+		return newInstrumentedRateLimiter[*http.Response](pc, strategyMetrics.RateLimit, metrics.WithRateLimiterWaiters(workload, strategy),
+			metrics.WithRateLimiterWaitTimes(workload, strategy))
 	} else if c.BulkheadConfig != nil {
 		pc := c.BulkheadConfig
 		metrics.WithConcurrencyLimit(workload, strategy).Set(float64(pc.MaxConcurrency))
-		return bulkhead.NewBuilder[*http.Response](pc.MaxConcurrency).
-			WithMaxWaitTime(pc.MaxWaitTime).
-			Build()
+		return &instrumentedBulkhead[*http.Response]{
+			Bulkhead:    bulkhead.New[*http.Response](pc.MaxConcurrency),
+			maxWaitTime: pc.MaxWaitTime,
+			waiters:     metrics.WithBulkheadWaiters(workload, strategy),
+			waitTimes:   metrics.WithBulkheadWaitTimes(workload, strategy),
+		}
 	} else if c.CircuitBreakerConfig != nil {
 		pc := c.CircuitBreakerConfig
 		builder := circuitbreaker.NewBuilder[*http.Response]()
@@ -72,19 +68,34 @@ func (c *Config) ToPolicy(metrics *metrics.Metrics, strategyMetrics *metrics.Str
 		} else if pc.FailureThresholdingPeriod != 0 && pc.FailureRateThreshold != 0 {
 			builder.WithFailureRateThreshold(pc.FailureRateThreshold, pc.FailureExecutionThreshold, pc.FailureThresholdingPeriod)
 		}
-		return builder.WithDelay(pc.Delay).
+		stateMetric := metrics.WithCircuitBreakerState(workload, strategy)
+		stateMetric.Set(0)
+		cb := &probedCircuitBreaker[*http.Response]{
+			probes: func(outcome string) prometheus.Counter {
+				return metrics.WithCircuitBreakerProbes(workload, strategy, outcome)
+			},
+		}
+		if pc.HalfOpenProbeRPS != 0 {
+			cb.probeLimiter = ratelimiter.NewSmooth[*http.Response](pc.HalfOpenProbeRPS, time.Second)
+		}
+		cb.CircuitBreaker = builder.WithDelay(pc.Delay).
 			WithSuccessThresholdRatio(pc.SuccessThreshold, pc.SuccessThresholdingCapacity).
 			OnOpen(func(event circuitbreaker.StateChangedEvent) {
 				metrics.WithThrottleProbability(workload, strategy).Set(1)
+				stateMetric.Set(2)
+			}).
+			OnHalfOpen(func(event circuitbreaker.StateChangedEvent) {
+				stateMetric.Set(1)
 			}).
 			OnClose(func(event circuitbreaker.StateChangedEvent) {
 				metrics.WithThrottleProbability(workload, strategy).Set(0)
+				stateMetric.Set(0)
 			}).
 			Build()
+		return cb
 	} else if c.AdaptiveLimiterConfig != nil {
 		lc := c.AdaptiveLimiterConfig
 		metrics.WithConcurrencyLimit(workload, strategy).Set(float64(lc.InitialLimit))
-		// log := slog.New(zapslog.NewHandler(logger.Core()))
 		builder := adaptivelimiter.NewBuilder[*http.Response]().
 			WithLimits(lc.MinLimit, lc.MaxLimit, lc.InitialLimit).
 			WithMaxLimitFactor(lc.MaxLimitFactor).
@@ -92,17 +103,16 @@ func (c *Config) ToPolicy(metrics *metrics.Metrics, strategyMetrics *metrics.Str
 			WithRecentQuantile(lc.RecentQuantile).
 			WithBaselineWindow(lc.BaselineWindowAge).
 			WithCorrelationWindow(lc.CorrelationWindowSize).
-			//WithLogger(log).
+			// The limiter's debug logging is used to record the signals behind its limit changes
+			WithLogger(newLimitUpdateLogger(metrics.WithAdaptiveLimiter(workload, strategy), slogger.Handler())).
 			OnLimitChanged(func(e adaptivelimiter.LimitChangedEvent) {
 				metrics.WithConcurrencyLimit(workload, strategy).Set(float64(e.NewLimit))
 			})
 		if lc.InitialRejectionFactor > 0 && lc.MaxRejectionFactor > 0 {
 			builder.WithQueueing(lc.InitialRejectionFactor, lc.MaxRejectionFactor)
 		}
-		if limiterPrioritizer != nil {
-			return builder.
-				// WithLogger(log.With("workload", workload)).
-				BuildPrioritized(limiterPrioritizer)
+		if _, prioritizer := prioritizers.forPolicy(c); prioritizer != nil {
+			return builder.BuildPrioritized(prioritizer)
 		} else {
 			return builder.Build()
 		}
@@ -111,13 +121,20 @@ func (c *Config) ToPolicy(metrics *metrics.Metrics, strategyMetrics *metrics.Str
 		builder := adaptivethrottler.NewBuilder[*http.Response]().
 			WithFailureRateThreshold(tc.FailureRateThreshold, tc.ExecutionThreshold, tc.ThresholdingPeriod).
 			WithMaxRejectionRate(tc.MaxRejectionRate)
-		if throttlerPrioritizer != nil {
-			return builder.
-				// WithLogger(log.With("workload", workload)).
-				BuildPrioritized(throttlerPrioritizer)
+		if _, prioritizer := prioritizers.forPolicy(c); prioritizer != nil {
+			return builder.BuildPrioritized(prioritizer)
 		} else {
 			return builder.Build()
 		}
+	} else if c.QueueConfig != nil {
+		return newQueue[*http.Response](c.QueueConfig, metrics.WithClientQueueSize(workload, strategy), metrics.WithClientQueueWaitTimes(workload, strategy),
+			func(reason string) prometheus.Counter {
+				return metrics.WithClientQueueShed(workload, strategy, reason)
+			}, metrics.WithClientQueueBoosted(workload, strategy))
+	} else if c.RetryConfig != nil {
+		return newRetryPolicy(c.RetryConfig, func(event string) prometheus.Counter {
+			return metrics.WithRetries(workload, strategy, event)
+		}, metrics.WithRetryBudgetUsed(workload, strategy))
 	} else if c.VegasConfig != nil {
 		metrics.WithConcurrencyLimit(workload, strategy).Set(float64(c.VegasConfig.InitialLimit))
 		return c.VegasConfig.Build(slogger, limitChangedListener)
@@ -132,64 +149,94 @@ func (c *Config) ToPolicy(metrics *metrics.Metrics, strategyMetrics *metrics.Str
 	return nil
 }
 
-func (c Configs) ToExecutors(strategy string, shareStrategies bool, stages []*client.Stage, workloads []*client.Workload, metrics *metrics.Metrics, strategyMetrics *metrics.StrategyMetrics, limiterPrioritizer priority.Prioritizer, throttlerPrioritizer priority.Prioritizer, logger *zap.Logger) (map[string]failsafe.Executor[*http.Response], time.Duration) {
+// instance returns the name of the policy instance that a workload uses, which is the defaultInstance unless the policy
+// is explicitly scoped or is a shared rate limiter.
+func (c *Config) instance(workload string, defaultInstance string) string {
+	if c.RateLimiterConfig != nil && c.RateLimiterConfig.Shared != "" {
+		return c.RateLimiterConfig.Shared
+	}
+	if c.CircuitBreakerConfig != nil && workload != "staged" {
+		switch c.CircuitBreakerConfig.Scope {
+		case SharedScope:
+			return "shared"
+		case WorkloadScope:
+			return workload
+		}
+	}
+	return defaultInstance
+}
+
+// ToExecutors builds an executor for each workload, returning the executors, the minimum timeout among the policies, and
+// a description of each workload's policy chain.
+func (c Configs) ToExecutors(strategy string, shareStrategies bool, stages []*client.Stage, workloads []*client.Workload, metrics *metrics.Metrics, strategyMetrics *metrics.StrategyMetrics, prioritizers *Prioritizers, sharedRateLimiters *SharedRateLimiters, logger *zap.Logger) (map[string]failsafe.Executor[*http.Response], time.Duration, map[string]Chain) {
 	var minTimeout time.Duration
-	var onDoneFuncs []func()
 	workloadExecutors := make(map[string]failsafe.Executor[*http.Response])
-
-	buildPolicies := func(name string) []failsafe.Policy[*http.Response] {
-		metrics.WithThrottleProbability(name, strategy).Set(0)
-
-		var policies []failsafe.Policy[*http.Response]
-		for _, config := range c {
-			policy := config.ToPolicy(metrics, strategyMetrics, limiterPrioritizer, throttlerPrioritizer, name, strategy, logger)
-			policies = append(policies, policy)
-
-			if config.Timeout != 0 {
-				policyTimeout := config.Timeout
-				if minTimeout == 0 {
-					minTimeout = policyTimeout
-				} else {
-					minTimeout = min(minTimeout, policyTimeout)
+	workloadChains := make(map[string]Chain)
+
+	// Policy instances are created once per config and instance name, and are shared by any workloads that use them
+	instances := make(map[string]failsafe.Policy[*http.Response])
+	onDoneFuncs := make(map[string]func())
+	buildChain := func(workload string, defaultInstance string) Chain {
+		var chain Chain
+		for i, config := range c {
+			name := config.instance(workload, defaultInstance)
+			key := fmt.Sprintf("%d/%s", i, name)
+			policy, ok := instances[key]
+			if !ok {
+				metrics.WithThrottleProbability(name, strategy).Set(0)
+				policy = config.ToPolicy(metrics, strategyMetrics, prioritizers, sharedRateLimiters, name, strategy, logger)
+				instances[key] = policy
+				if config.Timeout != 0 {
+					policyTimeout := config.Timeout
+					if minTimeout == 0 {
+						minTimeout = policyTimeout
+					} else {
+						minTimeout = min(minTimeout, policyTimeout)
+					}
+				} else if config.AdaptiveLimiterConfig != nil {
+					onDoneFuncs[key] = func() {
+						p := policy.(adaptivelimiter.Metrics)
+						metrics.WithConcurrencyLimit(name, strategy).Set(float64(p.Limit()))
+						metrics.WithQueueWorkload(name, strategy).Set(float64(p.Queued()))
+					}
+				} else if config.AdaptiveThrottlerConfig != nil {
+					onDoneFuncs[key] = func() {
+						p := policy.(adaptivethrottler.Metrics)
+						metrics.WithThrottleProbability(name, strategy).Set(p.RejectionRate())
+					}
 				}
-			} else if config.AdaptiveLimiterConfig != nil {
-				onDoneFuncs = append(onDoneFuncs, func() {
-					p := policy.(adaptivelimiter.Metrics)
-					metrics.WithConcurrencyLimit(name, strategy).Set(float64(p.Limit()))
-					metrics.WithQueueWorkload(name, strategy).Set(float64(p.Queued()))
-				})
-			} else if config.AdaptiveThrottlerConfig != nil {
-				onDoneFuncs = append(onDoneFuncs, func() {
-					p := policy.(adaptivethrottler.Metrics)
-					metrics.WithThrottleProbability(name, strategy).Set(p.RejectionRate())
-				})
 			}
+			policyType, params := config.Parameters()
+			prioritizerName, prioritizer := prioritizers.forPolicy(config)
+			chain = append(chain, &ChainEntry{
+				Type:        policyType,
+				Instance:    name,
+				Prioritized: prioritizer != nil,
+				Prioritizer: prioritizerName,
+				Parameters:  params,
+				policy:      policy,
+				onDone:      onDoneFuncs[key],
+			})
 		}
-		return policies
+		return chain
 	}
 
-	buildWorkloads := func(workload string, policies []failsafe.Policy[*http.Response]) {
-		workloadExecutors[workload] = failsafe.With(policies...).OnDone(func(e failsafe.ExecutionDoneEvent[*http.Response]) {
-			for _, onDoneFunc := range onDoneFuncs {
-				onDoneFunc()
-			}
-		})
+	buildWorkloads := func(workload string, chain Chain) {
+		workloadChains[workload] = chain
+		workloadExecutors[workload] = chain.Executor()
 	}
 
 	if len(stages) > 0 {
-		buildWorkloads("staged", buildPolicies("staged"))
+		buildWorkloads("staged", buildChain("staged", "staged"))
 	} else {
-		if shareStrategies {
-			policies := buildPolicies("shared")
-			for _, workload := range workloads {
-				buildWorkloads(workload.Name, policies)
-			}
-		} else {
-			for _, workload := range workloads {
-				buildWorkloads(workload.Name, buildPolicies(workload.Name))
+		for _, workload := range workloads {
+			defaultInstance := workload.Name
+			if shareStrategies {
+				defaultInstance = "shared"
 			}
+			buildWorkloads(workload.Name, buildChain(workload.Name, defaultInstance))
 		}
 	}
 
-	return workloadExecutors, minTimeout
+	return workloadExecutors, minTimeout, workloadChains
 }
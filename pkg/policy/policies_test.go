@@ -0,0 +1,51 @@
+package policy
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+
+	"tripwire/pkg/client"
+	"tripwire/pkg/metrics"
+)
+
+// testMetrics are shared by the package's tests, since metrics can only be registered once.
+var testMetrics = metrics.New(nil, zap.NewNop().Sugar())
+
+// BenchmarkExecutorChain measures the overhead of executing through a typical chain of client policies, which is paid by
+// every request.
+func BenchmarkExecutorChain(b *testing.B) {
+	var configs Configs
+	require.NoError(b, yaml.Unmarshal([]byte(`
+- timeout: 1s
+- circuitbreaker:
+    failure_threshold: 100
+- adaptivelimiter:
+    max_limit: 1000
+    initial_limit: 1000
+    max_limit_factor: 5
+    recent_window_min_duration: 1s
+    recent_window_max_duration: 1s
+    recent_window_min_samples: 10
+    baseline_window_age: 10
+    correlation_window_size: 20
+- bulkhead:
+    max_concurrency: 1000
+`), &configs))
+	m := testMetrics
+	workloads := []*client.Workload{{Name: "bench"}}
+	executors, _, _ := configs.ToExecutors("bench", false, nil, workloads, m, m.WithStrategy("bench", "bench"), nil, nil, zap.NewNop())
+	executor := executors["bench"]
+	resp := &http.Response{StatusCode: http.StatusOK}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = executor.Get(func() (*http.Response, error) {
+			return resp, nil
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package policy
+
+import (
+	"fmt"
+
+	"github.com/failsafe-go/failsafe-go/priority"
+)
+
+const (
+	LimiterPrioritizer   = "adaptivelimiter"
+	ThrottlerPrioritizer = "adaptivethrottler"
+)
+
+// PrioritizerConfig declares a named prioritizer. Prioritized policies that are bound to the same named prioritizer share
+// a priority domain, while policies bound to different prioritizers are prioritized independently.
+type PrioritizerConfig struct {
+	Type string `yaml:"type"` // adaptivelimiter or adaptivethrottler
+}
+
+// ValidatePrioritizers returns an error if any of the prioritizer configs are invalid.
+func ValidatePrioritizers(prioritizers map[string]*PrioritizerConfig) error {
+	for name, config := range prioritizers {
+		if config == nil || (config.Type != LimiterPrioritizer && config.Type != ThrottlerPrioritizer) {
+			return fmt.Errorf("prioritizer %s must have a type of %s or %s", name, LimiterPrioritizer, ThrottlerPrioritizer)
+		}
+	}
+	return nil
+}
+
+// Prioritizers are the prioritizers that prioritized policies are built with. Adaptive limiters and throttlers that
+// don't name a prioritizer use the default Limiter or Throttler prioritizer.
+type Prioritizers struct {
+	Limiter   priority.Prioritizer
+	Throttler priority.Prioritizer
+	Named     map[string]priority.Prioritizer
+}
+
+// forPolicy returns the name and instance of the prioritizer that a policy is built with, if any.
+func (p *Prioritizers) forPolicy(config *Config) (string, priority.Prioritizer) {
+	if p == nil {
+		return "", nil
+	}
+	var name string
+	var prioritizer priority.Prioritizer
+	if config.AdaptiveLimiterConfig != nil {
+		name, prioritizer = config.AdaptiveLimiterConfig.Prioritizer, p.Limiter
+	} else if config.AdaptiveThrottlerConfig != nil {
+		name, prioritizer = config.AdaptiveThrottlerConfig.Prioritizer, p.Throttler
+	} else {
+		return "", nil
+	}
+	if name != "" {
+		prioritizer = p.Named[name]
+	}
+	if prioritizer == nil {
+		return "", nil
+	}
+	return name, prioritizer
+}
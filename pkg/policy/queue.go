@@ -0,0 +1,294 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/failsafe-go/failsafe-go"
+	"github.com/failsafe-go/failsafe-go/adaptivelimiter"
+	"github.com/failsafe-go/failsafe-go/bulkhead"
+	"github.com/failsafe-go/failsafe-go/common"
+	"github.com/failsafe-go/failsafe-go/policy"
+	"github.com/failsafe-go/failsafe-go/priority"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/yaml.v3"
+
+	"tripwire/pkg/tracing"
+)
+
+const (
+	QueueFIFO     = "fifo"     // the oldest queued execution is admitted first
+	QueueLIFO     = "lifo"     // the youngest queued execution is admitted first
+	QueuePriority = "priority" // the highest priority queued execution is admitted first, then the oldest
+
+	ShedOldest   = "oldest"   // a full queue sheds its oldest execution to make room for a new one
+	ShedYoungest = "youngest" // a full queue sheds the new execution
+
+	shedReasonAge      = "age"
+	shedReasonOverflow = "overflow"
+)
+
+// ErrShed is returned, along with the limiter's rejection, for executions that a queue shed.
+var ErrShed = errors.New("shed from queue")
+
+// QueueConfig queues executions that the concurrency limiters after it in a chain reject, such as an adaptivelimiter or
+// bulkhead, and admits a queued execution each time an execution completes. Queued executions are shed once they
+// exceed the max age, or when the queue is full.
+type QueueConfig struct {
+	MaxSize uint          `yaml:"max_size"` // the most executions that can be queued
+	MaxAge  time.Duration `yaml:"max_age"`  // queued executions are shed after this long. 0 never sheds by age.
+	Order   string        `yaml:"order"`    // fifo, lifo, or priority
+	Shed    string        `yaml:"shed"`     // oldest or youngest, when the queue is full
+
+	// AgeBoost raises the priority of a queued execution by one level each time it's queued for this long, up to very
+	// high, so that low priority executions aren't starved under sustained load. Requires priority order. 0 admits
+	// executions in strict priority order.
+	AgeBoost time.Duration `yaml:"age_boost"`
+}
+
+func (c *QueueConfig) UnmarshalYAML(value *yaml.Node) error {
+	*c = QueueConfig{
+		MaxSize: 1000,
+		MaxAge:  time.Second,
+		Order:   QueueFIFO,
+		Shed:    ShedYoungest,
+	}
+	type Alias QueueConfig
+	var alias = Alias(*c)
+	if err := value.Decode(&alias); err != nil {
+		return err
+	}
+	*c = QueueConfig(alias)
+	return nil
+}
+
+func (c *QueueConfig) Validate() error {
+	if c.Order != QueueFIFO && c.Order != QueueLIFO && c.Order != QueuePriority {
+		return fmt.Errorf("invalid queue order: %s", c.Order)
+	}
+	if c.AgeBoost < 0 {
+		return errors.New("queue age_boost cannot be negative")
+	}
+	if c.AgeBoost != 0 && c.Order != QueuePriority {
+		return errors.New("queue age_boost requires priority order")
+	}
+	if c.Shed != ShedOldest && c.Shed != ShedYoungest {
+		return fmt.Errorf("invalid queue shed: %s", c.Shed)
+	}
+	if c.MaxSize == 0 {
+		return errors.New("queue max_size must be greater than 0")
+	}
+	return nil
+}
+
+// queue is a policy that implements QueueConfig.
+type queue[R any] struct {
+	config    *QueueConfig
+	size      prometheus.Gauge
+	waitTimes prometheus.Observer
+	shed      func(reason string) prometheus.Counter
+	boosted   prometheus.Counter // admissions of executions whose priority was boosted by their age
+
+	mtx     sync.Mutex
+	waiters []*queueWaiter // Guarded by mtx, ordered from oldest to youngest
+}
+
+type queueWaiter struct {
+	enqueued time.Time
+	priority priority.Priority
+	admitted chan bool // receives true when the waiter should retry, or false if it was shed
+}
+
+func newQueue[R any](config *QueueConfig, size prometheus.Gauge, waitTimes prometheus.Observer, shed func(reason string) prometheus.Counter, boosted prometheus.Counter) *queue[R] {
+	size.Set(0)
+	return &queue[R]{
+		config:    config,
+		size:      size,
+		waitTimes: waitTimes,
+		shed:      shed,
+		boosted:   boosted,
+	}
+}
+
+func (q *queue[R]) ToExecutor(_ R) any {
+	e := &queueExecutor[R]{
+		BaseExecutor: &policy.BaseExecutor[R]{},
+		queue:        q,
+	}
+	e.Executor = e
+	return e
+}
+
+type queueExecutor[R any] struct {
+	*policy.BaseExecutor[R]
+	*queue[R]
+}
+
+var _ policy.Executor[any] = &queueExecutor[any]{}
+
+func (e *queueExecutor[R]) Apply(innerFn func(failsafe.Execution[R]) *common.PolicyResult[R]) func(failsafe.Execution[R]) *common.PolicyResult[R] {
+	return func(exec failsafe.Execution[R]) *common.PolicyResult[R] {
+		execInternal := exec.(policy.ExecutionInternal[R])
+		var enqueued time.Time
+		for {
+			result := innerFn(exec)
+			if canceled, cancelResult := execInternal.IsCanceledWithResult(); canceled {
+				return cancelResult
+			}
+			if !isSaturated(result.Error) {
+				if !enqueued.IsZero() {
+					waited := time.Since(enqueued)
+					e.waitTimes.Observe(waited.Seconds())
+					tracing.RecordSpan(exec.Context(), "policy queue", enqueued, waited)
+				}
+				// The execution released its permit, so a queued execution can be admitted
+				e.admitNext()
+				return result
+			}
+
+			// Wait for an execution to complete, retaining the execution's place in the queue if it was already queued
+			if enqueued.IsZero() {
+				enqueued = time.Now()
+			}
+			waiter, ok := e.enqueue(enqueued, max(priority.FromContext(exec.Context()), priority.VeryLow))
+			if !ok {
+				return e.shedResult(shedReasonOverflow, result.Error)
+			}
+			if admitted, reason := e.await(exec, waiter); !admitted {
+				if reason == "" {
+					_, cancelResult := execInternal.IsCanceledWithResult()
+					return cancelResult
+				}
+				return e.shedResult(reason, result.Error)
+			}
+		}
+	}
+}
+
+// isSaturated returns whether err is a rejection from a concurrency limiter, which admits more executions as executions
+// complete.
+func isSaturated(err error) bool {
+	return errors.Is(err, adaptivelimiter.ErrExceeded) || errors.Is(err, bulkhead.ErrFull)
+}
+
+// enqueue adds a waiter with a priority that was enqueued at a time, in order, returning false if the waiter was shed
+// because the queue is full.
+func (q *queue[R]) enqueue(enqueued time.Time, p priority.Priority) (*queueWaiter, bool) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	if len(q.waiters) >= int(q.config.MaxSize) {
+		if q.config.Shed == ShedYoungest {
+			return nil, false
+		}
+		oldest := q.waiters[0]
+		q.waiters = q.waiters[1:]
+		oldest.admitted <- false
+	}
+	waiter := &queueWaiter{enqueued: enqueued, priority: p, admitted: make(chan bool, 1)}
+	i, _ := slices.BinarySearchFunc(q.waiters, enqueued, func(w *queueWaiter, t time.Time) int {
+		return w.enqueued.Compare(t)
+	})
+	q.waiters = slices.Insert(q.waiters, i, waiter)
+	q.size.Set(float64(len(q.waiters)))
+	return waiter, true
+}
+
+// await waits for the waiter to be admitted, returning false with the reason that it was shed, or with no reason if the
+// execution was canceled.
+func (q *queue[R]) await(exec failsafe.Execution[R], waiter *queueWaiter) (bool, string) {
+	var expired <-chan time.Time
+	if q.config.MaxAge != 0 {
+		timer := time.NewTimer(q.config.MaxAge - time.Since(waiter.enqueued))
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case admitted := <-waiter.admitted:
+		if !admitted {
+			return false, shedReasonOverflow
+		}
+		return true, ""
+	case <-expired:
+		if q.remove(waiter) {
+			return false, shedReasonAge
+		}
+	case <-exec.Canceled():
+		if q.remove(waiter) {
+			return false, ""
+		}
+	}
+	// The waiter was admitted or shed concurrently
+	if admitted := <-waiter.admitted; !admitted {
+		return false, shedReasonOverflow
+	}
+	return true, ""
+}
+
+// admitNext admits the next queued waiter, if any, according to the queue's order.
+func (q *queue[R]) admitNext() {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	if len(q.waiters) == 0 {
+		return
+	}
+	var i int
+	switch q.config.Order {
+	case QueueLIFO:
+		i = len(q.waiters) - 1
+	case QueuePriority:
+		i = q.highestPriority(time.Now())
+	}
+	waiter := q.waiters[i]
+	q.waiters = slices.Delete(q.waiters, i, i+1)
+	q.size.Set(float64(len(q.waiters)))
+	waiter.admitted <- true
+}
+
+// highestPriority returns the index of the oldest waiter with the highest priority after boosting by age, recording
+// whether its priority was boosted.
+func (q *queue[R]) highestPriority(now time.Time) int {
+	var highest int
+	var highestPriority priority.Priority = -1
+	for i, waiter := range q.waiters {
+		if p := q.boostedPriority(waiter, now); p > highestPriority {
+			highest, highestPriority = i, p
+		}
+	}
+	if highestPriority > q.waiters[highest].priority {
+		q.boosted.Inc()
+	}
+	return highest
+}
+
+// boostedPriority returns a waiter's priority, raised by a level for each age boost that it has been queued for.
+func (q *queue[R]) boostedPriority(waiter *queueWaiter, now time.Time) priority.Priority {
+	if q.config.AgeBoost == 0 {
+		return waiter.priority
+	}
+	boosts := now.Sub(waiter.enqueued) / q.config.AgeBoost
+	return priority.Priority(min(int64(waiter.priority)+int64(boosts), int64(priority.VeryHigh)))
+}
+
+// remove removes a waiter from the queue, returning false if it was no longer queued.
+func (q *queue[R]) remove(waiter *queueWaiter) bool {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	i := slices.Index(q.waiters, waiter)
+	if i < 0 {
+		return false
+	}
+	q.waiters = slices.Delete(q.waiters, i, i+1)
+	q.size.Set(float64(len(q.waiters)))
+	return true
+}
+
+func (q *queue[R]) shedResult(reason string, rejection error) *common.PolicyResult[R] {
+	q.shed(reason).Inc()
+	return &common.PolicyResult[R]{
+		Error: fmt.Errorf("%w: %w", ErrShed, rejection),
+		Done:  true,
+	}
+}
@@ -0,0 +1,148 @@
+package policy
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/failsafe-go/failsafe-go"
+	"github.com/failsafe-go/failsafe-go/bulkhead"
+	"github.com/failsafe-go/failsafe-go/priority"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestQueue(config *QueueConfig) (*queue[any], map[string]prometheus.Counter) {
+	shed := make(map[string]prometheus.Counter)
+	var mtx sync.Mutex
+	return newQueue[any](config, prometheus.NewGauge(prometheus.GaugeOpts{Name: "size"}),
+		prometheus.NewHistogram(prometheus.HistogramOpts{Name: "wait_times"}), func(reason string) prometheus.Counter {
+			mtx.Lock()
+			defer mtx.Unlock()
+			if shed[reason] == nil {
+				shed[reason] = prometheus.NewCounter(prometheus.CounterOpts{Name: "shed"})
+			}
+			return shed[reason]
+		}, prometheus.NewCounter(prometheus.CounterOpts{Name: "boosted"})), shed
+}
+
+func TestQueueAdmitsQueuedExecutions(t *testing.T) {
+	q, _ := newTestQueue(&QueueConfig{MaxSize: 10, MaxAge: time.Second, Order: QueueFIFO, Shed: ShedYoungest})
+	executor := failsafe.With[any](q, bulkhead.New[any](1))
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- executor.Run(func() error {
+				time.Sleep(20 * time.Millisecond)
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+}
+
+func TestQueueSheds(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *QueueConfig
+		reason string
+	}{
+		{"overflow", &QueueConfig{MaxSize: 1, MaxAge: time.Second, Order: QueueFIFO, Shed: ShedYoungest}, shedReasonOverflow},
+		{"age", &QueueConfig{MaxSize: 10, MaxAge: 10 * time.Millisecond, Order: QueueFIFO, Shed: ShedYoungest}, shedReasonAge},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			q, shed := newTestQueue(tc.config)
+			executor := failsafe.With[any](q, bulkhead.New[any](1))
+			release := make(chan struct{})
+			go executor.Run(func() error {
+				<-release
+				return nil
+			})
+			time.Sleep(10 * time.Millisecond)
+
+			var wg sync.WaitGroup
+			var shedErrs int
+			var mtx sync.Mutex
+			for i := 0; i < 2; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					err := executor.Run(func() error { return nil })
+					if errors.Is(err, ErrShed) {
+						assert.ErrorIs(t, err, bulkhead.ErrFull)
+						mtx.Lock()
+						shedErrs++
+						mtx.Unlock()
+					}
+				}()
+			}
+			time.Sleep(50 * time.Millisecond)
+			close(release)
+			wg.Wait()
+			assert.GreaterOrEqual(t, shedErrs, 1)
+			assert.NotNil(t, shed[tc.reason])
+		})
+	}
+}
+
+func TestQueuePriorityOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		ageBoost time.Duration
+		admitted []priority.Priority
+		boosted  float64
+	}{
+		{"strict", 0, []priority.Priority{priority.High, priority.Medium, priority.Medium, priority.Low}, 0},
+		// The low priority waiter has been queued long enough to be boosted to medium, and is older than the medium waiters
+		{"age boost", 10 * time.Millisecond, []priority.Priority{priority.High, priority.Low, priority.Medium, priority.Medium}, 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			q, _ := newTestQueue(&QueueConfig{MaxSize: 10, Order: QueuePriority, Shed: ShedYoungest, AgeBoost: tc.ageBoost})
+			now := time.Now()
+			var waiters []*queueWaiter
+			for _, w := range []struct {
+				age time.Duration
+				p   priority.Priority
+			}{{15 * time.Millisecond, priority.Low}, {time.Millisecond, priority.Medium}, {time.Millisecond, priority.High}, {0, priority.Medium}} {
+				waiter, ok := q.enqueue(now.Add(-w.age), w.p)
+				assert.True(t, ok)
+				waiters = append(waiters, waiter)
+			}
+
+			var admitted []priority.Priority
+			for range waiters {
+				q.admitNext()
+				for _, waiter := range waiters {
+					select {
+					case <-waiter.admitted:
+						admitted = append(admitted, waiter.priority)
+					default:
+					}
+				}
+			}
+			assert.Equal(t, tc.admitted, admitted)
+			assert.Equal(t, tc.boosted, testutil.ToFloat64(q.boosted))
+		})
+	}
+}
+
+func TestQueueConfigValidate(t *testing.T) {
+	config := &QueueConfig{MaxSize: 10, Order: QueueFIFO, Shed: ShedYoungest, AgeBoost: time.Second}
+	assert.ErrorContains(t, config.Validate(), "age_boost requires priority order")
+	config.Order = QueuePriority
+	assert.NoError(t, config.Validate())
+	config.AgeBoost = -time.Second
+	assert.ErrorContains(t, config.Validate(), "age_boost cannot be negative")
+}
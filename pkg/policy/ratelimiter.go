@@ -0,0 +1,166 @@
+package policy
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/failsafe-go/failsafe-go"
+	"github.com/failsafe-go/failsafe-go/common"
+	"github.com/failsafe-go/failsafe-go/policy"
+	"github.com/failsafe-go/failsafe-go/ratelimiter"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// instrumentedRateLimiter is a rate limiter that records how many executions are waiting for a permit and how long they
+// wait, and optionally rejects executions when too many are already waiting.
+type instrumentedRateLimiter[R any] struct {
+	*rateLimiterState[R]
+	waiters   prometheus.Gauge
+	waitTimes prometheus.Observer
+	permits   prometheus.Counter // counts acquired permits, if the rate limiter is shared
+	rejected  prometheus.Counter // counts rejected executions, if the rate limiter is shared
+}
+
+// rateLimiterState is the rate and waiters of a rate limiter, which may be shared by multiple instrumentedRateLimiters.
+//
+// When a warm-up is configured, the rate starts at warmUpRPS and ramps linearly to rps over the warm-up, similar to
+// Guava's SmoothWarmingUp rate limiter, by replacing the underlying rate limiter as the rate changes.
+type rateLimiterState[R any] struct {
+	newRateLimiter func(rps uint) ratelimiter.RateLimiter[R]
+	rps            uint
+	warmUp         time.Duration
+	warmUpRPS      uint
+	start          time.Time
+	current        atomic.Pointer[rateLimiterAtRate[R]]
+
+	maxWaitTime time.Duration
+	maxWaiters  uint
+	waiting     atomic.Int64
+	rateLimit   prometheus.Gauge
+}
+
+type rateLimiterAtRate[R any] struct {
+	ratelimiter.RateLimiter[R]
+	rps uint
+}
+
+func newInstrumentedRateLimiter[R any](config *RateLimiterConfig, rateLimit prometheus.Gauge, waiters prometheus.Gauge, waitTimes prometheus.Observer) *instrumentedRateLimiter[R] {
+	return &instrumentedRateLimiter[R]{
+		rateLimiterState: newRateLimiterState[R](config, rateLimit),
+		waiters:          waiters,
+		waitTimes:        waitTimes,
+	}
+}
+
+func newRateLimiterState[R any](config *RateLimiterConfig, rateLimit prometheus.Gauge) *rateLimiterState[R] {
+	r := &rateLimiterState[R]{
+		newRateLimiter: func(rps uint) ratelimiter.RateLimiter[R] {
+			if config.Type == Bursty {
+				return ratelimiter.NewBursty[R](rps, time.Second)
+			}
+			return ratelimiter.NewSmooth[R](rps, time.Second)
+		},
+		rps:         config.RPS,
+		warmUp:      config.WarmUp,
+		warmUpRPS:   config.WarmUpRPS,
+		start:       time.Now(),
+		maxWaitTime: config.MaxWaitTime,
+		maxWaiters:  config.MaxWaiters,
+		rateLimit:   rateLimit,
+	}
+	if r.warmUpRPS == 0 {
+		r.warmUpRPS = max(1, r.rps/3)
+	}
+	rps := r.rps
+	if r.warmUp != 0 {
+		rps = r.warmUpRPS
+	}
+	r.current.Store(&rateLimiterAtRate[R]{r.newRateLimiter(rps), rps})
+	rateLimit.Set(float64(rps))
+	return r
+}
+
+// rateLimiter returns the rate limiter for the current rate.
+func (r *rateLimiterState[R]) rateLimiter() ratelimiter.RateLimiter[R] {
+	current := r.current.Load()
+	if current.rps == r.rps {
+		return current.RateLimiter
+	}
+
+	rps := r.rps
+	if elapsed := time.Since(r.start); elapsed < r.warmUp {
+		progress := float64(elapsed) / float64(r.warmUp)
+		rps = uint(float64(r.warmUpRPS) + (float64(r.rps)-float64(r.warmUpRPS))*progress)
+	}
+	if rps != current.rps && r.current.CompareAndSwap(current, &rateLimiterAtRate[R]{r.newRateLimiter(rps), rps}) {
+		r.rateLimit.Set(float64(rps))
+	}
+	return r.current.Load().RateLimiter
+}
+
+func (r *instrumentedRateLimiter[R]) ToExecutor(_ R) any {
+	e := &rateLimiterExecutor[R]{
+		BaseExecutor:            &policy.BaseExecutor[R]{},
+		instrumentedRateLimiter: r,
+	}
+	e.Executor = e
+	return e
+}
+
+type rateLimiterExecutor[R any] struct {
+	*policy.BaseExecutor[R]
+	*instrumentedRateLimiter[R]
+}
+
+var _ policy.Executor[any] = &rateLimiterExecutor[any]{}
+
+func (e *rateLimiterExecutor[R]) Apply(innerFn func(failsafe.Execution[R]) *common.PolicyResult[R]) func(failsafe.Execution[R]) *common.PolicyResult[R] {
+	return func(exec failsafe.Execution[R]) *common.PolicyResult[R] {
+		if err := e.acquirePermit(exec); err != nil {
+			// Check for cancellation while waiting for a permit
+			if canceled, cancelResult := exec.(policy.ExecutionInternal[R]).IsCanceledWithResult(); canceled {
+				return cancelResult
+			}
+			return &common.PolicyResult[R]{
+				Error: err,
+				Done:  true,
+			}
+		}
+		return innerFn(exec)
+	}
+}
+
+func (e *rateLimiterExecutor[R]) acquirePermit(exec failsafe.Execution[R]) error {
+	err := e.tryAcquirePermit(exec)
+	if e.permits != nil {
+		if err == nil {
+			e.permits.Inc()
+		} else {
+			e.rejected.Inc()
+		}
+	}
+	return err
+}
+
+func (e *rateLimiterExecutor[R]) tryAcquirePermit(exec failsafe.Execution[R]) error {
+	rateLimiter := e.rateLimiter()
+	if rateLimiter.TryAcquirePermit() {
+		e.waitTimes.Observe(0)
+		return nil
+	}
+	if e.maxWaitTime == 0 {
+		return ratelimiter.ErrExceeded
+	}
+	if waiting := e.waiting.Add(1); e.maxWaiters != 0 && waiting > int64(e.maxWaiters) {
+		e.waiting.Add(-1)
+		return ratelimiter.ErrExceeded
+	}
+
+	start := time.Now()
+	e.waiters.Inc()
+	err := rateLimiter.AcquirePermitWithMaxWait(exec.Context(), e.maxWaitTime)
+	e.waiters.Dec()
+	e.waiting.Add(-1)
+	e.waitTimes.Observe(time.Since(start).Seconds())
+	return err
+}
@@ -0,0 +1,117 @@
+package policy
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"slices"
+
+	"github.com/failsafe-go/failsafe-go"
+	"github.com/failsafe-go/failsafe-go/adaptivelimiter"
+	"github.com/failsafe-go/failsafe-go/adaptivethrottler"
+	"github.com/failsafe-go/failsafe-go/bulkhead"
+	"github.com/failsafe-go/failsafe-go/circuitbreaker"
+	"github.com/failsafe-go/failsafe-go/failsafehttp"
+	"github.com/failsafe-go/failsafe-go/ratelimiter"
+	"github.com/failsafe-go/failsafe-go/retrypolicy"
+	"github.com/failsafe-go/failsafe-go/timeout"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// The outcomes of failed executions, which retries can be aborted on.
+const (
+	OutcomeRejected = "rejected"
+	OutcomeTimeout  = "timeout"
+	OutcomeFailure  = "failure"
+)
+
+// newRetryPolicy returns a retry policy that retries HTTP errors and failed responses, other than those whose outcome the
+// config aborts on or that would exceed the retry budget, and records its retries and how much of its budget is used. The
+// last failure is returned when retries are exceeded so that it's attributed the same as a failure that wasn't retried.
+func newRetryPolicy(config *RetryConfig, retries func(event string) prometheus.Counter, budgetUsed prometheus.Gauge) retrypolicy.RetryPolicy[*http.Response] {
+	var budget *retryBudget
+	if config.Budget != nil {
+		budget = newRetryBudget(config.Budget, budgetUsed)
+	}
+	abortsOn := func(resp *http.Response, err error) bool {
+		outcome := failureOutcome(resp, err)
+		return outcome != "" && slices.Contains(config.AbortOn, outcome)
+	}
+	builder := failsafehttp.NewRetryPolicyBuilder().
+		WithMaxAttempts(config.MaxAttempts).
+		WithMaxDuration(config.MaxDuration).
+		ReturnLastFailure().
+		OnRetry(func(e failsafe.ExecutionEvent[*http.Response]) {
+			// Release the connection of the response that's being retried
+			if resp := e.LastResult(); resp != nil {
+				_ = resp.Body.Close()
+			}
+			retries("retry").Inc()
+		}).
+		OnRetriesExceeded(func(failsafe.ExecutionEvent[*http.Response]) {
+			// Return the retry that was acquired when the failure wasn't aborted
+			if budget != nil {
+				budget.release()
+			}
+			retries("exceeded").Inc()
+		}).
+		OnAbort(func(e failsafe.ExecutionEvent[*http.Response]) {
+			switch {
+			case abortsOn(e.LastResult(), e.LastError()):
+				retries("aborted").Inc()
+			case config.MaxAttempts != -1 && e.Attempts() >= config.MaxAttempts:
+				// The budget was checked after the last attempt, which wouldn't have been retried anyway
+				retries("exceeded").Inc()
+			default:
+				retries("budget_exceeded").Inc()
+			}
+		})
+	if config.MaxDelay != 0 {
+		builder.WithBackoffFactor(config.Delay, config.MaxDelay, config.DelayFactor)
+	} else if config.Delay != 0 {
+		builder.WithDelay(config.Delay)
+	}
+	if config.JitterFactor != 0 {
+		builder.WithJitterFactor(config.JitterFactor)
+	}
+	if budget != nil {
+		// Budget requests when their first attempt completes, and retries when a failure isn't aborted
+		recordRequest := func(e failsafe.ExecutionEvent[*http.Response]) {
+			if e.Attempts() == 1 {
+				budget.recordRequest()
+			}
+		}
+		builder.OnSuccess(recordRequest).OnFailure(recordRequest)
+		builder.AbortIf(func(resp *http.Response, err error) bool {
+			return abortsOn(resp, err) || !budget.tryAcquire()
+		})
+	} else if len(config.AbortOn) > 0 {
+		builder.AbortIf(abortsOn)
+	}
+	return builder.Build()
+}
+
+// failureOutcome returns the outcome of a failed execution, the same as the client attributes it, or "" if it didn't fail.
+func failureOutcome(resp *http.Response, err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, ratelimiter.ErrExceeded), errors.Is(err, adaptivelimiter.ErrExceeded),
+		errors.Is(err, adaptivethrottler.ErrExceeded), errors.Is(err, bulkhead.ErrFull), errors.Is(err, circuitbreaker.ErrOpen):
+		return OutcomeRejected
+	case errors.Is(err, timeout.ErrExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return OutcomeTimeout
+	case err != nil:
+		return OutcomeFailure
+	case resp == nil:
+		return ""
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return OutcomeRejected
+	case http.StatusRequestTimeout, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return OutcomeTimeout
+	case http.StatusInternalServerError:
+		return OutcomeFailure
+	}
+	return ""
+}
@@ -0,0 +1,131 @@
+package policy
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/failsafe-go/failsafe-go"
+	"github.com/failsafe-go/failsafe-go/bulkhead"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func newTestRetryPolicy(t *testing.T, config string) (failsafe.Executor[*http.Response], map[string]prometheus.Counter) {
+	var retryConfig RetryConfig
+	require.NoError(t, yaml.Unmarshal([]byte(config), &retryConfig))
+	require.NoError(t, retryConfig.Validate())
+	retries := make(map[string]prometheus.Counter)
+	var mtx sync.Mutex
+	return failsafe.With[*http.Response](newRetryPolicy(&retryConfig, func(event string) prometheus.Counter {
+		mtx.Lock()
+		defer mtx.Unlock()
+		if retries[event] == nil {
+			retries[event] = prometheus.NewCounter(prometheus.CounterOpts{Name: "retries"})
+		}
+		return retries[event]
+	}, prometheus.NewGauge(prometheus.GaugeOpts{Name: "retry_budget_used"}))), retries
+}
+
+func response(status int) *http.Response {
+	return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(""))}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	executor, retries := newTestRetryPolicy(t, "max_attempts: 3")
+	attempts := 0
+	resp, err := executor.Get(func() (*http.Response, error) {
+		attempts++
+		return response(http.StatusTooManyRequests), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode, "the last failure is returned")
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, 2.0, testutil.ToFloat64(retries["retry"]))
+	assert.Equal(t, 1.0, testutil.ToFloat64(retries["exceeded"]))
+
+	attempts = 0
+	resp, err = executor.Get(func() (*http.Response, error) {
+		attempts++
+		if attempts == 1 {
+			return response(http.StatusServiceUnavailable), nil
+		}
+		return response(http.StatusOK), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 2, attempts)
+}
+
+func TestRetryPolicyAbortOn(t *testing.T) {
+	executor, retries := newTestRetryPolicy(t, "abort_on: [rejected]")
+	attempts := 0
+	_, err := executor.Get(func() (*http.Response, error) {
+		attempts++
+		return nil, bulkhead.ErrFull
+	})
+	assert.ErrorIs(t, err, bulkhead.ErrFull)
+	assert.Equal(t, 1, attempts)
+	assert.Equal(t, 1.0, testutil.ToFloat64(retries["aborted"]))
+
+	attempts = 0
+	resp, _ := executor.Get(func() (*http.Response, error) {
+		attempts++
+		return response(http.StatusGatewayTimeout), nil
+	})
+	assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+	assert.Equal(t, 3, attempts, "timeouts are retried")
+}
+
+func TestRetryConfigValidate(t *testing.T) {
+	assert.Error(t, (&RetryConfig{MaxAttempts: 0}).Validate())
+	assert.Error(t, (&RetryConfig{MaxAttempts: 3, Delay: 100, MaxDelay: 50, DelayFactor: 2}).Validate())
+	assert.Error(t, (&RetryConfig{MaxAttempts: 3, JitterFactor: 2}).Validate())
+	assert.Error(t, (&RetryConfig{MaxAttempts: 3, AbortOn: []string{"success"}}).Validate())
+	assert.Error(t, (&RetryConfig{MaxAttempts: 3, Budget: &RetryBudgetConfig{Window: time.Second}}).Validate())
+}
+
+func TestRetryBudget(t *testing.T) {
+	executor, retries := newTestRetryPolicy(t, `
+max_attempts: 2
+budget:
+  ratio: 0.5
+  min_retries: 1`)
+	for i := 0; i < 4; i++ {
+		_, _ = executor.Get(func() (*http.Response, error) {
+			return response(http.StatusServiceUnavailable), nil
+		})
+	}
+	// 4 requests allow 2 retries, and once the budget is used up, failures aren't retried
+	assert.Equal(t, 2.0, testutil.ToFloat64(retries["retry"]))
+	assert.Equal(t, 2.0, testutil.ToFloat64(retries["budget_exceeded"]))
+	assert.Equal(t, 2.0, testutil.ToFloat64(retries["exceeded"]))
+}
+
+func TestRetryBudgetWindow(t *testing.T) {
+	used := prometheus.NewGauge(prometheus.GaugeOpts{Name: "retry_budget_used"})
+	budget := newRetryBudget(&RetryBudgetConfig{Ratio: .1, Window: 10 * time.Second, MinRetries: 1}, used)
+	now := time.Unix(0, 0)
+	budget.now = func() time.Time { return now }
+
+	for i := 0; i < 20; i++ {
+		budget.recordRequest()
+	}
+	assert.True(t, budget.tryAcquire())
+	assert.Equal(t, .5, testutil.ToFloat64(used))
+	assert.True(t, budget.tryAcquire())
+	assert.False(t, budget.tryAcquire())
+	budget.release()
+	assert.True(t, budget.tryAcquire())
+
+	// Requests and retries expire once they slide out of the window, leaving the min retries
+	now = now.Add(10 * time.Second)
+	assert.True(t, budget.tryAcquire())
+	assert.False(t, budget.tryAcquire())
+}
@@ -0,0 +1,106 @@
+package policy
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// retryBudgetBuckets is how many buckets a retry budget's window is divided into, which it slides by.
+const retryBudgetBuckets = 10
+
+// retryBudget tracks the requests and retries over a sliding window, and allows retries until they exceed a ratio of the
+// requests, or the min retries when there are few requests.
+type retryBudget struct {
+	config     *RetryBudgetConfig
+	bucketSize time.Duration
+	used       prometheus.Gauge // the fraction of the budget that's been used
+	now        func() time.Time
+
+	mtx     sync.Mutex
+	buckets [retryBudgetBuckets]retryBudgetBucket // Guarded by mtx
+}
+
+type retryBudgetBucket struct {
+	epoch    int64 // which bucketSize interval since the Unix epoch the bucket counts
+	requests int
+	retries  int
+}
+
+func newRetryBudget(config *RetryBudgetConfig, used prometheus.Gauge) *retryBudget {
+	return &retryBudget{
+		config:     config,
+		bucketSize: max(config.Window/retryBudgetBuckets, time.Millisecond),
+		used:       used,
+		now:        time.Now,
+	}
+}
+
+// recordRequest records a request, which grows the budget.
+func (b *retryBudget) recordRequest() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.current().requests++
+	b.updateUsed()
+}
+
+// tryAcquire returns whether a retry is allowed by the budget, recording it if it is.
+func (b *retryBudget) tryAcquire() bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	bucket := b.current()
+	if requests, retries := b.totals(); float64(retries) >= b.allowed(requests) {
+		return false
+	}
+	bucket.retries++
+	b.updateUsed()
+	return true
+}
+
+// release returns a retry that was acquired but not performed to the budget.
+func (b *retryBudget) release() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if bucket := b.current(); bucket.retries > 0 {
+		bucket.retries--
+	}
+	b.updateUsed()
+}
+
+// current returns the bucket for the current time, resetting it if it last counted an earlier interval. Requires mtx.
+func (b *retryBudget) current() *retryBudgetBucket {
+	epoch := b.now().UnixNano() / int64(b.bucketSize)
+	bucket := &b.buckets[epoch%retryBudgetBuckets]
+	if bucket.epoch != epoch {
+		*bucket = retryBudgetBucket{epoch: epoch}
+	}
+	return bucket
+}
+
+// totals returns the requests and retries within the window. Requires mtx.
+func (b *retryBudget) totals() (requests int, retries int) {
+	epoch := b.now().UnixNano() / int64(b.bucketSize)
+	for _, bucket := range b.buckets {
+		if epoch-bucket.epoch < retryBudgetBuckets {
+			requests += bucket.requests
+			retries += bucket.retries
+		}
+	}
+	return requests, retries
+}
+
+// allowed returns how many retries are allowed for some requests.
+func (b *retryBudget) allowed(requests int) float64 {
+	return max(float64(b.config.MinRetries), b.config.Ratio*float64(requests))
+}
+
+// updateUsed records the fraction of the budget that's been used. Requires mtx.
+func (b *retryBudget) updateUsed() {
+	requests, retries := b.totals()
+	if allowed := b.allowed(requests); allowed > 0 {
+		b.used.Set(float64(retries) / allowed)
+	} else {
+		b.used.Set(0)
+	}
+}
@@ -0,0 +1,60 @@
+package policy
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"tripwire/pkg/metrics"
+)
+
+// ValidateSharedRateLimiters returns an error if any of the shared rate limiter configs are invalid.
+func ValidateSharedRateLimiters(configs map[string]*RateLimiterConfig) error {
+	for name, config := range configs {
+		if config == nil || config.RPS == 0 {
+			return fmt.Errorf("shared ratelimiter %s requires an rps", name)
+		}
+		if config.Shared != "" {
+			return fmt.Errorf("shared ratelimiter %s cannot reference another shared ratelimiter", name)
+		}
+	}
+	return nil
+}
+
+// SharedRateLimiters are named rate limiters whose rate and waiters are shared by the policies of multiple strategies,
+// such as to model a quota that's enforced by a common upstream. The permits that each strategy acquires are recorded
+// separately, as its share of the quota.
+type SharedRateLimiters struct {
+	configs map[string]*RateLimiterConfig
+	metrics *metrics.Metrics
+
+	mtx    sync.Mutex
+	states map[string]*rateLimiterState[*http.Response] // Guarded by mtx
+}
+
+func NewSharedRateLimiters(configs map[string]*RateLimiterConfig, metrics *metrics.Metrics) *SharedRateLimiters {
+	return &SharedRateLimiters{
+		configs: configs,
+		metrics: metrics,
+		states:  make(map[string]*rateLimiterState[*http.Response]),
+	}
+}
+
+// rateLimiter returns a rate limiter for a strategy that shares the state of the named rate limiter, which is created
+// when it's first used.
+func (s *SharedRateLimiters) rateLimiter(name string, workload string, strategy string) *instrumentedRateLimiter[*http.Response] {
+	s.mtx.Lock()
+	state, ok := s.states[name]
+	if !ok {
+		state = newRateLimiterState[*http.Response](s.configs[name], s.metrics.WithSharedRateLimit(name))
+		s.states[name] = state
+	}
+	s.mtx.Unlock()
+	return &instrumentedRateLimiter[*http.Response]{
+		rateLimiterState: state,
+		waiters:          s.metrics.WithRateLimiterWaiters(workload, strategy),
+		waitTimes:        s.metrics.WithRateLimiterWaitTimes(workload, strategy),
+		permits:          s.metrics.WithSharedRatePermits(name, strategy),
+		rejected:         s.metrics.WithSharedRateRejected(name, strategy),
+	}
+}
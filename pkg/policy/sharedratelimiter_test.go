@@ -0,0 +1,48 @@
+package policy
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/failsafe-go/failsafe-go/ratelimiter"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"tripwire/pkg/client"
+)
+
+func TestValidateSharedRateLimiters(t *testing.T) {
+	shared := map[string]*RateLimiterConfig{"upstream": {RPS: 100}}
+	assert.NoError(t, ValidateSharedRateLimiters(shared))
+	assert.Error(t, ValidateSharedRateLimiters(map[string]*RateLimiterConfig{"upstream": {}}))
+	assert.Error(t, ValidateSharedRateLimiters(map[string]*RateLimiterConfig{"upstream": {RPS: 100, Shared: "other"}}))
+
+	assert.NoError(t, Configs{{RateLimiterConfig: &RateLimiterConfig{Shared: "upstream"}}}.Validate(nil, shared))
+	assert.Error(t, Configs{{RateLimiterConfig: &RateLimiterConfig{Shared: "downstream"}}}.Validate(nil, shared))
+	assert.Error(t, Configs{{RateLimiterConfig: &RateLimiterConfig{Shared: "upstream", RPS: 10}}}.Validate(nil, shared))
+}
+
+func TestSharedRateLimiter(t *testing.T) {
+	m := testMetrics
+	shared := NewSharedRateLimiters(map[string]*RateLimiterConfig{"quota": {Type: Bursty, RPS: 3}}, m)
+	configs := Configs{{RateLimiterConfig: &RateLimiterConfig{Shared: "quota"}}}
+	workloads := []*client.Workload{{Name: "reads"}}
+	execute := func(strategy string) error {
+		executors, _, chains := configs.ToExecutors(strategy, false, nil, workloads, m, m.WithStrategy(strategy, strategy), nil, shared, zap.NewNop())
+		assert.Equal(t, "quota", chains["reads"][0].Instance)
+		_, err := executors["reads"].Get(func() (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		})
+		return err
+	}
+
+	// Strategies draw from the same quota
+	assert.NoError(t, execute("shared-a"))
+	assert.NoError(t, execute("shared-a"))
+	assert.NoError(t, execute("shared-b"))
+	assert.ErrorIs(t, execute("shared-b"), ratelimiter.ErrExceeded)
+	assert.Equal(t, 2.0, m.Value(m.WithSharedRatePermits("quota", "shared-a")))
+	assert.Equal(t, 1.0, m.Value(m.WithSharedRatePermits("quota", "shared-b")))
+	assert.Equal(t, 1.0, m.Value(m.WithSharedRateRejected("quota", "shared-b")))
+	assert.Equal(t, 3.0, m.Value(m.WithSharedRateLimit("quota")))
+}
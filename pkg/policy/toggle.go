@@ -0,0 +1,63 @@
+package policy
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/failsafe-go/failsafe-go"
+)
+
+// Index returns the index of a policy in the chain, which is identified by its position, starting at 1 as printed by
+// PrintChains, or by its type when the chain has only one policy of that type.
+func (c Chain) Index(policy string) (int, error) {
+	if position, err := strconv.Atoi(policy); err == nil {
+		if position < 1 || position > len(c) {
+			return 0, fmt.Errorf("unknown policy: %s", policy)
+		}
+		return position - 1, nil
+	}
+	index := -1
+	for i, entry := range c {
+		if entry.Type == policy {
+			if index != -1 {
+				return 0, fmt.Errorf("policy %s is ambiguous, so it must be identified by its position", policy)
+			}
+			index = i
+		}
+	}
+	if index == -1 {
+		return 0, fmt.Errorf("unknown policy: %s", policy)
+	}
+	return index, nil
+}
+
+// SetEnabled returns a copy of the chain with the policy at an index enabled or disabled. The copy shares the chain's
+// policy instances, so that a policy keeps its state, such as a circuit breaker's state or an adaptive limiter's limit,
+// while it's disabled.
+func (c Chain) SetEnabled(index int, enabled bool) Chain {
+	result := make(Chain, len(c))
+	for i, entry := range c {
+		copied := *entry
+		result[i] = &copied
+	}
+	result[index].Disabled = !enabled
+	return result
+}
+
+// SetEnabled returns copies of a strategy's workload chains with a policy enabled or disabled, along with executors that
+// are rebuilt from each chain's enabled policies. Since a strategy's workloads are built from the same policy configs,
+// a policy has the same position in each of their chains.
+func SetEnabled(chains map[string]Chain, policy string, enabled bool) (map[string]Chain, map[string]failsafe.Executor[*http.Response], error) {
+	resultChains := make(map[string]Chain)
+	executors := make(map[string]failsafe.Executor[*http.Response])
+	for workload, chain := range chains {
+		index, err := chain.Index(policy)
+		if err != nil {
+			return nil, nil, err
+		}
+		resultChains[workload] = chain.SetEnabled(index, enabled)
+		executors[workload] = resultChains[workload].Executor()
+	}
+	return resultChains, executors, nil
+}
@@ -0,0 +1,64 @@
+package policy
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/failsafe-go/failsafe-go"
+	"github.com/failsafe-go/failsafe-go/circuitbreaker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"tripwire/pkg/client"
+)
+
+func TestChainIndex(t *testing.T) {
+	chain := Chain{{Type: "timeout"}, {Type: "bulkhead"}, {Type: "bulkhead"}}
+	index, err := chain.Index("timeout")
+	require.NoError(t, err)
+	assert.Equal(t, 0, index)
+	index, err = chain.Index("3")
+	require.NoError(t, err)
+	assert.Equal(t, 2, index)
+
+	for _, policy := range []string{"bulkhead", "retry", "0", "4"} {
+		_, err = chain.Index(policy)
+		assert.Error(t, err, policy)
+	}
+}
+
+func TestSetEnabled(t *testing.T) {
+	m := testMetrics
+	configs := Configs{{Timeout: time.Second}, {CircuitBreakerConfig: &CircuitBreakerConfig{FailureThreshold: 1, Delay: time.Minute}}}
+	workloads := []*client.Workload{{Name: "reads"}, {Name: "writes"}}
+	executors, _, chains := configs.ToExecutors("toggled", false, nil, workloads, m, m.WithStrategy("toggled", "toggled"), nil, nil, zap.NewNop())
+	execute := func(executors map[string]failsafe.Executor[*http.Response], err error) error {
+		_, result := executors["reads"].Get(func() (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK}, err
+		})
+		return result
+	}
+
+	// Open the circuit breaker
+	assert.Error(t, execute(executors, errors.New("failed")))
+	assert.ErrorIs(t, execute(executors, nil), circuitbreaker.ErrOpen)
+
+	// Requests bypass the disabled circuit breaker, without changing the original chains
+	disabledChains, disabled, err := SetEnabled(chains, "circuitbreaker", false)
+	require.NoError(t, err)
+	assert.NoError(t, execute(disabled, nil))
+	assert.True(t, disabledChains["reads"][1].Disabled)
+	assert.True(t, disabledChains["writes"][1].Disabled)
+	assert.False(t, chains["reads"][1].Disabled)
+
+	// The circuit breaker is still open when it's enabled again
+	_, enabled, err := SetEnabled(disabledChains, "2", true)
+	require.NoError(t, err)
+	assert.ErrorIs(t, execute(enabled, nil), circuitbreaker.ErrOpen)
+
+	_, _, err = SetEnabled(chains, "retry", false)
+	assert.Error(t, err)
+}
@@ -0,0 +1,148 @@
+package reaction
+
+import (
+	"math"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config configures a reaction-time benchmark, which applies a step change in offered load or server capacity partway
+// through a strategy's run, then measures how long the strategy takes to settle and how much excess queueing builds up
+// during the transition.
+type Config struct {
+	At      time.Duration `yaml:"at"`      // when to apply the step, relative to the start of the strategy
+	RPS     uint          `yaml:"rps"`     // the offered load to step to, if any
+	Threads uint          `yaml:"threads"` // the server threads to step to, if any
+
+	Duration            time.Duration `yaml:"duration"`             // how long to observe the strategy after the step
+	SampleInterval      time.Duration `yaml:"sample_interval"`      // how often signals are sampled
+	StabilizationWindow time.Duration `yaml:"stabilization_window"` // how long a signal must stay within tolerance to be stable
+	Tolerance           float64       `yaml:"tolerance"`            // the allowed deviation from a signal's final value
+}
+
+func (c *Config) UnmarshalYAML(value *yaml.Node) error {
+	*c = Config{
+		Duration:            30 * time.Second,
+		SampleInterval:      time.Second,
+		StabilizationWindow: 5 * time.Second,
+		Tolerance:           0.1,
+	}
+	type Alias Config
+	var alias = Alias(*c)
+	if err := value.Decode(&alias); err != nil {
+		return err
+	}
+	*c = Config(alias)
+	return nil
+}
+
+// Signals returns the current concurrency limit, the cumulative rejected and total requests, and the number of requests
+// that are currently queued or inflight.
+type Signals func() (limit float64, rejected float64, total float64, queued float64)
+
+// Sample is a point in time observation of a strategy, relative to when the step was applied.
+type Sample struct {
+	Time          time.Duration
+	Limit         float64
+	RejectionRate float64
+	Queued        float64
+}
+
+type Result struct {
+	LimitSettleTime     time.Duration // time from the step until the limit stabilized
+	RejectionSettleTime time.Duration // time from the step until the rejection rate stabilized
+	ExcessQueueing      float64       // request-seconds of queueing above the settled level during the transition
+}
+
+// Run waits until the configured step time, applies the step, samples signals for the configured duration, and returns
+// the analyzed result.
+func Run(config *Config, step func(), signals Signals) *Result {
+	time.Sleep(config.At)
+	step()
+
+	var samples []Sample
+	start := time.Now()
+	_, lastRejected, lastTotal, _ := signals()
+	ticker := time.NewTicker(config.SampleInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		limit, rejected, total, queued := signals()
+		var rejectionRate float64
+		if total > lastTotal {
+			rejectionRate = (rejected - lastRejected) / (total - lastTotal)
+		}
+		lastRejected, lastTotal = rejected, total
+		elapsed := time.Since(start)
+		samples = append(samples, Sample{
+			Time:          elapsed,
+			Limit:         limit,
+			RejectionRate: rejectionRate,
+			Queued:        queued,
+		})
+		if elapsed >= config.Duration {
+			break
+		}
+	}
+
+	return Analyze(samples, config.StabilizationWindow, config.Tolerance)
+}
+
+// Analyze computes a Result from samples. A signal is considered settled once it stays within the tolerance of its final
+// value, which is the mean of the signal over the last stabilization window. Limits are compared relative to their final
+// value, while rejection rates are compared absolutely.
+func Analyze(samples []Sample, window time.Duration, tolerance float64) *Result {
+	if len(samples) == 0 {
+		return &Result{}
+	}
+
+	limitSettle := settleTime(samples, window, tolerance, true, func(s Sample) float64 { return s.Limit })
+	rejectionSettle := settleTime(samples, window, tolerance, false, func(s Sample) float64 { return s.RejectionRate })
+	settle := max(limitSettle, rejectionSettle)
+
+	// Accumulate queueing above the final level until both signals settled
+	finalQueued := finalValue(samples, window, func(s Sample) float64 { return s.Queued })
+	var excess float64
+	var last time.Duration
+	for _, s := range samples {
+		if s.Time > settle {
+			break
+		}
+		excess += max(0, s.Queued-finalQueued) * (s.Time - last).Seconds()
+		last = s.Time
+	}
+
+	return &Result{
+		LimitSettleTime:     limitSettle,
+		RejectionSettleTime: rejectionSettle,
+		ExcessQueueing:      excess,
+	}
+}
+
+func settleTime(samples []Sample, window time.Duration, tolerance float64, relative bool, signal func(Sample) float64) time.Duration {
+	final := finalValue(samples, window, signal)
+	band := tolerance
+	if relative && final != 0 {
+		band = tolerance * math.Abs(final)
+	}
+	for i := len(samples) - 1; i >= 0; i-- {
+		if math.Abs(signal(samples[i])-final) > band {
+			if i == len(samples)-1 {
+				return samples[i].Time
+			}
+			return samples[i+1].Time
+		}
+	}
+	return 0
+}
+
+func finalValue(samples []Sample, window time.Duration, signal func(Sample) float64) float64 {
+	end := samples[len(samples)-1].Time
+	var sum float64
+	var count int
+	for i := len(samples) - 1; i >= 0 && end-samples[i].Time < window; i-- {
+		sum += signal(samples[i])
+		count++
+	}
+	return sum / float64(count)
+}
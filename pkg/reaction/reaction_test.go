@@ -0,0 +1,35 @@
+package reaction
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyze(t *testing.T) {
+	samples := []Sample{
+		{Time: 1 * time.Second, Limit: 20, RejectionRate: 0.5, Queued: 30},
+		{Time: 2 * time.Second, Limit: 15, RejectionRate: 0.3, Queued: 20},
+		{Time: 3 * time.Second, Limit: 10, RejectionRate: 0.1, Queued: 10},
+		{Time: 4 * time.Second, Limit: 10, RejectionRate: 0.1, Queued: 10},
+		{Time: 5 * time.Second, Limit: 10, RejectionRate: 0.1, Queued: 10},
+	}
+
+	result := Analyze(samples, 2*time.Second, 0.1)
+	assert.Equal(t, 3*time.Second, result.LimitSettleTime)
+	assert.Equal(t, 3*time.Second, result.RejectionSettleTime)
+	assert.Equal(t, float64(30), result.ExcessQueueing)
+}
+
+func TestAnalyzeWhenAlreadySettled(t *testing.T) {
+	samples := []Sample{
+		{Time: 1 * time.Second, Limit: 10, Queued: 5},
+		{Time: 2 * time.Second, Limit: 10, Queued: 5},
+	}
+
+	result := Analyze(samples, time.Second, 0.1)
+	assert.Equal(t, time.Duration(0), result.LimitSettleTime)
+	assert.Equal(t, time.Duration(0), result.RejectionSettleTime)
+	assert.Equal(t, float64(0), result.ExcessQueueing)
+}
@@ -0,0 +1,41 @@
+package results
+
+import (
+	"tripwire/pkg/metrics"
+)
+
+// RejectionShare returns the share of the workload's requests that were rejected.
+func (w *WorkloadResult) RejectionShare() float64 {
+	if w.Total == 0 {
+		return 0
+	}
+	return float64(w.Rejected) / float64(w.Total)
+}
+
+// recordResults records the final results of each of the run's workloads as metrics.
+func (r *Run) recordResults(m *metrics.Metrics, scenario string) {
+	for _, wr := range r.Workloads {
+		m.RecordResult(scenario, r.Strategy, wr.Workload, wr.Goodput, wr.Latency.P99/1000, wr.RejectionShare())
+	}
+}
+
+// resultGroups returns the final results of each run's workloads as series, which remote sinks send when the results are
+// written. Like the result metrics, series are labeled by scenario rather than run ID, so that a scenario's results form
+// a continuous series across its runs, such as nightly runs, and are timestamped when their run ended.
+func (r *Results) resultGroups() []*seriesGroup {
+	var groups []*seriesGroup
+	for _, run := range r.Runs {
+		for _, wr := range run.Workloads {
+			groups = append(groups, &seriesGroup{
+				series: []sampleSeries{
+					{"tripwire_result_goodput", wr.Goodput, false},
+					{"tripwire_result_latency_p99", wr.Latency.P99 / 1000, false},
+					{"tripwire_result_rejection_share", wr.RejectionShare(), false},
+				},
+				labels:    map[string]string{"scenario": r.Scenario, "strategy": run.Strategy, "workload": wr.Workload},
+				timestamp: run.End,
+			})
+		}
+	}
+	return groups
+}
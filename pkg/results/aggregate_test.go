@@ -0,0 +1,31 @@
+package results
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultGroups(t *testing.T) {
+	end := time.UnixMilli(5000)
+	results := &Results{Scenario: "nightly", Runs: []*Run{{RunID: "abc", Strategy: "bulkhead", End: end, Workloads: []*WorkloadResult{
+		{Workload: "reads", Total: 200, Rejected: 50, Goodput: 15, Latency: Latency{P99: 250}},
+		{Workload: "idle"},
+	}}}}
+
+	// Series are labeled by scenario rather than run ID, and are timestamped when their run ended
+	groups := results.resultGroups()
+	require.Len(t, groups, 2)
+	assert.Equal(t, map[string]string{"scenario": "nightly", "strategy": "bulkhead", "workload": "reads"}, groups[0].labels)
+	assert.Equal(t, end, groups[0].timestamp)
+	assert.Equal(t, []sampleSeries{
+		{"tripwire_result_goodput", 15, false},
+		{"tripwire_result_latency_p99", .25, false},
+		{"tripwire_result_rejection_share", .25, false},
+	}, groups[0].series)
+
+	// Workloads without requests have no rejections
+	assert.Equal(t, 0.0, groups[1].series[2].value)
+}
@@ -0,0 +1,148 @@
+package results
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"text/tabwriter"
+	"time"
+)
+
+// AnalyticWorkload is a workload's arrival rate and mean service time, which an analytic model is computed from.
+type AnalyticWorkload struct {
+	RPS         float64
+	ServiceTime time.Duration
+}
+
+// Analytic describes a run's expected utilization and queueing according to an M/M/c model of its server, which assumes
+// Poisson arrivals at the configured RPS and exponential service times with the configured means, served by the
+// server's threads in FIFO order. Measured results that deviate from the model, such as when policies reject or reorder
+// requests, or when service times are less variable than exponential, can be compared to it as a sanity check.
+type Analytic struct {
+	Threads          uint    `json:"threads"`
+	RPS              float64 `json:"rps"`
+	Utilization      float64 `json:"utilization"`         // the fraction of threads that are expected to be busy
+	Saturated        bool    `json:"saturated,omitempty"` // whether the RPS exceeds what the threads can serve, so queueing is unbounded
+	QueueProbability float64 `json:"queue_probability"`   // the probability that a request waits for a thread
+	MeanWait         float64 `json:"mean_wait"`           // the expected time that requests wait for a thread, in milliseconds
+
+	workloads map[string]*ExpectedLatency
+}
+
+// ExpectedLatency is a workload's latency that's expected by its run's analytic model, in milliseconds.
+type ExpectedLatency struct {
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P99  float64 `json:"p99"`
+}
+
+// NewAnalytic computes an M/M/c model of a server with some threads that serves the workloads, by name. Returns nil if
+// there's no load to model.
+func NewAnalytic(threads uint, workloads map[string]AnalyticWorkload) *Analytic {
+	// The offered load is the mean number of busy threads that the workloads require
+	var rps, load float64
+	for _, workload := range workloads {
+		rps += workload.RPS
+		load += workload.RPS * workload.ServiceTime.Seconds()
+	}
+	if threads == 0 || rps == 0 || load == 0 {
+		return nil
+	}
+
+	c := float64(threads)
+	result := &Analytic{Threads: threads, RPS: rps, Utilization: load / c}
+	if load >= c {
+		result.Saturated = true
+		result.QueueProbability = 1
+		return result
+	}
+
+	// Compute the Erlang C probability of waiting from the Erlang B recurrence, which avoids overflowing factorials
+	erlangB := 1.0
+	for k := 1.0; k <= c; k++ {
+		erlangB = load * erlangB / (k + load*erlangB)
+	}
+	queueProbability := erlangB / (1 - result.Utilization*(1-erlangB))
+
+	// Waits are exponential with a rate of the threads' spare capacity, in requests per second
+	meanServiceTime := load / rps
+	waitRate := (c - load) / meanServiceTime
+	result.QueueProbability = queueProbability
+	result.MeanWait = 1000 * queueProbability / waitRate
+	result.workloads = make(map[string]*ExpectedLatency)
+	for name, workload := range workloads {
+		serviceTime := workload.ServiceTime.Seconds()
+		if serviceTime == 0 {
+			continue
+		}
+		exceeds := func(t float64) float64 {
+			return responseTimeExceeds(t, 1/serviceTime, waitRate, queueProbability)
+		}
+		result.workloads[name] = &ExpectedLatency{
+			Mean: 1000*serviceTime + result.MeanWait,
+			P50:  1000 * quantile(exceeds, .5),
+			P99:  1000 * quantile(exceeds, .99),
+		}
+	}
+	return result
+}
+
+// responseTimeExceeds returns the probability that a response time, which is an exponential service time with a rate plus
+// a wait that's exponential with a waitRate with the queueProbability and 0 otherwise, exceeds t seconds.
+func responseTimeExceeds(t float64, rate float64, waitRate float64, queueProbability float64) float64 {
+	served := math.Exp(-rate * t)
+	var queued float64
+	if math.Abs(waitRate-rate) < 1e-9*rate {
+		queued = served * (1 + rate*t)
+	} else {
+		queued = (waitRate*served - rate*math.Exp(-waitRate*t)) / (waitRate - rate)
+	}
+	return (1-queueProbability)*served + queueProbability*queued
+}
+
+// quantile returns the time, in seconds, that a decreasing probability of exceeding it reaches 1-q at, by bisection.
+func quantile(exceeds func(t float64) float64, q float64) float64 {
+	low, high := 0.0, 1e-3
+	for exceeds(high) > 1-q {
+		low, high = high, 2*high
+	}
+	for i := 0; i < 64; i++ {
+		mid := (low + high) / 2
+		if exceeds(mid) > 1-q {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return (low + high) / 2
+}
+
+// writeAnalytic writes a table of each strategy's expected utilization, and each of its workloads' measured latency
+// alongside the latency expected by its analytic model, for the runs that have one.
+func (r *Results) writeAnalytic(w io.Writer) error {
+	var analytic bool
+	for _, run := range r.Runs {
+		analytic = analytic || run.Analytic != nil
+	}
+	if !analytic {
+		return nil
+	}
+
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STRATEGY\tWORKLOAD\tM/M/C UTILIZATION\tMEAN\tM/M/C MEAN\tP99\tM/M/C P99")
+	for _, run := range r.Runs {
+		if run.Analytic == nil {
+			continue
+		}
+		for _, wr := range run.Workloads {
+			expectedMean, expectedP99 := "-", "-"
+			if wr.Expected != nil {
+				expectedMean, expectedP99 = fmt.Sprintf("%.1fms", wr.Expected.Mean), fmt.Sprintf("%.1fms", wr.Expected.P99)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%.1f%%\t%.1fms\t%s\t%.1fms\t%s\n", run.Strategy, wr.Workload, 100*run.Analytic.Utilization,
+				wr.Latency.Mean, expectedMean, wr.Latency.P99, expectedP99)
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,67 @@
+package results
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalytic(t *testing.T) {
+	// An M/M/1 queue's response times are exponential with a rate of its spare capacity
+	analytic := NewAnalytic(1, map[string]AnalyticWorkload{"reads": {RPS: 5, ServiceTime: 100 * time.Millisecond}})
+	require.NotNil(t, analytic)
+	assert.InDelta(t, .5, analytic.Utilization, 1e-9)
+	assert.InDelta(t, .5, analytic.QueueProbability, 1e-9)
+	assert.InDelta(t, 100, analytic.MeanWait, 1e-6)
+	expected := analytic.workloads["reads"]
+	assert.InDelta(t, 200, expected.Mean, 1e-6)
+	assert.InDelta(t, 1000*math.Ln2/5, expected.P50, 1e-3)
+	assert.InDelta(t, 1000*math.Log(100)/5, expected.P99, 1e-3)
+
+	// An M/M/2 queue at half utilization waits with the Erlang C probability of 1/3
+	analytic = NewAnalytic(2, map[string]AnalyticWorkload{
+		"reads":  {RPS: .5, ServiceTime: time.Second},
+		"writes": {RPS: .5, ServiceTime: time.Second},
+	})
+	assert.InDelta(t, .5, analytic.Utilization, 1e-9)
+	assert.InDelta(t, 1.0/3, analytic.QueueProbability, 1e-9)
+	assert.InDelta(t, 1000.0/3, analytic.MeanWait, 1e-6)
+	assert.InDelta(t, 1000+1000.0/3, analytic.workloads["writes"].Mean, 1e-6)
+
+	// Saturated servers have unbounded queueing, and there's nothing to model without load
+	analytic = NewAnalytic(2, map[string]AnalyticWorkload{"reads": {RPS: 30, ServiceTime: 100 * time.Millisecond}})
+	assert.True(t, analytic.Saturated)
+	assert.InDelta(t, 1.5, analytic.Utilization, 1e-9)
+	assert.Nil(t, analytic.workloads)
+	assert.Nil(t, NewAnalytic(2, map[string]AnalyticWorkload{"reads": {RPS: 10}}))
+	assert.Nil(t, NewAnalytic(0, map[string]AnalyticWorkload{"reads": {RPS: 10, ServiceTime: time.Second}}))
+}
+
+func TestAnalyticReport(t *testing.T) {
+	results := &Results{Runs: []*Run{
+		{Strategy: "bulkhead", Analytic: &Analytic{Utilization: .5}, Workloads: []*WorkloadResult{
+			{Workload: "reads", Latency: Latency{Mean: 150, P99: 700}, Expected: &ExpectedLatency{Mean: 200, P99: 921}},
+		}},
+		{Strategy: "saturated", Analytic: &Analytic{Utilization: 1.5, Saturated: true}, Workloads: []*WorkloadResult{
+			{Workload: "reads", Latency: Latency{Mean: 900, P99: 1500}},
+		}},
+		{Strategy: "staged", Workloads: []*WorkloadResult{{Workload: "staged"}}},
+	}}
+	var report bytes.Buffer
+	require.NoError(t, results.writeAnalytic(&report))
+	assert.Equal(t, `
+STRATEGY   WORKLOAD  M/M/C UTILIZATION  MEAN     M/M/C MEAN  P99       M/M/C P99
+bulkhead   reads     50.0%              150.0ms  200.0ms     700.0ms   921.0ms
+saturated  reads     150.0%             900.0ms  -           1500.0ms  -
+`, report.String())
+
+	// Without an analytic model, there's nothing to compare to
+	results.Runs = results.Runs[2:]
+	report.Reset()
+	require.NoError(t, results.writeAnalytic(&report))
+	assert.Empty(t, report.String())
+}
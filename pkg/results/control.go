@@ -0,0 +1,66 @@
+package results
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// control returns the run that the other runs are compared to, or nil if there isn't one.
+func (r *Results) control() *Run {
+	for _, run := range r.Runs {
+		if run.Control {
+			return run
+		}
+	}
+	return nil
+}
+
+// writeComparison writes a table of how each strategy and workload's goodput, p99 latency, and rejection and timeout
+// rates compare to the control's, if there's a control. Rates are compared in percentage points.
+func (r *Results) writeComparison(w io.Writer) error {
+	control := r.control()
+	if control == nil {
+		return nil
+	}
+	controlWorkloads := make(map[string]*WorkloadResult)
+	for _, wr := range control.Workloads {
+		controlWorkloads[wr.Workload] = wr
+	}
+
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STRATEGY\tWORKLOAD\tGOODPUT VS CONTROL\tP99 VS CONTROL\tREJECTION RATE VS CONTROL\tTIMEOUT RATE VS CONTROL")
+	for _, run := range r.Runs {
+		if run == control {
+			continue
+		}
+		for _, wr := range run.Workloads {
+			cw, ok := controlWorkloads[wr.Workload]
+			if !ok {
+				continue
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", run.Strategy, wr.Workload, formatChange(wr.Goodput, cw.Goodput),
+				formatChange(wr.Latency.P99, cw.Latency.P99), formatRateChange(wr.Rejected, wr.Total, cw.Rejected, cw.Total),
+				formatRateChange(wr.Timeouts, wr.Total, cw.Timeouts, cw.Total))
+		}
+	}
+	return tw.Flush()
+}
+
+// formatChange formats the relative change of a value from the control's, where a change from 0 is formatted as "-".
+func formatChange(value float64, control float64) string {
+	if control == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%+.1f%%", 100*(value-control)/control)
+}
+
+// formatRateChange formats the change of a rate from the control's rate in percentage points, where a change to or from
+// a rate with no total is formatted as "-".
+func formatRateChange(count uint64, total uint64, controlCount uint64, controlTotal uint64) string {
+	if total == 0 || controlTotal == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%+.1fpp", 100*(float64(count)/float64(total)-float64(controlCount)/float64(controlTotal)))
+}
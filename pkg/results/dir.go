@@ -0,0 +1,150 @@
+package results
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ConfigFile     = "config.yaml"
+	SeedFile       = "seed"
+	ResultsFile    = "results.json"
+	SummaryFile    = "summary.txt"
+	TimeSeriesFile = "timeseries.jsonl"
+	EventsFile     = "events.jsonl"
+)
+
+// Config configures where run output is written and how much of it is retained. Run directories that exceed any of the
+// retention limits are pruned, oldest first, when a run starts or when tripwire gc is run.
+type Config struct {
+	Dir     string        `yaml:"dir"`      // the directory that run directories are created in
+	Keep    int           `yaml:"keep"`     // the number of run directories to retain, including the current one. 0 retains all.
+	MaxAge  time.Duration `yaml:"max_age"`  // the age after which run directories are removed. 0 retains all.
+	MaxSize int64         `yaml:"max_size"` // the total size, in bytes, that run directories are pruned to. 0 retains all.
+
+	// RelativeTime records time series samples relative to the start of each strategy rather than by wall clock time,
+	// so that sequential strategy runs share a common time axis.
+	RelativeTime bool `yaml:"relative_time"`
+
+	// LatencyPeriod is how often workloads' response times are rotated into a new period, whose latency is reported
+	// separately. Response times are also rotated at each stage. 0 disables rotation by time.
+	LatencyPeriod time.Duration `yaml:"latency_period"`
+
+	// Sinks are destinations that samples, events, and results are written to in addition to the run directory.
+	Sinks []*SinkConfig `yaml:"sinks"`
+
+	// RequestLog writes the outcome of every request to the run directory
+	RequestLog *RequestLogConfig `yaml:"request_log"`
+}
+
+func (c *Config) UnmarshalYAML(value *yaml.Node) error {
+	*c = Config{
+		Dir: "results",
+	}
+	type Alias Config
+	var alias = Alias(*c)
+	if err := value.Decode(&alias); err != nil {
+		return err
+	}
+	*c = Config(alias)
+	return nil
+}
+
+func (c *Config) Validate() error {
+	if c.LatencyPeriod < 0 {
+		return fmt.Errorf("output latency_period cannot be negative")
+	}
+	for _, sink := range c.Sinks {
+		if err := sink.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.RequestLog != nil {
+		return c.RequestLog.Validate()
+	}
+	return nil
+}
+
+// Dir is the output directory for a single run, which contains everything needed to understand and reproduce the run:
+//
+//	config.yaml       a copy of the config the run used
+//	seed              the seed that random service times were generated with
+//	results.json      per strategy and workload results
+//	summary.txt       a human-readable table of the results
+//	timeseries.jsonl  workload metrics sampled over the course of the run
+//	events.jsonl      orchestration events, such as strategy starts and config updates
+//	requests.csv.gz   the outcome of every request, when a CSV request log is configured
+type Dir struct {
+	Path string
+}
+
+// ScenarioName returns the name of a scenario, which is its config file's name without its extension.
+func ScenarioName(configName string) string {
+	return strings.TrimSuffix(filepath.Base(configName), filepath.Ext(configName))
+}
+
+// Create creates a run directory named after the current time, the configName, and the shard, if any, writes the config
+// and seed to it, and prunes older run directories according to the config's retention. The shard is in the form i/n, and
+// is included in the name so that shards sharing an output dir don't write to the same run directory. Runs that start in
+// the same second are given a numbered suffix rather than sharing a run directory.
+func Create(config *Config, configName string, shard string, configData []byte, seed int64) (*Dir, error) {
+	name := time.Now().Format(dirTimeLayout) + "-" + ScenarioName(configName)
+	if shard != "" {
+		name += "-shard" + strings.Replace(shard, "/", "of", 1)
+	}
+	if err := os.MkdirAll(config.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(config.Dir, name)
+	dir := &Dir{Path: path}
+	for i := 2; ; i++ {
+		err := os.Mkdir(dir.Path, 0o755)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+		dir.Path = fmt.Sprintf("%s-%d", path, i)
+	}
+	if err := os.WriteFile(dir.File(ConfigFile), configData, 0o644); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(dir.File(SeedFile), []byte(fmt.Sprintf("%d\n", seed)), 0o644); err != nil {
+		return nil, err
+	}
+	if _, err := Prune(config, time.Now(), false); err != nil {
+		return nil, err
+	}
+	return dir, nil
+}
+
+// File returns the path of a file within the run directory.
+func (d *Dir) File(name string) string {
+	return filepath.Join(d.Path, name)
+}
+
+// WriteResults writes the results as JSON along with a human-readable summary.
+func (d *Dir) WriteResults(results *Results) error {
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(d.File(ResultsFile), append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	summary, err := os.Create(d.File(SummaryFile))
+	if err != nil {
+		return err
+	}
+	defer summary.Close()
+	return results.WriteSummary(summary)
+}
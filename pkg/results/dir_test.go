@@ -0,0 +1,42 @@
+package results
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	shard0, err := Create(&Config{Dir: dir}, "scenario.yaml", "0/2", []byte("seed: 1\n"), 1)
+	require.NoError(t, err)
+	shard1, err := Create(&Config{Dir: dir}, "scenario.yaml", "1/2", []byte("seed: 1\n"), 1)
+	require.NoError(t, err)
+	unsharded, err := Create(&Config{Dir: dir}, "scenario.yaml", "", nil, 1)
+	require.NoError(t, err)
+
+	assert.Regexp(t, `^\d{8}-\d{6}-scenario-shard0of2$`, filepath.Base(shard0.Path))
+	assert.Regexp(t, `^\d{8}-\d{6}-scenario-shard1of2$`, filepath.Base(shard1.Path))
+	assert.Regexp(t, `^\d{8}-\d{6}-scenario$`, filepath.Base(unsharded.Path))
+	seed, err := os.ReadFile(shard0.File(SeedFile))
+	require.NoError(t, err)
+	assert.Equal(t, "1\n", string(seed))
+}
+
+func TestCreateInSameSecond(t *testing.T) {
+	dir := t.TempDir()
+	first, err := Create(&Config{Dir: dir}, "scenario.yaml", "", nil, 1)
+	require.NoError(t, err)
+
+	// A run that starts in the same second as another gets its own run directory
+	second, err := Create(&Config{Dir: dir}, "scenario.yaml", "", nil, 2)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Path, second.Path)
+	assert.Regexp(t, `^\d{8}-\d{6}-scenario(-2)?$`, filepath.Base(second.Path))
+	seed, err := os.ReadFile(first.File(SeedFile))
+	require.NoError(t, err)
+	assert.Equal(t, "1\n", string(seed))
+}
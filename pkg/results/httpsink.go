@@ -0,0 +1,119 @@
+package results
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// httpSenderQueue is how many requests an httpSender buffers before dropping them, so that a slow endpoint doesn't block
+// sampling.
+const httpSenderQueue = 64
+
+// httpSender sends requests to a remote sink's endpoint in the background, in order. Failed and dropped requests are
+// counted and reported when the sender is closed.
+type httpSender struct {
+	config *SinkConfig
+	client *http.Client
+	queue  chan *httpRequest
+	done   sync.WaitGroup
+
+	mtx      sync.Mutex
+	closed   bool  // Guarded by mtx
+	sent     int   // Guarded by mtx
+	failed   int   // Guarded by mtx
+	dropped  int   // Guarded by mtx
+	firstErr error // Guarded by mtx
+}
+
+type httpRequest struct {
+	url     string
+	headers map[string]string
+	body    []byte
+}
+
+func newHTTPSender(config *SinkConfig) *httpSender {
+	s := &httpSender{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+		queue:  make(chan *httpRequest, httpSenderQueue),
+	}
+	s.done.Add(1)
+	go func() {
+		defer s.done.Done()
+		for request := range s.queue {
+			err := s.post(request)
+			s.mtx.Lock()
+			s.sent++
+			if err != nil {
+				s.failed++
+				if s.firstErr == nil {
+					s.firstErr = err
+				}
+			}
+			s.mtx.Unlock()
+		}
+	}()
+	return s
+}
+
+// send queues a request to the url with the headers, in addition to the configured headers, or drops it if the queue
+// is full.
+func (s *httpSender) send(url string, headers map[string]string, body []byte) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.queue <- &httpRequest{url: url, headers: headers, body: body}:
+	default:
+		s.dropped++
+	}
+}
+
+func (s *httpSender) post(request *httpRequest) error {
+	req, err := http.NewRequest(http.MethodPost, request.url, bytes.NewReader(request.body))
+	if err != nil {
+		return err
+	}
+	for name, value := range request.headers {
+		req.Header.Set(name, value)
+	}
+	for name, value := range s.config.Headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s responded with status %d", request.url, resp.StatusCode)
+	}
+	return nil
+}
+
+// close sends any queued requests, then returns an error describing any that failed or were dropped.
+func (s *httpSender) close() error {
+	s.mtx.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mtx.Unlock()
+	s.done.Wait()
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.failed > 0 {
+		return fmt.Errorf("%s sink: %d of %d requests failed: %w", s.config.Type, s.failed, s.sent, s.firstErr)
+	}
+	if s.dropped > 0 {
+		return fmt.Errorf("%s sink: dropped %d requests", s.config.Type, s.dropped)
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package results
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"tripwire/pkg/events"
+)
+
+// otlpSink sends samples as metrics and events as logs to an OTLP/HTTP endpoint, using OTLP's JSON encoding, along with
+// the final results of each run's workloads as metrics.
+type otlpSink struct {
+	metricsURL string
+	logsURL    string
+	sender     *httpSender
+}
+
+var otlpHeaders = map[string]string{"Content-Type": "application/json"}
+
+// otlpResource identifies tripwire as the source of the metrics and logs.
+var otlpResource = map[string]any{"attributes": []otlpAttribute{{Key: "service.name", Value: otlpValue{StringValue: "tripwire"}}}}
+
+var otlpScope = map[string]any{"name": "tripwire"}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string   `json:"stringValue,omitempty"`
+	IntValue    string   `json:"intValue,omitempty"` // 64 bit integers are encoded as strings
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+type otlpDataPoint struct {
+	Attributes   []otlpAttribute `json:"attributes"`
+	TimeUnixNano string          `json:"timeUnixNano"`
+	AsDouble     float64         `json:"asDouble"`
+}
+
+type otlpMetric struct {
+	Name  string         `json:"name"`
+	Sum   map[string]any `json:"sum,omitempty"`
+	Gauge map[string]any `json:"gauge,omitempty"`
+}
+
+// otlpCumulative is the aggregation temporality of sums whose values are totals since the start of a run.
+const otlpCumulative = 2
+
+func newOTLPSink(config *SinkConfig) *otlpSink {
+	endpoint := strings.TrimSuffix(config.URL, "/")
+	return &otlpSink{
+		metricsURL: endpoint + "/v1/metrics",
+		logsURL:    endpoint + "/v1/logs",
+		sender:     newHTTPSender(config),
+	}
+}
+
+func (s *otlpSink) WriteSamples(samples []*Sample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+	body, err := encodeOTLPMetrics(sampleGroups(samples, time.Now()))
+	if err != nil {
+		return err
+	}
+	s.sender.send(s.metricsURL, otlpHeaders, body)
+	return nil
+}
+
+func (s *otlpSink) WriteEvent(event *events.Event) error {
+	body, err := encodeOTLPLog(event)
+	if err != nil {
+		return err
+	}
+	s.sender.send(s.logsURL, otlpHeaders, body)
+	return nil
+}
+
+func (s *otlpSink) WriteResults(results *Results) error {
+	groups := results.resultGroups()
+	if len(groups) == 0 {
+		return nil
+	}
+	body, err := encodeOTLPMetrics(groups)
+	if err != nil {
+		return err
+	}
+	s.sender.send(s.metricsURL, otlpHeaders, body)
+	return nil
+}
+
+func (s *otlpSink) Close() error {
+	return s.sender.close()
+}
+
+// encodeOTLPMetrics encodes groups of series as an OTLP ExportMetricsServiceRequest, with a metric per series name,
+// where counters are cumulative sums and other values are gauges.
+func encodeOTLPMetrics(groups []*seriesGroup) ([]byte, error) {
+	var metrics []*otlpMetric
+	byName := make(map[string]*otlpMetric)
+	dataPoints := make(map[string][]otlpDataPoint)
+	for _, group := range groups {
+		attributes := otlpAttributes(group.labels)
+		timeUnixNano := strconv.FormatInt(group.timestamp.UnixNano(), 10)
+		for _, s := range group.series {
+			if byName[s.name] == nil {
+				metric := &otlpMetric{Name: s.name}
+				byName[s.name] = metric
+				metrics = append(metrics, metric)
+				if s.counter {
+					metric.Sum = map[string]any{"aggregationTemporality": otlpCumulative, "isMonotonic": true}
+				} else {
+					metric.Gauge = map[string]any{}
+				}
+			}
+			dataPoints[s.name] = append(dataPoints[s.name], otlpDataPoint{Attributes: attributes, TimeUnixNano: timeUnixNano, AsDouble: s.value})
+		}
+	}
+	for _, metric := range metrics {
+		if metric.Sum != nil {
+			metric.Sum["dataPoints"] = dataPoints[metric.Name]
+		} else {
+			metric.Gauge["dataPoints"] = dataPoints[metric.Name]
+		}
+	}
+	return json.Marshal(map[string]any{
+		"resourceMetrics": []any{map[string]any{
+			"resource":     otlpResource,
+			"scopeMetrics": []any{map[string]any{"scope": otlpScope, "metrics": metrics}},
+		}},
+	})
+}
+
+// encodeOTLPLog encodes an event as an OTLP ExportLogsServiceRequest with a single log record, whose body is the event
+// type and whose attributes are the event's attributes along with its run and strategy.
+func encodeOTLPLog(event *events.Event) ([]byte, error) {
+	labels := make(map[string]string)
+	if event.RunID != "" {
+		labels["run_id"] = event.RunID
+	}
+	if event.Strategy != "" {
+		labels["strategy"] = event.Strategy
+	}
+	attributes := otlpAttributes(labels)
+	for key, value := range event.Attributes {
+		attributes = append(attributes, otlpAttribute{Key: key, Value: newOTLPValue(value)})
+	}
+	sort.Slice(attributes, func(i, j int) bool {
+		return attributes[i].Key < attributes[j].Key
+	})
+	record := map[string]any{
+		"timeUnixNano": strconv.FormatInt(event.Time.UnixNano(), 10),
+		"body":         otlpValue{StringValue: string(event.Type)},
+		"attributes":   attributes,
+	}
+	return json.Marshal(map[string]any{
+		"resourceLogs": []any{map[string]any{
+			"resource":  otlpResource,
+			"scopeLogs": []any{map[string]any{"scope": otlpScope, "logRecords": []any{record}}},
+		}},
+	})
+}
+
+// otlpAttributes returns labels as attributes, sorted by key.
+func otlpAttributes(labels map[string]string) []otlpAttribute {
+	attributes := make([]otlpAttribute, 0, len(labels))
+	for key, value := range labels {
+		attributes = append(attributes, otlpAttribute{Key: key, Value: otlpValue{StringValue: value}})
+	}
+	sort.Slice(attributes, func(i, j int) bool {
+		return attributes[i].Key < attributes[j].Key
+	})
+	return attributes
+}
+
+func newOTLPValue(value any) otlpValue {
+	switch v := value.(type) {
+	case bool:
+		return otlpValue{BoolValue: &v}
+	case int:
+		return otlpValue{IntValue: strconv.Itoa(v)}
+	case int64:
+		return otlpValue{IntValue: strconv.FormatInt(v, 10)}
+	case float64:
+		return otlpValue{DoubleValue: &v}
+	default:
+		return otlpValue{StringValue: fmt.Sprint(v)}
+	}
+}
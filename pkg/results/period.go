@@ -0,0 +1,62 @@
+package results
+
+import (
+	"time"
+
+	"tripwire/pkg/metrics"
+)
+
+// LatencyPeriod describes a workload's response times during a period, such as a stage or an hour.
+type LatencyPeriod struct {
+	Name    string    `json:"name,omitempty"` // the stage that the period covers, if any
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Count   uint64    `json:"count"` // the response times that were recorded
+	Latency Latency   `json:"latency"`
+}
+
+// collectPeriods returns a workload's latency by period, or nil if its response times weren't rotated into more than
+// one period.
+func collectPeriods(h *metrics.RotatingHistogram, end time.Time) []*LatencyPeriod {
+	periods := h.Periods(end)
+	if len(periods) < 2 {
+		return nil
+	}
+	var results []*LatencyPeriod
+	for _, period := range periods {
+		results = append(results, &LatencyPeriod{
+			Name:    period.Name,
+			Start:   period.Start,
+			End:     period.End,
+			Count:   period.Count(),
+			Latency: latencyOf(period.Histogram),
+		})
+	}
+	return results
+}
+
+// SetLatencyPeriod sets how often the response time histograms of active runs are rotated into a new period, where 0
+// only rotates them at stage transitions.
+func (r *Recorder) SetLatencyPeriod(period time.Duration) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.latencyPeriod = period
+}
+
+// rotate rotates the response time histograms of a run's workloads if its latency period has elapsed. Must be called
+// while holding the recorder's mtx.
+func (r *Recorder) rotate(run *Run, now time.Time) {
+	if r.latencyPeriod == 0 {
+		return
+	}
+	if run.periodStart.IsZero() {
+		run.periodStart = run.Start
+	}
+	if now.Sub(run.periodStart) < r.latencyPeriod {
+		return
+	}
+	for _, workload := range run.workloads {
+		r.metrics.WithWorkload(run.RunID, workload, run.Strategy).ResponseTimes.Rotate(now)
+	}
+	run.periodStart = now
+}
@@ -0,0 +1,32 @@
+package results
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tripwire/pkg/metrics"
+)
+
+func TestCollectPeriods(t *testing.T) {
+	start := time.Unix(0, 0)
+	h := metrics.NewRotatingHistogram(start)
+	h.Record(10 * time.Millisecond)
+
+	// A single period isn't broken out
+	assert.Nil(t, collectPeriods(h, start.Add(time.Hour)))
+
+	h.Rotate(start.Add(time.Hour))
+	h.Record(100 * time.Millisecond)
+	h.Record(100 * time.Millisecond)
+	periods := collectPeriods(h, start.Add(2*time.Hour))
+	require.Len(t, periods, 2)
+	assert.Equal(t, start, periods[0].Start)
+	assert.Equal(t, start.Add(time.Hour), periods[0].End)
+	assert.Equal(t, uint64(1), periods[0].Count)
+	assert.Equal(t, uint64(2), periods[1].Count)
+	assert.InEpsilon(t, 100, periods[1].Latency.P99, .05)
+	assert.InEpsilon(t, 10, periods[0].Latency.P99, .05)
+}
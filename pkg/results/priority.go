@@ -0,0 +1,106 @@
+package results
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/failsafe-go/failsafe-go/priority"
+
+	"tripwire/pkg/metrics"
+)
+
+// PriorityResult describes a workload's requests of a single priority class.
+type PriorityResult struct {
+	Priority  int     `json:"priority"`
+	Total     uint64  `json:"total"`
+	Successes uint64  `json:"successes"`
+	Rejected  uint64  `json:"rejected"`
+	Timeouts  uint64  `json:"timeouts"`
+	Failures  uint64  `json:"failures"`
+	Goodput   float64 `json:"goodput"` // successful requests per second
+
+	// GoodputShare is the fraction of the strategy's goodput, across all of its workloads, that the priority class of
+	// the workload received
+	GoodputShare float64 `json:"goodput_share"`
+}
+
+// collectPriorities reads a workload's results by priority class, omitting priorities without requests.
+func collectPriorities(workloadMetrics *metrics.WorkloadMetrics, m *metrics.Metrics, seconds float64) []*PriorityResult {
+	var results []*PriorityResult
+	for p := priority.VeryLow; p <= priority.VeryHigh; p++ {
+		outcome := func(outcome string) uint64 {
+			return uint64(m.Value(workloadMetrics.ClientReqPriorities.WithLabelValues(strconv.Itoa(int(p)), outcome)))
+		}
+		result := &PriorityResult{
+			Priority:  int(p),
+			Successes: outcome("success"),
+			Rejected:  outcome("rejected"),
+			Timeouts:  outcome("timeout"),
+		}
+		result.Failures = result.Rejected + result.Timeouts + outcome("failure")
+		result.Total = result.Successes + result.Failures
+		if result.Total == 0 {
+			continue
+		}
+		if seconds > 0 {
+			result.Goodput = float64(result.Successes) / seconds
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// collectGoodputShares computes each workload's priority classes' shares of the run's goodput.
+func (r *Run) collectGoodputShares() {
+	var goodput float64
+	for _, wr := range r.Workloads {
+		goodput += wr.Goodput
+	}
+	for _, wr := range r.Workloads {
+		for _, pr := range wr.Priorities {
+			pr.GoodputShare = 0
+			if goodput > 0 {
+				pr.GoodputShare = pr.Goodput / goodput
+			}
+		}
+	}
+}
+
+// hasPriorityClasses returns whether any run sent requests of more than one priority, in which case results by priority
+// are worth breaking out.
+func (r *Results) hasPriorityClasses() bool {
+	for _, run := range r.Runs {
+		priorities := make(map[int]bool)
+		for _, wr := range run.Workloads {
+			for _, pr := range wr.Priorities {
+				priorities[pr.Priority] = true
+			}
+		}
+		if len(priorities) > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// writePriorities writes a table of each strategy and workload's results by priority class, with each class's share of
+// the strategy's goodput, if any run sent requests of more than one priority.
+func (r *Results) writePriorities(w io.Writer) error {
+	if !r.hasPriorityClasses() {
+		return nil
+	}
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STRATEGY\tWORKLOAD\tPRIORITY\tTOTAL\tSUCCESS\tREJECTED\tTIMEOUTS\tFAILURES\tGOODPUT\tGOODPUT SHARE")
+	for _, run := range r.Runs {
+		for _, wr := range run.Workloads {
+			for _, pr := range wr.Priorities {
+				fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\t%d\t%d\t%.1f/s\t%.1f%%\n", run.Strategy, wr.Workload, pr.Priority, pr.Total,
+					pr.Successes, pr.Rejected, pr.Timeouts, pr.Failures, pr.Goodput, 100*pr.GoodputShare)
+			}
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,38 @@
+package results
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWritePriorities(t *testing.T) {
+	run := &Run{Strategy: "prioritized", Workloads: []*WorkloadResult{
+		{Workload: "checkout", Goodput: 30, Priorities: []*PriorityResult{{Priority: 4, Total: 300, Successes: 300, Goodput: 30}}},
+		{Workload: "reports", Goodput: 10, Priorities: []*PriorityResult{
+			{Priority: 0, Total: 200, Successes: 50, Rejected: 150, Failures: 150, Goodput: 5},
+			{Priority: 1, Total: 100, Successes: 50, Timeouts: 50, Failures: 50, Goodput: 5},
+		}},
+	}}
+	run.collectGoodputShares()
+	assert.InDelta(t, .75, run.Workloads[0].Priorities[0].GoodputShare, .001)
+	assert.InDelta(t, .125, run.Workloads[1].Priorities[1].GoodputShare, .001)
+
+	var out bytes.Buffer
+	results := &Results{Runs: []*Run{run}}
+	require.NoError(t, results.writePriorities(&out))
+	assert.Equal(t, `
+STRATEGY     WORKLOAD  PRIORITY  TOTAL  SUCCESS  REJECTED  TIMEOUTS  FAILURES  GOODPUT  GOODPUT SHARE
+prioritized  checkout  4         300    300      0         0         0         30.0/s   75.0%
+prioritized  reports   0         200    50       150       0         150       5.0/s    12.5%
+prioritized  reports   1         100    50       0         50        50        5.0/s    12.5%
+`, out.String())
+
+	// A single priority class isn't broken out
+	out.Reset()
+	results = &Results{Runs: []*Run{{Workloads: []*WorkloadResult{{Priorities: []*PriorityResult{{Priority: 2, Total: 1}}}}}}}
+	require.NoError(t, results.writePriorities(&out))
+	assert.Empty(t, out.String())
+}
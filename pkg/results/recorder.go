@@ -0,0 +1,309 @@
+package results
+
+import (
+	"runtime"
+	"sync"
+	"time"
+
+	"tripwire/pkg/metrics"
+)
+
+// Recorder tracks the strategy runs within a tripwire run, periodically sampling their workload metrics to the sink's
+// time series, and collects their results.
+type Recorder struct {
+	sink         Sink
+	metrics      *metrics.Metrics
+	relativeTime bool
+	seed         int64
+	shard        string
+	metadata     Metadata
+	start        time.Time
+	done         chan struct{}
+	stopped      sync.WaitGroup
+
+	mtx           sync.Mutex
+	runs          []*Run            // Guarded by mtx
+	listeners     map[string]string // Guarded by mtx
+	seeds         map[string]int64  // Guarded by mtx
+	unseeded      []string          // Guarded by mtx
+	scenario      string            // Guarded by mtx
+	latencyPeriod time.Duration     // Guarded by mtx
+	requestLog    *RequestLog       // Guarded by mtx
+}
+
+// Sample is a point in time observation of a workload's cumulative metrics. Samples are timestamped with either the wall
+// clock Time or the Elapsed seconds since the strategy started.
+type Sample struct {
+	Time      *time.Time `json:"time,omitempty"`
+	Elapsed   *float64   `json:"elapsed,omitempty"`
+	RunID     string     `json:"run_id"`
+	Strategy  string     `json:"strategy"`
+	Workload  string     `json:"workload"`
+	Total     uint64     `json:"total"`
+	Successes uint64     `json:"successes"`
+	Rejected  uint64     `json:"rejected"`
+	Timeouts  uint64     `json:"timeouts"`
+	Failures  uint64     `json:"failures"`
+	Dropped   uint64     `json:"dropped"`
+	Inflight  float64    `json:"inflight"`
+	BurnRate  *float64   `json:"burn_rate,omitempty"` // the error budget burn rate over the SLO window, when there's an SLO
+}
+
+func NewRecorder(sink Sink, metrics *metrics.Metrics, seed int64, shard string, metadata Metadata, relativeTime bool) *Recorder {
+	return &Recorder{
+		sink:         sink,
+		metrics:      metrics,
+		relativeTime: relativeTime,
+		seed:         seed,
+		shard:        shard,
+		metadata:     metadata,
+		start:        time.Now(),
+		done:         make(chan struct{}),
+	}
+}
+
+// Start samples the workload metrics of active runs every interval until Stop is called.
+func (r *Recorder) Start(interval time.Duration) {
+	r.stopped.Add(1)
+	go func() {
+		defer r.stopped.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.done:
+				return
+			case now := <-ticker.C:
+				r.sample(now)
+			}
+		}
+	}()
+}
+
+// SetListener records the address that one of tripwire's listeners, such as metrics, is listening on.
+func (r *Recorder) SetListener(name string, addr string) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.listeners == nil {
+		r.listeners = make(map[string]string)
+	}
+	r.listeners[name] = addr
+}
+
+// SetScenario records the name of the scenario, which labels the final results that are recorded as metrics when each
+// run ends, so that they can be tracked across runs of the scenario.
+func (r *Recorder) SetScenario(scenario string) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.scenario = scenario
+}
+
+// SetSeeds records the seed of each source of randomness, so that any of them can be reproduced, along with the sources
+// that aren't seeded, which can't be.
+func (r *Recorder) SetSeeds(seeds map[string]int64, unseeded []string) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.seeds = seeds
+	r.unseeded = unseeded
+}
+
+// AddRun begins tracking a strategy run with the address of its server, the workloads that its metrics are recorded
+// under, and any metadata for the strategy and its workloads.
+func (r *Recorder) AddRun(runID string, strategy string, serverAddr string, metadata Metadata, workloads []string, workloadMetadata map[string]Metadata) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.runs = append(r.runs, &Run{
+		RunID:            runID,
+		Strategy:         strategy,
+		Metadata:         metadata,
+		ServerAddr:       serverAddr,
+		Start:            time.Now(),
+		workloads:        workloads,
+		workloadMetadata: workloadMetadata,
+		startStats:       metrics.ReadSelfStats(),
+	})
+}
+
+// SetControl records that a run is the control that the other runs are compared to.
+func (r *Recorder) SetControl(runID string) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	for _, run := range r.runs {
+		if run.RunID == runID {
+			run.Control = true
+		}
+	}
+}
+
+// SetParameters records the swept parameter values that a run's strategy was generated with.
+func (r *Recorder) SetParameters(runID string, parameters map[string]string) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	for _, run := range r.runs {
+		if run.RunID == runID {
+			run.Parameters = parameters
+		}
+	}
+}
+
+// SetBusinessWeights records the business weights of a run's workloads, by name, which its results are weighted by.
+func (r *Recorder) SetBusinessWeights(runID string, weights map[string]float64) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	for _, run := range r.runs {
+		if run.RunID == runID {
+			run.businessWeights = weights
+		}
+	}
+}
+
+// SetSLOs records the SLOs of a run's workloads, by name, whose error budgets and burn rates are measured.
+func (r *Recorder) SetSLOs(runID string, slos map[string]SLO) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	for _, run := range r.runs {
+		if run.RunID == runID {
+			run.burnRates = make(map[string]*burnRateWindow)
+			for workload, slo := range slos {
+				run.burnRates[workload] = newBurnRateWindow(slo, run.Start)
+			}
+		}
+	}
+}
+
+// SetRequestLog sets the log that the outcome of each request is written to, if any.
+func (r *Recorder) SetRequestLog(requestLog *RequestLog) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.requestLog = requestLog
+}
+
+// RequestLog returns the log that the outcome of each request is written to, or nil if there isn't one.
+func (r *Recorder) RequestLog() *RequestLog {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.requestLog
+}
+
+// SetAnalytic records the analytic model of a run's server, which its workloads' latencies are compared to.
+func (r *Recorder) SetAnalytic(runID string, analytic *Analytic) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	for _, run := range r.runs {
+		if run.RunID == runID {
+			run.Analytic = analytic
+		}
+	}
+}
+
+// SetResponsiveness records how quickly a run responded to overload and recovered from it, where nil values weren't
+// measured.
+func (r *Recorder) SetResponsiveness(runID string, timeToFirstRejection *time.Duration, recoveryTime *time.Duration) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	for _, run := range r.runs {
+		if run.RunID == runID {
+			run.TimeToFirstRejection = seconds(timeToFirstRejection)
+			run.RecoveryTime = seconds(recoveryTime)
+		}
+	}
+}
+
+// SetAborted records the reason that a run was aborted.
+func (r *Recorder) SetAborted(runID string, reason string) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	for _, run := range r.runs {
+		if run.RunID == runID {
+			run.Aborted = reason
+		}
+	}
+}
+
+func seconds(d *time.Duration) *float64 {
+	if d == nil {
+		return nil
+	}
+	s := d.Seconds()
+	return &s
+}
+
+// EndRuns collects the results of any active runs, which have completed, so that they're no longer sampled.
+func (r *Recorder) EndRuns() {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.endRuns(time.Now())
+}
+
+func (r *Recorder) endRuns(now time.Time) {
+	for _, run := range r.runs {
+		if run.End.IsZero() {
+			run.collect(r.metrics, now)
+			run.recordResults(r.metrics, r.scenario)
+		}
+	}
+}
+
+func (r *Recorder) sample(now time.Time) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	goroutines := runtime.NumGoroutine()
+	var samples []*Sample
+	for _, run := range r.runs {
+		if !run.End.IsZero() {
+			continue
+		}
+		run.maxGoroutines = max(run.maxGoroutines, goroutines)
+		r.rotate(run, now)
+		for _, workload := range run.workloads {
+			workloadMetrics := r.metrics.WithWorkload(run.RunID, workload, run.Strategy)
+			sample := &Sample{
+				RunID:     run.RunID,
+				Strategy:  run.Strategy,
+				Workload:  workload,
+				Total:     uint64(r.metrics.Value(workloadMetrics.ClientReqTotal)),
+				Successes: uint64(r.metrics.Value(workloadMetrics.ClientReqSuccesses)),
+				Rejected:  uint64(r.metrics.Value(workloadMetrics.ClientReqRejected)),
+				Timeouts:  uint64(r.metrics.Value(workloadMetrics.ClientReqTimeouts)),
+				Failures:  uint64(r.metrics.Value(workloadMetrics.ClientReqFailures)),
+				Dropped:   uint64(r.metrics.Value(workloadMetrics.ClientDroppedArrivals)),
+				Inflight:  r.metrics.Value(workloadMetrics.ClientInflightRequests),
+			}
+			if window := run.burnRates[workload]; window != nil {
+				burnRate := window.record(now, sample.Total, sample.Failures+uint64(r.metrics.Value(workloadMetrics.ClientReqSlow)))
+				workloadMetrics.SLOBurnRate.Set(burnRate)
+				sample.BurnRate = &burnRate
+			}
+			if r.relativeTime {
+				elapsed := now.Sub(run.Start).Seconds()
+				sample.Elapsed = &elapsed
+			} else {
+				sample.Time = &now
+			}
+			samples = append(samples, sample)
+		}
+	}
+	_ = r.sink.WriteSamples(samples)
+}
+
+// Stop stops sampling, ends any runs that are still active, and writes the results to the sink.
+func (r *Recorder) Stop() error {
+	close(r.done)
+	r.stopped.Wait()
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	now := time.Now()
+	r.endRuns(now)
+	return r.sink.WriteResults(&Results{
+		Metadata:  r.metadata,
+		Seed:      r.seed,
+		Seeds:     r.seeds,
+		Unseeded:  r.unseeded,
+		Scenario:  r.scenario,
+		Shard:     r.shard,
+		Listeners: r.listeners,
+		Start:     r.start,
+		End:       now,
+		Runs:      r.runs,
+	})
+}
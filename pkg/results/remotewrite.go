@@ -0,0 +1,97 @@
+package results
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"tripwire/pkg/events"
+)
+
+// remoteWriteSink sends samples to a Prometheus remote write endpoint, as a series per sample value, along with the final
+// results of each run's workloads. Events aren't sent, since remote write only carries time series.
+type remoteWriteSink struct {
+	config *SinkConfig
+	sender *httpSender
+}
+
+var remoteWriteHeaders = map[string]string{
+	"Content-Type":                      "application/x-protobuf",
+	"Content-Encoding":                  "snappy",
+	"X-Prometheus-Remote-Write-Version": "0.1.0",
+}
+
+func newRemoteWriteSink(config *SinkConfig) *remoteWriteSink {
+	return &remoteWriteSink{config: config, sender: newHTTPSender(config)}
+}
+
+func (s *remoteWriteSink) WriteSamples(samples []*Sample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+	s.sender.send(s.config.URL, remoteWriteHeaders, snappy.Encode(nil, encodeWriteRequest(sampleGroups(samples, time.Now()))))
+	return nil
+}
+
+func (s *remoteWriteSink) WriteEvent(*events.Event) error {
+	return nil
+}
+
+func (s *remoteWriteSink) WriteResults(results *Results) error {
+	if groups := results.resultGroups(); len(groups) > 0 {
+		s.sender.send(s.config.URL, remoteWriteHeaders, snappy.Encode(nil, encodeWriteRequest(groups)))
+	}
+	return nil
+}
+
+func (s *remoteWriteSink) Close() error {
+	return s.sender.close()
+}
+
+// encodeWriteRequest encodes groups of series as a remote write WriteRequest protobuf, which contains a TimeSeries per
+// series, each with sorted Labels and a single Sample.
+func encodeWriteRequest(groups []*seriesGroup) []byte {
+	var request []byte
+	for _, group := range groups {
+		timestamp := group.timestamp.UnixMilli()
+		for _, s := range group.series {
+			var timeSeries []byte
+			for _, label := range sortedLabels(s.name, group.labels) {
+				var encoded []byte
+				encoded = protowire.AppendTag(encoded, 1, protowire.BytesType)
+				encoded = protowire.AppendString(encoded, label[0])
+				encoded = protowire.AppendTag(encoded, 2, protowire.BytesType)
+				encoded = protowire.AppendString(encoded, label[1])
+				timeSeries = protowire.AppendTag(timeSeries, 1, protowire.BytesType)
+				timeSeries = protowire.AppendBytes(timeSeries, encoded)
+			}
+			var encoded []byte
+			encoded = protowire.AppendTag(encoded, 1, protowire.Fixed64Type)
+			encoded = protowire.AppendFixed64(encoded, math.Float64bits(s.value))
+			encoded = protowire.AppendTag(encoded, 2, protowire.VarintType)
+			encoded = protowire.AppendVarint(encoded, uint64(timestamp))
+			timeSeries = protowire.AppendTag(timeSeries, 2, protowire.BytesType)
+			timeSeries = protowire.AppendBytes(timeSeries, encoded)
+
+			request = protowire.AppendTag(request, 1, protowire.BytesType)
+			request = protowire.AppendBytes(request, timeSeries)
+		}
+	}
+	return request
+}
+
+// sortedLabels returns the name and value of each label, including the metric name, sorted by name as remote write
+// requires.
+func sortedLabels(name string, labels map[string]string) [][2]string {
+	result := [][2]string{{"__name__", name}}
+	for labelName, value := range labels {
+		result = append(result, [2]string{labelName, value})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i][0] < result[j][0]
+	})
+	return result
+}
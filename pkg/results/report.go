@@ -0,0 +1,105 @@
+package results
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"tripwire/pkg/util"
+)
+
+// Latest returns the most recent run directory in dir.
+func Latest(dir string) (*Dir, error) {
+	runs, err := readRunDirs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) == 0 {
+		return nil, fmt.Errorf("no run directories in %s", dir)
+	}
+	return &Dir{Path: runs[len(runs)-1].path}, nil
+}
+
+// ReadResults reads the results that were written to the run directory.
+func (d *Dir) ReadResults() (*Results, error) {
+	data, err := os.ReadFile(d.File(ResultsFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("no results in %s, the run may still be in progress", d.Path)
+		}
+		return nil, err
+	}
+	var results Results
+	if err = json.Unmarshal(data, &results); err != nil {
+		return nil, err
+	}
+	return &results, nil
+}
+
+// WriteReport writes a table of each strategy and workload's goodput, latency, and rejection and timeout rates to w,
+// followed by a comparison to the control, any analytic model, any error budgets, any results by priority class, and a
+// table of their failures by the tier that they originated from, if any.
+func (r *Results) WriteReport(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STRATEGY\tWORKLOAD\tGOODPUT\tP50\tP99\tREJECTION RATE\tTIMEOUT RATE")
+	for _, run := range r.Runs {
+		for _, wr := range run.Workloads {
+			fmt.Fprintf(tw, "%s\t%s\t%.1f/s\t%.1fms\t%.1fms\t%s\t%s\n", run.Strategy, wr.Workload, wr.Goodput, wr.Latency.P50,
+				wr.Latency.P99, formatRate(wr.Rejected, wr.Total), formatRate(wr.Timeouts, wr.Total))
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if err := r.writeComparison(w); err != nil {
+		return err
+	}
+	if err := r.writeAnalytic(w); err != nil {
+		return err
+	}
+	if err := r.writeErrorBudgets(w); err != nil {
+		return err
+	}
+	if err := r.writePriorities(w); err != nil {
+		return err
+	}
+	if !r.hasErrorsByTier() {
+		return nil
+	}
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STRATEGY\tWORKLOAD\tTIER\tREJECTED\tTIMEOUTS\tFAILURES")
+	for _, run := range r.Runs {
+		for _, wr := range run.Workloads {
+			for _, tier := range util.Tiers {
+				if errs, ok := wr.ErrorsByTier[tier]; ok {
+					fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%d\n", run.Strategy, wr.Workload, tier, errs.Rejected, errs.Timeouts, errs.Failures)
+				}
+			}
+		}
+	}
+	return tw.Flush()
+}
+
+func (r *Results) hasErrorsByTier() bool {
+	for _, run := range r.Runs {
+		for _, wr := range run.Workloads {
+			if len(wr.ErrorsByTier) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// formatRate formats count as a percentage of total, where a rate with no total is formatted as "-".
+func formatRate(count uint64, total uint64) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(count)/float64(total))
+}
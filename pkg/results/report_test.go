@@ -0,0 +1,78 @@
+package results
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport(t *testing.T) {
+	dir := t.TempDir()
+	_, err := Latest(dir)
+	assert.Error(t, err)
+
+	older := &Dir{Path: filepath.Join(dir, time.Now().Add(-time.Hour).Format(dirTimeLayout)+"-scenario")}
+	latest := &Dir{Path: filepath.Join(dir, time.Now().Format(dirTimeLayout)+"-scenario")}
+	for _, runDir := range []*Dir{older, latest} {
+		require.NoError(t, os.MkdirAll(runDir.Path, 0o755))
+	}
+	require.NoError(t, latest.WriteResults(&Results{Runs: []*Run{{
+		Strategy: "bulkhead",
+		Workloads: []*WorkloadResult{
+			{Workload: "reads", Total: 200, Successes: 150, Rejected: 40, Timeouts: 10, Failures: 50, Goodput: 15, Latency: Latency{P50: 20, P99: 95.5},
+				ErrorsByTier: map[string]*TierErrors{
+					"downstream": {Rejected: 30, Timeouts: 5, Failures: 35},
+					"client":     {Rejected: 10, Timeouts: 5, Failures: 15},
+				}},
+			{Workload: "idle"},
+		},
+	}}}))
+
+	found, err := Latest(dir)
+	require.NoError(t, err)
+	assert.Equal(t, latest.Path, found.Path)
+	_, err = older.ReadResults()
+	assert.Error(t, err)
+
+	results, err := found.ReadResults()
+	require.NoError(t, err)
+	var report bytes.Buffer
+	require.NoError(t, results.WriteReport(&report))
+	assert.Equal(t, `STRATEGY  WORKLOAD  GOODPUT  P50     P99     REJECTION RATE  TIMEOUT RATE
+bulkhead  reads     15.0/s   20.0ms  95.5ms  20.0%           5.0%
+bulkhead  idle      0.0/s    0.0ms   0.0ms   -               -
+
+STRATEGY  WORKLOAD  TIER        REJECTED  TIMEOUTS  FAILURES
+bulkhead  reads     client      10        5         15
+bulkhead  reads     downstream  30        5         35
+`, report.String())
+}
+
+func TestControlComparison(t *testing.T) {
+	results := &Results{Runs: []*Run{
+		{Strategy: "baseline", Control: true, Workloads: []*WorkloadResult{
+			{Workload: "reads", Total: 100, Rejected: 0, Timeouts: 20, Goodput: 40, Latency: Latency{P99: 200}},
+		}},
+		{Strategy: "bulkhead", Workloads: []*WorkloadResult{
+			{Workload: "reads", Total: 100, Rejected: 10, Timeouts: 5, Goodput: 50, Latency: Latency{P99: 150}},
+			{Workload: "writes", Total: 10},
+		}},
+	}}
+	var report bytes.Buffer
+	require.NoError(t, results.writeComparison(&report))
+	assert.Equal(t, `
+STRATEGY  WORKLOAD  GOODPUT VS CONTROL  P99 VS CONTROL  REJECTION RATE VS CONTROL  TIMEOUT RATE VS CONTROL
+bulkhead  reads     +25.0%              -25.0%          +10.0pp                    -15.0pp
+`, report.String())
+
+	// Without a control, there's nothing to compare to
+	results.Runs[0].Control = false
+	report.Reset()
+	require.NoError(t, results.writeComparison(&report))
+	assert.Empty(t, report.String())
+}
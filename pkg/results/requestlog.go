@@ -0,0 +1,193 @@
+package results
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	hdrhistogram "github.com/HdrHistogram/hdrhistogram-go"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	RequestLogCSV = "csv" // writes every request to requests.csv.gz
+	RequestLogHDR = "hdr" // records each strategy and workload's latencies in an HdrHistogram, written to an .hgrm file
+
+	RequestLogFile = "requests.csv.gz"
+)
+
+// RequestLogConfig configures a log of every request's outcome in the run directory, for offline analysis beyond what
+// the results' histograms provide, such as exact percentiles or per-second breakdowns.
+type RequestLogConfig struct {
+	Format string `yaml:"format"` // csv or hdr. Defaults to csv.
+}
+
+func (c *RequestLogConfig) UnmarshalYAML(value *yaml.Node) error {
+	*c = RequestLogConfig{
+		Format: RequestLogCSV,
+	}
+	type Alias RequestLogConfig
+	var alias = Alias(*c)
+	if err := value.Decode(&alias); err != nil {
+		return err
+	}
+	*c = RequestLogConfig(alias)
+	return nil
+}
+
+func (c *RequestLogConfig) Validate() error {
+	if c.Format != RequestLogCSV && c.Format != RequestLogHDR {
+		return fmt.Errorf("invalid request_log format: %s", c.Format)
+	}
+	return nil
+}
+
+// Request is the outcome of a request that's written to a request log.
+type Request struct {
+	Time     time.Time // when the request was sent
+	Strategy string
+	Workload string
+	Priority int
+	Latency  time.Duration
+	Outcome  string // success, rejected, timeout, or failure
+	Tier     string // the tier that a failure originated from
+	Reason   string // why a rejected request was rejected, such as the client policy that rejected it
+}
+
+// RequestLog writes the outcome of each request to a run directory. A nil RequestLog doesn't write anything.
+type RequestLog struct {
+	dir *Dir
+
+	mtx        sync.Mutex
+	closed     bool                               // Guarded by mtx
+	file       *os.File                           // Guarded by mtx
+	buffer     *bufio.Writer                      // Guarded by mtx
+	gzip       *gzip.Writer                       // Guarded by mtx
+	csv        *csv.Writer                        // Guarded by mtx
+	histograms map[string]*hdrhistogram.Histogram // by strategy and workload file name. Guarded by mtx
+}
+
+// OpenRequestLog opens a request log in the run directory, returning nil if config is nil.
+func OpenRequestLog(dir *Dir, config *RequestLogConfig) (*RequestLog, error) {
+	if config == nil {
+		return nil, nil
+	}
+	l := &RequestLog{dir: dir}
+	if config.Format == RequestLogHDR {
+		l.histograms = make(map[string]*hdrhistogram.Histogram)
+		return l, nil
+	}
+	file, err := os.Create(dir.File(RequestLogFile))
+	if err != nil {
+		return nil, err
+	}
+	l.file = file
+	l.buffer = bufio.NewWriter(file)
+	l.gzip = gzip.NewWriter(l.buffer)
+	l.csv = csv.NewWriter(l.gzip)
+	err = l.csv.Write([]string{"time", "strategy", "workload", "priority", "latency_ms", "outcome", "tier", "reason"})
+	return l, err
+}
+
+// Record writes a request to the log, ignoring requests that complete after the log is closed. Only requests whose
+// response times are recorded, which are successes and timeouts, are included in latency distributions.
+func (l *RequestLog) Record(request *Request) {
+	if l == nil {
+		return
+	}
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if l.closed {
+		return
+	}
+	if l.histograms != nil {
+		if request.Outcome == "success" || request.Outcome == "timeout" {
+			name := hgrmFileName(request.Strategy, request.Workload)
+			histogram, ok := l.histograms[name]
+			if !ok {
+				histogram = newLatencyHistogram()
+				l.histograms[name] = histogram
+			}
+			recordLatency(histogram, request.Latency)
+		}
+		return
+	}
+	// Errors are reported when the log is closed
+	_ = l.csv.Write([]string{
+		request.Time.Format(time.RFC3339Nano),
+		request.Strategy,
+		request.Workload,
+		strconv.Itoa(request.Priority),
+		strconv.FormatFloat(millis(request.Latency), 'f', 3, 64),
+		request.Outcome,
+		request.Tier,
+		request.Reason,
+	})
+}
+
+// Close flushes the log, writing any latency distributions.
+func (l *RequestLog) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if l.closed {
+		return nil
+	}
+	l.closed = true
+	if l.histograms != nil {
+		var errs []error
+		for name, histogram := range l.histograms {
+			errs = append(errs, l.writeDistribution(name, histogram))
+		}
+		return errors.Join(errs...)
+	}
+	l.csv.Flush()
+	return errors.Join(l.csv.Error(), l.gzip.Close(), l.buffer.Flush(), l.file.Close())
+}
+
+// hgrmFileName returns the name of the file that a strategy and workload's latency distribution is written to.
+func hgrmFileName(strategy string, workload string) string {
+	replacer := strings.NewReplacer("/", "_", " ", "_")
+	return "latency-" + replacer.Replace(strategy) + "-" + replacer.Replace(workload) + ".hgrm"
+}
+
+// writeDistribution writes a latency distribution to a file in the run directory.
+func (l *RequestLog) writeDistribution(name string, histogram *hdrhistogram.Histogram) error {
+	file, err := os.Create(l.dir.File(name))
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	return errors.Join(writeHgrm(w, histogram), w.Flush(), file.Close())
+}
+
+// hgrmTicksPerHalfDistance is how many percentiles are reported each time the distance to 100% halves.
+const hgrmTicksPerHalfDistance = 5
+
+// newLatencyHistogram returns a histogram that records latencies in microseconds, from 1µs to an hour, with 3 significant
+// digits, so that its memory is bounded regardless of how many requests are recorded.
+func newLatencyHistogram() *hdrhistogram.Histogram {
+	return hdrhistogram.New(1, time.Hour.Microseconds(), 3)
+}
+
+// recordLatency records a latency in a histogram, clamping it to the histogram's trackable range.
+func recordLatency(histogram *hdrhistogram.Histogram, latency time.Duration) {
+	_ = histogram.RecordValue(min(max(latency.Microseconds(), 1), histogram.HighestTrackableValue()))
+}
+
+// writeHgrm writes the percentile distribution of a histogram, in milliseconds, in the .hgrm format that HdrHistogram's
+// plotter and other tools can read.
+func writeHgrm(w io.Writer, histogram *hdrhistogram.Histogram) error {
+	_, err := histogram.PercentilesPrint(w, hgrmTicksPerHalfDistance, float64(time.Millisecond/time.Microsecond))
+	return err
+}
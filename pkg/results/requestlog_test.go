@@ -0,0 +1,65 @@
+package results
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestRequestLogConfig(t *testing.T) {
+	var config RequestLogConfig
+	require.NoError(t, yaml.Unmarshal([]byte("{}"), &config))
+	assert.Equal(t, RequestLogCSV, config.Format)
+	assert.NoError(t, config.Validate())
+	assert.ErrorContains(t, (&RequestLogConfig{Format: "parquet"}).Validate(), "invalid request_log format")
+}
+
+func TestHDRRequestLog(t *testing.T) {
+	dir := &Dir{Path: t.TempDir()}
+	requestLog, err := OpenRequestLog(dir, &RequestLogConfig{Format: RequestLogHDR})
+	require.NoError(t, err)
+	for i := 1; i <= 100; i++ {
+		requestLog.Record(&Request{Strategy: "bulkhead", Workload: "reads", Latency: time.Duration(i) * time.Millisecond, Outcome: "success"})
+	}
+	// Rejected requests don't have response times, and requests that complete after the log is closed are ignored
+	requestLog.Record(&Request{Strategy: "bulkhead", Workload: "reads", Latency: time.Second, Outcome: "rejected"})
+	require.NoError(t, requestLog.Close())
+	requestLog.Record(&Request{Strategy: "bulkhead", Workload: "reads", Latency: time.Second, Outcome: "success"})
+	require.NoError(t, requestLog.Close())
+
+	data, err := os.ReadFile(dir.File("latency-bulkhead-reads.hgrm"))
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	assert.Equal(t, " Value\tPercentile\tTotalCount\t1/(1-Percentile)", lines[0])
+	assert.Equal(t, "       1.000     0.000000            1         1.00", lines[2])
+
+	// Latencies are bucketed to 3 significant digits
+	assert.Contains(t, lines, "      50.015     0.500000           50         2.00")
+	assert.Contains(t, lines, "      90.047     0.900000           90        10.00")
+	assert.Equal(t, "     100.031     1.000000          100          inf", lines[len(lines)-4])
+	assert.Equal(t, "#[Max     =      100.031, Total count    =          100]", lines[len(lines)-2])
+}
+
+func TestRecordLatency(t *testing.T) {
+	histogram := newLatencyHistogram()
+
+	// Latencies outside of the histogram's range are clamped rather than dropped
+	recordLatency(histogram, 0)
+	recordLatency(histogram, 2*time.Hour)
+	assert.Equal(t, int64(2), histogram.TotalCount())
+	assert.Equal(t, int64(1), histogram.Min())
+	assert.True(t, histogram.ValuesAreEquivalent(time.Hour.Microseconds(), histogram.Max()))
+}
+
+func TestNilRequestLog(t *testing.T) {
+	requestLog, err := OpenRequestLog(&Dir{Path: t.TempDir()}, nil)
+	require.NoError(t, err)
+	assert.Nil(t, requestLog)
+	requestLog.Record(&Request{Outcome: "success"})
+	assert.NoError(t, requestLog.Close())
+}
@@ -0,0 +1,400 @@
+package results
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"tripwire/pkg/metrics"
+	"tripwire/pkg/util"
+)
+
+// Results describes a complete tripwire run, which may include several strategies.
+type Results struct {
+	Metadata
+	Scenario string           `json:"scenario,omitempty"` // the name of the scenario's config, which is the same across its runs
+	Seed     int64            `json:"seed"`
+	Seeds    map[string]int64 `json:"seeds,omitempty"`    // the seed of each source of randomness, such as arrivals
+	Unseeded []string         `json:"unseeded,omitempty"` // sources of randomness that the seeds don't reproduce, such as policies
+	Shard    string           `json:"shard,omitempty"`    // the shard of the scenario's load that was run, if any, such as 0/4
+	// Listeners are the addresses that tripwire listened on, such as for metrics, which may have been chosen at runtime
+	Listeners map[string]string `json:"listeners,omitempty"`
+	Start     time.Time         `json:"start"`
+	End       time.Time         `json:"end"`
+	Runs      []*Run            `json:"runs"`
+}
+
+// Metadata describes a scenario, strategy, or workload, so that stored results can be filtered and organized.
+type Metadata struct {
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+func (m Metadata) String() string {
+	if len(m.Tags) == 0 {
+		return m.Description
+	}
+	tags := "[" + strings.Join(m.Tags, ", ") + "]"
+	if m.Description == "" {
+		return tags
+	}
+	return m.Description + " " + tags
+}
+
+// Run describes the results of a single strategy.
+type Run struct {
+	RunID    string `json:"run_id"`
+	Strategy string `json:"strategy"`
+	Metadata
+	Parameters map[string]string `json:"parameters,omitempty"` // swept parameter values, by path
+	Control    bool              `json:"control,omitempty"`    // whether the run is the baseline that other runs are compared to
+	ServerAddr string            `json:"server_addr"`          // the address that the strategy's server listened on
+	Start      time.Time         `json:"start"`
+	End        time.Time         `json:"end"`
+	Workloads  []*WorkloadResult `json:"workloads"`
+	Tool       *ToolResult       `json:"tool"`
+
+	// Business weighted results, when workloads have business weights
+	WeightedGoodput float64 `json:"weighted_goodput,omitempty"` // successful requests per second, weighted by their workload's weight
+	RejectionCost   float64 `json:"rejection_cost,omitempty"`   // rejected requests, weighted by their workload's weight
+
+	// Responsiveness to staged overload, in seconds, when measured
+	TimeToFirstRejection *float64 `json:"time_to_first_rejection,omitempty"`
+	RecoveryTime         *float64 `json:"recovery_time,omitempty"`
+
+	// BudgetConsumed is the fraction of the error budgets consumed across the workloads with SLOs, if any
+	BudgetConsumed *float64 `json:"budget_consumed,omitempty"`
+
+	// StageSLOs are the outcomes of the SLOs of the run's stages, if any
+	StageSLOs []*StageSLOResult `json:"stage_slos,omitempty"`
+
+	// Aborted is the reason that the run was aborted before it stopped on its own, if it was
+	Aborted string `json:"aborted,omitempty"`
+
+	// Analytic is the expected utilization and queueing of the run's server according to an M/M/c model, for simple
+	// configurations
+	Analytic *Analytic `json:"analytic,omitempty"`
+
+	workloads        []string
+	workloadMetadata map[string]Metadata
+	businessWeights  map[string]float64
+	burnRates        map[string]*burnRateWindow // by workload, for workloads with SLOs
+	stageSLOs        []StageSLO
+	startStats       metrics.SelfStats
+	maxGoroutines    int
+	periodStart      time.Time // when the current latency period started
+}
+
+// ToolResult describes tripwire's own resource usage during a run, so that degradation of the tool can be distinguished
+// from degradation of the system under test. Since usage is process wide, it includes any strategies that ran in
+// parallel.
+type ToolResult struct {
+	CPU           float64 `json:"cpu"`      // CPU seconds used
+	GCPause       float64 `json:"gc_pause"` // GC pause time, in milliseconds
+	GCs           uint32  `json:"gcs"`
+	MaxGoroutines int     `json:"max_goroutines"`
+}
+
+type WorkloadResult struct {
+	Workload string `json:"workload"`
+	Metadata
+	Total     uint64  `json:"total"`
+	Successes uint64  `json:"successes"`
+	Rejected  uint64  `json:"rejected"`
+	Timeouts  uint64  `json:"timeouts"`
+	Failures  uint64  `json:"failures"`
+	Dropped   uint64  `json:"dropped"`              // arrivals that were never sent because the generator fell behind
+	BackedOff uint64  `json:"backed_off,omitempty"` // arrivals that were never sent because the host was saturated
+	Goodput   float64 `json:"goodput"`              // successful requests per second
+	Latency   Latency `json:"latency"`
+
+	BusinessWeight float64 `json:"business_weight,omitempty"` // the business value of each request, when weighted
+
+	// WastedWork is the seconds of server and downstream work that completed after the client's deadline had passed
+	WastedWork float64 `json:"wasted_work,omitempty"`
+
+	// ErrorsByTier are the failures by the tier that they originated from, such as the client, server, or downstream
+	ErrorsByTier map[string]*TierErrors `json:"errors_by_tier,omitempty"`
+
+	// ErrorBudget is how much of the workload's error budget was consumed, when it has an SLO
+	ErrorBudget *ErrorBudget `json:"error_budget,omitempty"`
+
+	// Periods are the workload's latency in each period that its response times were rotated into, such as each stage
+	Periods []*LatencyPeriod `json:"periods,omitempty"`
+
+	// Priorities are the workload's results by priority class
+	Priorities []*PriorityResult `json:"priorities,omitempty"`
+
+	// Expected is the workload's latency that's expected by the run's analytic model, if any
+	Expected *ExpectedLatency `json:"expected,omitempty"`
+}
+
+// TierErrors counts the failures that originated from a tier, where Failures includes rejections and timeouts.
+type TierErrors struct {
+	Rejected uint64 `json:"rejected"`
+	Timeouts uint64 `json:"timeouts"`
+	Failures uint64 `json:"failures"`
+}
+
+// Latency summarizes client response times, in milliseconds.
+type Latency struct {
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+}
+
+// collect reads the current workload results for the run from the metrics.
+func (r *Run) collect(m *metrics.Metrics, end time.Time) {
+	r.End = end
+	r.Workloads = nil
+	seconds := end.Sub(r.Start).Seconds()
+	for _, workload := range r.workloads {
+		workloadMetrics := m.WithWorkload(r.RunID, workload, r.Strategy)
+		result := &WorkloadResult{
+			Workload:  workload,
+			Metadata:  r.workloadMetadata[workload],
+			Total:     uint64(m.Value(workloadMetrics.ClientReqTotal)),
+			Successes: uint64(m.Value(workloadMetrics.ClientReqSuccesses)),
+			Rejected:  uint64(m.Value(workloadMetrics.ClientReqRejected)),
+			Timeouts:  uint64(m.Value(workloadMetrics.ClientReqTimeouts)),
+			Failures:  uint64(m.Value(workloadMetrics.ClientReqFailures)),
+			Dropped:   uint64(m.Value(workloadMetrics.ClientDroppedArrivals)),
+			BackedOff: uint64(m.Value(workloadMetrics.ClientBackedOffArrivals)),
+			Latency:   latencyOf(workloadMetrics.ResponseTimes.Merged()),
+			Periods:   collectPeriods(workloadMetrics.ResponseTimes, end),
+		}
+		if seconds > 0 {
+			result.Goodput = float64(result.Successes) / seconds
+		}
+		result.ErrorsByTier = errorsByTier(m, workloadMetrics)
+		result.Priorities = collectPriorities(workloadMetrics, m, seconds)
+		if r.Analytic != nil {
+			result.Expected = r.Analytic.workloads[workload]
+		}
+		for _, tier := range []string{util.TierServer, util.TierDownstream} {
+			result.WastedWork += m.Value(m.WithServerWastedWork(workload, r.Strategy, tier))
+		}
+		if window := r.burnRates[workload]; window != nil {
+			bad := result.Failures + uint64(m.Value(workloadMetrics.ClientReqSlow))
+			window.record(end, result.Total, bad)
+			result.ErrorBudget = window.errorBudget(result.Total, bad)
+		}
+		r.Workloads = append(r.Workloads, result)
+	}
+	r.collectBudgetConsumed()
+	r.collectGoodputShares()
+	if r.businessWeights != nil {
+		r.WeightedGoodput, r.RejectionCost = 0, 0
+		for _, result := range r.Workloads {
+			result.BusinessWeight = r.businessWeights[result.Workload]
+			r.WeightedGoodput += result.BusinessWeight * result.Goodput
+			r.RejectionCost += result.BusinessWeight * float64(result.Rejected)
+		}
+	}
+	r.collectStageSLOs(m)
+
+	stats := metrics.ReadSelfStats()
+	r.Tool = &ToolResult{
+		CPU:           (stats.CPU - r.startStats.CPU).Seconds(),
+		GCPause:       millis(stats.GCPause - r.startStats.GCPause),
+		GCs:           stats.GCs - r.startStats.GCs,
+		MaxGoroutines: max(r.maxGoroutines, stats.Goroutines),
+	}
+}
+
+// collectBudgetConsumed computes the fraction of the error budgets consumed across the workloads with SLOs, if any.
+func (r *Run) collectBudgetConsumed() {
+	var bad, allowed float64
+	for _, result := range r.Workloads {
+		if eb := result.ErrorBudget; eb != nil {
+			bad += float64(eb.Bad)
+			allowed += float64(result.Total) * (1 - eb.Target)
+		}
+	}
+	if r.burnRates != nil {
+		var consumed float64
+		if allowed > 0 {
+			consumed = bad / allowed
+		}
+		r.BudgetConsumed = &consumed
+	}
+}
+
+// errorsByTier returns a workload's failures by the tier that they originated from, omitting tiers without failures.
+func errorsByTier(m *metrics.Metrics, workloadMetrics *metrics.WorkloadMetrics) map[string]*TierErrors {
+	var result map[string]*TierErrors
+	for _, tier := range util.Tiers {
+		errs := &TierErrors{
+			Rejected: uint64(m.Value(workloadMetrics.ClientReqErrors.WithLabelValues(tier, "rejected"))),
+			Timeouts: uint64(m.Value(workloadMetrics.ClientReqErrors.WithLabelValues(tier, "timeout"))),
+		}
+		errs.Failures = errs.Rejected + errs.Timeouts + uint64(m.Value(workloadMetrics.ClientReqErrors.WithLabelValues(tier, "failure")))
+		if errs.Failures == 0 {
+			continue
+		}
+		if result == nil {
+			result = make(map[string]*TierErrors)
+		}
+		result[tier] = errs
+	}
+	return result
+}
+
+func latencyOf(h *metrics.Histogram) Latency {
+	return Latency{
+		Mean: millis(h.Mean()),
+		P50:  millis(h.Quantile(.5)),
+		P90:  millis(h.Quantile(.9)),
+		P99:  millis(h.Quantile(.99)),
+		Max:  millis(h.Max()),
+	}
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// WriteSummary writes human-readable tables of the results to w.
+func (r *Results) WriteSummary(w io.Writer) error {
+	if r.Description != "" {
+		fmt.Fprintf(w, "description: %s\n", r.Description)
+	}
+	if len(r.Tags) > 0 {
+		fmt.Fprintf(w, "tags: %s\n", strings.Join(r.Tags, ", "))
+	}
+	fmt.Fprintf(w, "seed: %d\n", r.Seed)
+	if len(r.Unseeded) > 0 {
+		fmt.Fprintf(w, "not reproducible from the seed: %s\n", strings.Join(r.Unseeded, ", "))
+	}
+	if r.Shard != "" {
+		fmt.Fprintf(w, "shard: %s\n", r.Shard)
+	}
+	fmt.Fprintf(w, "duration: %s\n\n", r.End.Sub(r.Start).Round(time.Second))
+	var described bool
+	for _, run := range r.Runs {
+		if run.Description != "" || len(run.Tags) > 0 {
+			fmt.Fprintf(w, "strategy %s: %s\n", run.Strategy, run.Metadata)
+			described = true
+		}
+	}
+	if described {
+		fmt.Fprintln(w)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STRATEGY\tWORKLOAD\tTOTAL\tSUCCESS\tREJECTED\tTIMEOUTS\tFAILURES\tDROPPED\tGOODPUT\tMEAN\tP50\tP90\tP99\tMAX")
+	for _, run := range r.Runs {
+		for _, wr := range run.Workloads {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\t%d\t%d\t%.1f/s\t%.1fms\t%.1fms\t%.1fms\t%.1fms\t%.1fms\n", run.Strategy,
+				wr.Workload, wr.Total, wr.Successes, wr.Rejected, wr.Timeouts, wr.Failures, wr.Dropped, wr.Goodput,
+				wr.Latency.Mean, wr.Latency.P50, wr.Latency.P90, wr.Latency.P99, wr.Latency.Max)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if backedOff := r.backedOff(); backedOff > 0 {
+		fmt.Fprintf(w, "\nnote: %d arrivals were not sent because the host was saturated, so results understate the configured load\n", backedOff)
+	}
+	for _, run := range r.Runs {
+		if run.Aborted != "" {
+			fmt.Fprintf(w, "\nnote: strategy %s was aborted since it %s, so its results are incomplete\n", run.Strategy, run.Aborted)
+		}
+	}
+
+	if err := r.writeComparison(w); err != nil {
+		return err
+	}
+
+	if err := r.writeErrorBudgets(w); err != nil {
+		return err
+	}
+	if err := r.writeStageSLOs(w); err != nil {
+		return err
+	}
+
+	if err := r.writePriorities(w); err != nil {
+		return err
+	}
+
+	if weighted := r.rankedByWeightedGoodput(); len(weighted) > 0 {
+		fmt.Fprintln(w)
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "STRATEGY\tWEIGHTED GOODPUT\tREJECTION COST")
+		for _, run := range weighted {
+			fmt.Fprintf(tw, "%s\t%.1f/s\t%.1f\n", run.Strategy, run.WeightedGoodput, run.RejectionCost)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if r.hasResponsiveness() {
+		fmt.Fprintln(w)
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "STRATEGY\tTIME TO FIRST REJECTION\tRECOVERY TIME")
+		for _, run := range r.Runs {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", run.Strategy, formatSeconds(run.TimeToFirstRejection), formatSeconds(run.RecoveryTime))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STRATEGY\tTOOL CPU\tGC PAUSE\tGCS\tMAX GOROUTINES")
+	for _, run := range r.Runs {
+		if run.Tool != nil {
+			fmt.Fprintf(tw, "%s\t%.2fs\t%.1fms\t%d\t%d\n", run.Strategy, run.Tool.CPU, run.Tool.GCPause, run.Tool.GCs, run.Tool.MaxGoroutines)
+		}
+	}
+	return tw.Flush()
+}
+
+// rankedByWeightedGoodput returns the runs that have business weights, from the highest weighted goodput to the lowest.
+func (r *Results) rankedByWeightedGoodput() []*Run {
+	var runs []*Run
+	for _, run := range r.Runs {
+		if run.businessWeights != nil {
+			runs = append(runs, run)
+		}
+	}
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].WeightedGoodput > runs[j].WeightedGoodput
+	})
+	return runs
+}
+
+// backedOff returns the total arrivals that weren't sent because the host was saturated.
+func (r *Results) backedOff() uint64 {
+	var backedOff uint64
+	for _, run := range r.Runs {
+		for _, wr := range run.Workloads {
+			backedOff += wr.BackedOff
+		}
+	}
+	return backedOff
+}
+
+func (r *Results) hasResponsiveness() bool {
+	for _, run := range r.Runs {
+		if run.TimeToFirstRejection != nil || run.RecoveryTime != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// formatSeconds formats an optional number of seconds, where a missing value is formatted as "-".
+func formatSeconds(seconds *float64) string {
+	if seconds == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.2fs", *seconds)
+}
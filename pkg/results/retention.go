@@ -0,0 +1,106 @@
+package results
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// dirTimeLayout is the layout of the timestamp that run directory names begin with, which sorts chronologically.
+const dirTimeLayout = "20060102-150405"
+
+// runDir is a run directory that is subject to retention.
+type runDir struct {
+	path    string
+	created time.Time
+	size    int64
+}
+
+// Prune removes the run directories in the config's Dir that exceed its retention, oldest first, and returns the paths
+// of the removed directories. The newest run directory is always retained, since it may belong to a run that's in
+// progress. Only directories whose names begin with a run timestamp are considered. When dryRun is true, the directories
+// that would be removed are returned without removing them.
+func Prune(config *Config, now time.Time, dryRun bool) ([]string, error) {
+	if config.Keep <= 0 && config.MaxAge <= 0 && config.MaxSize <= 0 {
+		return nil, nil
+	}
+	runs, err := readRunDirs(config.Dir)
+	if err != nil || len(runs) == 0 {
+		return nil, err
+	}
+
+	var totalSize int64
+	for _, run := range runs {
+		totalSize += run.size
+	}
+	var removed []string
+	// Runs are sorted from oldest to newest, and the newest is never removed
+	for i, run := range runs[:len(runs)-1] {
+		remaining := len(runs) - i
+		expired := (config.Keep > 0 && remaining > config.Keep) ||
+			(config.MaxAge > 0 && now.Sub(run.created) > config.MaxAge) ||
+			(config.MaxSize > 0 && totalSize > config.MaxSize)
+		if !expired {
+			continue
+		}
+		if !dryRun {
+			if err = os.RemoveAll(run.path); err != nil {
+				return removed, err
+			}
+		}
+		totalSize -= run.size
+		removed = append(removed, run.path)
+	}
+	return removed, nil
+}
+
+// readRunDirs returns the run directories in dir, sorted from oldest to newest.
+func readRunDirs(dir string) ([]*runDir, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var runs []*runDir
+	for _, entry := range entries {
+		if !entry.IsDir() || len(entry.Name()) < len(dirTimeLayout) {
+			continue
+		}
+		created, err := time.ParseInLocation(dirTimeLayout, entry.Name()[:len(dirTimeLayout)], time.Local)
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		size, err := dirSize(path)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, &runDir{path: path, created: created, size: size})
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].path < runs[j].path
+	})
+	return runs, nil
+}
+
+func dirSize(path string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(path, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
@@ -0,0 +1,64 @@
+package results
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrune(t *testing.T) {
+	now := time.Date(2025, 1, 10, 12, 0, 0, 0, time.Local)
+	create := func(dir string, age time.Duration, size int) string {
+		path := filepath.Join(dir, now.Add(-age).Format(dirTimeLayout)+"-scenario")
+		require.NoError(t, os.MkdirAll(path, 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(path, ResultsFile), make([]byte, size), 0o644))
+		return path
+	}
+	setup := func() (string, []string) {
+		dir := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "notes"), 0o755))
+		return dir, []string{
+			create(dir, 72*time.Hour, 100),
+			create(dir, 48*time.Hour, 100),
+			create(dir, 24*time.Hour, 100),
+			create(dir, time.Hour, 100),
+		}
+	}
+
+	t.Run("keep", func(t *testing.T) {
+		dir, runs := setup()
+		removed, err := Prune(&Config{Dir: dir, Keep: 2}, now, false)
+		require.NoError(t, err)
+		assert.Equal(t, runs[:2], removed)
+		assert.NoDirExists(t, runs[1])
+		assert.DirExists(t, runs[2])
+		assert.DirExists(t, filepath.Join(dir, "notes"))
+	})
+
+	t.Run("max age", func(t *testing.T) {
+		dir, runs := setup()
+		removed, err := Prune(&Config{Dir: dir, MaxAge: 36 * time.Hour}, now, false)
+		require.NoError(t, err)
+		assert.Equal(t, runs[:2], removed)
+	})
+
+	t.Run("max size retains newest", func(t *testing.T) {
+		dir, runs := setup()
+		removed, err := Prune(&Config{Dir: dir, MaxSize: 50}, now, false)
+		require.NoError(t, err)
+		assert.Equal(t, runs[:3], removed)
+		assert.DirExists(t, runs[3])
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		dir, runs := setup()
+		removed, err := Prune(&Config{Dir: dir, Keep: 1}, now, true)
+		require.NoError(t, err)
+		assert.Equal(t, runs[:3], removed)
+		assert.DirExists(t, runs[0])
+	})
+}
@@ -0,0 +1,318 @@
+package results
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"tripwire/pkg/events"
+)
+
+// Sink is a destination for a run's output: the time series samples taken while it runs, the events that occur, and its
+// results when it ends.
+type Sink interface {
+	WriteSamples(samples []*Sample) error
+	WriteEvent(event *events.Event) error
+	WriteResults(results *Results) error
+	Close() error
+}
+
+// SinkType is the type of an output sink.
+type SinkType string
+
+const (
+	FileSink        SinkType = "file"         // JSON lines written to a file
+	StdoutJSONSink  SinkType = "stdout_json"  // JSON lines written to stdout
+	RemoteWriteSink SinkType = "remote_write" // time series sent to a Prometheus remote write endpoint
+	OTLPSink        SinkType = "otlp"         // time series and events sent to an OTLP/HTTP endpoint as metrics and logs
+)
+
+// SinkConfig configures an output sink that a run's output is written to in addition to its run directory.
+type SinkConfig struct {
+	Type    SinkType          `yaml:"type"`
+	Path    string            `yaml:"path"`    // the file that a file sink writes to
+	URL     string            `yaml:"url"`     // the endpoint that remote_write and otlp sinks send to
+	Headers map[string]string `yaml:"headers"` // headers sent with each request, such as for authentication
+	Timeout time.Duration     `yaml:"timeout"` // the timeout for each request. Defaults to 10s.
+}
+
+func (c *SinkConfig) UnmarshalYAML(value *yaml.Node) error {
+	*c = SinkConfig{
+		Timeout: 10 * time.Second,
+	}
+	type Alias SinkConfig
+	var alias = Alias(*c)
+	if err := value.Decode(&alias); err != nil {
+		return err
+	}
+	*c = SinkConfig(alias)
+	return nil
+}
+
+func (c *SinkConfig) Validate() error {
+	switch c.Type {
+	case FileSink:
+		if c.Path == "" {
+			return fmt.Errorf("file sink requires a path")
+		}
+	case StdoutJSONSink:
+	case RemoteWriteSink, OTLPSink:
+		if c.URL == "" {
+			return fmt.Errorf("%s sink requires a url", c.Type)
+		}
+		if _, err := url.Parse(c.URL); err != nil {
+			return fmt.Errorf("invalid %s sink url: %w", c.Type, err)
+		}
+		if c.Timeout <= 0 {
+			return fmt.Errorf("%s sink timeout must be positive", c.Type)
+		}
+	default:
+		return fmt.Errorf("invalid sink type: %s", c.Type)
+	}
+	return nil
+}
+
+// Sinks writes to several sinks, returning any of their errors.
+type Sinks []Sink
+
+// OpenSinks opens a sink for the run directory, which is always written, followed by any configured sinks.
+func OpenSinks(dir *Dir, configs []*SinkConfig) (Sinks, error) {
+	dirSink, err := newDirSink(dir)
+	if err != nil {
+		return nil, err
+	}
+	sinks := Sinks{dirSink}
+	for _, config := range configs {
+		sink, err := newSink(config)
+		if err != nil {
+			_ = sinks.Close()
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	return sinks, nil
+}
+
+// HasStdoutSink returns whether any of the config's sinks write to stdout.
+func (c *Config) HasStdoutSink() bool {
+	for _, sink := range c.Sinks {
+		if sink.Type == StdoutJSONSink {
+			return true
+		}
+	}
+	return false
+}
+
+func newSink(config *SinkConfig) (Sink, error) {
+	switch config.Type {
+	case FileSink:
+		file, err := os.Create(config.Path)
+		if err != nil {
+			return nil, err
+		}
+		return newJSONSink(file, file), nil
+	case StdoutJSONSink:
+		return newJSONSink(os.Stdout, nil), nil
+	case RemoteWriteSink:
+		return newRemoteWriteSink(config), nil
+	case OTLPSink:
+		return newOTLPSink(config), nil
+	default:
+		return nil, fmt.Errorf("invalid sink type: %s", config.Type)
+	}
+}
+
+func (s Sinks) WriteSamples(samples []*Sample) error {
+	var errs []error
+	for _, sink := range s {
+		errs = append(errs, sink.WriteSamples(samples))
+	}
+	return errors.Join(errs...)
+}
+
+func (s Sinks) WriteEvent(event *events.Event) error {
+	var errs []error
+	for _, sink := range s {
+		errs = append(errs, sink.WriteEvent(event))
+	}
+	return errors.Join(errs...)
+}
+
+func (s Sinks) WriteResults(results *Results) error {
+	var errs []error
+	for _, sink := range s {
+		errs = append(errs, sink.WriteResults(results))
+	}
+	return errors.Join(errs...)
+}
+
+func (s Sinks) Close() error {
+	var errs []error
+	for _, sink := range s {
+		errs = append(errs, sink.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// dirSink writes samples, events, and results to their files in a run directory.
+type dirSink struct {
+	dir *Dir
+
+	mtx        sync.Mutex
+	timeSeries *os.File      // Guarded by mtx
+	samples    *json.Encoder // Guarded by mtx
+	eventsFile *os.File      // Guarded by mtx
+	events     *json.Encoder // Guarded by mtx
+}
+
+func newDirSink(dir *Dir) (*dirSink, error) {
+	timeSeries, err := os.Create(dir.File(TimeSeriesFile))
+	if err != nil {
+		return nil, err
+	}
+	eventsFile, err := os.Create(dir.File(EventsFile))
+	if err != nil {
+		_ = timeSeries.Close()
+		return nil, err
+	}
+	return &dirSink{
+		dir:        dir,
+		timeSeries: timeSeries,
+		samples:    json.NewEncoder(timeSeries),
+		eventsFile: eventsFile,
+		events:     json.NewEncoder(eventsFile),
+	}, nil
+}
+
+func (s *dirSink) WriteSamples(samples []*Sample) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for _, sample := range samples {
+		if err := s.samples.Encode(sample); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *dirSink) WriteEvent(event *events.Event) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.events.Encode(event)
+}
+
+func (s *dirSink) WriteResults(results *Results) error {
+	return s.dir.WriteResults(results)
+}
+
+func (s *dirSink) Close() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return errors.Join(s.timeSeries.Close(), s.eventsFile.Close())
+}
+
+// jsonSink writes samples, events, and results as JSON lines, each of which contains one of them.
+type jsonSink struct {
+	closer io.Closer // closes the writer, if it should be closed
+
+	mtx     sync.Mutex
+	encoder *json.Encoder // Guarded by mtx
+}
+
+type jsonRecord struct {
+	Sample  *Sample       `json:"sample,omitempty"`
+	Event   *events.Event `json:"event,omitempty"`
+	Results *Results      `json:"results,omitempty"`
+}
+
+func newJSONSink(w io.Writer, closer io.Closer) *jsonSink {
+	return &jsonSink{closer: closer, encoder: json.NewEncoder(w)}
+}
+
+func (s *jsonSink) WriteSamples(samples []*Sample) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for _, sample := range samples {
+		if err := s.encoder.Encode(&jsonRecord{Sample: sample}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *jsonSink) WriteEvent(event *events.Event) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.encoder.Encode(&jsonRecord{Event: event})
+}
+
+func (s *jsonSink) WriteResults(results *Results) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.encoder.Encode(&jsonRecord{Results: results})
+}
+
+func (s *jsonSink) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
+
+// sampleSeries is one of a sample's values, which remote sinks send as a separate series.
+type sampleSeries struct {
+	name    string
+	value   float64
+	counter bool
+}
+
+// series returns the sample's values as series, along with the labels that identify them.
+func (s *Sample) series() ([]sampleSeries, map[string]string) {
+	series := []sampleSeries{
+		{"tripwire_requests_total", float64(s.Total), true},
+		{"tripwire_successes_total", float64(s.Successes), true},
+		{"tripwire_rejected_total", float64(s.Rejected), true},
+		{"tripwire_timeouts_total", float64(s.Timeouts), true},
+		{"tripwire_failures_total", float64(s.Failures), true},
+		{"tripwire_dropped_total", float64(s.Dropped), true},
+		{"tripwire_inflight", s.Inflight, false},
+	}
+	if s.BurnRate != nil {
+		series = append(series, sampleSeries{"tripwire_burn_rate", *s.BurnRate, false})
+	}
+	return series, map[string]string{"run_id": s.RunID, "strategy": s.Strategy, "workload": s.Workload}
+}
+
+// seriesGroup is a set of series that share the labels that identify them and a timestamp.
+type seriesGroup struct {
+	series    []sampleSeries
+	labels    map[string]string
+	timestamp time.Time
+}
+
+// sampleGroups returns the series of each sample, where samples that are timestamped relative to their strategy's start
+// are timestamped now.
+func sampleGroups(samples []*Sample, now time.Time) []*seriesGroup {
+	groups := make([]*seriesGroup, 0, len(samples))
+	for _, sample := range samples {
+		series, labels := sample.series()
+		groups = append(groups, &seriesGroup{series: series, labels: labels, timestamp: sample.timestamp(now)})
+	}
+	return groups
+}
+
+// timestamp returns the sample's wall clock time, or now if the sample is timestamped relative to its strategy's start,
+// since remote sinks require wall clock times.
+func (s *Sample) timestamp(now time.Time) time.Time {
+	if s.Time != nil {
+		return *s.Time
+	}
+	return now
+}
@@ -0,0 +1,215 @@
+package results
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"tripwire/pkg/events"
+)
+
+func TestSinkConfigValidate(t *testing.T) {
+	assert.NoError(t, (&SinkConfig{Type: StdoutJSONSink}).Validate())
+	assert.NoError(t, (&SinkConfig{Type: OTLPSink, URL: "http://localhost:4318", Timeout: time.Second}).Validate())
+	assert.Error(t, (&SinkConfig{Type: FileSink}).Validate())
+	assert.Error(t, (&SinkConfig{Type: RemoteWriteSink, Timeout: time.Second}).Validate())
+	assert.Error(t, (&SinkConfig{Type: "kafka"}).Validate())
+}
+
+func TestOpenSinks(t *testing.T) {
+	dir := &Dir{Path: t.TempDir()}
+	path := filepath.Join(t.TempDir(), "output.jsonl")
+	sinks, err := OpenSinks(dir, []*SinkConfig{{Type: FileSink, Path: path}})
+	require.NoError(t, err)
+
+	now := time.Now()
+	require.NoError(t, sinks.WriteSamples([]*Sample{{Time: &now, RunID: "abc", Strategy: "bulkhead", Workload: "reads", Total: 10}}))
+	require.NoError(t, sinks.WriteEvent(&events.Event{Time: now, Type: events.StrategyStarted, RunID: "abc"}))
+	require.NoError(t, sinks.WriteResults(&Results{Seed: 1}))
+	require.NoError(t, sinks.Close())
+
+	// The run directory is written along with the configured sinks
+	for _, name := range []string{TimeSeriesFile, EventsFile, ResultsFile, SummaryFile} {
+		assert.FileExists(t, dir.File(name))
+	}
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	var records []jsonRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record jsonRecord
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	require.Len(t, records, 3)
+	assert.Equal(t, uint64(10), records[0].Sample.Total)
+	assert.Equal(t, events.StrategyStarted, records[1].Event.Type)
+	assert.Equal(t, int64(1), records[2].Results.Seed)
+}
+
+func TestRemoteWriteSink(t *testing.T) {
+	var mtx sync.Mutex
+	var bodies [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "snappy", r.Header.Get("Content-Encoding"))
+		assert.Equal(t, "secret", r.Header.Get("Authorization"))
+		body, _ := io.ReadAll(r.Body)
+		mtx.Lock()
+		bodies = append(bodies, body)
+		mtx.Unlock()
+	}))
+	defer server.Close()
+
+	sink := newRemoteWriteSink(&SinkConfig{Type: RemoteWriteSink, URL: server.URL, Headers: map[string]string{"Authorization": "secret"}, Timeout: time.Second})
+	now := time.UnixMilli(1000)
+	require.NoError(t, sink.WriteSamples([]*Sample{{Time: &now, RunID: "abc", Strategy: "bulkhead", Workload: "reads", Total: 10, Inflight: 2}}))
+	require.NoError(t, sink.Close())
+
+	require.Len(t, bodies, 1)
+	request, err := snappy.Decode(nil, bodies[0])
+	require.NoError(t, err)
+	series := decodeWriteRequest(t, request)
+	require.Len(t, series, 7)
+	assert.Equal(t, map[string]string{"__name__": "tripwire_requests_total", "run_id": "abc", "strategy": "bulkhead", "workload": "reads"}, series[0].labels)
+	assert.Equal(t, 10.0, series[0].value)
+	assert.Equal(t, int64(1000), series[0].timestamp)
+	assert.Equal(t, "tripwire_inflight", series[6].labels["__name__"])
+	assert.Equal(t, 2.0, series[6].value)
+}
+
+func TestRemoteWriteSinkResults(t *testing.T) {
+	var mtx sync.Mutex
+	var bodies [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mtx.Lock()
+		bodies = append(bodies, body)
+		mtx.Unlock()
+	}))
+	defer server.Close()
+
+	sink := newRemoteWriteSink(&SinkConfig{Type: RemoteWriteSink, URL: server.URL, Timeout: time.Second})
+	require.NoError(t, sink.WriteResults(&Results{Scenario: "nightly"}))
+	require.NoError(t, sink.WriteResults(&Results{Scenario: "nightly", Runs: []*Run{{Strategy: "bulkhead", End: time.UnixMilli(2000),
+		Workloads: []*WorkloadResult{{Workload: "reads", Total: 10, Rejected: 1, Goodput: 9}}}}}))
+	require.NoError(t, sink.Close())
+
+	// Results without workloads aren't sent
+	require.Len(t, bodies, 1)
+	request, err := snappy.Decode(nil, bodies[0])
+	require.NoError(t, err)
+	series := decodeWriteRequest(t, request)
+	require.Len(t, series, 3)
+	assert.Equal(t, map[string]string{"__name__": "tripwire_result_goodput", "scenario": "nightly", "strategy": "bulkhead", "workload": "reads"}, series[0].labels)
+	assert.Equal(t, 9.0, series[0].value)
+	assert.Equal(t, int64(2000), series[0].timestamp)
+	assert.Equal(t, "tripwire_result_rejection_share", series[2].labels["__name__"])
+	assert.Equal(t, .1, series[2].value)
+}
+
+func TestOTLPSink(t *testing.T) {
+	var mtx sync.Mutex
+	requests := make(map[string]map[string]any)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		mtx.Lock()
+		requests[r.URL.Path] = body
+		mtx.Unlock()
+	}))
+	defer server.Close()
+
+	sink := newOTLPSink(&SinkConfig{Type: OTLPSink, URL: server.URL + "/", Timeout: time.Second})
+	now := time.Now()
+	burnRate := 1.5
+	require.NoError(t, sink.WriteSamples([]*Sample{{Time: &now, RunID: "abc", Strategy: "bulkhead", Workload: "reads", Total: 10, BurnRate: &burnRate}}))
+	require.NoError(t, sink.WriteEvent(&events.Event{Time: now, Type: events.Fault, RunID: "abc", Attributes: map[string]any{"kind": "latency"}}))
+	require.NoError(t, sink.Close())
+
+	metrics := requests["/v1/metrics"]["resourceMetrics"].([]any)[0].(map[string]any)["scopeMetrics"].([]any)[0].(map[string]any)["metrics"].([]any)
+	require.Len(t, metrics, 8)
+	assert.Equal(t, "tripwire_requests_total", metrics[0].(map[string]any)["name"])
+	assert.Contains(t, metrics[0], "sum")
+	assert.Equal(t, "tripwire_burn_rate", metrics[7].(map[string]any)["name"])
+	assert.Contains(t, metrics[7], "gauge")
+
+	records := requests["/v1/logs"]["resourceLogs"].([]any)[0].(map[string]any)["scopeLogs"].([]any)[0].(map[string]any)["logRecords"].([]any)
+	require.Len(t, records, 1)
+	record := records[0].(map[string]any)
+	assert.Equal(t, "fault", record["body"].(map[string]any)["stringValue"])
+	assert.Len(t, record["attributes"], 2)
+}
+
+func TestHTTPSenderReportsFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	sink := newRemoteWriteSink(&SinkConfig{Type: RemoteWriteSink, URL: server.URL, Timeout: time.Second})
+	require.NoError(t, sink.WriteSamples([]*Sample{{RunID: "abc"}}))
+	assert.ErrorContains(t, sink.Close(), "1 of 1 requests failed")
+}
+
+type decodedSeries struct {
+	labels    map[string]string
+	value     float64
+	timestamp int64
+}
+
+// decodeWriteRequest decodes the series in a remote write WriteRequest.
+func decodeWriteRequest(t *testing.T, request []byte) []decodedSeries {
+	var result []decodedSeries
+	forEachField(t, request, func(_ protowire.Number, timeSeries []byte) {
+		series := decodedSeries{labels: make(map[string]string)}
+		forEachField(t, timeSeries, func(num protowire.Number, field []byte) {
+			if num == 1 {
+				var name string
+				forEachField(t, field, func(num protowire.Number, value []byte) {
+					if num == 1 {
+						name = string(value)
+					} else {
+						series.labels[name] = string(value)
+					}
+				})
+				return
+			}
+			value, n := protowire.ConsumeFixed64(field[1:])
+			require.Positive(t, n)
+			series.value = math.Float64frombits(value)
+			timestamp, m := protowire.ConsumeVarint(field[2+n:])
+			require.Positive(t, m)
+			series.timestamp = int64(timestamp)
+		})
+		result = append(result, series)
+	})
+	return result
+}
+
+// forEachField calls fn with each length delimited field in a message.
+func forEachField(t *testing.T, message []byte, fn func(num protowire.Number, value []byte)) {
+	for len(message) > 0 {
+		num, typ, n := protowire.ConsumeTag(message)
+		require.Positive(t, n)
+		require.Equal(t, protowire.BytesType, typ)
+		message = message[n:]
+		value, n := protowire.ConsumeBytes(message)
+		require.Positive(t, n)
+		fn(num, value)
+		message = message[n:]
+	}
+}
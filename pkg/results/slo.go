@@ -0,0 +1,120 @@
+package results
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// SLO is a workload's service level objective, which its error budget is measured against.
+type SLO struct {
+	Target float64       // the fraction of requests that must be good
+	Window time.Duration // the window that the burn rate is measured over
+}
+
+// ErrorBudget describes how much of a workload's error budget a run consumed, where its budget is the fraction of its
+// requests that may be bad.
+type ErrorBudget struct {
+	Target      float64 `json:"target"`
+	Bad         uint64  `json:"bad"`           // failed requests, and successful requests that were slower than the SLO latency
+	Consumed    float64 `json:"consumed"`      // the fraction of the budget consumed, where more than 1 exhausted it
+	MaxBurnRate float64 `json:"max_burn_rate"` // the highest burn rate over the SLO window, where 1 consumes the budget exactly
+}
+
+// burnRateWindow measures how fast a workload consumes its error budget over its SLO window.
+type burnRateWindow struct {
+	slo    SLO
+	points []burnRatePoint // the oldest point is at least a window old, once the run is that old
+	max    float64
+}
+
+type burnRatePoint struct {
+	time  time.Time
+	total uint64
+	bad   uint64
+}
+
+func newBurnRateWindow(slo SLO, start time.Time) *burnRateWindow {
+	return &burnRateWindow{slo: slo, points: []burnRatePoint{{time: start}}}
+}
+
+// record records a workload's cumulative total and bad requests, and returns the burn rate over the window, which is the
+// rate of bad requests relative to the rate that the budget allows.
+func (w *burnRateWindow) record(now time.Time, total uint64, bad uint64) float64 {
+	w.points = append(w.points, burnRatePoint{time: now, total: total, bad: bad})
+	windowStart := now.Add(-w.slo.Window)
+	for len(w.points) > 2 && !w.points[1].time.After(windowStart) {
+		w.points = w.points[1:]
+	}
+	var burnRate float64
+	if oldest := w.points[0]; total > oldest.total {
+		burnRate = float64(bad-oldest.bad) / float64(total-oldest.total) / (1 - w.slo.Target)
+	}
+	w.max = max(w.max, burnRate)
+	return burnRate
+}
+
+// errorBudget returns how much of the budget was consumed by a workload's total and bad requests.
+func (w *burnRateWindow) errorBudget(total uint64, bad uint64) *ErrorBudget {
+	budget := &ErrorBudget{Target: w.slo.Target, Bad: bad, MaxBurnRate: w.max}
+	if total > 0 {
+		budget.Consumed = float64(bad) / (float64(total) * (1 - w.slo.Target))
+	}
+	return budget
+}
+
+// hasErrorBudgets returns whether any workload has an error budget.
+func (r *Results) hasErrorBudgets() bool {
+	for _, run := range r.Runs {
+		if run.BudgetConsumed != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// writeErrorBudgets writes a table of the error budget that each strategy and workload consumed, if any, along with the
+// budget that each strategy consumed across its workloads when it has several with SLOs.
+func (r *Results) writeErrorBudgets(w io.Writer) error {
+	if !r.hasErrorBudgets() {
+		return nil
+	}
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STRATEGY\tWORKLOAD\tSLO\tBAD\tBUDGET CONSUMED\tMAX BURN RATE")
+	for _, run := range r.Runs {
+		budgets := 0
+		for _, wr := range run.Workloads {
+			if eb := wr.ErrorBudget; eb != nil {
+				fmt.Fprintf(tw, "%s\t%s\t%g%%\t%d\t%.1f%%\t%.2f\n", run.Strategy, wr.Workload, 100*eb.Target, eb.Bad, 100*eb.Consumed, eb.MaxBurnRate)
+				budgets++
+			}
+		}
+		if budgets > 1 {
+			fmt.Fprintf(tw, "%s\t(all)\t\t\t%.1f%%\n", run.Strategy, 100**run.BudgetConsumed)
+		}
+	}
+	return tw.Flush()
+}
+
+// Failures describes the objectives that the runs failed, which are the stage SLOs that were missed and the error budgets
+// that were exhausted, so that they can be called out where summaries are skimmed, such as in notifications.
+func (r *Results) Failures() []string {
+	var failures []string
+	for _, run := range r.Runs {
+		for _, wr := range run.Workloads {
+			if eb := wr.ErrorBudget; eb != nil && eb.Consumed > 1 {
+				failures = append(failures, fmt.Sprintf("strategy %s workload %s exhausted its error budget, consuming %.1f%%",
+					run.Strategy, wr.Workload, 100*eb.Consumed))
+			}
+		}
+		for _, slo := range run.StageSLOs {
+			if !slo.Met {
+				failures = append(failures, fmt.Sprintf("strategy %s missed stage SLO %s for stage %d, with %.2f%% good of a %g%% target",
+					run.Strategy, slo.Name, slo.Stage, 100*slo.Good, 100*slo.Target))
+			}
+		}
+	}
+	return failures
+}
@@ -0,0 +1,60 @@
+package results
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBurnRateWindow(t *testing.T) {
+	start := time.Unix(0, 0)
+	window := newBurnRateWindow(SLO{Target: .99, Window: 10 * time.Second}, start)
+
+	// 2% of requests are bad, which burns the budget twice as fast as it allows
+	assert.InDelta(t, 2, window.record(start.Add(5*time.Second), 500, 10), .001)
+	assert.InDelta(t, 2, window.record(start.Add(10*time.Second), 1000, 20), .001)
+
+	// Once the bad requests are older than the window, the burn rate falls, but the max is retained
+	assert.InDelta(t, 0, window.record(start.Add(20*time.Second), 2000, 20), .001)
+	budget := window.errorBudget(2000, 20)
+	assert.InDelta(t, 1, budget.Consumed, .001)
+	assert.InDelta(t, 2, budget.MaxBurnRate, .001)
+}
+
+func TestWriteErrorBudgets(t *testing.T) {
+	consumed := .75
+	results := &Results{Runs: []*Run{{Strategy: "bulkhead", BudgetConsumed: &consumed, Workloads: []*WorkloadResult{
+		{Workload: "reads", ErrorBudget: &ErrorBudget{Target: .99, Bad: 5, Consumed: .5, MaxBurnRate: 1.5}},
+		{Workload: "writes", ErrorBudget: &ErrorBudget{Target: .999, Bad: 1, Consumed: 1, MaxBurnRate: 3}},
+	}}}}
+	var out bytes.Buffer
+	require.NoError(t, results.writeErrorBudgets(&out))
+	assert.Equal(t, `
+STRATEGY  WORKLOAD  SLO    BAD  BUDGET CONSUMED  MAX BURN RATE
+bulkhead  reads     99%    5    50.0%            1.50
+bulkhead  writes    99.9%  1    100.0%           3.00
+bulkhead  (all)                 75.0%
+`, out.String())
+}
+
+func TestFailures(t *testing.T) {
+	results := &Results{Runs: []*Run{{
+		Strategy: "bulkhead",
+		Workloads: []*WorkloadResult{
+			{Workload: "reads", ErrorBudget: &ErrorBudget{Target: .99, Consumed: 1}},
+			{Workload: "writes", ErrorBudget: &ErrorBudget{Target: .99, Consumed: 1.5}},
+		},
+		StageSLOs: []*StageSLOResult{
+			{Name: "overload", Stage: 1, Target: .99, Good: .95},
+			{Name: "recovery", Stage: 2, Target: .99, Good: 1, Met: true},
+		},
+	}}}
+	assert.Equal(t, []string{
+		"strategy bulkhead workload writes exhausted its error budget, consuming 150.0%",
+		"strategy bulkhead missed stage SLO overload for stage 1, with 95.00% good of a 99% target",
+	}, results.Failures())
+	assert.Empty(t, (&Results{}).Failures())
+}
@@ -0,0 +1,128 @@
+package results
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"tripwire/pkg/metrics"
+)
+
+// StageSLO is an objective that the requests sent during a stage are evaluated against, which may be scoped to a workload
+// and priority.
+type StageSLO struct {
+	Name     string
+	Stage    int
+	Workload string // all workloads, if empty
+	Priority *int   // all priorities, if nil
+	Target   float64
+	Latency  time.Duration // successful requests slower than this are bad, if set
+}
+
+// StageSLOResult describes how the requests that were sent during a stage fared against one of its SLOs.
+type StageSLOResult struct {
+	Name     string  `json:"name"`
+	Stage    int     `json:"stage"`
+	Workload string  `json:"workload,omitempty"`
+	Priority *int    `json:"priority,omitempty"`
+	Target   float64 `json:"target"`
+	Latency  float64 `json:"latency,omitempty"` // in milliseconds
+	Total    uint64  `json:"total"`
+	Bad      uint64  `json:"bad"`  // failed requests, and successful requests that were slower than the latency
+	Good     float64 `json:"good"` // the fraction of requests that were good, which is 1 when there weren't any
+	Met      bool    `json:"met"`  // whether the fraction of good requests met the target
+}
+
+// SetStageSLOs records the SLOs of a run's stages, which are evaluated when the run's results are collected.
+func (r *Recorder) SetStageSLOs(runID string, slos []StageSLO) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	for _, run := range r.runs {
+		if run.RunID == runID {
+			run.stageSLOs = slos
+		}
+	}
+}
+
+// collectStageSLOs evaluates the run's stage SLOs from the requests that were recorded for each.
+func (r *Run) collectStageSLOs(m *metrics.Metrics) {
+	r.StageSLOs = nil
+	for _, slo := range r.stageSLOs {
+		requests := func(outcome string) uint64 {
+			return uint64(m.Value(m.ClientStageSLOReqs.WithLabelValues(r.RunID, r.Strategy, strconv.Itoa(slo.Stage), slo.Name, outcome)))
+		}
+		good, bad := requests("good"), requests("bad")
+		result := &StageSLOResult{
+			Name:     slo.Name,
+			Stage:    slo.Stage,
+			Workload: slo.Workload,
+			Priority: slo.Priority,
+			Target:   slo.Target,
+			Latency:  millis(slo.Latency),
+			Total:    good + bad,
+			Bad:      bad,
+			Good:     1,
+		}
+		if result.Total > 0 {
+			result.Good = float64(good) / float64(result.Total)
+		}
+		result.Met = result.Good >= slo.Target
+		r.StageSLOs = append(r.StageSLOs, result)
+	}
+}
+
+// MissedStageSLOs returns the stage SLOs of every run that weren't met.
+func (r *Results) MissedStageSLOs() []*StageSLOResult {
+	var missed []*StageSLOResult
+	for _, run := range r.Runs {
+		for _, slo := range run.StageSLOs {
+			if !slo.Met {
+				missed = append(missed, slo)
+			}
+		}
+	}
+	return missed
+}
+
+// writeStageSLOs writes a table of whether each strategy met its stage SLOs, if there are any, followed by the number
+// that were missed.
+func (r *Results) writeStageSLOs(w io.Writer) error {
+	var hasSLOs bool
+	for _, run := range r.Runs {
+		hasSLOs = hasSLOs || len(run.StageSLOs) > 0
+	}
+	if !hasSLOs {
+		return nil
+	}
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STRATEGY\tSTAGE SLO\tSTAGE\tWORKLOAD\tPRIORITY\tTARGET\tLATENCY\tTOTAL\tBAD\tGOOD\tMET")
+	for _, run := range r.Runs {
+		for _, slo := range run.StageSLOs {
+			workload, priority, latency, met := "all", "all", "-", "yes"
+			if slo.Workload != "" {
+				workload = slo.Workload
+			}
+			if slo.Priority != nil {
+				priority = strconv.Itoa(*slo.Priority)
+			}
+			if slo.Latency != 0 {
+				latency = fmt.Sprintf("%.1fms", slo.Latency)
+			}
+			if !slo.Met {
+				met = "no"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\t%g%%\t%s\t%d\t%d\t%.2f%%\t%s\n", run.Strategy, slo.Name, slo.Stage, workload,
+				priority, 100*slo.Target, latency, slo.Total, slo.Bad, 100*slo.Good, met)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if missed := len(r.MissedStageSLOs()); missed > 0 {
+		fmt.Fprintf(w, "%d stage SLOs were missed\n", missed)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package results
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteStageSLOs(t *testing.T) {
+	high := 3
+	results := &Results{Runs: []*Run{{
+		Strategy: "timeout",
+		StageSLOs: []*StageSLOResult{
+			{Name: "overload", Stage: 1, Priority: &high, Target: 0.99, Latency: 200, Total: 100, Bad: 5, Good: 0.95},
+			{Name: "recovery", Stage: 2, Target: 0.9, Total: 100, Good: 1, Met: true},
+		},
+	}}}
+	assert.Len(t, results.MissedStageSLOs(), 1)
+
+	var buf bytes.Buffer
+	require.NoError(t, results.writeStageSLOs(&buf))
+	summary := buf.String()
+	assert.Contains(t, summary, "STAGE SLO")
+	assert.Regexp(t, `timeout\s+overload\s+1\s+all\s+3\s+99%\s+200.0ms\s+100\s+5\s+95.00%\s+no`, summary)
+	assert.Regexp(t, `timeout\s+recovery\s+2\s+all\s+all\s+90%\s+-\s+100\s+0\s+100.00%\s+yes`, summary)
+	assert.Contains(t, summary, "1 stage SLOs were missed")
+}
+
+func TestWriteStageSLOsWithoutSLOs(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, (&Results{Runs: []*Run{{Strategy: "timeout"}}}).writeStageSLOs(&buf))
+	assert.Empty(t, buf.String())
+}
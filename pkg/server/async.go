@@ -0,0 +1,242 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"tripwire/pkg/util"
+)
+
+const JobsPath = "/jobs/"
+
+// AsyncConfig configures the server to accept work with a 202 and complete it asynchronously, while clients poll for
+// completion. Accepted jobs wait in a FIFO queue for an available thread.
+type AsyncConfig struct {
+	MaxQueue uint          `yaml:"max_queue"` // jobs are rejected when the queue is full
+	MaxAge   time.Duration `yaml:"max_age"`   // jobs that wait longer than this before starting are shed, if set
+	JobTTL   time.Duration `yaml:"job_ttl"`   // finished jobs that aren't polled within this are expired, along with their redeliveries
+}
+
+func (c *AsyncConfig) UnmarshalYAML(value *yaml.Node) error {
+	*c = AsyncConfig{
+		MaxQueue: 1000,
+		JobTTL:   time.Minute,
+	}
+	type Alias AsyncConfig
+	var alias = Alias(*c)
+	if err := value.Decode(&alias); err != nil {
+		return err
+	}
+	*c = AsyncConfig(alias)
+	return nil
+}
+
+type jobStatus int
+
+const (
+	jobPending jobStatus = iota
+	jobDone
+	jobShed
+)
+
+type job struct {
+	id          string
+	requestID   string // the ID of the request that submitted the job, which its completion is delivered with
+	workload    string
+	serviceTime time.Duration
+	accepted    time.Time
+	finished    time.Time // Guarded by asyncQueue.mtx
+	status      jobStatus
+}
+
+// asyncQueue tracks asynchronous jobs and dispatches them to available server threads.
+type asyncQueue struct {
+	server *Server
+	config *AsyncConfig
+	queue  chan *job
+	nextID atomic.Uint64
+
+	mtx          sync.Mutex
+	jobs         map[string]*job   // Guarded by mtx
+	redeliveries map[string][]*job // Completed jobs to deliver again, by workload. Guarded by mtx.
+}
+
+func newAsyncQueue(server *Server, config *AsyncConfig) *asyncQueue {
+	return &asyncQueue{
+		server: server,
+		config: config,
+		queue:  make(chan *job, config.MaxQueue),
+		jobs:   make(map[string]*job),
+
+		redeliveries: make(map[string][]*job),
+	}
+}
+
+// submit accepts a job, responding with a 202 and the job's location, or a 429 if the queue is full.
+func (q *asyncQueue) submit(w http.ResponseWriter, r *http.Request, req Request) {
+	j := &job{
+		id:          strconv.FormatUint(q.nextID.Add(1), 10),
+		requestID:   r.Header.Get(util.RequestIdHeaderId),
+		workload:    r.Header.Get(util.WorkloadHeaderId),
+		serviceTime: req.ServiceTime,
+		accepted:    time.Now(),
+	}
+	q.mtx.Lock()
+	q.jobs[j.id] = j
+	q.mtx.Unlock()
+
+	_, strategyMetrics, _ := q.server.current()
+	select {
+	case q.queue <- j:
+		strategyMetrics.ServerAsyncQueued.Inc()
+		w.Header().Set("Location", JobsPath+j.id)
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		q.remove(j.id)
+		httpError(w, "Queue full", http.StatusTooManyRequests, util.TierServer)
+	}
+}
+
+// poll responds with a 202 while a job is pending, a 200 once it's done, or a 429 if it was shed. Completions are
+// delivered with the ID of the request that submitted the job. When a job's completion is duplicated, it's delivered
+// again in response to a poll for another pending job of the same workload.
+func (q *asyncQueue) poll(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, JobsPath)
+	q.mtx.Lock()
+	j, ok := q.jobs[id]
+	status := jobPending
+	var redelivery *job
+	if ok {
+		status = j.status
+		if status != jobPending {
+			delete(q.jobs, id)
+		} else if redeliveries := q.redeliveries[j.workload]; len(redeliveries) > 0 {
+			redelivery = redeliveries[0]
+			q.redeliveries[j.workload] = redeliveries[1:]
+		}
+	}
+	q.mtx.Unlock()
+
+	if !ok {
+		http.Error(w, "Unknown job", http.StatusNotFound)
+	} else if redelivery != nil {
+		w.Header().Set(util.RequestIdHeaderId, redelivery.requestID)
+	} else if status == jobPending {
+		w.WriteHeader(http.StatusAccepted)
+	} else if status == jobShed {
+		httpError(w, "Job shed", http.StatusTooManyRequests, util.TierServer)
+	} else {
+		w.Header().Set(util.RequestIdHeaderId, j.requestID)
+		if q.server.delivery.duplicate(j.workload) {
+			q.mtx.Lock()
+			q.redeliveries[j.workload] = append(q.redeliveries[j.workload], j)
+			q.mtx.Unlock()
+		}
+	}
+}
+
+// dispatch runs queued jobs as threads become available, shedding jobs that exceeded the max age, until ctx is done.
+func (q *asyncQueue) dispatch(ctx context.Context) {
+	for {
+		var j *job
+		select {
+		case <-ctx.Done():
+			return
+		case j = <-q.queue:
+		}
+		strategy, strategyMetrics, _ := q.server.current()
+		strategyMetrics.ServerAsyncQueued.Dec()
+
+		if q.config.MaxAge != 0 && time.Since(j.accepted) > q.config.MaxAge {
+			strategyMetrics.ServerAsyncShed.Inc()
+			q.complete(j, jobShed)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.server.resources.Threads:
+		}
+		go func() {
+			inflightMetric := q.server.metrics.WithServerInflight(j.workload, strategy)
+			inflightMetric.Inc()
+			q.server.inflight.Add(1)
+			strategyMetrics.ServerServiceTime.Set(j.serviceTime.Seconds())
+			time.Sleep(j.serviceTime)
+			q.server.resources.Threads <- struct{}{}
+			inflightMetric.Dec()
+			q.server.inflight.Add(-1)
+			if delay := q.server.delivery.reorderDelay(j.workload); delay > 0 {
+				time.AfterFunc(delay, func() { q.complete(j, jobDone) })
+			} else {
+				q.complete(j, jobDone)
+			}
+		}()
+	}
+}
+
+func (q *asyncQueue) complete(j *job, status jobStatus) {
+	q.mtx.Lock()
+	j.status = status
+	j.finished = time.Now()
+	q.mtx.Unlock()
+}
+
+// expireJobs periodically expires finished jobs that haven't been polled within the job TTL, such as when their client
+// timed out, until ctx is done.
+func (q *asyncQueue) expireJobs(ctx context.Context) {
+	if q.config.JobTTL <= 0 {
+		return
+	}
+	ticker := time.NewTicker(q.config.JobTTL)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			q.expire(now)
+		}
+	}
+}
+
+// expire removes jobs and redeliveries that finished more than the job TTL before now.
+func (q *asyncQueue) expire(now time.Time) {
+	expired := func(j *job) bool {
+		return j.status != jobPending && now.Sub(j.finished) > q.config.JobTTL
+	}
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	for id, j := range q.jobs {
+		if expired(j) {
+			delete(q.jobs, id)
+		}
+	}
+	for workload, redeliveries := range q.redeliveries {
+		var retained []*job
+		for _, j := range redeliveries {
+			if !expired(j) {
+				retained = append(retained, j)
+			}
+		}
+		if len(retained) == 0 {
+			delete(q.redeliveries, workload)
+		} else {
+			q.redeliveries[workload] = retained
+		}
+	}
+}
+
+func (q *asyncQueue) remove(id string) {
+	q.mtx.Lock()
+	delete(q.jobs, id)
+	q.mtx.Unlock()
+}
@@ -0,0 +1,27 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAsyncExpire(t *testing.T) {
+	q := newAsyncQueue(nil, &AsyncConfig{MaxQueue: 10, JobTTL: time.Minute})
+	now := time.Now()
+	expired := &job{id: "1", workload: "reads", status: jobDone, finished: now.Add(-2 * time.Minute)}
+	shed := &job{id: "2", workload: "reads", status: jobShed, finished: now.Add(-2 * time.Minute)}
+	recent := &job{id: "3", workload: "reads", status: jobDone, finished: now}
+	pending := &job{id: "4", workload: "reads", status: jobPending}
+	for _, j := range []*job{expired, shed, recent, pending} {
+		q.jobs[j.id] = j
+	}
+	q.redeliveries["reads"] = []*job{expired, recent}
+	q.redeliveries["writes"] = []*job{expired}
+
+	// Finished jobs that weren't polled within the TTL are expired, along with their redeliveries
+	q.expire(now)
+	assert.Equal(t, map[string]*job{"3": recent, "4": pending}, q.jobs)
+	assert.Equal(t, map[string][]*job{"reads": {recent}}, q.redeliveries)
+}
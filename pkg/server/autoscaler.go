@@ -0,0 +1,198 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	SignalUtilization = "utilization" // the fraction of threads that are busy
+	SignalQueueDepth  = "queue_depth" // the number of requests that are waiting for a thread
+	SignalLatency     = "latency"     // the mean time from a request's arrival until its work completes
+
+	// autoscalerTolerance is how far the observed signal can be from its target, as a fraction, before scaling
+	autoscalerTolerance = 0.1
+
+	autoscalerSampleInterval = 100 * time.Millisecond
+)
+
+// AutoscalerConfig configures a simulated autoscaler, which adjusts the server's threads to keep a signal near its target,
+// similar to a Kubernetes horizontal pod autoscaler. Each interval, the signal's mean over the interval is compared to its
+// target, and the desired threads are scaled proportionally. Scaling takes effect after a reaction delay, simulating the
+// time it takes to provision capacity, during which no further scaling decisions are made.
+type AutoscalerConfig struct {
+	Signal            string        `yaml:"signal"`             // utilization, queue_depth, or latency
+	TargetUtilization float64       `yaml:"target_utilization"` // for the utilization signal
+	TargetQueueDepth  float64       `yaml:"target_queue_depth"` // for the queue_depth signal
+	TargetLatency     time.Duration `yaml:"target_latency"`     // for the latency signal
+	Interval          time.Duration `yaml:"interval"`           // how often scaling decisions are made
+	Delay             time.Duration `yaml:"delay"`              // how long after a decision until threads are scaled
+	MinThreads        uint          `yaml:"min_threads"`
+	MaxThreads        uint          `yaml:"max_threads"`
+}
+
+func (c *AutoscalerConfig) UnmarshalYAML(value *yaml.Node) error {
+	*c = AutoscalerConfig{
+		Signal:            SignalUtilization,
+		TargetUtilization: .7,
+		Interval:          15 * time.Second,
+		Delay:             30 * time.Second,
+		MinThreads:        1,
+		MaxThreads:        MaxThreads,
+	}
+	type Alias AutoscalerConfig
+	var alias = Alias(*c)
+	if err := value.Decode(&alias); err != nil {
+		return err
+	}
+	*c = AutoscalerConfig(alias)
+	return nil
+}
+
+func (c *AutoscalerConfig) Validate() error {
+	switch c.Signal {
+	case SignalUtilization:
+		if c.TargetUtilization <= 0 || c.TargetUtilization > 1 {
+			return fmt.Errorf("autoscaler target_utilization must be greater than 0 and at most 1")
+		}
+	case SignalQueueDepth:
+		if c.TargetQueueDepth <= 0 {
+			return fmt.Errorf("autoscaler target_queue_depth must be greater than 0")
+		}
+	case SignalLatency:
+		if c.TargetLatency <= 0 {
+			return fmt.Errorf("autoscaler target_latency must be greater than 0")
+		}
+	default:
+		return fmt.Errorf("unknown autoscaler signal: %s", c.Signal)
+	}
+	if c.Interval <= 0 {
+		return fmt.Errorf("autoscaler interval must be greater than 0")
+	}
+	if c.MinThreads == 0 || c.MinThreads > c.MaxThreads || c.MaxThreads > MaxThreads {
+		return fmt.Errorf("autoscaler threads must be at least 1, with min_threads at most max_threads, and max_threads at most %d", MaxThreads)
+	}
+	return nil
+}
+
+// target returns the signal's target, in the signal's units.
+func (c *AutoscalerConfig) target() float64 {
+	switch c.Signal {
+	case SignalQueueDepth:
+		return c.TargetQueueDepth
+	case SignalLatency:
+		return c.TargetLatency.Seconds()
+	default:
+		return c.TargetUtilization
+	}
+}
+
+// desiredThreads returns the threads that would bring the observed signal to its target, within the configured bounds,
+// or the current threads if the signal is within tolerance of its target.
+func (c *AutoscalerConfig) desiredThreads(threads uint, observed float64) uint {
+	ratio := observed / c.target()
+	if math.Abs(ratio-1) <= autoscalerTolerance {
+		return threads
+	}
+	desired := uint(math.Ceil(float64(threads) * ratio))
+	return min(max(desired, c.MinThreads), c.MaxThreads)
+}
+
+// autoscaler scales a server's threads according to an AutoscalerConfig.
+type autoscaler struct {
+	server *Server
+	config *AutoscalerConfig
+
+	mtx        sync.Mutex
+	latencySum time.Duration // Guarded by mtx
+	latencies  int           // Guarded by mtx
+}
+
+func newAutoscaler(server *Server, config *AutoscalerConfig) *autoscaler {
+	return &autoscaler{
+		server: server,
+		config: config,
+	}
+}
+
+// observe records the latency of a request whose work completed.
+func (a *autoscaler) observe(latency time.Duration) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.latencySum += latency
+	a.latencies++
+}
+
+// meanLatency returns and resets the mean latency in seconds that was observed since it was last called, and whether any
+// latencies were observed.
+func (a *autoscaler) meanLatency() (float64, bool) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	sum, count := a.latencySum, a.latencies
+	a.latencySum, a.latencies = 0, 0
+	if count == 0 {
+		return 0, false
+	}
+	return sum.Seconds() / float64(count), true
+}
+
+// run samples the server's load and makes scaling decisions until ctx is done.
+func (a *autoscaler) run(ctx context.Context) {
+	sampler := time.NewTicker(autoscalerSampleInterval)
+	defer sampler.Stop()
+	evaluator := time.NewTicker(a.config.Interval)
+	defer evaluator.Stop()
+
+	var utilizationSum, queuedSum float64
+	var samples int
+	var scaled <-chan time.Time
+	var pendingThreads uint
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sampler.C:
+			threads, busy, queued := a.server.load()
+			if threads > 0 {
+				utilizationSum += float64(busy) / float64(threads)
+			}
+			queuedSum += float64(queued)
+			samples++
+		case <-evaluator.C:
+			observed, ok := 0.0, samples > 0
+			switch a.config.Signal {
+			case SignalUtilization:
+				observed = utilizationSum / float64(samples)
+			case SignalQueueDepth:
+				observed = queuedSum / float64(samples)
+			case SignalLatency:
+				observed, ok = a.meanLatency()
+			}
+			utilizationSum, queuedSum, samples = 0, 0, 0
+
+			// Scaling decisions aren't made while a previous decision is still taking effect
+			if !ok || scaled != nil {
+				continue
+			}
+			threads, _, _ := a.server.load()
+			desired := a.config.desiredThreads(threads, observed)
+			if desired == threads {
+				continue
+			}
+			_, strategyMetrics, logger := a.server.current()
+			strategyMetrics.ServerDesiredThreads.Set(float64(desired))
+			logger.Infow("autoscaling server threads", "signal", a.config.Signal, "observed", observed, "target",
+				a.config.target(), "threads", threads, "desiredThreads", desired, "delay", a.config.Delay)
+			pendingThreads = desired
+			scaled = time.After(a.config.Delay)
+		case <-scaled:
+			scaled = nil
+			a.server.UpdateConfig(&Config{Threads: pendingThreads})
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestAutoscalerConfigValidate(t *testing.T) {
+	var config AutoscalerConfig
+	assert.NoError(t, yaml.Unmarshal([]byte("max_threads: 20"), &config))
+	assert.NoError(t, config.Validate())
+	assert.Equal(t, SignalUtilization, config.Signal)
+
+	assert.Error(t, (&AutoscalerConfig{Signal: SignalLatency, Interval: time.Second, MinThreads: 1, MaxThreads: 2}).Validate())
+	assert.Error(t, (&AutoscalerConfig{Signal: "cpu", Interval: time.Second, MinThreads: 1, MaxThreads: 2}).Validate())
+	assert.Error(t, (&AutoscalerConfig{Signal: SignalQueueDepth, TargetQueueDepth: 5, Interval: time.Second, MinThreads: 4, MaxThreads: 2}).Validate())
+}
+
+func TestAutoscalerDesiredThreads(t *testing.T) {
+	config := &AutoscalerConfig{Signal: SignalUtilization, TargetUtilization: .5, MinThreads: 2, MaxThreads: 30}
+	assert.Equal(t, uint(20), config.desiredThreads(10, 1))
+	assert.Equal(t, uint(10), config.desiredThreads(10, .54), "within tolerance")
+	assert.Equal(t, uint(2), config.desiredThreads(10, 0), "clamped to min")
+	assert.Equal(t, uint(30), config.desiredThreads(20, 1), "clamped to max")
+
+	config = &AutoscalerConfig{Signal: SignalLatency, TargetLatency: 100 * time.Millisecond, MinThreads: 1, MaxThreads: 100}
+	assert.Equal(t, uint(15), config.desiredThreads(10, .15))
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/failsafe-go/failsafe-go/bulkhead"
+	"github.com/failsafe-go/failsafe-go/ratelimiter"
+)
+
+// ClientLimitsConfig configures per-client quotas that the server enforces, keyed on the client identity that requests
+// carry, to model per-caller limits. Requests without a client identity are not limited.
+type ClientLimitsConfig struct {
+	RPS            uint `yaml:"rps"`             // requests per second allowed per client, if set
+	MaxConcurrency uint `yaml:"max_concurrency"` // concurrent requests allowed per client, if set
+}
+
+type clientLimit struct {
+	rateLimiter ratelimiter.RateLimiter[any]
+	bulkhead    bulkhead.Bulkhead[any]
+}
+
+// clientLimiter lazily creates a rate limiter and bulkhead for each client.
+type clientLimiter struct {
+	config *ClientLimitsConfig
+
+	mtx    sync.Mutex
+	limits map[string]*clientLimit // Guarded by mtx
+}
+
+func newClientLimiter(config *ClientLimitsConfig) *clientLimiter {
+	return &clientLimiter{
+		config: config,
+		limits: make(map[string]*clientLimit),
+	}
+}
+
+// acquire attempts to admit a request for the client, returning a func that must be called once the request is done,
+// else false if the client is over its limits.
+func (l *clientLimiter) acquire(clientID string) (func(), bool) {
+	if clientID == "" {
+		return func() {}, true
+	}
+	limit := l.limitFor(clientID)
+	if limit.bulkhead != nil {
+		if !limit.bulkhead.TryAcquirePermit() {
+			return nil, false
+		}
+	}
+	if limit.rateLimiter != nil && !limit.rateLimiter.TryAcquirePermit() {
+		if limit.bulkhead != nil {
+			limit.bulkhead.ReleasePermit()
+		}
+		return nil, false
+	}
+	return func() {
+		if limit.bulkhead != nil {
+			limit.bulkhead.ReleasePermit()
+		}
+	}, true
+}
+
+func (l *clientLimiter) limitFor(clientID string) *clientLimit {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	limit, ok := l.limits[clientID]
+	if !ok {
+		limit = &clientLimit{}
+		if l.config.RPS > 0 {
+			limit.rateLimiter = ratelimiter.NewSmooth[any](l.config.RPS, time.Second)
+		}
+		if l.config.MaxConcurrency > 0 {
+			limit.bulkhead = bulkhead.New[any](l.config.MaxConcurrency)
+		}
+		l.limits[clientID] = limit
+	}
+	return limit
+}
@@ -0,0 +1,24 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientLimiterLimitsClientsIndependently(t *testing.T) {
+	l := newClientLimiter(&ClientLimitsConfig{MaxConcurrency: 1})
+
+	release, ok := l.acquire("a")
+	assert.True(t, ok)
+	_, ok = l.acquire("a")
+	assert.False(t, ok)
+	_, ok = l.acquire("b")
+	assert.True(t, ok)
+	_, ok = l.acquire("")
+	assert.True(t, ok)
+
+	release()
+	_, ok = l.acquire("a")
+	assert.True(t, ok)
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"tripwire/pkg/util"
+)
+
+func TestClockSkew(t *testing.T) {
+	serve := func(strategy string, skew time.Duration, timeout time.Duration, serviceTime time.Duration) int {
+		s := newTestServer(t, &Config{Threads: 1, EnforceDeadline: true, ClockSkew: skew}, strategy)
+		r := httptest.NewRequest(http.MethodPost, "/", io.NopCloser(bytes.NewReader(Request{ServiceTime: serviceTime}.AppendYAML(nil))))
+		r.Header.Set(util.WorkloadHeaderId, "skewed")
+		r.Header.Set(util.TimeoutHeaderId, timeout.String())
+		w := httptest.NewRecorder()
+		s.handleRequest(w, r)
+		return w.Code
+	}
+
+	// A server clock that's ahead of the client's expires deadlines early
+	assert.Equal(t, http.StatusServiceUnavailable, serve("early", -50*time.Millisecond, 100*time.Millisecond, 80*time.Millisecond))
+	assert.Equal(t, 1.0, testMetrics.Value(testMetrics.WithServerCancelled("skewed", "early", "skewed_deadline")))
+
+	// A server clock that's behind the client's keeps working after the client's deadline
+	assert.Equal(t, http.StatusOK, serve("late", 100*time.Millisecond, 20*time.Millisecond, 40*time.Millisecond))
+	assert.InDelta(t, .04, testMetrics.Value(testMetrics.WithServerWastedWork("skewed", "late", util.TierServer)), .001)
+}
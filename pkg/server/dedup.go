@@ -0,0 +1,101 @@
+package server
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DeduplicationConfig configures the server to deduplicate attempts of the same logical request, so that only one attempt
+// consumes capacity while others wait for and share its result.
+type DeduplicationConfig struct {
+	Window time.Duration `yaml:"window"` // how long completed results are remembered
+}
+
+func (c *DeduplicationConfig) UnmarshalYAML(value *yaml.Node) error {
+	*c = DeduplicationConfig{
+		Window: 10 * time.Second,
+	}
+	type Alias DeduplicationConfig
+	var alias = Alias(*c)
+	if err := value.Decode(&alias); err != nil {
+		return err
+	}
+	*c = DeduplicationConfig(alias)
+	return nil
+}
+
+type dedupEntry struct {
+	done      chan struct{}
+	status    int
+	tier      string // the tier that a failed status originated from
+	abandoned bool   // whether the attempt that owned the entry was abandoned before completing
+}
+
+type deduplicator struct {
+	window time.Duration
+
+	mtx     sync.Mutex
+	entries map[string]*dedupEntry // Guarded by mtx
+}
+
+func newDeduplicator(config *DeduplicationConfig) *deduplicator {
+	return &deduplicator{
+		window:  config.Window,
+		entries: make(map[string]*dedupEntry),
+	}
+}
+
+// begin returns the entry for a logical request ID, along with whether the caller owns the entry and should perform the
+// work. Callers that don't own the entry should wait for it to be done.
+func (d *deduplicator) begin(id string) (*dedupEntry, bool) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	if entry, ok := d.entries[id]; ok {
+		return entry, false
+	}
+	entry := &dedupEntry{done: make(chan struct{})}
+	d.entries[id] = entry
+	return entry, true
+}
+
+// finish completes an entry with the status that was returned for it, and the tier that a failed status originated
+// from. Abandoned entries are forgotten immediately so a later attempt can perform the work, while completed entries are
+// remembered for the window.
+func (d *deduplicator) finish(id string, entry *dedupEntry, status int, tier string, abandoned bool) {
+	entry.status = status
+	entry.tier = tier
+	entry.abandoned = abandoned
+	close(entry.done)
+	if abandoned {
+		d.forget(id)
+	} else {
+		time.AfterFunc(d.window, func() {
+			d.forget(id)
+		})
+	}
+}
+
+func (d *deduplicator) forget(id string) {
+	d.mtx.Lock()
+	delete(d.entries, id)
+	d.mtx.Unlock()
+}
+
+// statusRecorder records the status code written to a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap allows the underlying response to be controlled, such as to flush streamed responses.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
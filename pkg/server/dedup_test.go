@@ -0,0 +1,34 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeduplicator(t *testing.T) {
+	d := newDeduplicator(&DeduplicationConfig{Window: time.Minute})
+
+	entry, owner := d.begin("1")
+	assert.True(t, owner)
+	duplicate, owner := d.begin("1")
+	assert.False(t, owner)
+	assert.Same(t, entry, duplicate)
+
+	d.finish("1", entry, http.StatusTooManyRequests, "", false)
+	<-duplicate.done
+	assert.Equal(t, http.StatusTooManyRequests, duplicate.status)
+	_, owner = d.begin("1")
+	assert.False(t, owner)
+}
+
+func TestDeduplicatorForgetsAbandonedEntries(t *testing.T) {
+	d := newDeduplicator(&DeduplicationConfig{Window: time.Minute})
+
+	entry, _ := d.begin("1")
+	d.finish("1", entry, http.StatusOK, "", true)
+	_, owner := d.begin("1")
+	assert.True(t, owner)
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// DegradedConfig degrades a server partway through each strategy's run, by multiplying its service times or limiting
+// its threads, such as to evaluate whether client policies isolate a single bad backend among several.
+type DegradedConfig struct {
+	At                    time.Duration `yaml:"at"`                      // when the server is degraded, relative to the start of the strategy
+	Duration              time.Duration `yaml:"duration"`                // how long the server is degraded for. 0 degrades it for the rest of the run.
+	ServiceTimeMultiplier float64       `yaml:"service_time_multiplier"` // multiplies the service time of each request
+	Threads               uint          `yaml:"threads"`                 // limits the server's threads
+}
+
+func (c *DegradedConfig) Validate() error {
+	if c.At < 0 || c.Duration < 0 {
+		return fmt.Errorf("degraded at and duration cannot be negative")
+	}
+	if c.ServiceTimeMultiplier == 0 && c.Threads == 0 {
+		return fmt.Errorf("degraded requires a service_time_multiplier or threads")
+	}
+	if c.ServiceTimeMultiplier < 0 {
+		return fmt.Errorf("degraded service_time_multiplier cannot be negative")
+	}
+	if c.Threads > MaxThreads {
+		return fmt.Errorf("degraded threads cannot exceed %d", MaxThreads)
+	}
+	return nil
+}
+
+// Degrade degrades the server at the config's time, relative to when it's called, and restores it after the config's
+// duration or once done is closed, so that a reused server isn't degraded for later strategies, calling onChange each
+// time. Returns once the server is restored.
+func (s *Server) Degrade(config *DegradedConfig, done <-chan struct{}, onChange func(degraded bool)) {
+	if !wait(done, config.At) {
+		return
+	}
+	var threads uint // the threads to restore, if they're limited
+	if config.Threads != 0 {
+		threads = s.Config().Threads
+	}
+	s.setDegraded(config.ServiceTimeMultiplier, config.Threads)
+	onChange(true)
+	if config.Duration == 0 {
+		<-done
+	} else {
+		wait(done, config.Duration)
+	}
+	s.setDegraded(1, threads)
+	onChange(false)
+}
+
+// setDegraded sets the multiplier of subsequent requests' service times and, if non-zero, the server's threads.
+func (s *Server) setDegraded(multiplier float64, threads uint) {
+	if multiplier != 0 {
+		s.serviceTimeMultiplier.Store(math.Float64bits(multiplier))
+	}
+	if threads != 0 {
+		s.UpdateConfig(&Config{Threads: threads})
+	}
+}
+
+// degradedServiceTime returns a request's service time, multiplied while the server is degraded.
+func (s *Server) degradedServiceTime(serviceTime time.Duration) time.Duration {
+	bits := s.serviceTimeMultiplier.Load()
+	if bits == 0 {
+		return serviceTime
+	}
+	return time.Duration(float64(serviceTime) * math.Float64frombits(bits))
+}
+
+// wait waits for the duration, returning false if done is closed first.
+func wait(done <-chan struct{}, duration time.Duration) bool {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-done:
+		return false
+	}
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDegradedConfigValidate(t *testing.T) {
+	assert.NoError(t, (&DegradedConfig{At: time.Second, ServiceTimeMultiplier: 5}).Validate())
+	assert.NoError(t, (&DegradedConfig{Threads: 1}).Validate())
+	assert.Error(t, (&DegradedConfig{At: time.Second}).Validate())
+	assert.Error(t, (&DegradedConfig{At: -time.Second, Threads: 1}).Validate())
+	assert.Error(t, (&DegradedConfig{ServiceTimeMultiplier: -1}).Validate())
+}
+
+func TestDegrade(t *testing.T) {
+	s := newTestServer(t, &Config{Threads: 8}, "degraded")
+	defer s.listener.Close()
+	changes := make(chan bool)
+	go s.Degrade(&DegradedConfig{At: 10 * time.Millisecond, Duration: 10 * time.Millisecond, ServiceTimeMultiplier: 5, Threads: 2}, nil,
+		func(degraded bool) { changes <- degraded })
+
+	assert.Equal(t, 10*time.Millisecond, s.degradedServiceTime(10*time.Millisecond))
+	assert.True(t, <-changes)
+	assert.Equal(t, 50*time.Millisecond, s.degradedServiceTime(10*time.Millisecond))
+	assert.Equal(t, uint(2), s.Config().Threads)
+
+	// The server is restored after the duration
+	assert.False(t, <-changes)
+	assert.Equal(t, 10*time.Millisecond, s.degradedServiceTime(10*time.Millisecond))
+	assert.Equal(t, uint(8), s.Config().Threads)
+}
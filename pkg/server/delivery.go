@@ -0,0 +1,82 @@
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"tripwire/pkg/util"
+)
+
+const (
+	DeliveryDuplicate = "duplicate" // a response is delivered twice
+	DeliveryReorder   = "reorder"   // a response is delayed, so that it's delivered after responses to later requests
+)
+
+// DeliveryConfig injects duplicate and out-of-order deliveries into async job completions and streaming responses, so
+// that client-side correlation and idempotent handling can be validated under load.
+type DeliveryConfig struct {
+	DuplicateRate float64       `yaml:"duplicate_rate"` // the fraction of responses that are delivered twice
+	ReorderRate   float64       `yaml:"reorder_rate"`   // the fraction of responses that are delayed
+	ReorderDelay  time.Duration `yaml:"reorder_delay"`  // the max time that reordered responses are delayed by. Defaults to 100ms.
+}
+
+func (c *DeliveryConfig) UnmarshalYAML(value *yaml.Node) error {
+	*c = DeliveryConfig{
+		ReorderDelay: 100 * time.Millisecond,
+	}
+	type Alias DeliveryConfig
+	var alias = Alias(*c)
+	if err := value.Decode(&alias); err != nil {
+		return err
+	}
+	*c = DeliveryConfig(alias)
+	return nil
+}
+
+// Validate validates the config, where hasResponses is whether the server is configured with async or streaming
+// responses that delivery faults apply to.
+func (c *DeliveryConfig) Validate(hasResponses bool) error {
+	if !hasResponses {
+		return fmt.Errorf("delivery faults require async or streaming responses")
+	}
+	for _, rate := range []float64{c.DuplicateRate, c.ReorderRate} {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("delivery rates must be between 0 and 1")
+		}
+	}
+	if c.ReorderRate > 0 && c.ReorderDelay <= 0 {
+		return fmt.Errorf("delivery reorder_delay must be positive")
+	}
+	return nil
+}
+
+// deliveryFaults decides which responses delivery faults are injected into. A nil deliveryFaults injects none.
+type deliveryFaults struct {
+	config  *DeliveryConfig
+	rng     *util.Rand
+	onFault func(workload string, fault string)
+}
+
+func newDeliveryFaults(config *DeliveryConfig, seed int64, onFault func(workload string, fault string)) *deliveryFaults {
+	return &deliveryFaults{config: config, rng: util.NewRand(seed), onFault: onFault}
+}
+
+// duplicate returns whether a workload's response should be delivered twice.
+func (d *deliveryFaults) duplicate(workload string) bool {
+	if d == nil || d.rng.Float64() >= d.config.DuplicateRate {
+		return false
+	}
+	d.onFault(workload, DeliveryDuplicate)
+	return true
+}
+
+// reorderDelay returns how long a workload's response should be delayed by, or 0 if it shouldn't be.
+func (d *deliveryFaults) reorderDelay(workload string) time.Duration {
+	if d == nil || d.rng.Float64() >= d.config.ReorderRate {
+		return 0
+	}
+	d.onFault(workload, DeliveryReorder)
+	return time.Duration(d.rng.Float64() * float64(d.config.ReorderDelay))
+}
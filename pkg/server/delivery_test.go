@@ -0,0 +1,51 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"tripwire/pkg/util"
+)
+
+func TestDeliveryConfigValidate(t *testing.T) {
+	assert.NoError(t, (&DeliveryConfig{DuplicateRate: .1, ReorderRate: .1, ReorderDelay: time.Second}).Validate(true))
+	assert.Error(t, (&DeliveryConfig{DuplicateRate: .1}).Validate(false))
+	assert.Error(t, (&DeliveryConfig{DuplicateRate: 1.5}).Validate(true))
+	assert.Error(t, (&DeliveryConfig{ReorderRate: .1}).Validate(true))
+}
+
+func TestAsyncRedelivery(t *testing.T) {
+	config := &Config{Threads: 1, Async: &AsyncConfig{MaxQueue: 10}, Delivery: &DeliveryConfig{DuplicateRate: 1}}
+	s := newTestServer(t, config, "redelivered")
+	s.async.jobs["1"] = &job{id: "1", requestID: "10", workload: "reads", status: jobDone}
+	s.async.jobs["2"] = &job{id: "2", requestID: "20", workload: "reads", status: jobPending}
+	poll := func(id string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.async.poll(w, httptest.NewRequest(http.MethodGet, JobsPath+id, nil))
+		return w
+	}
+
+	// A completion is delivered with its request ID
+	w := poll("1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "10", w.Header().Get(util.RequestIdHeaderId))
+
+	// And then delivered again to a poll for another pending job
+	w = poll("2")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "10", w.Header().Get(util.RequestIdHeaderId))
+	assert.Equal(t, http.StatusAccepted, poll("2").Code)
+	assert.Equal(t, 1.0, testMetrics.Value(testMetrics.WithServerDeliveryFaults("reads", "redelivered", DeliveryDuplicate)))
+}
+
+func TestStreamDuplicate(t *testing.T) {
+	s := &Server{}
+	w := httptest.NewRecorder()
+	assert.NoError(t, s.stream(w, &StreamingConfig{ResponseSize: 100, ChunkSize: 30}, true))
+	assert.Equal(t, "100", w.Header().Get(util.StreamSizeHeaderId))
+	assert.Equal(t, 200, w.Body.Len())
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/failsafe-go/failsafe-go"
+	"github.com/failsafe-go/failsafe-go/adaptivelimiter"
+	"github.com/failsafe-go/failsafe-go/adaptivethrottler"
+	"github.com/failsafe-go/failsafe-go/bulkhead"
+	"github.com/failsafe-go/failsafe-go/circuitbreaker"
+	"github.com/failsafe-go/failsafe-go/ratelimiter"
+	"github.com/failsafe-go/failsafe-go/timeout"
+
+	"tripwire/pkg/util"
+)
+
+// DownstreamConfig configures a simulated downstream dependency that the server calls after performing its own work.
+type DownstreamConfig struct {
+	Threads     uint          `yaml:"threads"`
+	ServiceTime time.Duration `yaml:"service_time"`
+	Timeout     time.Duration `yaml:"timeout"` // the server's own timeout for downstream calls, which may be misaligned with the client's
+
+	// Whether the priority and deadline of incoming requests are inherited by downstream calls
+	PropagatePriority bool `yaml:"propagate_priority"`
+	PropagateDeadline bool `yaml:"propagate_deadline"`
+}
+
+// downstream simulates a dependency with its own fixed capacity, guarded by per-workload executors.
+type downstream struct {
+	config           *DownstreamConfig
+	clockSkew        time.Duration // how far the server's clock is behind the client's when propagating deadlines
+	availableThreads chan struct{}
+
+	mtx       sync.RWMutex
+	executors map[string]failsafe.Executor[*http.Response] // Guarded by mtx
+}
+
+func newDownstream(config *DownstreamConfig, clockSkew time.Duration, executors map[string]failsafe.Executor[*http.Response]) *downstream {
+	d := &downstream{
+		config:           config,
+		clockSkew:        clockSkew,
+		executors:        executors,
+		availableThreads: make(chan struct{}, config.Threads),
+	}
+	for i := 0; i < int(config.Threads); i++ {
+		d.availableThreads <- struct{}{}
+	}
+	return d
+}
+
+func (d *downstream) setExecutors(executors map[string]failsafe.Executor[*http.Response]) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	d.executors = executors
+}
+
+// call performs a downstream call on behalf of the request, returning the status code to respond with if it failed.
+func (d *downstream) call(r *http.Request, arrival time.Time) (int, error) {
+	ctx := r.Context()
+	if d.config.PropagateDeadline {
+		if deadline, ok := clientDeadline(r, arrival); ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(ctx, deadline.Add(d.clockSkew))
+			defer cancel()
+		}
+	}
+	if d.config.Timeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.config.Timeout)
+		defer cancel()
+	}
+
+	work := func() (*http.Response, error) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-d.availableThreads:
+		}
+		defer func() { d.availableThreads <- struct{}{} }()
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(d.config.ServiceTime):
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		}
+	}
+
+	var err error
+	d.mtx.RLock()
+	executor, ok := d.executors[r.Header.Get(util.WorkloadHeaderId)]
+	d.mtx.RUnlock()
+	if ok {
+		_, err = executor.WithContext(ctx).Get(work)
+	} else {
+		_, err = work()
+	}
+	return statusForError(err), err
+}
+
+func statusForError(err error) int {
+	if err == nil {
+		return http.StatusOK
+	} else if errors.Is(err, ratelimiter.ErrExceeded) ||
+		errors.Is(err, adaptivelimiter.ErrExceeded) ||
+		errors.Is(err, adaptivethrottler.ErrExceeded) ||
+		errors.Is(err, bulkhead.ErrFull) ||
+		errors.Is(err, circuitbreaker.ErrOpen) {
+		return http.StatusTooManyRequests
+	} else if errors.Is(err, timeout.ErrExceeded) || errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/failsafe-go/failsafe-go/bulkhead"
+)
+
+// EndpointConfig configures an endpoint that the server routes requests to by their method and path, with an optional
+// bulkhead, so that server-side limits can be partitioned by endpoint rather than applying to all traffic.
+type EndpointConfig struct {
+	Method         string        `yaml:"method"`          // the method that the endpoint accepts. Defaults to any method.
+	Path           string        `yaml:"path"`            // the path that the endpoint serves, which must match exactly
+	MaxConcurrency uint          `yaml:"max_concurrency"` // concurrent requests allowed for the endpoint, if set
+	MaxWaitTime    time.Duration `yaml:"max_wait_time"`   // how long requests wait for the bulkhead before they're rejected
+}
+
+func (c *EndpointConfig) Validate() error {
+	if !strings.HasPrefix(c.Path, "/") {
+		return fmt.Errorf("endpoint path must start with /: %q", c.Path)
+	}
+	if c.MaxWaitTime != 0 && c.MaxConcurrency == 0 {
+		return fmt.Errorf("endpoint %s max_wait_time requires a max_concurrency", c.Name())
+	}
+	return nil
+}
+
+// Name returns the name that the endpoint's metrics are recorded with, such as "GET /orders".
+func (c *EndpointConfig) Name() string {
+	if c.Method == "" {
+		return c.Path
+	}
+	return c.Method + " " + c.Path
+}
+
+// ValidateEndpoints validates the endpoints and that no two of them route the same requests.
+func ValidateEndpoints(endpoints []*EndpointConfig) error {
+	for i, endpoint := range endpoints {
+		if err := endpoint.Validate(); err != nil {
+			return err
+		}
+		for _, other := range endpoints[:i] {
+			if other.Path == endpoint.Path && (other.accepts(endpoint.Method) || endpoint.accepts(other.Method)) {
+				return fmt.Errorf("endpoints %s and %s overlap", other.Name(), endpoint.Name())
+			}
+		}
+	}
+	return nil
+}
+
+// accepts returns whether the endpoint accepts a method.
+func (c *EndpointConfig) accepts(method string) bool {
+	return c.Method == "" || strings.EqualFold(c.Method, method)
+}
+
+// Route returns the endpoint that a request with the method and path is routed to, if any.
+func Route(endpoints []*EndpointConfig, method string, path string) *EndpointConfig {
+	for _, endpoint := range endpoints {
+		if endpoint.Path == path && endpoint.accepts(method) {
+			return endpoint
+		}
+	}
+	return nil
+}
+
+type endpoint struct {
+	config   *EndpointConfig
+	bulkhead bulkhead.Bulkhead[any] // nil if the endpoint's concurrency isn't limited
+}
+
+// router routes requests to the server's endpoints. A nil router accepts every request.
+type router struct {
+	endpoints []*endpoint
+}
+
+func newRouter(configs []*EndpointConfig) *router {
+	r := &router{}
+	for _, config := range configs {
+		e := &endpoint{config: config}
+		if config.MaxConcurrency > 0 {
+			e.bulkhead = bulkhead.New[any](config.MaxConcurrency)
+		}
+		r.endpoints = append(r.endpoints, e)
+	}
+	return r
+}
+
+// route returns the endpoint that a request is routed to, else the status that the request should be rejected with
+// since no endpoint serves its path or method.
+func (r *router) route(req *http.Request) (*endpoint, int) {
+	pathMatched := false
+	for _, e := range r.endpoints {
+		if e.config.Path != req.URL.Path {
+			continue
+		}
+		pathMatched = true
+		if e.config.accepts(req.Method) {
+			return e, 0
+		}
+	}
+	if pathMatched {
+		return nil, http.StatusMethodNotAllowed
+	}
+	return nil, http.StatusNotFound
+}
+
+// acquire attempts to admit a request to the endpoint, waiting up to the endpoint's max wait time, returning a func that
+// must be called once the request is done, else false if the endpoint is full.
+func (e *endpoint) acquire(ctx context.Context) (func(), bool) {
+	if e.bulkhead == nil {
+		return func() {}, true
+	}
+	if e.config.MaxWaitTime == 0 {
+		if !e.bulkhead.TryAcquirePermit() {
+			return nil, false
+		}
+	} else if err := e.bulkhead.AcquirePermitWithMaxWait(ctx, e.config.MaxWaitTime); err != nil {
+		return nil, false
+	}
+	return e.bulkhead.ReleasePermit, true
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateEndpoints(t *testing.T) {
+	assert.NoError(t, ValidateEndpoints([]*EndpointConfig{{Method: "GET", Path: "/orders"}, {Method: "POST", Path: "/orders"}, {Path: "/search"}}))
+	assert.Error(t, ValidateEndpoints([]*EndpointConfig{{Path: "orders"}}))
+	assert.Error(t, ValidateEndpoints([]*EndpointConfig{{Path: "/orders", MaxWaitTime: time.Second}}))
+	assert.Error(t, ValidateEndpoints([]*EndpointConfig{{Method: "GET", Path: "/orders"}, {Path: "/orders"}}))
+	assert.Error(t, ValidateEndpoints([]*EndpointConfig{{Method: "GET", Path: "/orders"}, {Method: "get", Path: "/orders"}}))
+}
+
+func TestEndpointRouting(t *testing.T) {
+	config := &Config{Threads: 10, Endpoints: []*EndpointConfig{{Method: "GET", Path: "/orders", MaxConcurrency: 1}, {Path: "/search"}}}
+	s := newTestServer(t, config, "routed")
+	serve := func(method string, path string, serviceTime time.Duration) int {
+		r := httptest.NewRequest(method, path, io.NopCloser(bytes.NewReader(Request{ServiceTime: serviceTime}.AppendYAML(nil))))
+		w := httptest.NewRecorder()
+		s.handleRequest(w, r)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve("GET", "/orders", 0))
+	assert.Equal(t, http.StatusOK, serve("PUT", "/search", 0))
+	assert.Equal(t, http.StatusMethodNotAllowed, serve("POST", "/orders", 0))
+	assert.Equal(t, http.StatusNotFound, serve("POST", "/", 0))
+
+	// Each endpoint's bulkhead only limits its own requests
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		serve("GET", "/orders", 100*time.Millisecond)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, http.StatusTooManyRequests, serve("GET", "/orders", 0))
+	assert.Equal(t, http.StatusOK, serve("GET", "/search", 0))
+	wg.Wait()
+	assert.Equal(t, 1.0, testMetrics.Value(testMetrics.WithServerEndpointRejected("GET /orders", "routed")))
+}
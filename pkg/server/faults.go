@@ -0,0 +1,107 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"tripwire/pkg/util"
+)
+
+const (
+	FaultReset = "reset" // the connection is reset with a RST before it's served
+	FaultClose = "close" // the connection is closed with a FIN after the request is read, without a response
+	FaultHang  = "hang"  // the connection is left half-open, never being read from or responded to
+)
+
+// FaultsConfig configures connection level faults that are injected into a fraction of the server's connections. Since
+// the client uses a connection per request, rates are effectively per request.
+type FaultsConfig struct {
+	ResetRate float64 `yaml:"reset_rate"`
+	CloseRate float64 `yaml:"close_rate"`
+	HangRate  float64 `yaml:"hang_rate"`
+}
+
+func (c *FaultsConfig) Validate() error {
+	for _, rate := range []float64{c.ResetRate, c.CloseRate, c.HangRate} {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("fault rates must be between 0 and 1")
+		}
+	}
+	if c.ResetRate+c.CloseRate+c.HangRate > 1 {
+		return fmt.Errorf("fault rates cannot add up to more than 1")
+	}
+	return nil
+}
+
+// faultyListener is a net.Listener that injects faults into some of the connections it accepts, rather than returning
+// them to be served. Hung connections are held until the listener is closed.
+type faultyListener struct {
+	net.Listener
+	config    *FaultsConfig
+	rng       *util.Rand
+	onFault   func(fault string)
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newFaultyListener(listener net.Listener, config *FaultsConfig, seed int64, onFault func(fault string)) *faultyListener {
+	return &faultyListener{
+		Listener: listener,
+		config:   config,
+		rng:      util.NewRand(seed),
+		onFault:  onFault,
+		closed:   make(chan struct{}),
+	}
+}
+
+func (l *faultyListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		fault := l.nextFault()
+		if fault == "" {
+			return conn, nil
+		}
+		l.onFault(fault)
+		go l.inject(conn, fault)
+	}
+}
+
+func (l *faultyListener) Close() error {
+	l.closeOnce.Do(func() { close(l.closed) })
+	return l.Listener.Close()
+}
+
+// nextFault returns the fault to inject into the next connection, if any.
+func (l *faultyListener) nextFault() string {
+	r := l.rng.Float64()
+	if r < l.config.ResetRate {
+		return FaultReset
+	} else if r < l.config.ResetRate+l.config.CloseRate {
+		return FaultClose
+	} else if r < l.config.ResetRate+l.config.CloseRate+l.config.HangRate {
+		return FaultHang
+	}
+	return ""
+}
+
+func (l *faultyListener) inject(conn net.Conn, fault string) {
+	switch fault {
+	case FaultReset:
+		// Discarding unsent data on close causes a RST rather than a FIN
+		if tcpConn, ok := conn.(*net.TCPConn); ok {
+			_ = tcpConn.SetLinger(0)
+		}
+	case FaultClose:
+		// Read the request first, since closing with unread data causes a RST rather than a FIN
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, _ = conn.Read(make([]byte, 64*1024))
+	case FaultHang:
+		<-l.closed
+	}
+	_ = conn.Close()
+}
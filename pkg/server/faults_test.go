@@ -0,0 +1,34 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFaultsConfigValidate(t *testing.T) {
+	assert.NoError(t, (&FaultsConfig{ResetRate: .5, CloseRate: .25, HangRate: .25}).Validate())
+	assert.Error(t, (&FaultsConfig{ResetRate: 1.5}).Validate())
+	assert.Error(t, (&FaultsConfig{ResetRate: .5, CloseRate: .6}).Validate())
+}
+
+func TestFaultyListenerInjectsFaults(t *testing.T) {
+	for _, config := range []*FaultsConfig{{ResetRate: 1}, {CloseRate: 1}} {
+		var faults []string
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		server.Listener = newFaultyListener(listener, config, 1, func(fault string) {
+			faults = append(faults, fault)
+		})
+		server.Start()
+
+		_, err = http.Post(server.URL, "text/plain", nil)
+		server.Close()
+		assert.Error(t, err)
+		assert.Len(t, faults, 1)
+	}
+}
@@ -0,0 +1,22 @@
+package server
+
+import (
+	"net/http"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// HTTP2Config serves requests over HTTP/2 without TLS, known as h2c, in addition to HTTP/1.1, so that strategies can be
+// evaluated with requests multiplexed over shared connections, where head-of-line blocking differs from HTTP/1.1.
+type HTTP2Config struct {
+	MaxConcurrentStreams uint32 `yaml:"max_concurrent_streams"` // the most concurrent requests on each connection. Defaults to 250.
+}
+
+// handler returns a handler that serves h2c requests with the config, or the handler itself if the config is nil.
+func (c *HTTP2Config) handler(handler http.Handler) http.Handler {
+	if c == nil {
+		return handler
+	}
+	return h2c.NewHandler(handler, &http2.Server{MaxConcurrentStreams: c.MaxConcurrentStreams})
+}
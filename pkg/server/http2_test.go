@@ -0,0 +1,40 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+)
+
+func TestHTTP2Handler(t *testing.T) {
+	var protos []int
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		protos = append(protos, r.ProtoMajor)
+	})
+	server := httptest.NewServer((&HTTP2Config{MaxConcurrentStreams: 10}).handler(handler))
+	defer server.Close()
+
+	// Both h2c and HTTP/1.1 requests are served
+	h2c := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network string, addr string, _ *tls.Config) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}}
+	for _, client := range []*http.Client{h2c, http.DefaultClient} {
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+	}
+	assert.Equal(t, []int{2, 1}, protos)
+
+	var config *HTTP2Config
+	assert.NotNil(t, config.handler(handler))
+}
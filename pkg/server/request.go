@@ -0,0 +1,56 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Request is the YAML body of a client request.
+type Request struct {
+	ServiceTime time.Duration `yaml:"service_time"`
+}
+
+const serviceTimeKey = "service_time: "
+
+// AppendYAML appends the request's YAML encoding to buf. Requests are encoded directly rather than with a YAML encoder,
+// since encoding is on the client's hot path.
+func (r Request) AppendYAML(buf []byte) []byte {
+	buf = append(buf, serviceTimeKey...)
+	buf = strconv.AppendInt(buf, int64(r.ServiceTime), 10)
+	return append(buf, "ns\n"...)
+}
+
+var requestBuffers = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// decodeRequest decodes a request body. Bodies that were encoded with AppendYAML are parsed directly, while any other
+// YAML is decoded with a YAML decoder.
+func decodeRequest(body io.Reader) (Request, error) {
+	buf := requestBuffers.Get().(*bytes.Buffer)
+	defer func() {
+		buf.Reset()
+		requestBuffers.Put(buf)
+	}()
+	if _, err := buf.ReadFrom(body); err != nil {
+		return Request{}, err
+	}
+	data := bytes.TrimSpace(buf.Bytes())
+	if len(data) == 0 {
+		return Request{}, io.EOF
+	}
+
+	if value, ok := bytes.CutPrefix(data, []byte(serviceTimeKey)); ok && bytes.HasSuffix(value, []byte("ns")) {
+		if nanos, err := strconv.ParseInt(string(value[:len(value)-2]), 10, 64); err == nil {
+			return Request{ServiceTime: time.Duration(nanos)}, nil
+		}
+	}
+	var req Request
+	err := yaml.Unmarshal(data, &req)
+	return req, err
+}
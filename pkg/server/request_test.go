@@ -0,0 +1,76 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+
+	"tripwire/pkg/metrics"
+)
+
+func TestDecodeRequest(t *testing.T) {
+	expected := Request{ServiceTime: 15 * time.Millisecond}
+	req, err := decodeRequest(bytes.NewReader(expected.AppendYAML(nil)))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, req)
+
+	// Other YAML encodings are decoded too
+	body, err := yaml.Marshal(&expected)
+	assert.NoError(t, err)
+	req, err = decodeRequest(bytes.NewReader(body))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, req)
+
+	_, err = decodeRequest(bytes.NewReader(nil))
+	assert.Error(t, err)
+}
+
+func BenchmarkAppendRequest(b *testing.B) {
+	b.ReportAllocs()
+	buf := make([]byte, 0, 32)
+	for i := 0; i < b.N; i++ {
+		buf = Request{ServiceTime: 10 * time.Millisecond}.AppendYAML(buf[:0])
+	}
+}
+
+func BenchmarkDecodeRequest(b *testing.B) {
+	body := Request{ServiceTime: 10 * time.Millisecond}.AppendYAML(nil)
+	reader := bytes.NewReader(body)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		reader.Reset(body)
+		_, _ = decodeRequest(reader)
+	}
+}
+
+// testMetrics are shared by the package's tests, since metrics can only be registered once.
+var testMetrics = metrics.New(nil, zap.NewNop().Sugar())
+
+// newTestServer returns a server for the strategy that isn't started, whose listener is closed when the test ends.
+func newTestServer(t testing.TB, config *Config, strategy string) *Server {
+	s, _ := NewServer(config, strategy, testMetrics, testMetrics.WithStrategy(strategy, strategy), nil, nil, zap.NewNop().Sugar())
+	t.Cleanup(func() { _ = s.listener.Close() })
+	return s
+}
+
+// BenchmarkHandleRequest measures the server's overhead for a request with no service time.
+func BenchmarkHandleRequest(b *testing.B) {
+	s := newTestServer(b, &Config{Threads: 10}, "bench")
+	body := Request{}.AppendYAML(nil)
+	reader := bytes.NewReader(body)
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	w := httptest.NewRecorder()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		reader.Reset(body)
+		r.Body = io.NopCloser(reader)
+		s.handleRequest(w, r)
+	}
+}
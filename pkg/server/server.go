@@ -6,134 +6,468 @@ import (
 	"net"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/failsafe-go/failsafe-go"
 	"github.com/failsafe-go/failsafe-go/failsafehttp"
+	"go.opentelemetry.io/otel"
 	"go.uber.org/zap"
-	"gopkg.in/yaml.v3"
 
 	"tripwire/pkg/metrics"
+	"tripwire/pkg/tracing"
 	"tripwire/pkg/util"
 )
 
+// MaxThreads is the most threads that a server can be configured or updated with.
+const MaxThreads = 10000
+
 type Config struct {
 	Prioritize bool `yaml:"prioritize"`
 
-	Threads  uint `yaml:"threads"`
-	Duration time.Duration
+	Threads         uint                `yaml:"threads"`
+	EnforceDeadline bool                `yaml:"enforce_deadline"` // stop work when a deadline propagated by the client expires
+	ClockSkew       time.Duration       `yaml:"clock_skew"`       // how far the server's clock is behind the client's when it interprets deadlines
+	WorkModel       *WorkModelConfig    `yaml:"work_model"`       // defaults to the threads model
+	Downstream      *DownstreamConfig   `yaml:"downstream"`
+	Async           *AsyncConfig        `yaml:"async"`
+	Streaming       *StreamingConfig    `yaml:"streaming"`
+	Delivery        *DeliveryConfig     `yaml:"delivery"` // injects duplicate and out-of-order async and streaming responses
+	Faults          *FaultsConfig       `yaml:"faults"`
+	ClientLimits    *ClientLimitsConfig `yaml:"client_limits"`
+	Endpoints       []*EndpointConfig   `yaml:"endpoints"` // routes requests by method and path. Accepts every request when empty.
+	Autoscaler      *AutoscalerConfig   `yaml:"autoscaler"`
+	TraceDecisions  bool                `yaml:"trace_decisions"` // responds with a header that summarizes how each request was handled
+	HTTP2           *HTTP2Config        `yaml:"http2"`           // serves h2c requests in addition to HTTP/1.1
+	ExternalURL     string              `yaml:"external_url"`    // sends requests to a real service instead of the embedded server
+
+	Deduplication *DeduplicationConfig `yaml:"deduplication"`
+	Duration      time.Duration        // how long to run before stopping. 0 runs until Stop is called.
+	Seed          int64                // seeds injected faults
+	Addr          string               // the address to listen on, where a port of 0 chooses a free port
 }
 
 type Server struct {
-	listener         net.Listener
-	strategy         string
-	metrics          *metrics.Metrics
-	strategyMetrics  *metrics.StrategyMetrics
-	logger           *zap.SugaredLogger
-	executor         failsafe.Executor[*http.Response]
-	availableThreads chan struct{}
+	listener   net.Listener
+	metrics    *metrics.Metrics
+	executor   failsafe.Executor[*http.Response]
+	workModel  WorkModel
+	resources  *Resources
+	downstream *downstream
+	async      *asyncQueue
+	delivery   *deliveryFaults
+	dedup      *deduplicator
+	clients    *clientLimiter
+	router     *router
+	autoscaler *autoscaler
+	stop       chan struct{}
+	inflight   atomic.Int64
+
+	// The bits of the float64 that service times are multiplied by while the server is degraded, or 0 if it's not
+	serviceTimeMultiplier atomic.Uint64
 
-	mtx    sync.RWMutex
-	config *Config // Guarded by mtx
+	// The config and run are immutable snapshots, which are replaced rather than mutated when they're updated, so that
+	// requests can read them without locking
+	mtx    sync.Mutex // Serializes updates to the config and run
+	config atomic.Pointer[Config]
+	run    atomic.Pointer[serverRun]
 }
 
-func NewServer(config *Config, strategy string, metrics *metrics.Metrics, strategyMetrics *metrics.StrategyMetrics, executor failsafe.Executor[*http.Response], logger *zap.SugaredLogger) (*Server, net.Addr) {
-	listener, err := net.Listen("tcp", ":0")
+// serverRun is the strategy that a server is serving, which changes when the server is handed off to another strategy.
+type serverRun struct {
+	strategy        string
+	strategyMetrics *metrics.StrategyMetrics
+	logger          *zap.SugaredLogger
+}
+
+func NewServer(config *Config, strategy string, metrics *metrics.Metrics, strategyMetrics *metrics.StrategyMetrics, executor failsafe.Executor[*http.Response], downstreamExecutors map[string]failsafe.Executor[*http.Response], logger *zap.SugaredLogger) (*Server, net.Addr) {
+	addr := config.Addr
+	if addr == "" {
+		addr = ":0"
+	}
+	listener, err := net.Listen("tcp", addr)
 	if err != nil {
 		logger.Fatalw("failed to listen", "err", err)
 	}
-	return &Server{
-		listener:         listener,
-		strategy:         strategy,
-		config:           config,
-		metrics:          metrics,
-		strategyMetrics:  strategyMetrics,
-		logger:           logger.With("runID", strategyMetrics.RunID),
-		executor:         executor,
-		availableThreads: make(chan struct{}, config.Threads),
-	}, listener.Addr()
+	workModel, err := NewWorkModel(config.WorkModel)
+	if err != nil {
+		logger.Fatalw("failed to create work model", "err", err)
+	}
+	var aDownstream *downstream
+	if config.Downstream != nil {
+		aDownstream = newDownstream(config.Downstream, config.ClockSkew, downstreamExecutors)
+	}
+	// Copy the config since it's shared by the servers of parallel strategies, and is updated in place
+	configCopy := *config
+	s := &Server{
+		listener:   listener,
+		metrics:    metrics,
+		executor:   executor,
+		workModel:  workModel,
+		resources:  &Resources{Threads: make(chan struct{}, MaxThreads)},
+		downstream: aDownstream,
+		stop:       make(chan struct{}),
+	}
+	s.config.Store(&configCopy)
+	s.run.Store(&serverRun{strategy: strategy, strategyMetrics: strategyMetrics, logger: logger.With("runID", strategyMetrics.RunID)})
+	for i := 0; i < int(config.Threads); i++ {
+		s.resources.Threads <- struct{}{}
+	}
+	if config.Faults != nil {
+		s.listener = newFaultyListener(listener, config.Faults, config.Seed, func(fault string) {
+			strategy, _, _ := s.current()
+			metrics.WithServerConnectionFaults(strategy, fault).Inc()
+		})
+	}
+	if config.Delivery != nil {
+		s.delivery = newDeliveryFaults(config.Delivery, config.Seed, func(workload string, fault string) {
+			strategy, _, _ := s.current()
+			metrics.WithServerDeliveryFaults(workload, strategy, fault).Inc()
+		})
+	}
+	if config.Async != nil {
+		s.async = newAsyncQueue(s, config.Async)
+	}
+	if config.Deduplication != nil {
+		s.dedup = newDeduplicator(config.Deduplication)
+	}
+	if config.ClientLimits != nil {
+		s.clients = newClientLimiter(config.ClientLimits)
+	}
+	if len(config.Endpoints) > 0 {
+		s.router = newRouter(config.Endpoints)
+	}
+	if config.Autoscaler != nil {
+		s.autoscaler = newAutoscaler(s, config.Autoscaler)
+	}
+	return s, listener.Addr()
 }
 
 func (s *Server) Start(wg *sync.WaitGroup) {
 	defer wg.Done()
 
-	// Prepare workers
-	s.strategyMetrics.ServerThreads.Set(float64(s.config.Threads))
-	for i := 0; i < int(s.config.Threads); i++ {
-		s.availableThreads <- struct{}{}
-	}
+	s.mtx.Lock()
+	threads := s.Config().Threads
+	_, strategyMetrics, _ := s.current()
+	strategyMetrics.ServerThreads.Set(float64(threads))
+	strategyMetrics.ServerDesiredThreads.Set(float64(threads))
+	s.mtx.Unlock()
 
 	// Listen for requests
 	var handler http.Handler = http.HandlerFunc(s.handleRequest)
 	if s.executor != nil {
 		handler = failsafehttp.NewHandlerWithExecutor(handler, s.executor)
 	}
+	if s.downstream != nil && s.downstream.config.PropagatePriority {
+		handler = failsafehttp.NewHandlerWithLevel(handler, false)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if s.async != nil {
+		mux := http.NewServeMux()
+		mux.HandleFunc(JobsPath, s.async.poll)
+		mux.Handle("/", handler)
+		handler = mux
+		go s.async.dispatch(ctx)
+		go s.async.expireJobs(ctx)
+	}
+	if s.autoscaler != nil {
+		go s.autoscaler.run(ctx)
+	}
+	handler = tracing.Handler(handler, otel.Tracer("tripwire/pkg/server"))
 	server := &http.Server{
-		Handler:     handler,
+		Handler:     s.Config().HTTP2.handler(handler),
 		ReadTimeout: 10 * time.Second,
 	}
 	go func() {
 		if err := server.Serve(s.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			s.logger.Fatalw("server error", "error", err)
+			_, _, logger := s.current()
+			logger.Fatalw("server error", "error", err)
 		}
 	}()
 
-	time.Sleep(s.config.Duration)
-	s.logger.Infow("server stopping")
+	var timeout <-chan time.Time
+	if duration := s.Config().Duration; duration != 0 {
+		timeout = time.After(duration)
+	}
+	select {
+	case <-timeout:
+	case <-s.stop:
+	}
+	_, strategyMetrics, logger := s.current()
+	logger.Infow("server stopping")
 	_ = server.Shutdown(context.Background())
-	s.strategyMetrics.ServerServiceTime.Set(0)
+	strategyMetrics.ServerServiceTime.Set(0)
+}
+
+// Stop stops a server that was started without a duration.
+func (s *Server) Stop() {
+	close(s.stop)
 }
 
-type Request struct {
-	ServiceTime time.Duration `yaml:"service_time"`
+// Inflight returns the number of requests that the server is currently serving.
+func (s *Server) Inflight() int64 {
+	return s.inflight.Load()
+}
+
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// load returns the server's threads, how many of them are busy, and how many requests are waiting for a thread.
+func (s *Server) load() (uint, int, int) {
+	threads := s.Config().Threads
+	busy := max(int(threads)-len(s.resources.Threads), 0)
+	queued := max(int(s.inflight.Load())-busy, 0)
+	if s.async != nil {
+		queued += len(s.async.queue)
+	}
+	return threads, busy, queued
+}
+
+// current returns the strategy that the server is serving, along with its metrics and logger.
+func (s *Server) current() (string, *metrics.StrategyMetrics, *zap.SugaredLogger) {
+	run := s.run.Load()
+	return run.strategy, run.strategyMetrics, run.logger
+}
+
+// Config returns a snapshot of the server's current config, which must not be modified.
+func (s *Server) Config() *Config {
+	return s.config.Load()
+}
+
+// Handoff hands a running server off to another strategy, keeping its warmed state, such as its threads and any
+// downstream threads. Requests that are still being served are recorded under the new strategy once they complete.
+func (s *Server) Handoff(strategy string, strategyMetrics *metrics.StrategyMetrics, downstreamExecutors map[string]failsafe.Executor[*http.Response], logger *zap.SugaredLogger) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	run := &serverRun{strategy: strategy, strategyMetrics: strategyMetrics, logger: logger.With("runID", strategyMetrics.RunID)}
+	s.run.Store(run)
+	if s.downstream != nil {
+		s.downstream.setExecutors(downstreamExecutors)
+	}
+	threads := s.Config().Threads
+	run.strategyMetrics.ServerThreads.Set(float64(threads))
+	run.strategyMetrics.ServerDesiredThreads.Set(float64(threads))
+	run.logger.Infow("server handed off", "threads", threads)
 }
 
 func (s *Server) handleRequest(w http.ResponseWriter, r *http.Request) {
-	var req Request
-	if err := yaml.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Error decoding YAML: "+err.Error(), http.StatusBadRequest)
+	arrival := time.Now()
+	config := s.Config()
+	if config.TraceDecisions {
+		var tw *traceWriter
+		tw, r = withDecisionTrace(w, r)
+		defer tw.finish()
+		w = tw
+	}
+	trace := traceFromContext(r.Context())
+	var route *endpoint
+	if s.router != nil {
+		var status int
+		if route, status = s.router.route(r); route == nil {
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		trace.add("endpoint", route.config.Name())
+	}
+	req, err := decodeRequest(r.Body)
+	if err != nil {
+		http.Error(w, "Error decoding request: "+err.Error(), http.StatusBadRequest)
 		return
 	}
+	req.ServiceTime = s.degradedServiceTime(req.ServiceTime)
+	if s.clients != nil {
+		release, ok := s.clients.acquire(r.Header.Get(util.ClientIdHeaderId))
+		if !ok {
+			trace.add("client_limit", "rejected")
+			strategy, _, _ := s.current()
+			s.metrics.WithServerClientRejections(r.Header.Get(util.WorkloadHeaderId), strategy).Inc()
+			httpError(w, "Client limit exceeded", http.StatusTooManyRequests, util.TierServer)
+			return
+		}
+		trace.add("client_limit", "admitted")
+		defer release()
+	}
+	if route != nil {
+		strategy, _, _ := s.current()
+		release, ok := route.acquire(r.Context())
+		if !ok {
+			trace.add("endpoint_limit", "rejected")
+			s.metrics.WithServerEndpointRejected(route.config.Name(), strategy).Inc()
+			httpError(w, "Endpoint limit exceeded", http.StatusTooManyRequests, util.TierServer)
+			return
+		}
+		inflightMetric := s.metrics.WithServerEndpointInflight(route.config.Name(), strategy)
+		inflightMetric.Inc()
+		defer func() {
+			inflightMetric.Dec()
+			release()
+		}()
+	}
+	if s.async != nil {
+		trace.add("async", "queued")
+		s.async.submit(w, r, req)
+		return
+	}
+	if s.dedup != nil {
+		if id := r.Header.Get(util.RequestIdHeaderId); id != "" {
+			s.handleDeduplicated(w, r, req, arrival, id)
+			return
+		}
+	}
+	s.serve(w, r, req, arrival)
+}
+
+// handleDeduplicated serves the first attempt of a logical request, while other attempts wait for and share its result
+// without consuming capacity. If the first attempt is abandoned, a waiting attempt takes over.
+func (s *Server) handleDeduplicated(w http.ResponseWriter, r *http.Request, req Request, arrival time.Time, id string) {
+	for {
+		entry, owner := s.dedup.begin(id)
+		if owner {
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			s.serve(recorder, r, req, arrival)
+			s.dedup.finish(id, entry, recorder.status, recorder.Header().Get(util.TierHeaderId), r.Context().Err() != nil)
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-entry.done:
+		}
+		if !entry.abandoned {
+			traceFromContext(r.Context()).add("deduplicated", true)
+			strategy, _, _ := s.current()
+			s.metrics.WithServerDeduplicated(r.Header.Get(util.WorkloadHeaderId), strategy).Inc()
+			if entry.status != http.StatusOK {
+				httpError(w, "Duplicate of failed request", entry.status, entry.tier)
+			}
+			return
+		}
+	}
+}
 
-	s.recordServiceTime(req.ServiceTime)
-	inflightMetric := s.metrics.WithServerInflight(r.Header.Get(util.WorkloadHeaderId), s.strategy)
+// serve simulates servicing a request.
+func (s *Server) serve(w http.ResponseWriter, r *http.Request, req Request, arrival time.Time) {
+	workload := r.Header.Get(util.WorkloadHeaderId)
+	strategy, strategyMetrics, _ := s.current()
+	strategyMetrics.ServerServiceTime.Set(req.ServiceTime.Seconds())
+	inflightMetric := s.metrics.WithServerInflight(workload, strategy)
 	inflightMetric.Inc()
+	trace := traceFromContext(r.Context())
+	trace.add("inflight", s.inflight.Add(1))
+	defer func() {
+		inflightMetric.Dec()
+		s.inflight.Add(-1)
+	}()
 
-	// Simulate servicing a request, performing work in increments to simulate context switching between workers
-	workIncrement := req.ServiceTime / 100
-	var workCompleted time.Duration
-	for workCompleted < req.ServiceTime && r.Context().Err() == nil {
-		<-s.availableThreads
-		time.Sleep(workIncrement)
-		s.availableThreads <- struct{}{}
-		workCompleted += workIncrement
+	// Enforce the client's deadline, measured from when the request arrived, as a gRPC server would. The deadline is
+	// interpreted with the server's clock, which may be skewed from the client's.
+	deadline, hasDeadline := clientDeadline(r, arrival)
+	config := s.Config()
+	if config.EnforceDeadline && hasDeadline {
+		ctx, cancel := context.WithDeadline(r.Context(), deadline.Add(config.ClockSkew))
+		defer cancel()
+		r = r.WithContext(ctx)
 	}
 
-	inflightMetric.Dec()
+	workStart := time.Now()
+	workCompleted := s.workModel.Consume(r.Context(), req.ServiceTime, s.resources)
+	queued := max(time.Since(workStart)-workCompleted, 0)
+	trace.add("queue", queued.Round(time.Microsecond))
+	tracing.RecordSpan(r.Context(), "queue", workStart, queued)
+	if err := r.Context().Err(); err != nil && workCompleted < req.ServiceTime {
+		if req.ServiceTime > 0 {
+			s.metrics.WithServerCancelledWork(workload, strategy).Observe(float64(workCompleted) / float64(req.ServiceTime))
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			// A skewed clock can expire a deadline before the client's has actually passed
+			cause := "deadline"
+			if time.Now().Before(deadline) {
+				cause = "skewed_deadline"
+			}
+			s.metrics.WithServerCancelled(workload, strategy, cause).Inc()
+			trace.add("cancelled", cause)
+			httpError(w, "Deadline exceeded", http.StatusServiceUnavailable, util.TierServer)
+		} else {
+			s.metrics.WithServerCancelled(workload, strategy, "client").Inc()
+		}
+		return
+	}
+	if hasDeadline && time.Now().After(deadline) {
+		s.metrics.WithServerWastedWork(workload, strategy, util.TierServer).Add(workCompleted.Seconds())
+	}
+	if s.autoscaler != nil {
+		s.autoscaler.observe(time.Since(arrival))
+	}
+
+	// Call the downstream dependency once the server's own work is done
+	if s.downstream != nil {
+		status, err := s.downstream.call(r, arrival)
+		trace.add("downstream", policyDecision(err))
+		if err != nil {
+			httpError(w, "Downstream error: "+err.Error(), status, util.TierDownstream)
+			return
+		}
+		if hasDeadline && time.Now().After(deadline) {
+			s.metrics.WithServerWastedWork(workload, strategy, util.TierDownstream).Add(s.downstream.config.ServiceTime.Seconds())
+		}
+	}
+
+	if config.Streaming != nil {
+		if delay := s.delivery.reorderDelay(workload); delay > 0 {
+			time.Sleep(delay)
+		}
+		if err := s.stream(w, config.Streaming, s.delivery.duplicate(workload)); err != nil {
+			cause := "client"
+			if isWriteTimeout(err) {
+				cause = "write_timeout"
+			}
+			s.metrics.WithServerStreamFailures(workload, strategy, cause).Inc()
+		}
+	}
+}
+
+// clientDeadline returns the deadline that the client propagated with a request, according to the client's clock, if any.
+func clientDeadline(r *http.Request, arrival time.Time) (time.Time, bool) {
+	if remaining, ok := util.TimeoutFromRequest(r); ok {
+		return arrival.Add(remaining), true
+	}
+	return time.Time{}, false
+}
+
+// httpError responds with an error that originated from the tier.
+func httpError(w http.ResponseWriter, message string, status int, tier string) {
+	w.Header().Set(util.TierHeaderId, tier)
+	http.Error(w, message, status)
 }
 
+// UpdateConfig updates the server's threads, which must not exceed MaxThreads. When threads are reduced, this waits for
+// busy threads to be released.
 func (s *Server) UpdateConfig(config *Config) {
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
 
-	oldThreads := s.config.Threads
+	// Replace the config with an updated copy rather than updating it in place
+	updated := *s.Config()
+	oldThreads := updated.Threads
 	newThreads := config.Threads
-	s.config.Threads = config.Threads
+	updated.Threads = newThreads
+	s.config.Store(&updated)
 
 	if newThreads > oldThreads {
 		for i := 0; i < int(newThreads-oldThreads); i++ {
-			s.availableThreads <- struct{}{}
+			s.resources.Threads <- struct{}{}
 		}
 	} else if newThreads < oldThreads {
 		for i := 0; i < int(oldThreads-newThreads); i++ {
-			<-s.availableThreads
+			<-s.resources.Threads
 		}
 	}
 
-	s.strategyMetrics.ServerThreads.Set(float64(newThreads))
-	s.logger.Infow("Updated thread count", "oldThreads", oldThreads, "newThreads", newThreads)
-}
-
-func (s *Server) recordServiceTime(serviceTime time.Duration) {
-	s.strategyMetrics.ServerServiceTime.Set(serviceTime.Seconds())
+	_, strategyMetrics, logger := s.current()
+	strategyMetrics.ServerThreads.Set(float64(newThreads))
+	strategyMetrics.ServerDesiredThreads.Set(float64(newThreads))
+	logger.Infow("Updated thread count", "oldThreads", oldThreads, "newThreads", newThreads)
 }
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"tripwire/pkg/util"
+)
+
+// StreamingConfig configures the server to stream a response body once a request's work is done, so that slow clients
+// apply backpressure to the server. Streaming responses count as inflight, but don't hold a server thread.
+type StreamingConfig struct {
+	ResponseSize uint          `yaml:"response_size"` // bytes streamed in each response
+	ChunkSize    uint          `yaml:"chunk_size"`    // bytes written and flushed at a time
+	WriteTimeout time.Duration `yaml:"write_timeout"` // how long a response may take to write, if set
+}
+
+func (c *StreamingConfig) UnmarshalYAML(value *yaml.Node) error {
+	*c = StreamingConfig{
+		ChunkSize: 32 * 1024,
+	}
+	type Alias StreamingConfig
+	var alias = Alias(*c)
+	if err := value.Decode(&alias); err != nil {
+		return err
+	}
+	*c = StreamingConfig(alias)
+	return nil
+}
+
+// stream writes the configured response size in flushed chunks, or writes it twice if it's duplicated, returning an
+// error if the response could not be written before the write timeout or before the client went away. The response size
+// is sent in a header so that clients can detect duplicates.
+func (s *Server) stream(w http.ResponseWriter, config *StreamingConfig, duplicate bool) error {
+	controller := http.NewResponseController(w)
+	if config.WriteTimeout != 0 {
+		if err := controller.SetWriteDeadline(time.Now().Add(config.WriteTimeout)); err != nil {
+			return err
+		}
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set(util.StreamSizeHeaderId, strconv.FormatUint(uint64(config.ResponseSize), 10))
+	chunk := make([]byte, max(1, config.ChunkSize))
+	size := config.ResponseSize
+	if duplicate {
+		size *= 2
+	}
+	for remaining := size; remaining > 0; {
+		n := min(remaining, uint(len(chunk)))
+		if _, err := w.Write(chunk[:n]); err != nil {
+			return err
+		}
+		if err := controller.Flush(); err != nil {
+			return err
+		}
+		remaining -= n
+	}
+	return nil
+}
+
+// isWriteTimeout returns whether err is the result of a response's write deadline being exceeded.
+func isWriteTimeout(err error) bool {
+	return errors.Is(err, os.ErrDeadlineExceeded)
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/failsafe-go/failsafe-go/adaptivelimiter"
+	"github.com/failsafe-go/failsafe-go/adaptivethrottler"
+	"github.com/failsafe-go/failsafe-go/bulkhead"
+	"github.com/failsafe-go/failsafe-go/circuitbreaker"
+	"github.com/failsafe-go/failsafe-go/ratelimiter"
+	"github.com/failsafe-go/failsafe-go/timeout"
+
+	"tripwire/pkg/util"
+)
+
+// decisionTrace records the decisions that a server made while handling a request, such as whether it was admitted by
+// client limits, how long it queued for threads, and the outcome of its downstream policies. A nil decisionTrace
+// discards decisions.
+type decisionTrace struct {
+	mtx       sync.Mutex
+	decisions []string // Guarded by mtx
+}
+
+type traceKey struct{}
+
+// traceFromContext returns the decision trace for a request, or nil if decisions aren't being traced.
+func traceFromContext(ctx context.Context) *decisionTrace {
+	trace, _ := ctx.Value(traceKey{}).(*decisionTrace)
+	return trace
+}
+
+func (t *decisionTrace) add(name string, value any) {
+	if t == nil {
+		return
+	}
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	t.decisions = append(t.decisions, fmt.Sprintf("%s=%v", name, value))
+}
+
+func (t *decisionTrace) String() string {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	return strings.Join(t.decisions, "; ")
+}
+
+// traceWriter adds a request's decision trace as a response header before the response is written.
+type traceWriter struct {
+	http.ResponseWriter
+	trace       *decisionTrace
+	wroteHeader bool
+}
+
+// withDecisionTrace returns a writer and request that trace the decisions made while handling the request. The trace is
+// added to the response when its header is written, or when finish is called if the response isn't otherwise written.
+func withDecisionTrace(w http.ResponseWriter, r *http.Request) (*traceWriter, *http.Request) {
+	trace := &decisionTrace{}
+	return &traceWriter{ResponseWriter: w, trace: trace}, r.WithContext(context.WithValue(r.Context(), traceKey{}, trace))
+}
+
+func (w *traceWriter) WriteHeader(status int) {
+	w.finish()
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *traceWriter) Write(b []byte) (int, error) {
+	w.finish()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *traceWriter) finish() {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.Header().Set(util.DecisionTraceHeaderId, w.trace.String())
+	}
+}
+
+// Unwrap allows the underlying response to be controlled, such as to flush streamed responses.
+func (w *traceWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// policyDecision describes the outcome of an execution through a policy chain, naming the policy that rejected it, if any.
+func policyDecision(err error) string {
+	switch {
+	case err == nil:
+		return "admitted"
+	case errors.Is(err, adaptivelimiter.ErrExceeded):
+		return "rejected(adaptivelimiter)"
+	case errors.Is(err, adaptivethrottler.ErrExceeded):
+		return "rejected(adaptivethrottler)"
+	case errors.Is(err, ratelimiter.ErrExceeded):
+		return "rejected(ratelimiter)"
+	case errors.Is(err, bulkhead.ErrFull):
+		return "rejected(bulkhead)"
+	case errors.Is(err, circuitbreaker.ErrOpen):
+		return "rejected(circuitbreaker)"
+	case errors.Is(err, timeout.ErrExceeded):
+		return "timeout"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "deadline"
+	}
+	return "failed"
+}
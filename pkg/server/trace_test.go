@@ -0,0 +1,37 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"tripwire/pkg/util"
+)
+
+func TestDecisionTrace(t *testing.T) {
+	config := &Config{Threads: 1, TraceDecisions: true, ClientLimits: &ClientLimitsConfig{MaxConcurrency: 1}}
+	s := newTestServer(t, config, "traced")
+	serve := func() *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/", io.NopCloser(bytes.NewReader(Request{ServiceTime: time.Millisecond}.AppendYAML(nil))))
+		r.Header.Set(util.WorkloadHeaderId, "traced")
+		r.Header.Set(util.ClientIdHeaderId, "a")
+		w := httptest.NewRecorder()
+		s.handleRequest(w, r)
+		return w
+	}
+
+	w := serve()
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Regexp(t, `^client_limit=admitted; inflight=1; queue=\S+$`, w.Header().Get(util.DecisionTraceHeaderId))
+
+	release, _ := s.clients.acquire("a")
+	defer release()
+	w = serve()
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "client_limit=rejected", w.Header().Get(util.DecisionTraceHeaderId))
+}
@@ -0,0 +1,27 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateConfigReplacesSnapshot(t *testing.T) {
+	s := newTestServer(t, &Config{Threads: 2, EnforceDeadline: true}, "updated")
+	before := s.Config()
+
+	s.UpdateConfig(&Config{Threads: 4})
+	after := s.Config()
+
+	// Earlier snapshots are unchanged, and fields other than threads are retained
+	assert.Equal(t, uint(2), before.Threads)
+	assert.Equal(t, uint(4), after.Threads)
+	assert.True(t, after.EnforceDeadline)
+	threads, _, _ := s.load()
+	assert.Equal(t, uint(4), threads)
+
+	_, err := json.Marshal(after)
+	require.NoError(t, err)
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// WorkModel simulates how a server performs the work for a request.
+type WorkModel interface {
+	// Consume performs serviceTime of work using the server's shared resources, returning early if ctx is done. Returns
+	// how much of the work was completed.
+	Consume(ctx context.Context, serviceTime time.Duration, resources *Resources) time.Duration
+}
+
+// Resources are shared by all the requests that a server is servicing.
+type Resources struct {
+	// Threads contains a token for each available server thread. Work models that use threads should take a token while
+	// working and return it afterwards.
+	Threads chan struct{}
+}
+
+// WorkModelConfig selects a registered work model by Type. Options are available for work models that need them.
+type WorkModelConfig struct {
+	Type    string         `yaml:"type"`
+	Options map[string]any `yaml:",inline"`
+}
+
+// WorkModelFactory creates a work model for a config.
+type WorkModelFactory func(config *WorkModelConfig) (WorkModel, error)
+
+var (
+	workModelsMtx sync.RWMutex
+	workModels    = map[string]WorkModelFactory{
+		"threads": func(*WorkModelConfig) (WorkModel, error) { return &threadsModel{}, nil },
+	}
+)
+
+// RegisterWorkModel registers a work model factory under a name, which can then be used as a work model type in configs.
+func RegisterWorkModel(name string, factory WorkModelFactory) {
+	workModelsMtx.Lock()
+	defer workModelsMtx.Unlock()
+	workModels[name] = factory
+}
+
+// NewWorkModel creates a work model for the config, defaulting to the threads model if config is nil.
+func NewWorkModel(config *WorkModelConfig) (WorkModel, error) {
+	if config == nil {
+		return &threadsModel{}, nil
+	}
+	workModelsMtx.RLock()
+	factory, ok := workModels[config.Type]
+	workModelsMtx.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown work model type: %s", config.Type)
+	}
+	return factory(config)
+}
+
+// threadsModel performs work in increments, taking a thread for each increment, to simulate context switching between
+// a fixed number of threads.
+type threadsModel struct{}
+
+func (m *threadsModel) Consume(ctx context.Context, serviceTime time.Duration, resources *Resources) time.Duration {
+	workIncrement := serviceTime / 100
+	var workCompleted time.Duration
+	for workCompleted < serviceTime && ctx.Err() == nil {
+		select {
+		case <-ctx.Done():
+			return workCompleted
+		case <-resources.Threads:
+		}
+		time.Sleep(workIncrement)
+		resources.Threads <- struct{}{}
+		workCompleted += workIncrement
+	}
+	return min(workCompleted, serviceTime)
+}
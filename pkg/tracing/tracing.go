@@ -0,0 +1,135 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gopkg.in/yaml.v3"
+)
+
+// Config exports spans for the client's requests and the server's handling of them over OTLP/HTTP, so that individual
+// slow or rejected requests can be inspected in a tracing backend, such as Jaeger or Tempo, alongside the metrics.
+type Config struct {
+	Endpoint    string  `yaml:"endpoint"`     // the host and port of the OTLP/HTTP collector, such as localhost:4318
+	Insecure    bool    `yaml:"insecure"`     // exports spans over HTTP rather than HTTPS
+	SampleRate  float64 `yaml:"sample_rate"`  // the fraction of requests that are traced. Defaults to 1.
+	ServiceName string  `yaml:"service_name"` // the service that spans are exported as. Defaults to tripwire.
+}
+
+func (c *Config) UnmarshalYAML(value *yaml.Node) error {
+	*c = Config{
+		SampleRate:  1,
+		ServiceName: "tripwire",
+	}
+	type Alias Config
+	var alias = Alias(*c)
+	if err := value.Decode(&alias); err != nil {
+		return err
+	}
+	*c = Config(alias)
+	return nil
+}
+
+func (c *Config) Validate() error {
+	if c.Endpoint == "" {
+		return fmt.Errorf("tracing requires an endpoint")
+	}
+	if c.SampleRate <= 0 || c.SampleRate > 1 {
+		return fmt.Errorf("tracing sample_rate must be greater than 0 and at most 1")
+	}
+	return nil
+}
+
+// Start registers a global tracer provider that exports spans to the config's endpoint, along with a W3C trace context
+// propagator, so that the server's spans continue the client's traces. Returns a func that flushes any buffered spans
+// and stops exporting.
+func Start(config *Config) (func(ctx context.Context) error, error) {
+	options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(config.Endpoint)}
+	if config.Insecure {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(context.Background(), options...)
+	if err != nil {
+		return nil, err
+	}
+	return register(config, sdktrace.WithBatcher(exporter)), nil
+}
+
+// register registers a global tracer provider with the span processing option, returning a func that shuts it down.
+func register(config *Config, option sdktrace.TracerProviderOption) func(ctx context.Context) error {
+	provider := sdktrace.NewTracerProvider(
+		option,
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRate))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(config.ServiceName))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return provider.Shutdown
+}
+
+// Inject propagates the trace of a request's context to the server via its headers.
+func Inject(ctx context.Context, header http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// Handler returns a handler that records a server span for each request, which continues any trace that the client
+// propagated, and whose status is the response's status.
+func Handler(next http.Handler, tracer trace.Tracer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, "serve", trace.WithSpanKind(trace.SpanKindServer))
+		defer span.End()
+		if !span.IsRecording() {
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r.WithContext(ctx))
+		span.SetAttributes(attribute.Int("http.status_code", sw.status))
+		if sw.status >= http.StatusBadRequest {
+			span.SetStatus(codes.Error, http.StatusText(sw.status))
+		}
+	})
+}
+
+// RecordSpan records a child span of the context's span that has already completed, such as a time spent queueing that's
+// only known afterwards.
+func RecordSpan(ctx context.Context, name string, start time.Time, duration time.Duration) {
+	parent := trace.SpanFromContext(ctx)
+	if !parent.IsRecording() {
+		return
+	}
+	_, span := parent.TracerProvider().Tracer("").Start(ctx, name, trace.WithTimestamp(start))
+	span.End(trace.WithTimestamp(start.Add(duration)))
+}
+
+// statusWriter records the status that a response is written with.
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap allows the underlying response to be controlled, such as to flush streamed responses.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,72 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gopkg.in/yaml.v3"
+)
+
+func TestConfigValidate(t *testing.T) {
+	var config Config
+	require.NoError(t, yaml.Unmarshal([]byte("endpoint: localhost:4318"), &config))
+	assert.Equal(t, 1.0, config.SampleRate)
+	assert.Equal(t, "tripwire", config.ServiceName)
+	assert.NoError(t, config.Validate())
+
+	assert.ErrorContains(t, (&Config{SampleRate: 1}).Validate(), "requires an endpoint")
+	assert.ErrorContains(t, (&Config{Endpoint: "localhost:4318", SampleRate: 1.5}).Validate(), "sample_rate")
+}
+
+func TestTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	shutdown := register(&Config{SampleRate: 1, ServiceName: "test"}, sdktrace.WithSyncer(exporter))
+	defer shutdown(context.Background())
+
+	server := httptest.NewServer(Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		RecordSpan(r.Context(), "queue", time.Now().Add(-time.Millisecond), time.Millisecond)
+		w.WriteHeader(http.StatusTooManyRequests)
+	}), otel.Tracer("server")))
+	defer server.Close()
+
+	ctx, span := otel.Tracer("client").Start(context.Background(), "request")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	Inject(ctx, req.Header)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	span.End()
+
+	// The server's spans continue the client's trace, and a rejected request is recorded as an error
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 3)
+	byName := map[string]tracetest.SpanStub{}
+	for _, s := range spans {
+		byName[s.Name] = s
+		assert.Equal(t, span.SpanContext().TraceID(), s.SpanContext.TraceID())
+	}
+	assert.Equal(t, span.SpanContext().SpanID(), byName["serve"].Parent.SpanID())
+	assert.Equal(t, byName["serve"].SpanContext.SpanID(), byName["queue"].Parent.SpanID())
+	assert.Equal(t, codes.Error, byName["serve"].Status.Code)
+	assert.Equal(t, time.Millisecond, byName["queue"].EndTime.Sub(byName["queue"].StartTime))
+}
+
+func TestRecordSpanWithoutTrace(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	shutdown := register(&Config{SampleRate: 1, ServiceName: "test"}, sdktrace.WithSyncer(exporter))
+	defer shutdown(context.Background())
+
+	// A span isn't recorded for a request that isn't traced
+	RecordSpan(context.Background(), "queue", time.Now(), time.Millisecond)
+	assert.Empty(t, exporter.GetSpans())
+}
@@ -3,39 +3,65 @@ package util
 import (
 	"context"
 	"errors"
+	"net"
 	"net/http"
-	"strconv"
+	"sync/atomic"
+	"time"
 
 	"go.uber.org/zap"
 )
 
+// Server serves a mux on an address, such as for metrics or a REST API. If the address's port is 0, a free port is
+// chosen when the server starts, which is reported by Addr.
 type Server struct {
-	logger *zap.SugaredLogger
-	mux    *http.ServeMux
-	port   int
-	server *http.Server
+	logger   *zap.SugaredLogger
+	mux      *http.ServeMux
+	addr     string
+	server   *http.Server
+	listener net.Listener
 }
 
-func NewServer(mux *http.ServeMux, port int, logger *zap.SugaredLogger) *Server {
+func NewServer(mux *http.ServeMux, addr string, logger *zap.SugaredLogger) *Server {
 	return &Server{
 		logger: logger,
 		mux:    mux,
-		port:   port,
+		addr:   addr,
 	}
 }
 
+// SetAddr sets the address that the server listens on when it starts.
+func (s *Server) SetAddr(addr string) {
+	s.addr = addr
+}
+
+// Start listens on the server's address and serves in the background. If the server can't listen, such as when its
+// port is already in use, the error is logged and the server doesn't serve. A server can be started again after it's
+// shut down, on the same address.
 func (s *Server) Start() {
+	s.server = &http.Server{Handler: s.mux}
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		s.logger.Info(err)
+		return
+	}
+	s.listener = listener
+	// Keep any discovered port if the server is restarted
+	s.addr = listener.Addr().String()
 	go func() {
-		s.server = &http.Server{
-			Addr:    ":" + strconv.Itoa(s.port),
-			Handler: s.mux,
-		}
-		if err := s.server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		if err := s.server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
 			s.logger.Info(err)
 		}
 	}()
 }
 
+// Addr returns the address that the server is listening on, or "" if it isn't listening.
+func (s *Server) Addr() string {
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
 func (s *Server) Shutdown() {
 	if err := s.server.Shutdown(context.Background()); err != nil {
 		s.logger.Fatal(err)
@@ -43,19 +69,97 @@ func (s *Server) Shutdown() {
 }
 
 const WorkloadHeaderId = "X-Workload"
+const RequestIdHeaderId = "X-Request-Id"
+const ClientIdHeaderId = "X-Client-Id"
+
+// TierHeaderId identifies the tier that a failed response originated from, so that failures aren't blamed on the server
+// that returned them when they originated downstream.
+const TierHeaderId = "X-Tier"
+
+// DecisionTraceHeaderId summarizes the decisions that a server made while handling a request, when the server traces
+// its decisions.
+const DecisionTraceHeaderId = "X-Decision-Trace"
 
+// StreamSizeHeaderId is the size of a streamed response body, which clients can compare to the bytes they received,
+// recorded in the ReceivedSizeHeaderId, to detect duplicate deliveries.
+const StreamSizeHeaderId = "X-Stream-Size"
+const ReceivedSizeHeaderId = "X-Received-Size"
+
+const (
+	TierClient     = "client"     // failures from the client's own policies or timeouts
+	TierServer     = "server"     // failures from the server, including connection failures
+	TierDownstream = "downstream" // failures from the server's downstream dependency
+)
+
+// Tiers are the tiers that failures can originate from, from the front door inward.
+var Tiers = []string{TierClient, TierServer, TierDownstream}
+
+// WorkloadRoundTripper routes requests to the round tripper for their workload, which can be replaced at runtime.
 type WorkloadRoundTripper struct {
-	workloadRoundTrippers map[string]http.RoundTripper
+	workloadRoundTrippers atomic.Pointer[map[string]http.RoundTripper]
 }
 
-func NewWorkloadRoundTripper(workloadRoundTrippers map[string]http.RoundTripper) http.RoundTripper {
-	return &WorkloadRoundTripper{workloadRoundTrippers}
+func NewWorkloadRoundTripper(workloadRoundTrippers map[string]http.RoundTripper) *WorkloadRoundTripper {
+	r := &WorkloadRoundTripper{}
+	r.Update(workloadRoundTrippers)
+	return r
+}
+
+// Update replaces the round trippers of the workloads in workloadRoundTrippers, which requests that are already in
+// progress aren't affected by.
+func (r *WorkloadRoundTripper) Update(workloadRoundTrippers map[string]http.RoundTripper) {
+	updated := make(map[string]http.RoundTripper)
+	if current := r.workloadRoundTrippers.Load(); current != nil {
+		for workload, rt := range *current {
+			updated[workload] = rt
+		}
+	}
+	for workload, rt := range workloadRoundTrippers {
+		updated[workload] = rt
+	}
+	r.workloadRoundTrippers.Store(&updated)
 }
 
 func (r *WorkloadRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
 	workload := request.Header.Get(WorkloadHeaderId)
-	if rt, ok := r.workloadRoundTrippers[workload]; ok {
+	if rt, ok := (*r.workloadRoundTrippers.Load())[workload]; ok {
 		return rt.RoundTrip(request)
 	}
 	return nil, nil
 }
+
+const TimeoutHeaderId = "X-Timeout"
+
+type deadlineKey struct{}
+
+// ContextWithDeadline returns a context that carries a deadline to be propagated to servers, without enforcing it.
+func ContextWithDeadline(ctx context.Context, deadline time.Time) context.Context {
+	return context.WithValue(ctx, deadlineKey{}, deadline)
+}
+
+type deadlineRoundTripper struct {
+	next http.RoundTripper
+}
+
+// NewDeadlineRoundTripper propagates any deadline carried by a request's context to the server as the remaining timeout.
+func NewDeadlineRoundTripper(next http.RoundTripper) http.RoundTripper {
+	return &deadlineRoundTripper{next}
+}
+
+func (r *deadlineRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	if deadline, ok := request.Context().Value(deadlineKey{}).(time.Time); ok {
+		request = request.Clone(request.Context())
+		request.Header.Set(TimeoutHeaderId, time.Until(deadline).String())
+	}
+	return r.next.RoundTrip(request)
+}
+
+// TimeoutFromRequest returns the remaining timeout that was propagated with a request, if any.
+func TimeoutFromRequest(request *http.Request) (time.Duration, bool) {
+	if header := request.Header.Get(TimeoutHeaderId); header != "" {
+		if timeout, err := time.ParseDuration(header); err == nil {
+			return timeout, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,34 @@
+package util
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// Rand is a seeded source of randomness that is safe for concurrent use.
+type Rand struct {
+	mtx sync.Mutex
+	rng *rand.Rand
+}
+
+func NewRand(seed int64) *Rand {
+	return &Rand{rng: rand.New(rand.NewSource(seed))}
+}
+
+func (r *Rand) Intn(n int) int {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.rng.Intn(n)
+}
+
+func (r *Rand) Float64() float64 {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.rng.Float64()
+}
+
+func (r *Rand) NormFloat64() float64 {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.rng.NormFloat64()
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// record proxies real traffic to a service, recording the shape of the traffic to a trace file that the trace generator
+// can replay.
+func record(args []string) {
+	recordFlags := flag.NewFlagSet("record", flag.ExitOnError)
+	listen := recordFlags.String("listen", ":8081", "the address to listen for traffic on")
+	forward := recordFlags.String("forward", "", "the URL of the service to forward traffic to")
+	out := recordFlags.String("out", "trace.txt", "the trace file to write")
+	duration := recordFlags.Duration("duration", 0, "how long to record for, where 0 records until interrupted")
+	args = parseArgs(recordFlags, args)
+	if len(args) != 0 || *forward == "" {
+		fmt.Println("Usage: ./tripwire record [flags] --forward <url>")
+		recordFlags.PrintDefaults()
+		os.Exit(1)
+	}
+	target, err := url.Parse(*forward)
+	if err != nil || target.Scheme == "" || target.Host == "" {
+		fmt.Printf("invalid forward URL: %s\n", *forward)
+		os.Exit(1)
+	}
+
+	recorder := &traceRecorder{}
+	server := &http.Server{Addr: *listen, Handler: recorder.handler(httputil.NewSingleHostReverseProxy(target))}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Println("failed to listen:", err)
+			os.Exit(1)
+		}
+	}()
+	fmt.Printf("recording traffic on %s to %s\n", *listen, target)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	var timeout <-chan time.Time
+	if *duration > 0 {
+		timeout = time.After(*duration)
+	}
+	select {
+	case <-signals:
+	case <-timeout:
+	}
+	_ = server.Close()
+
+	file, err := os.Create(*out)
+	if err != nil {
+		fmt.Println("failed to create trace file:", err)
+		os.Exit(1)
+	}
+	defer file.Close()
+	if err = recorder.write(file, fmt.Sprintf("recorded from %s at %s", target, time.Now().Format(time.RFC3339))); err != nil {
+		fmt.Println("failed to write trace file:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("recorded %d requests to %s\n", recorder.len(), *out)
+}
+
+// traceEntry is a recorded request.
+type traceEntry struct {
+	arrival time.Time
+	latency time.Duration
+	status  int
+}
+
+// traceRecorder records the requests that a handler serves.
+type traceRecorder struct {
+	mtx     sync.Mutex
+	entries []traceEntry // Guarded by mtx
+}
+
+func (r *traceRecorder) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		arrival := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, req)
+		r.mtx.Lock()
+		defer r.mtx.Unlock()
+		r.entries = append(r.entries, traceEntry{arrival: arrival, latency: time.Since(arrival), status: recorder.status})
+	})
+}
+
+func (r *traceRecorder) len() int {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return len(r.entries)
+}
+
+// write writes the recorded requests in the order they arrived, as lines of the delay since the previous arrival, the
+// request's latency, which is replayed as its service time, and its status.
+func (r *traceRecorder) write(w io.Writer, comment string) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	sort.Slice(r.entries, func(i, j int) bool {
+		return r.entries[i].arrival.Before(r.entries[j].arrival)
+	})
+	writer := bufio.NewWriter(w)
+	fmt.Fprintf(writer, "# %s\n# delay service_time status\n", comment)
+	for i, entry := range r.entries {
+		var delay time.Duration
+		if i > 0 {
+			delay = entry.arrival.Sub(r.entries[i-1].arrival)
+		}
+		fmt.Fprintf(writer, "%s %s %d\n", delay, entry.latency, entry.status)
+	}
+	return writer.Flush()
+}
+
+// statusRecorder records the status code that a handler responds with.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tripwire/pkg/client"
+)
+
+func TestRecordedTraceReplays(t *testing.T) {
+	recorder := &traceRecorder{}
+	handler := recorder.handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	for _, path := range []string{"/", "/missing"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	path := filepath.Join(t.TempDir(), "trace.txt")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	require.NoError(t, recorder.write(file, "test"))
+	require.NoError(t, file.Close())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), " 404\n")
+
+	generator, err := client.NewGenerator(&client.GeneratorConfig{Type: "trace", Path: path})
+	require.NoError(t, err)
+	params := &client.GeneratorParams{}
+	first, ok := generator.Next(params)
+	assert.True(t, ok)
+	assert.Equal(t, time.Duration(0), first.Delay)
+	second, ok := generator.Next(params)
+	assert.True(t, ok)
+	assert.GreaterOrEqual(t, second.Delay, 10*time.Millisecond)
+	_, ok = generator.Next(params)
+	assert.False(t, ok)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/failsafe-go/failsafe-go"
+	"go.uber.org/zap"
+
+	"tripwire/pkg/client"
+	"tripwire/pkg/events"
+	"tripwire/pkg/metrics"
+	"tripwire/pkg/server"
+)
+
+// regionServerConfig returns the server config for a region, which uses the region's threads, if any.
+func regionServerConfig(config *server.Config, region *client.Region) *server.Config {
+	if region == nil || region.Threads == 0 {
+		return config
+	}
+	regionConfig := *config
+	regionConfig.Threads = region.Threads
+	return &regionConfig
+}
+
+// startRegions starts a server for each of the client's regions besides its home region, which the homeServer serves,
+// and stops them once the client is done. Regional servers are recorded as a separate strategy for each region, such as
+// "adaptivelimiter/us-west". Any degraded regions' servers are degraded relative to when the regions are started.
+func startRegions(logger *zap.SugaredLogger, config *Config, runID string, strategy *Strategy, metrics *metrics.Metrics, eventLog *events.Log,
+	aClient *client.Client, homeServer *server.Server, downstreamExecutors map[string]failsafe.Executor[*http.Response], wg *sync.WaitGroup) {
+	regions := config.Client.Regions
+	addrs := map[string]net.Addr{regions.Home().Name: homeServer.Addr()}
+	degrade(logger, eventLog, runID, strategy.Name, regions.Home(), homeServer, aClient.Done())
+	var servers []*server.Server
+	for _, region := range regions.Regions[1:] {
+		regionStrategy := strategy.Name + "/" + region.Name
+		serverConfig := *regionServerConfig(config.Server, region)
+		serverConfig.Addr = ""
+		serverConfig.Duration = 0
+		regionServer, addr := server.NewServer(&serverConfig, regionStrategy, metrics, metrics.WithStrategy(runID, regionStrategy), nil,
+			downstreamExecutors, logger.With("region", region.Name))
+		addrs[region.Name] = addr
+		servers = append(servers, regionServer)
+		wg.Add(1)
+		go regionServer.Start(wg)
+		degrade(logger, eventLog, runID, regionStrategy, region, regionServer, aClient.Done())
+	}
+	aClient.SetRegionAddrs(addrs)
+
+	go func() {
+		<-aClient.Done()
+		for _, regionServer := range servers {
+			regionServer.Stop()
+		}
+	}()
+}
+
+// degrade degrades a region's server in the background, if the region is degraded, until done is closed.
+func degrade(logger *zap.SugaredLogger, eventLog *events.Log, runID string, strategy string, region *client.Region, aServer *server.Server,
+	done <-chan struct{}) {
+	if region.Degraded == nil {
+		return
+	}
+	logger = logger.With("region", region.Name)
+	go aServer.Degrade(region.Degraded, done, func(degraded bool) {
+		if degraded {
+			logger.Infow("degrading region server", "serviceTimeMultiplier", region.Degraded.ServiceTimeMultiplier, "threads", region.Degraded.Threads)
+		} else {
+			logger.Infow("restoring region server")
+		}
+		eventLog.Record(events.Fault, runID, strategy, map[string]any{
+			"kind":                    "degraded",
+			"region":                  region.Name,
+			"degraded":                degraded,
+			"service_time_multiplier": region.Degraded.ServiceTimeMultiplier,
+			"threads":                 region.Degraded.Threads,
+		})
+	})
+}
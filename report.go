@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"tripwire/pkg/results"
+)
+
+// report prints a summary of a completed run's results, which defaults to the most recent run.
+func report(args []string) {
+	reportFlags := flag.NewFlagSet("report", flag.ExitOnError)
+	dir := reportFlags.String("dir", "results", "the directory that run directories are created in, for finding the most recent run")
+	args = parseArgs(reportFlags, args)
+	if len(args) > 1 {
+		fmt.Println("Usage: ./tripwire report [flags] [runDir]")
+		reportFlags.PrintDefaults()
+		os.Exit(1)
+	}
+
+	var runDir *results.Dir
+	if len(args) == 1 {
+		path := args[0]
+		// Accept the results file as well as its run directory
+		if filepath.Base(path) == results.ResultsFile {
+			path = filepath.Dir(path)
+		}
+		runDir = &results.Dir{Path: path}
+	} else {
+		var err error
+		if runDir, err = results.Latest(*dir); err != nil {
+			fmt.Println("failed to find run:", err)
+			os.Exit(1)
+		}
+	}
+
+	runResults, err := runDir.ReadResults()
+	if err != nil {
+		fmt.Println("failed to read results:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("run: %s\n\n", runDir.Path)
+	if err = runResults.WriteReport(os.Stdout); err != nil {
+		fmt.Println("failed to write report:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"tripwire/pkg/results"
+)
+
+// resultVarPattern matches a reference to a value in a previous run's results, with an optional factor to multiply it
+// by, such as ${result:runs.adaptivelimiter.workloads.writes.goodput * 0.8}.
+var resultVarPattern = regexp.MustCompile(`\$\{result:\s*([^}*\s]+)\s*(?:\*\s*([0-9.]+)\s*)?}`)
+
+// resolveResultVars replaces references to values in the results of the most recent run in the output dir, so that an
+// experiment can build on the results of a previous one. A reference is a dot separated path within results.json, where
+// a list element is selected by its index or by its strategy or workload name. Values that are multiplied by a factor are
+// rounded to whole numbers, so that they can be used as RPS.
+func resolveResultVars(configData []byte) ([]byte, error) {
+	if !resultVarPattern.Match(configData) {
+		return configData, nil
+	}
+	var config struct {
+		Output *results.Config `yaml:"output"`
+	}
+	if err := yaml.Unmarshal(configData, &config); err != nil {
+		return nil, err
+	}
+	dir := "results"
+	if config.Output != nil {
+		dir = config.Output.Dir
+	}
+	previous, err := readResultsTree(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read previous results: %w", err)
+	}
+
+	var resolveErr error
+	resolved := resultVarPattern.ReplaceAllFunc(configData, func(match []byte) []byte {
+		groups := resultVarPattern.FindSubmatch(match)
+		value, err := resolveResultVar(previous, string(groups[1]), string(groups[2]))
+		if err != nil && resolveErr == nil {
+			resolveErr = fmt.Errorf("%s: %w", match, err)
+		}
+		return []byte(value)
+	})
+	return resolved, resolveErr
+}
+
+// readResultsTree reads the results of the most recent run in dir as generic JSON values.
+func readResultsTree(dir string) (any, error) {
+	runDir, err := results.Latest(dir)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := runDir.ReadResults()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(previous)
+	if err != nil {
+		return nil, err
+	}
+	var tree any
+	return tree, json.Unmarshal(data, &tree)
+}
+
+// resolveResultVar returns the value at the path within the results tree, multiplied by the factor, if any.
+func resolveResultVar(tree any, path string, factor string) (string, error) {
+	node := tree
+	for _, key := range strings.Split(path, ".") {
+		switch n := node.(type) {
+		case map[string]any:
+			value, ok := n[key]
+			if !ok {
+				return "", fmt.Errorf("no %s in results", key)
+			}
+			node = value
+		case []any:
+			value := resultListElement(n, key)
+			if value == nil {
+				return "", fmt.Errorf("no %s in results", key)
+			}
+			node = value
+		default:
+			return "", fmt.Errorf("%s is not within an object or list", key)
+		}
+	}
+
+	switch value := node.(type) {
+	case float64:
+		if factor == "" {
+			return strconv.FormatFloat(value, 'f', -1, 64), nil
+		}
+		multiplier, err := strconv.ParseFloat(factor, 64)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatFloat(math.Round(value*multiplier), 'f', -1, 64), nil
+	case string, bool:
+		if factor != "" {
+			return "", fmt.Errorf("%s is not a number", path)
+		}
+		return fmt.Sprint(value), nil
+	default:
+		return "", fmt.Errorf("%s is not a value", path)
+	}
+}
+
+// resultListElement returns the element of a results list by its index or its strategy or workload name, else nil.
+func resultListElement(list []any, key string) any {
+	if index, err := strconv.Atoi(key); err == nil {
+		if index >= 0 && index < len(list) {
+			return list[index]
+		}
+		return nil
+	}
+	for _, element := range list {
+		if fields, ok := element.(map[string]any); ok && (fields["strategy"] == key || fields["workload"] == key) {
+			return element
+		}
+	}
+	return nil
+}
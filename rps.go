@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// setRPS changes the RPS of a running workload via the config server.
+func setRPS(args []string) {
+	rpsFlags := flag.NewFlagSet("rps", flag.ExitOnError)
+	addr := rpsFlags.String("addr", "localhost:9095", "the address of the config server")
+	args = parseArgs(rpsFlags, args)
+	if len(args) != 2 {
+		fmt.Println("Usage: ./tripwire rps [flags] <workload> <rps>")
+		rpsFlags.PrintDefaults()
+		os.Exit(1)
+	}
+
+	if err := putWorkloadRPS(*addr, args[0], args[1]); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func putWorkloadRPS(addr string, workload string, rps string) error {
+	url := fmt.Sprintf("http://%s/client/workloads/%s/rps", addr, workload)
+	req, err := http.NewRequest(http.MethodPut, url, strings.NewReader(rps))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to update workload %s: %s", workload, strings.TrimSpace(string(body)))
+	}
+	fmt.Print(string(body))
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/fnv"
+)
+
+// SeedsConfig seeds each source of randomness separately, so that one source can be varied while the others are held
+// constant, such as to measure how sensitive results are to arrivals alone. Seeds that aren't configured are derived from
+// the config's seed.
+type SeedsConfig struct {
+	Arrivals     int64  `yaml:"arrivals"`      // seeds poisson inter-arrival times and closed-loop think times
+	ServiceTimes int64  `yaml:"service_times"` // seeds service times, along with priorities and client identities. Defaults to the seed.
+	Faults       int64  `yaml:"faults"`        // seeds the server's connection and delivery faults. Defaults to the seed.
+	Policies     *int64 `yaml:"policies"`      // not supported, since randomness within policies can't be seeded
+}
+
+func (c *SeedsConfig) Validate() error {
+	if c.Policies != nil {
+		return errors.New("seeds policies is not supported, since randomness within policies, such as retry jitter and probabilistic rejections, isn't reproducible")
+	}
+	return nil
+}
+
+// resolve fills in any seeds that aren't configured from the config's seed. Service times and faults use the seed itself,
+// so that they're reproduced by configs that only set a seed, while other sources derive independent seeds from it.
+func (c *SeedsConfig) resolve(seed int64) {
+	if c.Arrivals == 0 {
+		c.Arrivals = deriveSeed(seed, "arrivals")
+	}
+	if c.ServiceTimes == 0 {
+		c.ServiceTimes = seed
+	}
+	if c.Faults == 0 {
+		c.Faults = seed
+	}
+}
+
+// apply seeds the config's client and server.
+func (c *SeedsConfig) apply(config *Config) {
+	config.Client.Seed = c.ServiceTimes
+	config.Client.ArrivalSeed = c.Arrivals
+	config.Server.Seed = c.Faults
+}
+
+// byComponent returns the seeds by the name they're configured with, for recording in results.
+func (c *SeedsConfig) byComponent() map[string]int64 {
+	return map[string]int64{
+		"arrivals":      c.Arrivals,
+		"service_times": c.ServiceTimes,
+		"faults":        c.Faults,
+	}
+}
+
+// unseededSources returns the sources of randomness that the config uses but that aren't seeded, so that results can note
+// what a run's seeds don't reproduce. Policies use failsafe-go's global source of randomness, which can't be seeded
+// reproducibly.
+func unseededSources(config *Config) []string {
+	for _, strategy := range config.Strategies {
+		if len(strategy.ClientPolicies) > 0 || len(strategy.ServerPolicies) > 0 || len(strategy.DownstreamPolicies) > 0 {
+			return []string{"policies"}
+		}
+	}
+	return nil
+}
+
+// deriveSeed derives a seed for a source of randomness from the config's seed by hashing them together, so that sources
+// don't share a random stream.
+func deriveSeed(seed int64, source string) int64 {
+	h := fnv.New64a()
+	_ = binary.Write(h, binary.LittleEndian, seed)
+	h.Write([]byte(source))
+	return int64(h.Sum64())
+}
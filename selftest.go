@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"tripwire/pkg/events"
+	"tripwire/pkg/metrics"
+	"tripwire/pkg/results"
+)
+
+// selftestWorkloads runs prioritized workloads against several strategies in parallel, while the config server is used to
+// update them.
+const selftestWorkloads = `
+client:
+  prioritize: true
+  track_usage: true
+  update_transition: 500ms
+  log_sample: 0.001
+  generator:
+    type: poisson
+  workloads:
+    - name: writes
+      rps: 200
+      user: writer
+      priority: 4
+      service_times:
+        - service_time: 5ms
+        - service_time: 20ms
+    - name: reads
+      rps: 200
+      user: reader
+      priority: 1
+      service_times:
+        - service_time: 10ms
+
+server:
+  threads: 8
+  enforce_deadline: true
+  deduplication:
+    window: 1s
+  downstream:
+    threads: 4
+    service_time: 5ms
+    propagate_priority: true
+    propagate_deadline: true
+
+reaction:
+  at: 1s
+  rps: 300
+  threads: 4
+  duration: 2s
+  sample_interval: 100ms
+  stabilization_window: 500ms
+
+strategies:
+  - name: adaptivelimiter
+    client_policies:
+      - timeout: 500ms
+      - adaptivelimiter:
+          min_limit: 2
+          max_limit: 100
+          initial_limit: 10
+          max_limit_factor: 5
+          recent_window_min_duration: 500ms
+          recent_window_max_duration: 500ms
+          recent_window_min_samples: 10
+          baseline_window_age: 10
+          correlation_window_size: 20
+          initial_rejection_factor: 2
+          max_rejection_factor: 3
+    # Prioritized adaptive throttlers are not included, since failsafe-go v0.9.1 calibrates them without synchronizing
+    # with their stats
+    downstream_policies:
+      - adaptivelimiter:
+          min_limit: 2
+          max_limit: 50
+          initial_limit: 4
+          max_limit_factor: 5
+          recent_window_min_duration: 500ms
+          recent_window_max_duration: 500ms
+          recent_window_min_samples: 10
+          baseline_window_age: 10
+          correlation_window_size: 20
+          initial_rejection_factor: 2
+          max_rejection_factor: 3
+
+  - name: ratelimiter and bulkhead
+    client_policies:
+      - ratelimiter:
+          rps: 300
+          max_wait_time: 100ms
+          max_waiters: 20
+          warm_up: 1s
+      - bulkhead:
+          max_concurrency: 20
+          max_wait_time: 100ms
+
+  - name: circuitbreaker
+    client_policies:
+      - circuitbreaker:
+          scope: workload
+          failure_threshold: 5
+          delay: 500ms
+          half_open_probe_rps: 10
+      - timeout: 100ms
+`
+
+// selftestStages runs staged strategies sequentially, handing the server off between them.
+const selftestStages = `
+client:
+  drain: 500ms
+  stages:
+    - duration: 1s
+      rps: 200
+      service_times:
+        - service_time: 10ms
+    - duration: 1s
+      rps: 400
+      drain: 200ms
+
+server:
+  threads: 8
+  async:
+    max_queue: 50
+    max_age: 200ms
+
+sequential:
+  reuse_server: true
+  cooldown: 2s
+  cooldown_until: idle
+
+reaction:
+  at: 500ms
+  rps: 300
+  threads: 12
+  duration: 1s
+  sample_interval: 100ms
+  stabilization_window: 200ms
+
+strategies:
+  - name: adaptivelimiter
+    client_policies:
+      - adaptivelimiter:
+          min_limit: 2
+          max_limit: 100
+          initial_limit: 10
+          max_limit_factor: 5
+          recent_window_min_duration: 500ms
+          recent_window_max_duration: 500ms
+          recent_window_min_samples: 10
+          baseline_window_age: 10
+          correlation_window_size: 20
+
+  - name: vegas limiter
+    client_policies:
+      - vegaslimiter:
+          max_limit: 100
+          initial_limit: 10
+          recent_window_min_duration: 500ms
+          recent_window_max_duration: 500ms
+          recent_window_min_samples: 10
+          smoothing_factor: .1
+
+  - name: no policies
+`
+
+// selftest runs a short, high concurrency run that exercises runtime updates, strategy handoffs, and shutdown. It's
+// intended to be run with the race detector enabled, such as via go run -race . selftest, to catch data races.
+func selftest(args []string) {
+	selftestFlags := flag.NewFlagSet("selftest", flag.ExitOnError)
+	duration := selftestFlags.Duration("duration", 5*time.Second, "how long to run workloads while updating them")
+	verbose := selftestFlags.Bool("v", false, "log at info level rather than warn")
+	parseArgs(selftestFlags, args)
+
+	level := zap.WarnLevel
+	if *verbose {
+		level = zap.InfoLevel
+	}
+	logger, _ := newLogger(consoleLogFormat, level)
+	metrics := metrics.New(nil, logger)
+	dir, err := os.MkdirTemp("", "tripwire-selftest")
+	if err != nil {
+		logger.Fatalw("failed to create selftest directory", "error", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var errs []error
+	if err := selftestRun(logger, metrics, dir, "workloads", selftestWorkloads, *duration); err != nil {
+		errs = append(errs, fmt.Errorf("workloads: %w", err))
+	}
+	if err := selftestRun(logger, metrics, dir, "stages", selftestStages, *duration); err != nil {
+		errs = append(errs, fmt.Errorf("stages: %w", err))
+	}
+	if err := errors.Join(errs...); err != nil {
+		fmt.Printf("selftest failed: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("selftest passed")
+}
+
+// selftestRun runs a config, recording results and events as a normal run would. Configs with workloads are updated via
+// the config server for the duration, then stopped.
+func selftestRun(logger *zap.SugaredLogger, metrics *metrics.Metrics, dir string, name string, configData string, duration time.Duration) error {
+	config, err := parseConfig([]byte(configData))
+	if err != nil {
+		return err
+	}
+	resultsDir, err := results.Create(&results.Config{Dir: dir}, name+".yaml", "", []byte(configData), config.Seed)
+	if err != nil {
+		return err
+	}
+	sinks, err := results.OpenSinks(resultsDir, nil)
+	if err != nil {
+		return err
+	}
+	recorder := results.NewRecorder(sinks, metrics, config.Seed, "", results.Metadata{}, false)
+	recorder.Start(100 * time.Millisecond)
+	eventLog := events.New(sinks)
+
+	var updateErr error
+	if len(config.Client.Workloads) == 0 {
+		runSequential(logger, config, metrics, recorder, eventLog, false)
+	} else {
+		stop := make(chan struct{})
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			updateErr = selftestUpdates(duration)
+			close(stop)
+		}()
+		runParallel(logger, config, metrics, recorder, eventLog, false, stop)
+		wg.Wait()
+	}
+
+	eventLog.Record(events.StopCondition, "", "", map[string]any{"reason": "completed"})
+	if err := errors.Join(recorder.Stop(), sinks.Close()); err != nil {
+		return err
+	}
+	return updateErr
+}
+
+// selftestUpdates concurrently updates workloads, workload rates, and server threads via the config server for the
+// duration.
+func selftestUpdates(duration time.Duration) error {
+	deadline := time.Now().Add(duration)
+	update := func(method string, path string, interval time.Duration, body func(rng *rand.Rand) string) error {
+		rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+		for time.Now().Before(deadline) {
+			time.Sleep(interval)
+			req, _ := http.NewRequest(method, "http://localhost:9095"+path, bytes.NewBufferString(body(rng)))
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				// The config server may not be listening yet
+				continue
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				return fmt.Errorf("update to %s failed with status %d", path, resp.StatusCode)
+			}
+		}
+		return nil
+	}
+
+	errs := make(chan error, 3)
+	go func() {
+		errs <- update(http.MethodPost, "/client/workloads", 50*time.Millisecond, func(rng *rand.Rand) string {
+			return fmt.Sprintf(`
+- name: writes
+  rps: %d
+  priority: 4
+  service_times:
+    - service_time: 5ms
+- name: reads
+  rps: %d
+  priority: %d
+  log_sample: 0.01
+  service_times:
+    - service_time: 10ms
+`, 50+rng.Intn(300), 50+rng.Intn(300), rng.Intn(5))
+		})
+	}()
+	go func() {
+		errs <- update(http.MethodPost, "/server", 100*time.Millisecond, func(rng *rand.Rand) string {
+			return fmt.Sprintf("threads: %d\n", 2+rng.Intn(12))
+		})
+	}()
+	go func() {
+		errs <- update(http.MethodPut, "/client/workloads/reads/rps", 70*time.Millisecond, func(rng *rand.Rand) string {
+			return fmt.Sprint(50 + rng.Intn(300))
+		})
+	}()
+	return errors.Join(<-errs, <-errs, <-errs)
+}
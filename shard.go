@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"tripwire/pkg/client"
+)
+
+// Shard identifies one of several tripwire processes that a scenario's load is split across. Each shard runs its own
+// client and server, so a sharded scenario models Count replicas that each receive a share of the load.
+type Shard struct {
+	Index uint
+	Count uint
+	Run   string // identifies the sharded run, which every shard must share, so that repeated runs have distinct run IDs
+}
+
+// parseShard parses a shard in the form i/n, where i is a zero based index less than n.
+func parseShard(value string) (*Shard, error) {
+	index, count, ok := strings.Cut(value, "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid shard %q, expected i/n", value)
+	}
+	i, err := strconv.ParseUint(index, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid shard index %q", index)
+	}
+	n, err := strconv.ParseUint(count, 10, 32)
+	if err != nil || n == 0 {
+		return nil, fmt.Errorf("invalid shard count %q", count)
+	}
+	if i >= n {
+		return nil, fmt.Errorf("shard index %d must be less than shard count %d", i, n)
+	}
+	return &Shard{Index: uint(i), Count: uint(n)}, nil
+}
+
+func (s *Shard) String() string {
+	return fmt.Sprintf("%d/%d", s.Index, s.Count)
+}
+
+// rps returns the shard's share of an RPS. Any remainder is spread across the lowest shards, so that the shares of all
+// shards sum to the RPS.
+func (s *Shard) rps(rps uint) uint {
+	share := rps / s.Count
+	if s.Index < rps%s.Count {
+		share++
+	}
+	return share
+}
+
+// seed derives the shard's seed for a source of randomness from the scenario's seed for it, so that shards don't generate
+// identical arrivals. Seeds are hashed with the index rather than offset by it, since offset seeds would overlap with
+// those of other shards and scenarios whose seeds differ by less than the shard count.
+func (s *Shard) seed(seed int64) int64 {
+	return deriveSeed(seed, "shard "+strconv.FormatUint(uint64(s.Index), 10))
+}
+
+// splitStages replaces the RPS of each stage with the shard's share, where the prefix describes what the stages belong to
+// in errors. Stages that replay a trace, which don't use their RPS, and stages without an RPS, which aren't changed by
+// splitting, can always be split.
+func (s *Shard) splitStages(prefix string, stages []*client.Stage, trace bool) error {
+	for i, stage := range stages {
+		if !trace && stage.RPS != 0 && stage.RPS < s.Count {
+			return fmt.Errorf("%sstage %d rps %d cannot be split across %d shards", prefix, i, stage.RPS, s.Count)
+		}
+		stage.RPS = s.rps(stage.RPS)
+		if stage.Ramping() {
+			if !trace && stage.RPSStart != 0 && stage.RPSStart < s.Count {
+				return fmt.Errorf("%sstage %d rps_start %d cannot be split across %d shards", prefix, i, stage.RPSStart, s.Count)
+			}
+			stage.RPSStart, stage.RPSEnd = s.rps(stage.RPSStart), s.rps(stage.RPSEnd)
+		}
+	}
+	return nil
+}
+
+// splitWorkloads replaces each workload's RPS, or concurrency for closed-loop workloads, including that of its stages,
+// with the shard's share. The generator is the client's, which workloads use unless they configure their own.
+func (s *Shard) splitWorkloads(workloads []*client.Workload, generator *client.GeneratorConfig) error {
+	for _, workload := range workloads {
+		if workload.Concurrency > 0 {
+			if workload.Concurrency < s.Count {
+				return fmt.Errorf("workload %s concurrency %d cannot be split across %d shards", workload.Name, workload.Concurrency, s.Count)
+			}
+			workload.Concurrency = s.rps(workload.Concurrency)
+			if err := s.splitUserStages(workload); err != nil {
+				return err
+			}
+			continue
+		}
+		trace := isTrace(generator)
+		if workload.Generator != nil {
+			trace = isTrace(workload.Generator)
+		}
+		if !trace && workload.RPS != 0 && workload.RPS < s.Count {
+			return fmt.Errorf("workload %s rps %d cannot be split across %d shards", workload.Name, workload.RPS, s.Count)
+		}
+		workload.RPS = s.rps(workload.RPS)
+		if err := s.splitStages("workload "+workload.Name+" ", workload.Stages, trace); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitUserStages replaces the concurrency of each of a closed-loop workload's stages with the shard's share.
+func (s *Shard) splitUserStages(workload *client.Workload) error {
+	for i, stage := range workload.Stages {
+		if stage.Concurrency < s.Count || (stage.RampingUsers() && stage.ConcurrencyStart < s.Count) {
+			return fmt.Errorf("workload %s stage %d concurrency cannot be split across %d shards", workload.Name, i, s.Count)
+		}
+		stage.Concurrency = s.rps(stage.Concurrency)
+		if stage.RampingUsers() {
+			stage.ConcurrencyStart, stage.ConcurrencyEnd = s.rps(stage.ConcurrencyStart), s.rps(stage.ConcurrencyEnd)
+		}
+	}
+	return nil
+}
+
+// isTrace returns whether a generator replays a trace.
+func isTrace(generator *client.GeneratorConfig) bool {
+	return generator != nil && generator.Type == "trace"
+}
+
+// applyShard splits the config's load for a shard and derives the shard's seed. Since shards must agree on their run IDs
+// and seeds, the config must have a configured seed.
+func applyShard(config *Config, shard *Shard) error {
+	if config.seedGenerated {
+		return fmt.Errorf("sharded runs require a configured seed")
+	}
+	if err := shard.splitStages("", config.Client.Stages, isTrace(config.Client.Generator)); err != nil {
+		return err
+	}
+	if err := shard.splitWorkloads(config.Client.Workloads, config.Client.Generator); err != nil {
+		return err
+	}
+	if config.Reaction != nil && config.Reaction.RPS != 0 {
+		config.Reaction.RPS = shard.rps(config.Reaction.RPS)
+	}
+	for _, burst := range config.Bursts {
+		burst.Requests = shard.rps(burst.Requests)
+	}
+	config.Seeds = &SeedsConfig{
+		Arrivals:     shard.seed(config.Seeds.Arrivals),
+		ServiceTimes: shard.seed(config.Seeds.ServiceTimes),
+		Faults:       shard.seed(config.Seeds.Faults),
+	}
+	config.Seeds.apply(config)
+	config.Shard = shard
+	return nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const sweepKey = "sweep"
+
+// sweepParameter is a parameter whose values are swept, where the path is a dot separated path within a strategy, such
+// as client_policies.adaptivelimiter.max_limit.
+type sweepParameter struct {
+	path   string
+	values []*yaml.Node
+}
+
+// name returns the last element of the parameter's path, which is used to name generated strategies.
+func (p *sweepParameter) name() string {
+	return p.path[strings.LastIndex(p.path, ".")+1:]
+}
+
+// expandSweep replaces the strategy that a config's sweep section refers to with a strategy for each combination of the
+// sweep's parameter values, and removes the sweep section, so that the config can be decoded as usual:
+//
+//	sweep:
+//	  strategy: adaptivelimiter
+//	  parameters:
+//	    client_policies.adaptivelimiter.max_limit: [50, 100, 200]
+//	    client_policies.adaptivelimiter.recent_quantile: [0.5, 0.9]
+//
+// Path elements select mapping keys, or within lists, the items that contain the key or the item at an index. Generated
+// strategies are named after the base strategy and their parameter values, such as "adaptivelimiter max_limit=50
+// recent_quantile=0.5". Returns the parameter values of each generated strategy, by name.
+func expandSweep(doc *yaml.Node) (map[string]map[string]string, error) {
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		doc = doc.Content[0]
+	}
+	i := mappingIndex(doc, sweepKey)
+	if doc.Kind != yaml.MappingNode || i < 0 {
+		return nil, nil
+	}
+	sweep := doc.Content[i+1]
+	doc.Content = append(doc.Content[:i], doc.Content[i+2:]...)
+	expandAliases(doc)
+
+	var baseName string
+	var parameters []*sweepParameter
+	if j := mappingIndex(sweep, "strategy"); j >= 0 {
+		baseName = sweep.Content[j+1].Value
+	}
+	if j := mappingIndex(sweep, "parameters"); j >= 0 && sweep.Content[j+1].Kind == yaml.MappingNode {
+		params := sweep.Content[j+1]
+		for k := 0; k+1 < len(params.Content); k += 2 {
+			values := params.Content[k+1]
+			if values.Kind != yaml.SequenceNode || len(values.Content) == 0 {
+				return nil, fmt.Errorf("sweep parameter %s must have a list of values", params.Content[k].Value)
+			}
+			for _, value := range values.Content {
+				if value.Kind != yaml.ScalarNode {
+					return nil, fmt.Errorf("sweep parameter %s values must be scalars", params.Content[k].Value)
+				}
+			}
+			parameters = append(parameters, &sweepParameter{path: params.Content[k].Value, values: values.Content})
+		}
+	}
+	if len(parameters) == 0 {
+		return nil, errors.New("sweep requires parameters")
+	}
+
+	// Find the base strategy, which may be omitted if there's only one strategy
+	var strategies *yaml.Node
+	if j := mappingIndex(doc, "strategies"); j >= 0 {
+		strategies = doc.Content[j+1]
+	}
+	baseIndex := -1
+	if strategies != nil && strategies.Kind == yaml.SequenceNode {
+		for j, strategy := range strategies.Content {
+			if k := mappingIndex(strategy, "name"); k >= 0 && strategy.Content[k+1].Value == baseName ||
+				baseName == "" && len(strategies.Content) == 1 {
+				baseIndex = j
+				break
+			}
+		}
+	}
+	if baseIndex == -1 {
+		return nil, fmt.Errorf("unknown sweep strategy: %s", baseName)
+	}
+	base := strategies.Content[baseIndex]
+	if k := mappingIndex(base, "name"); k >= 0 {
+		baseName = base.Content[k+1].Value
+	}
+
+	strategyParameters := make(map[string]map[string]string)
+	var generated []*yaml.Node
+	for _, combination := range combinations(parameters) {
+		strategy := deepCopy(base)
+		name := []string{baseName}
+		values := make(map[string]string)
+		for j, parameter := range parameters {
+			if !setParameter(strategy, strings.Split(parameter.path, "."), combination[j]) {
+				return nil, fmt.Errorf("sweep parameter %s not found in strategy %s", parameter.path, baseName)
+			}
+			name = append(name, parameter.name()+"="+combination[j].Value)
+			values[parameter.path] = combination[j].Value
+		}
+		strategyName := strings.Join(name, " ")
+		setParameter(strategy, []string{"name"}, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: strategyName})
+		strategyParameters[strategyName] = values
+		generated = append(generated, strategy)
+	}
+	strategies.Content = append(append(append([]*yaml.Node{}, strategies.Content[:baseIndex]...), generated...),
+		strategies.Content[baseIndex+1:]...)
+	return strategyParameters, nil
+}
+
+// combinations returns the cartesian product of the parameters' values, where earlier parameters vary slowest.
+func combinations(parameters []*sweepParameter) [][]*yaml.Node {
+	result := [][]*yaml.Node{nil}
+	for _, parameter := range parameters {
+		var next [][]*yaml.Node
+		for _, combination := range result {
+			for _, value := range parameter.values {
+				next = append(next, append(append([]*yaml.Node{}, combination...), value))
+			}
+		}
+		result = next
+	}
+	return result
+}
+
+// setParameter sets the value at path within node, returning false if the path doesn't exist. The last element of the
+// path is added to its mapping if it's missing.
+func setParameter(node *yaml.Node, path []string, value *yaml.Node) bool {
+	switch node.Kind {
+	case yaml.MappingNode:
+		i := mappingIndex(node, path[0])
+		if len(path) == 1 {
+			if i >= 0 {
+				node.Content[i+1] = deepCopy(value)
+			} else {
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: path[0]}, deepCopy(value))
+			}
+			return true
+		}
+		if i < 0 {
+			return false
+		}
+		child := node.Content[i+1]
+		if child.Kind == yaml.ScalarNode && child.Tag == "!!null" {
+			// Policies without options, such as "adaptivelimiter:", can still have parameters swept
+			*child = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		return setParameter(child, path[1:], value)
+	case yaml.SequenceNode:
+		if index, err := strconv.Atoi(path[0]); err == nil {
+			if index < 0 || index >= len(node.Content) {
+				return false
+			} else if len(path) == 1 {
+				node.Content[index] = deepCopy(value)
+				return true
+			}
+			return setParameter(node.Content[index], path[1:], value)
+		}
+		found := false
+		for _, item := range node.Content {
+			if item.Kind == yaml.MappingNode && mappingIndex(item, path[0]) >= 0 && setParameter(item, path, value) {
+				found = true
+			}
+		}
+		return found
+	}
+	return false
+}
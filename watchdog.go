@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// watchdogTimeout returns how long a staged strategy can run before it's aborted, or 0 if the watchdog is disabled.
+func watchdogTimeout(config *Config) time.Duration {
+	if config.Sequential.WatchdogMargin == 0 {
+		return 0
+	}
+	return config.Client.MaxDuration + config.Client.Drain + config.Sequential.WatchdogMargin
+}
+
+// awaitStrategy waits for a strategy's client and server to stop, returning false if they haven't stopped within the
+// timeout, where 0 waits indefinitely. The strategy's goroutines are abandoned rather than waited on once it times out.
+func awaitStrategy(wg *sync.WaitGroup, timeout time.Duration) bool {
+	if timeout == 0 {
+		wg.Wait()
+		return true
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// abortReason describes why a strategy that exceeded its watchdog timeout was aborted.
+func abortReason(config *Config) string {
+	return fmt.Sprintf("exceeded its expected duration of %s by %s", config.Client.MaxDuration+config.Client.Drain,
+		config.Sequential.WatchdogMargin)
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"tripwire/pkg/client"
+)
+
+func TestWatchdogTimeout(t *testing.T) {
+	config := &Config{Client: &client.Config{MaxDuration: time.Minute, Drain: 10 * time.Second}, Sequential: &SequentialConfig{}}
+	assert.Equal(t, time.Duration(0), watchdogTimeout(config))
+
+	config.Sequential.WatchdogMargin = 30 * time.Second
+	assert.Equal(t, 100*time.Second, watchdogTimeout(config))
+	assert.Equal(t, "exceeded its expected duration of 1m10s by 30s", abortReason(config))
+}
+
+func TestAwaitStrategy(t *testing.T) {
+	var wg sync.WaitGroup
+	assert.True(t, awaitStrategy(&wg, time.Second))
+
+	// A strategy that doesn't stop in time is abandoned
+	wg.Add(1)
+	assert.False(t, awaitStrategy(&wg, 10*time.Millisecond))
+	wg.Done()
+	assert.True(t, awaitStrategy(&wg, 0))
+}